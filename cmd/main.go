@@ -1,12 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/gigiozzz/driver-scanner/internal/command"
 	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/crypt"
+	"github.com/gigiozzz/driver-scanner/internal/device/driver"
+	"github.com/gigiozzz/driver-scanner/internal/device/lvm"
+	"github.com/gigiozzz/driver-scanner/internal/device/md"
+	"github.com/gigiozzz/driver-scanner/internal/device/parttable"
+	"github.com/gigiozzz/driver-scanner/internal/device/probe"
+	"github.com/gigiozzz/driver-scanner/internal/device/statfs"
+	"github.com/gigiozzz/driver-scanner/internal/device/udev"
 	"github.com/gigiozzz/driver-scanner/internal/provider"
 	"github.com/gigiozzz/driver-scanner/internal/service"
 )
@@ -17,12 +30,43 @@ func main() {
 
 	log.Debug().Msg("starting driver-scanner")
 
-	deviceProvider := device.NewLsblkProvider()
+	// DEVICE_PROVIDER selects how block devices are listed: auto, lsblk or sysfs.
+	deviceProvider, err := device.NewBlockDeviceProvider(os.Getenv("DEVICE_PROVIDER"))
+	if err != nil {
+		log.Error().Err(err).Msg("invalid device provider")
+		os.Exit(1)
+	}
 	mountProvider := device.NewSystemMountInfoProvider()
-	scanner := service.NewDeviceScanner(deviceProvider, mountProvider)
+	// UDEV_ROOT points at the host /run/udev and /dev when running in a container (e.g. /host).
+	udevRoot := os.Getenv("UDEV_ROOT")
+	// SYSFS_ROOT is where the host sysfs is mounted, the default of --sysfs-root.
+	defaults := command.Settings{SysRoot: os.Getenv("SYSFS_ROOT")}
+	// STATFS_TIMEOUT bounds each statfs call (e.g. 500ms), the default of --statfs-timeout.
+	if value := os.Getenv("STATFS_TIMEOUT"); value != "" {
+		if defaults.StatfsTimeout, err = time.ParseDuration(value); err != nil {
+			log.Error().Err(err).Msg("invalid STATFS_TIMEOUT")
+			os.Exit(1)
+		}
+	}
+	newScanner := func(settings command.Settings) service.Scanner {
+		return service.NewDeviceScanner(deviceProvider, mountProvider,
+			service.WithEnrichers(probe.NewEnricher(), parttable.NewEnricher(), udev.NewEnricher(udevRoot, settings.SysRoot),
+				driver.NewEnricher(settings.SysRoot), lvm.NewEnricher(settings.SysRoot), md.NewEnricher(settings.SysRoot),
+				crypt.NewEnricher(settings.SysRoot), statfs.NewEnricher(settings.StatfsTimeout)))
+	}
+
+	// Cancel running scans (and kill lsblk) on Ctrl-C or SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	rootCmd := command.NewRootCommand(scanner)
-	if err := rootCmd.Execute(); err != nil {
+	rootCmd := command.NewRootCommand(defaults, newScanner)
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		stop()
+		var exitErr *command.ExitError
+		if errors.As(err, &exitErr) {
+			log.Debug().Err(err).Msg("command completed with problems")
+			os.Exit(exitErr.Code)
+		}
 		log.Error().Err(err).Msg("command failed")
 		os.Exit(1)
 	}
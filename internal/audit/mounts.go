@@ -0,0 +1,210 @@
+// Package audit checks the system against declarative security rules.
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/mounttable"
+)
+
+// Rule statuses.
+const (
+	// StatusPass is the status of a rule every matching mount satisfies.
+	StatusPass = "pass"
+	// StatusFail is the status of a rule with at least one violation.
+	StatusFail = "fail"
+	// StatusSkip is the status of a rule no mount matches, when the mount is optional.
+	StatusSkip = "skip"
+)
+
+// MountRule is a declarative rule on the options of the mounts it matches, e.g.
+// "/tmp must be nodev,nosuid,noexec" or "every xfs mount below /data must use noatime".
+type MountRule struct {
+	// ID identifies the rule in reports (e.g. "tmp-options").
+	ID string `json:"id"`
+	// Description says what the rule checks and why.
+	Description string `json:"description,omitempty"`
+	// Match selects the mounts the rule applies to. An empty filter matches every mount.
+	Match mounttable.Filter `json:"match"`
+	// Require lists the options every matching mount must have, as checked by
+	// mounttable.Mount.HasOption. "key" matches the flag or any value of key, "key=value"
+	// only that value.
+	Require []string `json:"require,omitempty"`
+	// Forbid lists the options no matching mount may have (e.g. "rw").
+	Forbid []string `json:"forbid,omitempty"`
+	// Mounted makes the rule fail when no mount matches, e.g. to require /tmp to be a
+	// separate mount. Otherwise the rule is skipped.
+	Mounted bool `json:"mounted,omitempty"`
+}
+
+// RuleFile is the format of a rules file, in YAML or JSON.
+type RuleFile struct {
+	// Rules are the rules of the file, checked in order.
+	Rules []MountRule `json:"rules"`
+}
+
+// LoadMountRules reads and validates the rules of a YAML or JSON rules file.
+// Unknown fields are rejected so that a misspelled option does not silently disable a check.
+func LoadMountRules(path string) ([]MountRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	var file RuleFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", path, err)
+	}
+	if len(file.Rules) == 0 {
+		return nil, fmt.Errorf("rules file %s has no rules", path)
+	}
+	if err := ValidateMountRules(file.Rules); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+	return file.Rules, nil
+}
+
+// ValidateMountRules checks that every rule has a unique ID and checks something.
+func ValidateMountRules(rules []MountRule) error {
+	seen := make(map[string]bool, len(rules))
+	var errs []error
+	for i, rule := range rules {
+		switch {
+		case rule.ID == "":
+			errs = append(errs, fmt.Errorf("rule %d has no id", i+1))
+		case seen[rule.ID]:
+			errs = append(errs, fmt.Errorf("duplicate rule id %q", rule.ID))
+		case len(rule.Require) == 0 && len(rule.Forbid) == 0 && !rule.Mounted:
+			errs = append(errs, fmt.Errorf("rule %q checks nothing, set require, forbid or mounted", rule.ID))
+		}
+		seen[rule.ID] = true
+		for _, option := range slices.Concat(rule.Require, rule.Forbid, rule.Match.Options) {
+			if strings.TrimSpace(option) == "" || strings.Contains(option, ",") {
+				errs = append(errs, fmt.Errorf("rule %q has an invalid option %q, list options one by one", rule.ID, option))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RuleResult is the outcome of one rule.
+type RuleResult struct {
+	// ID is the ID of the rule.
+	ID string `json:"id"`
+	// Description is the description of the rule.
+	Description string `json:"description,omitempty"`
+	// Status is StatusPass, StatusFail or StatusSkip.
+	Status string `json:"status"`
+	// Mounts are the mount points the rule was checked on.
+	Mounts []string `json:"mounts"`
+	// Violations are the mounts breaking the rule, or a single violation without a
+	// mount when a required mount is missing.
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Violation is a mount breaking a rule.
+type Violation struct {
+	// Target is the mount point, or the mount points the rule matches for a missing mount.
+	Target string `json:"target,omitempty"`
+	// Source is the mounted device or remote.
+	Source string `json:"source,omitempty"`
+	// FSType is the filesystem type.
+	FSType string `json:"fstype,omitempty"`
+	// Missing lists the required options the mount does not have.
+	Missing []string `json:"missing,omitempty"`
+	// Forbidden lists the forbidden options the mount has.
+	Forbidden []string `json:"forbidden,omitempty"`
+	// NotMounted is true when the rule requires a mount and none matches.
+	NotMounted bool `json:"notMounted,omitempty"`
+}
+
+// Problem describes the violation in words (e.g. "missing nodev,noexec").
+func (v Violation) Problem() string {
+	if v.NotMounted {
+		return "not a separate mount"
+	}
+	var parts []string
+	if len(v.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(v.Missing, ","))
+	}
+	if len(v.Forbidden) > 0 {
+		parts = append(parts, "has "+strings.Join(v.Forbidden, ","))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CheckMounts checks every rule against the visible mounts, in rule order. Mounts hidden
+// by another mount stacked on the same mount point are not checked: their files cannot
+// be reached through the mount point.
+func CheckMounts(rules []MountRule, mounts []mounttable.Mount) []RuleResult {
+	mounts = visibleMounts(mounts)
+	results := make([]RuleResult, 0, len(rules))
+	for _, rule := range rules {
+		result := RuleResult{ID: rule.ID, Description: rule.Description, Status: StatusPass, Mounts: []string{}}
+		for _, m := range rule.Match.Apply(mounts) {
+			result.Mounts = append(result.Mounts, m.Target)
+			violation := Violation{Target: m.Target, Source: m.Source, FSType: m.FSType}
+			for _, option := range rule.Require {
+				if !m.HasOption(option) {
+					violation.Missing = append(violation.Missing, option)
+				}
+			}
+			for _, option := range rule.Forbid {
+				if m.HasOption(option) {
+					violation.Forbidden = append(violation.Forbidden, option)
+				}
+			}
+			if len(violation.Missing) > 0 || len(violation.Forbidden) > 0 {
+				result.Violations = append(result.Violations, violation)
+			}
+		}
+		switch {
+		case len(result.Mounts) == 0 && rule.Mounted:
+			result.Violations = []Violation{{Target: strings.Join(rule.Match.Targets, ","), NotMounted: true}}
+			result.Status = StatusFail
+		case len(result.Mounts) == 0:
+			result.Status = StatusSkip
+		case len(result.Violations) > 0:
+			result.Status = StatusFail
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Failed returns the IDs of the failed rules.
+func Failed(results []RuleResult) []string {
+	failed := []string{}
+	for _, result := range results {
+		if result.Status == StatusFail {
+			failed = append(failed, result.ID)
+		}
+	}
+	return failed
+}
+
+// visibleMounts drops the mounts covered by a mount stacked on the same mount point.
+func visibleMounts(mounts []mounttable.Mount) []mounttable.Mount {
+	targets := make(map[int]string, len(mounts))
+	for _, m := range mounts {
+		targets[m.ID] = m.Target
+	}
+	covered := make(map[int]bool)
+	for _, m := range mounts {
+		if target, ok := targets[m.ParentID]; ok && m.ParentID != m.ID && target == m.Target {
+			covered[m.ParentID] = true
+		}
+	}
+	visible := make([]mounttable.Mount, 0, len(mounts))
+	for _, m := range mounts {
+		if !covered[m.ID] {
+			visible = append(visible, m)
+		}
+	}
+	return visible
+}
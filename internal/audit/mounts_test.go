@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/mounttable"
+)
+
+func testMounts(t *testing.T) []mounttable.Mount {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "mountinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := device.ParseMountInfo(f)
+	if err != nil {
+		t.Fatalf("ParseMountInfo: %v", err)
+	}
+	return mounttable.FromEntries(entries)
+}
+
+func TestCheckMounts_Presets(t *testing.T) {
+	rules, err := Preset(PresetCISLevel2)
+	if err != nil {
+		t.Fatalf("Preset: %v", err)
+	}
+	if err := ValidateMountRules(rules); err != nil {
+		t.Fatalf("invalid preset: %v", err)
+	}
+
+	statuses := make(map[string]string)
+	results := CheckMounts(rules, testMounts(t))
+	for _, result := range results {
+		statuses[result.ID] = result.Status
+	}
+	want := map[string]string{
+		"tmp-separate-mount":           StatusPass,
+		"tmp-options":                  StatusFail,
+		"dev-shm-separate-mount":       StatusPass,
+		"dev-shm-options":              StatusPass, // the mount stacked on top is checked
+		"home-options":                 StatusSkip,
+		"var-options":                  StatusPass,
+		"var-tmp-options":              StatusSkip,
+		"var-log-options":              StatusSkip,
+		"var-log-audit-options":        StatusSkip,
+		"home-separate-mount":          StatusFail,
+		"var-separate-mount":           StatusPass,
+		"var-tmp-separate-mount":       StatusFail,
+		"var-log-separate-mount":       StatusFail,
+		"var-log-audit-separate-mount": StatusFail,
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("got %v\nwant %v", statuses, want)
+	}
+
+	tmp := results[1]
+	if len(tmp.Violations) != 1 || tmp.Violations[0].Problem() != "missing noexec" {
+		t.Errorf("unexpected /tmp violations %+v", tmp.Violations)
+	}
+	home := results[9]
+	if len(home.Violations) != 1 || !home.Violations[0].NotMounted || home.Violations[0].Target != "/home" {
+		t.Errorf("unexpected /home violations %+v", home.Violations)
+	}
+	if failed := Failed(results); len(failed) != 5 || failed[0] != "tmp-options" {
+		t.Errorf("unexpected failed rules %v", failed)
+	}
+}
+
+func TestCheckMounts_ReadOnlyBindMount(t *testing.T) {
+	mounts := mounttable.FromEntries([]device.MountEntry{{
+		MountID: 30, ParentID: 21, MountPoint: "/srv/data", Root: "/data", FSType: "xfs",
+		Source: "/dev/mapper/vg0-a", Options: "ro,nosuid,relatime", SuperOptions: "rw,attr2",
+	}})
+	rules := []MountRule{
+		{ID: "srv-data-ro", Match: mounttable.Filter{Targets: []string{"/srv/data"}}, Require: []string{"ro", "attr2"}, Forbid: []string{"rw"}},
+	}
+	if results := CheckMounts(rules, mounts); results[0].Status != StatusPass {
+		t.Errorf("the superblock rw must not make a read-only bind mount rw, got %+v", results[0].Violations)
+	}
+}
+
+func TestLoadMountRules(t *testing.T) {
+	rules, err := LoadMountRules(filepath.Join("testdata", "rules.yaml"))
+	if err != nil {
+		t.Fatalf("LoadMountRules: %v", err)
+	}
+	results := CheckMounts(rules, testMounts(t))
+	if results[0].Status != StatusFail || len(results[0].Violations) != 1 || results[0].Violations[0].Target != "/data/b" {
+		t.Errorf("unexpected xfs result %+v", results[0])
+	}
+	if !reflect.DeepEqual(results[0].Mounts, []string{"/data/a", "/data/b"}) {
+		t.Errorf("unexpected checked mounts %v", results[0].Mounts)
+	}
+	if results[1].Status != StatusPass {
+		t.Errorf("the superblock options must be checked, got %+v", results[1])
+	}
+}
+
+func TestLoadMountRules_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown field": "rules:\n  - id: a\n    requires: [nodev]\n",
+		"no rules":      "rules: []\n",
+		"no id":         "rules:\n  - require: [nodev]\n",
+		"duplicate id":  "rules:\n  - id: a\n    require: [nodev]\n  - id: a\n    require: [nosuid]\n",
+		"no check":      "rules:\n  - id: a\n    match:\n      targets: [/tmp]\n",
+		"joined option": "rules:\n  - id: a\n    require: [\"nodev,nosuid\"]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.yaml")
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadMountRules(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestPreset_Unknown(t *testing.T) {
+	if _, err := Preset("cis-level3"); err == nil || !strings.Contains(err.Error(), "cis-level1, cis-level2") {
+		t.Errorf("expected the supported presets in the error, got %v", err)
+	}
+}
+
+func TestPresets_Overlapping(t *testing.T) {
+	level2, err := Preset(PresetCISLevel2)
+	if err != nil {
+		t.Fatalf("Preset: %v", err)
+	}
+	rules, err := Presets([]string{PresetCISLevel1, PresetCISLevel2})
+	if err != nil {
+		t.Fatalf("Presets: %v", err)
+	}
+	if len(rules) != len(level2) {
+		t.Errorf("expected the %d rules of %s once each, got %d", len(level2), PresetCISLevel2, len(rules))
+	}
+	if err := ValidateMountRules(rules); err != nil {
+		t.Errorf("merged presets must be valid: %v", err)
+	}
+	if _, err := Presets([]string{PresetCISLevel1, "cis-level3"}); err == nil {
+		t.Error("expected an unknown preset error")
+	}
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/mounttable"
+)
+
+// Built-in presets, after the filesystem partition controls of the CIS Linux benchmarks.
+const (
+	// PresetCISLevel1 requires /tmp and /dev/shm to be separate mounts and the usual
+	// nodev, nosuid and noexec options on the temporary, home and log filesystems
+	// that are separate mounts.
+	PresetCISLevel1 = "cis-level1"
+	// PresetCISLevel2 adds to PresetCISLevel1 separate mounts for /home, /var, /var/tmp,
+	// /var/log and /var/log/audit.
+	PresetCISLevel2 = "cis-level2"
+)
+
+// DefaultPreset is the preset checked when no rules are given.
+const DefaultPreset = PresetCISLevel1
+
+var cisLevel1 = []MountRule{
+	separateMount("tmp-separate-mount", "/tmp"),
+	mountOptions("tmp-options", "/tmp", "nodev", "nosuid", "noexec"),
+	separateMount("dev-shm-separate-mount", "/dev/shm"),
+	mountOptions("dev-shm-options", "/dev/shm", "nodev", "nosuid", "noexec"),
+	mountOptions("home-options", "/home", "nodev", "nosuid"),
+	mountOptions("var-options", "/var", "nodev", "nosuid"),
+	mountOptions("var-tmp-options", "/var/tmp", "nodev", "nosuid", "noexec"),
+	mountOptions("var-log-options", "/var/log", "nodev", "nosuid", "noexec"),
+	mountOptions("var-log-audit-options", "/var/log/audit", "nodev", "nosuid", "noexec"),
+}
+
+var presets = map[string][]MountRule{
+	PresetCISLevel1: cisLevel1,
+	PresetCISLevel2: slices.Concat(cisLevel1, []MountRule{
+		separateMount("home-separate-mount", "/home"),
+		separateMount("var-separate-mount", "/var"),
+		separateMount("var-tmp-separate-mount", "/var/tmp"),
+		separateMount("var-log-separate-mount", "/var/log"),
+		separateMount("var-log-audit-separate-mount", "/var/log/audit"),
+	}),
+}
+
+// PresetNames returns the names of the built-in presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Preset returns a copy of the rules of a built-in preset.
+func Preset(name string) ([]MountRule, error) {
+	rules, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q, supported: %s", name, strings.Join(PresetNames(), ", "))
+	}
+	return slices.Clone(rules), nil
+}
+
+// Presets returns the rules of several built-in presets, in order. A rule included by more
+// than one preset, like the cis-level1 rules of cis-level2, is returned once.
+func Presets(names []string) ([]MountRule, error) {
+	var rules []MountRule
+	seen := make(map[string]bool)
+	for _, name := range names {
+		preset, err := Preset(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range preset {
+			if !seen[rule.ID] {
+				seen[rule.ID] = true
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules, nil
+}
+
+// separateMount returns a rule requiring target to be a mount point.
+func separateMount(id, target string) MountRule {
+	return MountRule{
+		ID:          id,
+		Description: fmt.Sprintf("%s must be a separate mount", target),
+		Match:       mounttable.Filter{Targets: []string{target}},
+		Mounted:     true,
+	}
+}
+
+// mountOptions returns a rule requiring options on target, skipped when it is not a mount point.
+func mountOptions(id, target string, options ...string) MountRule {
+	return MountRule{
+		ID:          id,
+		Description: fmt.Sprintf("%s must be mounted %s", target, strings.Join(options, ",")),
+		Match:       mounttable.Filter{Targets: []string{target}},
+		Require:     options,
+	}
+}
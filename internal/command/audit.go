@@ -0,0 +1,187 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/audit"
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/mounttable"
+	"github.com/gigiozzz/driver-scanner/internal/output"
+)
+
+// AuditMountsOptions holds the configuration for the audit mounts command.
+type AuditMountsOptions struct {
+	// Presets are the names of the built-in presets to check.
+	Presets []string
+	// RuleFiles are the paths of the rules files to check.
+	RuleFiles []string
+	// Output is the output format, one of output.ReportFormats.
+	Output        string
+	MountProvider device.MountInfoProvider
+	Out           io.Writer
+}
+
+// Run checks the mounts against the rules of the presets and the rules files, or of
+// audit.DefaultPreset when neither is given, and prints the results. It returns an
+// *ExitError with ExitStatusCheckFailed when a rule fails.
+func (o *AuditMountsOptions) Run(ctx context.Context) error {
+	presets := o.Presets
+	if len(presets) == 0 && len(o.RuleFiles) == 0 {
+		presets = []string{audit.DefaultPreset}
+	}
+	rules, err := audit.Presets(presets)
+	if err != nil {
+		return err
+	}
+	for _, path := range o.RuleFiles {
+		file, err := audit.LoadMountRules(path)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, file...)
+	}
+	if err := audit.ValidateMountRules(rules); err != nil {
+		return err
+	}
+
+	entries, err := o.MountProvider.GetMounts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read mounts: %w", err)
+	}
+	results := audit.CheckMounts(rules, mounttable.FromEntries(entries))
+
+	host, err := os.Hostname()
+	if err != nil {
+		log.Debug().Err(err).Msg("cannot read hostname")
+	}
+	report := output.NewMountAuditReport(results, output.AuditMetadata{
+		Host:        host,
+		Timestamp:   time.Now().UTC(),
+		ToolVersion: Version,
+		Presets:     presets,
+		RuleFiles:   o.RuleFiles,
+	})
+	log.Info().
+		Int("ruleCount", len(rules)).
+		Int("failedCount", len(report.Failed)).
+		Msg("mount rules checked")
+	if err := output.PrintMountAuditReport(o.Out, o.Output, report); err != nil {
+		return err
+	}
+	if len(report.Failed) > 0 {
+		return &ExitError{
+			Code:   ExitStatusCheckFailed,
+			Reason: fmt.Sprintf("failed rules: %s", strings.Join(report.Failed, ", ")),
+		}
+	}
+	return nil
+}
+
+// newAuditCommand creates the "audit" command grouping the security checks.
+func newAuditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Check the system against security rules",
+		Long: `Check the system against security rules.
+
+Each subcommand checks one area and exits with status 4 when a rule fails.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.AddCommand(newAuditMountsCommand())
+	return cmd
+}
+
+// newAuditMountsCommand creates the "audit mounts" subcommand.
+func newAuditMountsCommand() *cobra.Command {
+	o := &AuditMountsOptions{}
+	var mountInfoPath string
+
+	cmd := &cobra.Command{
+		Use:   "mounts",
+		Short: "Check the mount options against rules and CIS presets",
+		Long: `Check the mount options against rules and CIS presets.
+
+Rules select mounts like the mounts command (mount points, filesystem types,
+sources, options) and list the options the selected mounts must have or must
+not have. The ro, rw, nosuid, nodev, noexec and access time flags are checked
+on the per-mount options, so a read-only bind mount is ro even when its
+filesystem is mounted rw elsewhere; the other options are also looked up among
+the superblock options. A rule can also require a mount point to be a separate
+mount. Only the top mount of a mount point is checked when several are stacked
+on it.
+
+The built-in presets follow the filesystem controls of the CIS Linux
+benchmarks:
+
+  cis-level1  /tmp and /dev/shm are separate mounts; /tmp, /dev/shm, /var/tmp,
+              /var/log and /var/log/audit are nodev,nosuid,noexec and /home and
+              /var are nodev,nosuid when they are separate mounts
+  cis-level2  cis-level1, and /home, /var, /var/tmp, /var/log and
+              /var/log/audit are separate mounts
+
+Rules files are YAML or JSON:
+
+  rules:
+    - id: tmp-options
+      description: /tmp must be nodev,nosuid,noexec
+      match:
+        targets: [/tmp]
+      require: [nodev, nosuid, noexec]
+    - id: xfs-data-noatime
+      match:
+        fstypes: [xfs]
+        targetPrefix: /data
+      require: [noatime]
+
+Without --preset or --rules the cis-level1 preset is checked. The command exits
+with status 4 when a rule fails.`,
+		Example: `  # Check the CIS level 1 rules
+  driver-scanner audit mounts
+
+  # Check the CIS level 2 rules and site rules, as JSON evidence
+  driver-scanner audit mounts --preset cis-level2 --rules /etc/driver-scanner/mounts.yaml -o json
+
+  # Audit the mounts of the host from a container
+  driver-scanner audit mounts --mountinfo /host/proc/1/mountinfo`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Presets = normalizeList(o.Presets, strings.ToLower)
+			o.RuleFiles = normalizeList(o.RuleFiles, nil)
+			o.MountProvider = device.NewSystemMountInfoProvider()
+			if mountInfoPath != "" {
+				o.MountProvider = device.NewFileMountInfoProvider(mountInfoPath)
+			}
+			o.Out = cmd.OutOrStdout()
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			err := o.Run(ctx)
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				// The failed rules have already been printed.
+				cmd.SilenceErrors = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.Presets, "preset", nil,
+		"built-in rule presets to check, repeatable or comma-separated: "+strings.Join(audit.PresetNames(), ", "))
+	cmd.Flags().StringSliceVar(&o.RuleFiles, "rules", nil, "YAML or JSON rules files to check, repeatable or comma-separated")
+	cmd.Flags().StringVar(&mountInfoPath, "mountinfo", "",
+		"mountinfo file to read (e.g. /host/proc/1/mountinfo), defaults to the mounts of this process")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatTable,
+		"output format: "+strings.Join(output.ReportFormats, ", "))
+
+	return cmd
+}
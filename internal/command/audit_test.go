@@ -0,0 +1,61 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+func TestAuditMountsOptions_Run_FailedRulesSetExitStatus(t *testing.T) {
+	var out bytes.Buffer
+	o := &AuditMountsOptions{
+		RuleFiles:     []string{filepath.Join("..", "audit", "testdata", "rules.yaml")},
+		Output:        "json",
+		MountProvider: device.NewFileMountInfoProvider(filepath.Join("..", "audit", "testdata", "mountinfo")),
+		Out:           &out,
+	}
+
+	err := o.Run(context.Background())
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitStatusCheckFailed {
+		t.Fatalf("expected exit status %d, got %v", ExitStatusCheckFailed, err)
+	}
+	if exitErr.Reason != "failed rules: xfs-data-noatime" {
+		t.Errorf("unexpected reason %q", exitErr.Reason)
+	}
+	if !strings.Contains(out.String(), `"target": "/data/b"`) {
+		t.Errorf("the report must still be printed, got:\n%s", out.String())
+	}
+}
+
+func TestAuditMountsOptions_Run_DefaultPreset(t *testing.T) {
+	var out bytes.Buffer
+	o := &AuditMountsOptions{
+		MountProvider: device.NewFileMountInfoProvider(filepath.Join("..", "audit", "testdata", "mountinfo")),
+		Out:           &out,
+	}
+	err := o.Run(context.Background())
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Reason != "failed rules: tmp-options" {
+		t.Fatalf("expected the cis-level1 /tmp options to fail, got %v", err)
+	}
+
+	o.Presets = []string{"cis-level1", "cis-level2"}
+	if err := o.Run(context.Background()); errors.As(err, &exitErr) {
+		if strings.Contains(exitErr.Reason, "tmp-options, tmp-options") {
+			t.Errorf("overlapping presets must check each rule once, got %q", exitErr.Reason)
+		}
+	} else {
+		t.Errorf("expected overlapping presets to be checked, got %v", err)
+	}
+
+	o.Presets = []string{"cis-level3"}
+	if err := o.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown preset") {
+		t.Errorf("expected an unknown preset error, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/boot"
+	"github.com/gigiozzz/driver-scanner/internal/output"
+)
+
+// BootDisksOptions holds the configuration for the boot-disks command.
+type BootDisksOptions struct {
+	// SysRoot is the mount point of sysfs to read.
+	SysRoot string
+	// DevRoot is the directory holding the device nodes to read partition tables from.
+	DevRoot string
+	// MountInfoPath is a mountinfo file to read instead of the mounts of the current process.
+	MountInfoPath string
+	// Output is the output format, one of output.ReportFormats.
+	Output string
+	Out    io.Writer
+	ErrOut io.Writer
+}
+
+// Run resolves the boot disks and prints them. Disks whose partition table could not be
+// read are printed to ErrOut and set the exit status to ExitStatusWarning.
+func (o *BootDisksOptions) Run(ctx context.Context) error {
+	var mounts device.MountInfoProvider = device.NewSystemMountInfoProvider()
+	if o.MountInfoPath != "" {
+		mounts = device.NewFileMountInfoProvider(o.MountInfoPath)
+	}
+	result, err := boot.NewResolver(mounts, o.SysRoot, o.DevRoot).Resolve(ctx)
+	if err != nil {
+		return err
+	}
+	log.Info().
+		Int("mountCount", len(result.Mounts)).
+		Int("espCount", len(result.ESPs)).
+		Int("diskCount", len(result.Disks)).
+		Msg("boot disks resolved")
+
+	if err := output.PrintBootDisksReport(o.Out, o.Output, output.NewBootDisksReport(result)); err != nil {
+		return err
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintln(o.ErrOut, "warning: "+warning)
+	}
+	if len(result.Warnings) > 0 {
+		return &ExitError{Code: ExitStatusWarning, Reason: "boot disks resolved with warnings"}
+	}
+	return nil
+}
+
+// newBootDisksCommand creates the "boot-disks" subcommand.
+func newBootDisksCommand() *cobra.Command {
+	o := &BootDisksOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "boot-disks",
+		Short: "Show the physical disks holding /, /boot and the EFI System Partitions",
+		Long: `Show the physical disks holding /, /boot and the EFI System Partitions.
+
+The filesystems mounted on /, /boot and /boot/efi are followed down through
+logical volumes, crypt mappings, md arrays and partitions to the disks they
+are built on. The partition table of every disk is read to find the EFI
+System Partitions by their GPT type GUID, mounted or not, so that a mirrored
+ESP on a second disk is listed too. Reading partition tables needs read access
+to the device nodes, usually root.`,
+		Example: `  # Which disks must not be pulled?
+  sudo driver-scanner boot-disks
+
+  # Resolve the host boot disks from a container with /sys, /dev and /proc of the host mounted
+  driver-scanner boot-disks --sysfs-root /host/sys --dev-root /host/dev --mountinfo /host/proc/1/mountinfo -o json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.SysRoot = settings.SysRoot
+			o.Out = cmd.OutOrStdout()
+			o.ErrOut = cmd.ErrOrStderr()
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			err := o.Run(ctx)
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				// The warnings have already been printed.
+				cmd.SilenceErrors = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&o.DevRoot, "dev-root", "/dev", "directory holding the device nodes")
+	cmd.Flags().StringVar(&o.MountInfoPath, "mountinfo", "",
+		"mountinfo file to read (e.g. /host/proc/1/mountinfo), defaults to the mounts of this process")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatTable,
+		"output format: "+strings.Join(output.ReportFormats, ", "))
+
+	return cmd
+}
@@ -0,0 +1,86 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/output"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+// DriversOptions holds the configuration for the drivers command.
+type DriversOptions struct {
+	// Output is the output format, one of output.ReportFormats.
+	Output  string
+	Scanner service.Scanner
+	Out     io.Writer
+	ErrOut  io.Writer
+}
+
+// Run scans every device and prints them grouped by controller and driver.
+// Diagnostics are printed to ErrOut and set the exit status like the scan command.
+func (o *DriversOptions) Run(ctx context.Context) error {
+	result, err := o.Scanner.Scan(ctx, service.ScanFilter{})
+	if err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+
+	report := output.NewDriverReport(result, newScanMetadata(service.ScanFilter{}))
+	log.Info().Int("groupCount", len(report.Drivers)).Msg("devices grouped by driver")
+	if err := output.PrintDriverReport(o.Out, o.Output, report); err != nil {
+		return err
+	}
+
+	for _, diagnostic := range report.Diagnostics {
+		fmt.Fprintln(o.ErrOut, diagnostic.String())
+	}
+	return exitErrorFor(result.WorstSeverity())
+}
+
+// newDriversCommand creates the "drivers" subcommand.
+func newDriversCommand(scanner service.Scanner) *cobra.Command {
+	o := &DriversOptions{Scanner: scanner}
+
+	cmd := &cobra.Command{
+		Use:   "drivers",
+		Short: "Group block devices by kernel driver and host controller",
+		Long: `Group block devices by kernel driver and host controller.
+
+Each row is a PCI controller with the driver bound to it, the transport and the
+driver and module handling the disks behind it. Partitions and virtual devices
+(loop, device-mapper, md) are not listed. The exit status follows the scan
+command.`,
+		Example: `  # Show which driver handles each disk
+  driver-scanner drivers
+
+  # Print the groups as JSON
+  driver-scanner drivers -o json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Out = cmd.OutOrStdout()
+			o.ErrOut = cmd.ErrOrStderr()
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			err := o.Run(ctx)
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				cmd.SilenceErrors = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatTable,
+		"output format: "+strings.Join(output.ReportFormats, ", "))
+
+	return cmd
+}
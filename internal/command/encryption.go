@@ -0,0 +1,105 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/output"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+// EncryptionReportOptions holds the configuration for the encryption-report command.
+type EncryptionReportOptions struct {
+	// Exclude lists mount points that are not required to be encrypted (e.g. /boot/efi).
+	Exclude []string
+	// Output is the output format, one of output.ReportFormats.
+	Output  string
+	Scanner service.Scanner
+	Out     io.Writer
+	ErrOut  io.Writer
+}
+
+// Run scans every device and prints the encryption status of each mount. It returns an
+// *ExitError with ExitStatusCheckFailed when a mount is not encrypted, and
+// otherwise sets the exit status from the diagnostics like the scan command.
+func (o *EncryptionReportOptions) Run(ctx context.Context) error {
+	result, err := o.Scanner.Scan(ctx, service.ScanFilter{})
+	if err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+
+	report := output.NewEncryptionReport(result, newScanMetadata(service.ScanFilter{}), o.Exclude)
+	log.Info().
+		Int("mountCount", len(report.Mounts)).
+		Int("unencryptedCount", len(report.Unencrypted)).
+		Msg("encryption of mounts checked")
+	if err := output.PrintEncryptionReport(o.Out, o.Output, report); err != nil {
+		return err
+	}
+
+	for _, diagnostic := range report.Diagnostics {
+		fmt.Fprintln(o.ErrOut, diagnostic.String())
+	}
+	if len(report.Unencrypted) > 0 {
+		return &ExitError{
+			Code:   ExitStatusCheckFailed,
+			Reason: fmt.Sprintf("unencrypted mounts: %s", strings.Join(report.Unencrypted, ", ")),
+		}
+	}
+	return exitErrorFor(result.WorstSeverity())
+}
+
+// newEncryptionReportCommand creates the "encryption-report" subcommand.
+func newEncryptionReportCommand(scanner service.Scanner) *cobra.Command {
+	o := &EncryptionReportOptions{Scanner: scanner}
+
+	cmd := &cobra.Command{
+		Use:   "encryption-report",
+		Short: "Check that every mounted filesystem sits on an encrypted device",
+		Long: `Check that every mounted filesystem sits on an encrypted device.
+
+Each mounted filesystem is followed down to its physical disks through every
+layer (partitions, LVM, RAID) looking for a dm-crypt mapping. The LUKS header of
+the encrypted volume adds the cipher, key size, keyslots and key derivation
+function. Reading LUKS headers needs read access to the devices, usually root.
+
+A filesystem on several devices, like an LV spanning two PVs, is only encrypted
+when every path down to a disk crosses a dm-crypt mapping; the unencrypted
+paths of a partially encrypted mount are listed. The command exits with status
+4 when a mount is not encrypted, unless its mount point is excluded. Otherwise the exit status follows the scan command.`,
+		Example: `  # Check the encryption of every mount
+  driver-scanner encryption-report
+
+  # Produce evidence for an audit, the ESP cannot be encrypted
+  driver-scanner encryption-report --exclude /boot/efi -o json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Out = cmd.OutOrStdout()
+			o.ErrOut = cmd.ErrOrStderr()
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			err := o.Run(ctx)
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				cmd.SilenceErrors = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.Exclude, "exclude", nil,
+		"mount points not required to be encrypted, repeatable or comma-separated (e.g. /boot,/boot/efi)")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatTable,
+		"output format: "+strings.Join(output.ReportFormats, ", "))
+
+	return cmd
+}
@@ -0,0 +1,34 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+func TestEncryptionReportOptions_Run(t *testing.T) {
+	scanner := &fakeScanner{result: service.ScanResult{Devices: []device.BlockDevice{
+		{Path: "/dev/sda", Type: "disk"},
+		{Path: "/dev/sda1", Type: "part", Parents: []string{"/dev/sda"}, Mounts: []device.Mount{{MountPoint: "/boot"}}},
+		{Path: "/dev/sda2", Type: "part", Parents: []string{"/dev/sda"}},
+		{Path: "/dev/mapper/root", Type: "crypt", CryptType: "LUKS2", Parents: []string{"/dev/sda2"},
+			Mounts: []device.Mount{{MountPoint: "/"}}},
+	}}}
+
+	var out, errOut bytes.Buffer
+	o := &EncryptionReportOptions{Output: "json", Scanner: scanner, Out: &out, ErrOut: &errOut}
+	err := o.Run(context.Background())
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitStatusCheckFailed || exitErr.Reason != "unencrypted mounts: /boot" {
+		t.Fatalf("expected exit status %d for /boot, got %v", ExitStatusCheckFailed, err)
+	}
+
+	o.Exclude = []string{"/boot"}
+	if err := o.Run(context.Background()); err != nil {
+		t.Errorf("excluded mounts must not fail the check: %v", err)
+	}
+}
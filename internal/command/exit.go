@@ -0,0 +1,45 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+// Exit statuses of commands that completed but reported problems.
+const (
+	// ExitStatusWarning is the exit status when the worst diagnostic is a warning.
+	ExitStatusWarning = 2
+	// ExitStatusError is the exit status when the worst diagnostic is an error.
+	ExitStatusError = 3
+	// ExitStatusCheckFailed is the exit status of commands checking the health of the
+	// system when they found a problem (e.g. raid with a degraded array).
+	ExitStatusCheckFailed = 4
+)
+
+// ExitError is returned by a command that completed and printed its output,
+// but wants the process to exit with a non-zero status. The problems have
+// already been reported, so callers only need to exit with Code.
+type ExitError struct {
+	// Code is the process exit status.
+	Code int
+	// Reason summarizes why the status is non-zero.
+	Reason string
+}
+
+// Error returns the reason with the exit status.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s (exit status %d)", e.Reason, e.Code)
+}
+
+// exitErrorFor returns the ExitError matching the worst severity of a scan,
+// or nil if the scan had no warnings or errors.
+func exitErrorFor(worst service.Severity) error {
+	switch {
+	case worst >= service.SeverityError:
+		return &ExitError{Code: ExitStatusError, Reason: "scan completed with errors"}
+	case worst >= service.SeverityWarning:
+		return &ExitError{Code: ExitStatusWarning, Reason: "scan completed with warnings"}
+	}
+	return nil
+}
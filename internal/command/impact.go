@@ -0,0 +1,92 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/graph"
+	"github.com/gigiozzz/driver-scanner/internal/output"
+)
+
+// ImpactOptions holds the configuration for the impact command.
+type ImpactOptions struct {
+	// Device is the device whose loss is analysed: a device path, kernel name or /dev symlink.
+	Device string
+	// SysRoot is the mount point of sysfs to read.
+	SysRoot string
+	// Output is the output format, one of output.ImpactFormats.
+	Output        string
+	MountProvider device.MountInfoProvider
+	Out           io.Writer
+}
+
+// Run builds the dependency graph and prints what depends on the device.
+func (o *ImpactOptions) Run(ctx context.Context) error {
+	mounts, err := o.MountProvider.GetMounts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read mounts: %w", err)
+	}
+	g, err := graph.Build(ctx, o.SysRoot, mounts)
+	if err != nil {
+		return err
+	}
+	lost, ok := g.Lookup(o.Device)
+	if !ok {
+		return fmt.Errorf("device %q not found in %s", o.Device, o.SysRoot)
+	}
+
+	report := output.NewImpactReport(g, lost)
+	log.Info().
+		Str("device", report.Device).
+		Int("failing", report.Failing).
+		Int("degraded", report.Degraded).
+		Msg("impact computed")
+	return output.PrintImpactReport(o.Out, o.Output, report)
+}
+
+// newImpactCommand creates the "impact" subcommand.
+func newImpactCommand() *cobra.Command {
+	o := &ImpactOptions{MountProvider: device.NewSystemMountInfoProvider()}
+
+	cmd := &cobra.Command{
+		Use:   "impact <device>",
+		Short: "Show what fails or degrades if a device is pulled",
+		Long: `Show what fails or degrades if a device is pulled.
+
+The dependency graph is built from sysfs, following partitions, the slaves of
+device-mapper and md devices and the backing files of loop devices, up to the
+mounted filesystems. Every partition, logical volume, array, crypt mapping, loop
+device and mount built on the device is listed with its impact: "fail" when it
+stops working, "degrade" when it keeps working with less redundancy, like a
+RAID1 array losing one of its mirrors or a multipath device losing a path.
+Arrays already degraded and multipath paths already offline are taken into
+account: a RAID5 array missing a device fails with the next one.`,
+		Example: `  # What breaks if sda is pulled?
+  driver-scanner impact /dev/sda
+
+  # Draw the affected devices with Graphviz
+  driver-scanner impact sdb -o dot | dot -Tsvg > impact.svg`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.SysRoot = settings.SysRoot
+			o.Device = args[0]
+			o.Out = cmd.OutOrStdout()
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			return o.Run(ctx)
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatText,
+		"output format: "+strings.Join(output.ImpactFormats, ", "))
+
+	return cmd
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/lvm"
+	"github.com/gigiozzz/driver-scanner/internal/output"
+)
+
+// LVMOptions holds the configuration for the lvm command.
+type LVMOptions struct {
+	// SysRoot is the mount point of sysfs to read.
+	SysRoot string
+	// NoStatus skips "dmsetup status", leaving thin pool usage empty.
+	NoStatus bool
+	// Output is the output format, one of output.ReportFormats.
+	Output string
+	Out    io.Writer
+}
+
+// Run collects the volume groups and prints them.
+func (o *LVMOptions) Run(ctx context.Context) error {
+	collector := lvm.NewCollector(o.SysRoot)
+	if o.NoStatus {
+		collector.Status = nil
+	}
+	vgs, err := collector.Collect(ctx)
+	if err != nil {
+		return err
+	}
+	log.Info().Int("vgCount", len(vgs)).Msg("LVM volume groups collected")
+	return output.PrintLVMReport(o.Out, o.Output, output.NewLVMReport(vgs))
+}
+
+// newLVMCommand creates the "lvm" subcommand.
+func newLVMCommand() *cobra.Command {
+	o := &LVMOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "lvm",
+		Short: "Show LVM volume groups, physical volumes and logical volumes",
+		Long: `Show LVM volume groups, physical volumes and logical volumes.
+
+Active logical volumes and the physical volumes below them are found through
+device-mapper in sysfs, without the LVM tools. When the physical volumes are
+readable (usually as root), their LVM2 metadata adds the VG size and free space,
+the segment types and inactive logical volumes. Thin pool and snapshot usage is
+read with "dmsetup status" when dmsetup is installed.`,
+		Example: `  # Show volume groups and logical volumes
+  driver-scanner lvm
+
+  # Print thin pool usage and segments as JSON
+  driver-scanner lvm -o json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.SysRoot = settings.SysRoot
+			o.Out = cmd.OutOrStdout()
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			return o.Run(ctx)
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.NoStatus, "no-status", false, `do not run "dmsetup status" for thin pool usage`)
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatTable,
+		"output format: "+strings.Join(output.ReportFormats, ", "))
+
+	return cmd
+}
@@ -0,0 +1,171 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/mounttable"
+	"github.com/gigiozzz/driver-scanner/internal/output"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+// MountsOptions holds the configuration for the mounts command.
+type MountsOptions struct {
+	// Filter selects the mounts to show.
+	Filter mounttable.Filter
+	// NoUsage skips the statfs calls.
+	NoUsage bool
+	// StatfsTimeout bounds each statfs call.
+	StatfsTimeout time.Duration
+	// Output is the output format, one of output.MountFormats or a template format.
+	Output string
+	// Print customizes the column-based output.
+	Print         output.MountsOptions
+	MountProvider device.MountInfoProvider
+	Out           io.Writer
+	ErrOut        io.Writer
+}
+
+// Run reads the mount table, filters it, reads the usage of the remaining mounts and
+// prints them. Mounts whose usage could not be read are reported as warnings.
+func (o *MountsOptions) Run(ctx context.Context) error {
+	entries, err := o.MountProvider.GetMounts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read mounts: %w", err)
+	}
+	mounts := o.Filter.Apply(mounttable.FromEntries(entries))
+
+	var diagnostics []service.Diagnostic
+	if !o.NoUsage {
+		for _, err := range mounttable.ReadUsage(ctx, mounts, o.StatfsTimeout) {
+			diagnostics = append(diagnostics, service.Diagnostic{
+				Provider: "statfs",
+				Severity: service.SeverityWarning,
+				Err:      err,
+			})
+		}
+	}
+	log.Info().
+		Int("entryCount", len(entries)).
+		Int("mountCount", len(mounts)).
+		Int("diagnosticCount", len(diagnostics)).
+		Msg("mounts listed")
+
+	host, err := os.Hostname()
+	if err != nil {
+		log.Debug().Err(err).Msg("cannot read hostname")
+	}
+	report := output.NewMountsReport(mounts, diagnostics, output.MountMetadata{
+		Host:        host,
+		Timestamp:   time.Now().UTC(),
+		ToolVersion: Version,
+		Filter:      o.Filter,
+	})
+	if err := output.PrintMountsReport(o.Out, o.Output, report, o.Print); err != nil {
+		return err
+	}
+
+	result := service.ScanResult{Diagnostics: diagnostics}
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintln(o.ErrOut, diagnostic.String())
+	}
+	return exitErrorFor(result.WorstSeverity())
+}
+
+// newMountsCommand creates the "mounts" subcommand.
+func newMountsCommand() *cobra.Command {
+	o := &MountsOptions{}
+	var mountInfoPath, columns string
+
+	cmd := &cobra.Command{
+		Use:   "mounts",
+		Short: "List every mount with its usage, like findmnt",
+		Long: `List every mount with its usage, like findmnt.
+
+Unlike scan, which only shows the mounts of block devices, every entry of the
+mount table is listed: network filesystems, tmpfs, overlay, fuse, cgroup and
+the other virtual filesystems. The usage of each filesystem is read with
+statfs; each call is abandoned after --statfs-timeout so that a stale NFS mount
+cannot hang the command, and reported as a warning (exit status 2).
+
+The tree output arranges the mounts by parent mount ID. With --propagation the
+propagation type, peer group and master of each mount are shown as well.`,
+		Example: `  # Show the mount tree
+  driver-scanner mounts -o tree
+
+  # Show network filesystems and their usage
+  driver-scanner mounts --fstype nfs,nfs4,cifs
+
+  # Show the mounts below /var/lib/kubelet with their propagation
+  driver-scanner mounts --target-prefix /var/lib/kubelet --propagation
+
+  # Show read-only mounts without calling statfs
+  driver-scanner mounts --options ro --no-usage
+
+  # Print the targets of overlay mounts
+  driver-scanner mounts --fstype overlay -o jsonpath='{.mounts[*].target}'`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Filter.FSTypes = normalizeList(o.Filter.FSTypes, strings.ToLower)
+			o.Filter.ExcludeFSTypes = normalizeList(o.Filter.ExcludeFSTypes, strings.ToLower)
+			o.Filter.Targets = normalizeList(o.Filter.Targets, nil)
+			o.Filter.Sources = normalizeList(o.Filter.Sources, nil)
+			o.Filter.Options = normalizeList(o.Filter.Options, nil)
+			if fsType, ok := firstCommon(o.Filter.FSTypes, o.Filter.ExcludeFSTypes); ok {
+				return fmt.Errorf("filesystem type %q is both included and excluded", fsType)
+			}
+			if columns != "" {
+				var err error
+				if o.Print.Columns, err = output.ParseMountColumns(columns); err != nil {
+					return err
+				}
+			}
+			o.StatfsTimeout = settings.StatfsTimeout
+			o.MountProvider = device.NewSystemMountInfoProvider()
+			if mountInfoPath != "" {
+				o.MountProvider = device.NewFileMountInfoProvider(mountInfoPath)
+			}
+			o.Out = cmd.OutOrStdout()
+			o.ErrOut = cmd.ErrOrStderr()
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			err := o.Run(ctx)
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				// The diagnostics have already been printed.
+				cmd.SilenceErrors = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatTable,
+		"output format: "+strings.Join(output.MountFormats, ", ")+", "+strings.Join(output.TemplateFormats, "=..., ")+"=...")
+	cmd.Flags().StringVar(&columns, "columns", "",
+		"comma-separated columns for table, wide, tree and csv output: "+strings.Join(output.MountColumnNames(), ", "))
+	cmd.Flags().BoolVar(&o.Print.NoHeaders, "no-headers", false, "omit the header row of table, wide, tree and csv output")
+	cmd.Flags().BoolVar(&o.Print.Propagation, "propagation", false, "show the propagation, peer group and master of each mount")
+	cmd.Flags().StringSliceVar(&o.Filter.FSTypes, "fstype", nil, "filter by filesystem type, repeatable or comma-separated (e.g. nfs4,tmpfs)")
+	cmd.Flags().StringSliceVar(&o.Filter.ExcludeFSTypes, "exclude-fstype", nil, "exclude filesystem types, repeatable or comma-separated")
+	cmd.Flags().StringSliceVar(&o.Filter.Sources, "source", nil, "filter by mount source, repeatable or comma-separated (e.g. /dev/sda1)")
+	cmd.Flags().StringSliceVar(&o.Filter.Targets, "target", nil, "filter by mount point, repeatable or comma-separated (e.g. /tmp,/var/tmp)")
+	cmd.Flags().StringVar(&o.Filter.TargetPrefix, "target-prefix", "", "show the mounts at or below this path (e.g. /var)")
+	cmd.Flags().StringSliceVar(&o.Filter.Options, "options", nil,
+		"show the mounts having all these mount or superblock options, repeatable or comma-separated (e.g. ro,nosuid)")
+	cmd.Flags().BoolVar(&o.NoUsage, "no-usage", false, "do not read the filesystem usage with statfs")
+	cmd.Flags().StringVar(&mountInfoPath, "mountinfo", "",
+		"mountinfo file to read (e.g. /host/proc/1/mountinfo), defaults to the mounts of this process")
+
+	return cmd
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/parttable"
+	"github.com/gigiozzz/driver-scanner/internal/output"
+)
+
+// PartitionsOptions holds the configuration for the partitions command.
+type PartitionsOptions struct {
+	// Device is the disk device node or image file to read.
+	Device string
+	// Output is the output format, one of output.ReportFormats.
+	Output string
+	Out    io.Writer
+}
+
+// Run reads the partition table of the device and prints it.
+func (o *PartitionsOptions) Run() error {
+	table, err := parttable.ReadFile(o.Device)
+	if err != nil {
+		return err
+	}
+	return output.PrintPartitionsReport(o.Out, o.Output, output.NewPartitionsReport(o.Device, *table))
+}
+
+// newPartitionsCommand creates the "partitions" subcommand.
+func newPartitionsCommand() *cobra.Command {
+	o := &PartitionsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "partitions <device>",
+		Short: "Show the partition table of a disk or disk image",
+		Example: `  # Show the partitions of a disk
+  driver-scanner partitions /dev/sda
+
+  # Inspect a disk image file
+  driver-scanner partitions disk.img
+
+  # Print the GPT headers and partitions as JSON
+  driver-scanner partitions /dev/nvme0n1 -o json`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Device = args[0]
+			o.Out = cmd.OutOrStdout()
+			return o.Run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatTable,
+		"output format: "+strings.Join(output.ReportFormats, ", "))
+
+	return cmd
+}
@@ -0,0 +1,103 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/md"
+	"github.com/gigiozzz/driver-scanner/internal/output"
+)
+
+// RAIDOptions holds the configuration for the raid command.
+type RAIDOptions struct {
+	// SysRoot is the mount point of sysfs to read.
+	SysRoot string
+	// MdstatPath is the path of the mdstat file to read.
+	MdstatPath string
+	// Output is the output format, one of output.ReportFormats.
+	Output string
+	Out    io.Writer
+}
+
+// Run collects the md arrays and prints them. It returns an *ExitError with
+// ExitStatusCheckFailed when an array is degraded or inactive.
+func (o *RAIDOptions) Run(ctx context.Context) error {
+	collector := md.NewCollector(o.SysRoot)
+	if o.MdstatPath != "" {
+		collector.MdstatPath = o.MdstatPath
+	}
+	arrays, err := collector.Collect(ctx)
+	if err != nil {
+		return err
+	}
+	report := output.NewRAIDReport(arrays)
+	log.Info().
+		Int("arrayCount", len(arrays)).
+		Int("degradedCount", len(report.Degraded)).
+		Int("inactiveCount", len(report.Inactive)).
+		Msg("md arrays collected")
+	if err := output.PrintRAIDReport(o.Out, o.Output, report); err != nil {
+		return err
+	}
+	var reasons []string
+	if len(report.Degraded) > 0 {
+		reasons = append(reasons, fmt.Sprintf("degraded arrays: %s", strings.Join(report.Degraded, ", ")))
+	}
+	if len(report.Inactive) > 0 {
+		reasons = append(reasons, fmt.Sprintf("inactive arrays: %s", strings.Join(report.Inactive, ", ")))
+	}
+	if len(reasons) > 0 {
+		return &ExitError{Code: ExitStatusCheckFailed, Reason: strings.Join(reasons, "; ")}
+	}
+	return nil
+}
+
+// newRAIDCommand creates the "raid" subcommand.
+func newRAIDCommand() *cobra.Command {
+	o := &RAIDOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "raid",
+		Short: "Show md software RAID arrays and fail when one is degraded or inactive",
+		Long: `Show md software RAID arrays and fail when one is degraded or inactive.
+
+Arrays are read from /proc/mdstat, refined with the md attributes in sysfs:
+level, member devices and their roles, resync or recovery progress and the
+mismatch count of the last check. The command exits with status 4 when any
+array is degraded, or inactive because it could not be started, so it can be
+used as a health check.`,
+		Example: `  # Show the arrays
+  driver-scanner raid
+
+  # Check the arrays from a monitoring script
+  driver-scanner raid -o json || alert "degraded RAID"`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.SysRoot = settings.SysRoot
+			o.Out = cmd.OutOrStdout()
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			err := o.Run(ctx)
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				// The failed arrays have already been printed.
+				cmd.SilenceErrors = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&o.MdstatPath, "mdstat", "/proc/mdstat", "path of the mdstat file")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatTable,
+		"output format: "+strings.Join(output.ReportFormats, ", "))
+
+	return cmd
+}
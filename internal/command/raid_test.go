@@ -0,0 +1,47 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRAIDOptions_Run_DegradedSetsExitStatus(t *testing.T) {
+	var out bytes.Buffer
+	o := &RAIDOptions{
+		SysRoot:    t.TempDir(),
+		MdstatPath: filepath.Join("..", "device", "md", "testdata", "degraded.mdstat"),
+		Output:     "json",
+		Out:        &out,
+	}
+
+	err := o.Run(context.Background())
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitStatusCheckFailed {
+		t.Fatalf("expected exit status %d, got %v", ExitStatusCheckFailed, err)
+	}
+	if exitErr.Reason != "degraded arrays: /dev/md1, /dev/md2; inactive arrays: /dev/md127" {
+		t.Errorf("unexpected reason %q", exitErr.Reason)
+	}
+	if !strings.Contains(out.String(), `"degraded": [`) {
+		t.Errorf("the report must still be printed, got:\n%s", out.String())
+	}
+}
+
+func TestRAIDOptions_Run_Healthy(t *testing.T) {
+	var out bytes.Buffer
+	o := &RAIDOptions{
+		SysRoot:    t.TempDir(),
+		MdstatPath: filepath.Join("..", "device", "md", "testdata", "healthy.mdstat"),
+		Out:        &out,
+	}
+	if err := o.Run(context.Background()); err != nil {
+		t.Fatalf("healthy arrays must not fail: %v", err)
+	}
+	if !strings.Contains(out.String(), "/dev/md0  raid1   active  2/2    ok") {
+		t.Errorf("unexpected table:\n%s", out.String())
+	}
+}
@@ -1,9 +1,13 @@
 package command
 
 import (
+	"context"
+	"time"
+
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
+	"github.com/gigiozzz/driver-scanner/internal/device/statfs"
 	"github.com/gigiozzz/driver-scanner/internal/provider"
 	"github.com/gigiozzz/driver-scanner/internal/service"
 )
@@ -13,10 +17,36 @@ var (
 	debug bool
 	// verbose is set by the -v flag.
 	verbose bool
+	// timeout is set by the --timeout flag. Zero disables it.
+	timeout time.Duration
+	// settings are set by the --sysfs-root and --statfs-timeout flags.
+	settings Settings
 )
 
-// NewRootCommand creates the root cobra command for driver-scanner.
-func NewRootCommand(scanner service.Scanner) *cobra.Command {
+// Settings are the host paths and limits shared by the subcommands and the scanner.
+type Settings struct {
+	// SysRoot is the mount point of sysfs (e.g. "/host/sys" in a container).
+	SysRoot string
+	// StatfsTimeout bounds each statfs call.
+	StatfsTimeout time.Duration
+}
+
+// ScannerFactory builds the scanner from the settings, once the flags are parsed.
+type ScannerFactory func(Settings) service.Scanner
+
+// NewRootCommand creates the root cobra command for driver-scanner. defaults are the
+// defaults of the --sysfs-root and --statfs-timeout flags, /sys and statfs.DefaultTimeout
+// when empty; newScanner builds the scanner of the scan, drivers and encryption-report
+// commands.
+func NewRootCommand(defaults Settings, newScanner ScannerFactory) *cobra.Command {
+	if defaults.SysRoot == "" {
+		defaults.SysRoot = "/sys"
+	}
+	if defaults.StatfsTimeout <= 0 {
+		defaults.StatfsTimeout = statfs.DefaultTimeout
+	}
+	scanner := &lazyScanner{newScanner: newScanner}
+
 	rootCmd := &cobra.Command{
 		Use:   "driver-scanner",
 		Short: "Scan and list block devices with mount and filesystem information",
@@ -32,9 +62,49 @@ func NewRootCommand(scanner service.Scanner) *cobra.Command {
 
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug output")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0,
+		"abort when the device and mount providers do not answer within this duration (e.g. 30s, 0 disables)")
+	rootCmd.PersistentFlags().StringVar(&settings.SysRoot, "sysfs-root", defaults.SysRoot,
+		"mount point of sysfs (e.g. /host/sys in a container), overrides SYSFS_ROOT")
+	rootCmd.PersistentFlags().DurationVar(&settings.StatfsTimeout, "statfs-timeout", defaults.StatfsTimeout,
+		"abandon a statfs call after this duration, overrides STATFS_TIMEOUT")
 
 	rootCmd.AddCommand(newScanCommand(scanner))
+	rootCmd.AddCommand(newAuditCommand())
+	rootCmd.AddCommand(newBootDisksCommand())
+	rootCmd.AddCommand(newDriversCommand(scanner))
+	rootCmd.AddCommand(newEncryptionReportCommand(scanner))
+	rootCmd.AddCommand(newImpactCommand())
+	rootCmd.AddCommand(newLVMCommand())
+	rootCmd.AddCommand(newMountsCommand())
+	rootCmd.AddCommand(newPartitionsCommand())
+	rootCmd.AddCommand(newRAIDCommand())
+	rootCmd.AddCommand(newTopologyCommand())
 	rootCmd.AddCommand(newVersionCommand())
 
 	return rootCmd
 }
+
+// commandContext returns the context of cmd, bounded by the --timeout flag when it is set.
+// The caller must call the returned cancel function.
+func commandContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(cmd.Context())
+	}
+	log.Debug().Dur("timeout", timeout).Msg("applying command timeout")
+	return context.WithTimeout(cmd.Context(), timeout)
+}
+
+// lazyScanner builds the scanner on the first scan, after the flags are parsed.
+type lazyScanner struct {
+	newScanner ScannerFactory
+	scanner    service.Scanner
+}
+
+// Scan builds the scanner from the settings if needed and scans with it.
+func (s *lazyScanner) Scan(ctx context.Context, filter service.ScanFilter) (service.ScanResult, error) {
+	if s.scanner == nil {
+		s.scanner = s.newScanner(settings)
+	}
+	return s.scanner.Scan(ctx, filter)
+}
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/statfs"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+func TestNewRootCommand_SettingsReachTheScanner(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults Settings
+		args     []string
+		want     Settings
+	}{
+		{"built-in defaults", Settings{}, nil, Settings{SysRoot: "/sys", StatfsTimeout: statfs.DefaultTimeout}},
+		{"environment", Settings{SysRoot: "/host/sys", StatfsTimeout: time.Second}, nil,
+			Settings{SysRoot: "/host/sys", StatfsTimeout: time.Second}},
+		{"flags", Settings{SysRoot: "/host/sys"}, []string{"--sysfs-root", "/mnt/sys", "--statfs-timeout", "500ms"},
+			Settings{SysRoot: "/mnt/sys", StatfsTimeout: 500 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Settings
+			newScanner := func(s Settings) service.Scanner {
+				got = s
+				return &fakeScanner{result: service.ScanResult{Devices: []device.BlockDevice{{Name: "sda", Path: "/dev/sda"}}}}
+			}
+			cmd := NewRootCommand(tt.defaults, newScanner)
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetArgs(append([]string{"scan", "-o", "json"}, tt.args...))
+			if err := cmd.ExecuteContext(context.Background()); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got settings %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
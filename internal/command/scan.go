@@ -2,31 +2,81 @@ package command
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
-	"text/tabwriter"
+	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/probe"
+	"github.com/gigiozzz/driver-scanner/internal/expr"
+	"github.com/gigiozzz/driver-scanner/internal/output"
 	"github.com/gigiozzz/driver-scanner/internal/service"
 )
 
 // newScanCommand creates the "scan" subcommand.
 func newScanCommand(scanner service.Scanner) *cobra.Command {
 	var filter service.ScanFilter
+	var outputFormat string
+	var columns string
+	var sortBy string
+	var noHeaders bool
 
 	cmd := &cobra.Command{
 		Use:   "scan",
 		Short: "Scan block devices and display their information",
+		Long: `Scan block devices and display their information.
+
+Problems that do not prevent the scan, such as unreadable mount information or
+a failed enricher, are printed to stderr and included in JSON and YAML output.
+The exit status reflects the worst of them: 0 for none, 2 for warnings and 3
+for errors. Failures that prevent the scan exit with status 1.`,
+		Example: `  # List every block device
+  driver-scanner scan
+
+  # Show ext4 and xfs filesystems between 100G and 4T with at least 20% free space
+  driver-scanner scan --fstype ext4,xfs --exclude-type loop,rom --min-size 100G --max-size 4T --max-used-percent 80
+
+  # Show selected columns, largest devices first
+  driver-scanner scan --columns uuid,path,fstype,size --sort-by -size
+
+  # Show partitions with less than 10 GiB available, excluding snap mounts
+  driver-scanner scan --where 'type == "part" && fsavail < 10Gi && !(mountpoint =~ "^/snap")'
+
+  # Show USB disks using the udev database
+  driver-scanner scan --where 'type == disk && udev.ID_BUS == usb' --columns path,vendor,model,wwn
+
+  # Show label, major:minor, model and every mount point
+  driver-scanner scan -o wide
+
+  # Print path and UUID of every device with a Go template
+  driver-scanner scan -o go-template='{{range .}}{{.Path}} {{.UUID}}{{"\n"}}{{end}}'
+
+  # Print only the device paths with JSONPath
+  driver-scanner scan -o jsonpath='{.devices[*].path}'`,
+		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			log.Info().
-				Str("fstype", filter.FSType).
+				Str("output", outputFormat).
+				Strs("fstype", filter.FSTypes).
+				Strs("excludeFstype", filter.ExcludeFSTypes).
+				Strs("type", filter.Types).
+				Strs("excludeType", filter.ExcludeTypes).
 				Str("minSize", filter.MinSize).
+				Str("maxSize", filter.MaxSize).
+				Str("minAvail", filter.MinAvail).
+				Float64("maxUsedPercent", filter.MaxUsedPercent).
+				Strs("label", filter.Labels).
+				Strs("uuid", filter.UUIDs).
 				Str("mountPoint", filter.MountPoint).
+				Str("where", filter.Where).
 				Msg("scan command invoked")
 
 			processedFilter, err := buildScanFilter(filter)
@@ -38,51 +88,188 @@ func newScanCommand(scanner service.Scanner) *cobra.Command {
 				return err
 			}
 
-			return runScan(scanner, processedFilter)
+			printerOptions := output.Options{NoHeaders: noHeaders}
+			if columns != "" {
+				printerOptions.Columns, err = output.ParseColumns(columns)
+				if err != nil {
+					return err
+				}
+			}
+
+			sortKeys, err := output.ParseSortKeys(sortBy)
+			if err != nil {
+				return err
+			}
+
+			printer, err := output.NewPrinter(outputFormat, printerOptions)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			err = runScan(ctx, cmd.OutOrStdout(), cmd.ErrOrStderr(), scanner, processedFilter, sortKeys, printer)
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				// The diagnostics have already been printed.
+				cmd.SilenceErrors = true
+			}
+			return err
 		},
 	}
 
-	cmd.Flags().StringVar(&filter.FSType, "fstype", "", "filter by filesystem type (e.g. ext4)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", output.FormatTable,
+		"output format: "+strings.Join(output.Formats, ", ")+", "+strings.Join(output.TemplateFormats, "=..., ")+"=...")
+	cmd.Flags().StringVar(&columns, "columns", "",
+		"comma-separated columns for table, wide and csv output: "+strings.Join(output.ColumnNames(), ", "))
+	cmd.Flags().StringVar(&sortBy, "sort-by", "", "comma-separated columns to sort by, prefix with - for descending (e.g. size,-fsavail)")
+	cmd.Flags().BoolVar(&noHeaders, "no-headers", false, "omit the header row of table, wide and csv output")
+	cmd.Flags().StringSliceVar(&filter.FSTypes, "fstype", nil, "filter by filesystem type, repeatable or comma-separated (e.g. ext4,xfs)")
+	cmd.Flags().StringSliceVar(&filter.ExcludeFSTypes, "exclude-fstype", nil, "exclude filesystem types, repeatable or comma-separated")
+	cmd.Flags().StringSliceVar(&filter.Types, "type", nil, "filter by device type, repeatable or comma-separated (e.g. disk,part)")
+	cmd.Flags().StringSliceVar(&filter.ExcludeTypes, "exclude-type", nil, "exclude device types, repeatable or comma-separated (e.g. loop,rom)")
 	cmd.Flags().StringVar(&filter.MinSize, "min-size", "", "filter by minimum device size (e.g. 1G, 500M)")
-	cmd.Flags().StringVar(&filter.MountPoint, "mount-point", "", "filter by mount point (substring match)")
+	cmd.Flags().StringVar(&filter.MaxSize, "max-size", "", "filter by maximum device size (e.g. 4T)")
+	cmd.Flags().StringVar(&filter.MinAvail, "min-avail", "", "filter by minimum available filesystem space (e.g. 10G)")
+	cmd.Flags().Float64Var(&filter.MaxUsedPercent, "max-used-percent", 0, "filter by maximum filesystem usage in percent (e.g. 80)")
+	cmd.Flags().StringSliceVar(&filter.Labels, "label", nil, "filter by filesystem label, repeatable or comma-separated")
+	cmd.Flags().StringSliceVar(&filter.UUIDs, "uuid", nil, "filter by filesystem UUID, repeatable or comma-separated")
+	cmd.Flags().StringVar(&filter.MountPoint, "mount-point", "", "filter by mount point (substring match against every mount of a device)")
+	cmd.Flags().StringVar(&filter.Where, "where", "",
+		"filter expression over device fields, e.g. 'type == \"part\" && fsavail < 10Gi' (fields: "+strings.Join(expr.FieldNames(), ", ")+")")
 
 	return cmd
 }
 
 // buildScanFilter processes and normalizes filter input from CLI flags.
-// Currently returns the filter as-is; extend here for input transformations.
+// List values are trimmed and type names lowercased; sizes and the --where expression
+// are parsed here, once, so that filtering does not parse them again.
 func buildScanFilter(raw service.ScanFilter) (service.ScanFilter, error) {
-	return raw, nil
+	filter := raw
+	filter.FSTypes = normalizeList(raw.FSTypes, strings.ToLower)
+	filter.ExcludeFSTypes = normalizeList(raw.ExcludeFSTypes, strings.ToLower)
+	filter.Types = normalizeList(raw.Types, strings.ToLower)
+	filter.ExcludeTypes = normalizeList(raw.ExcludeTypes, strings.ToLower)
+	filter.Labels = normalizeList(raw.Labels, nil)
+	filter.UUIDs = normalizeList(raw.UUIDs, nil)
+
+	var err error
+	if filter.MinSizeBytes, err = parseSizeFlag("min-size", raw.MinSize); err != nil {
+		return raw, err
+	}
+	if filter.MaxSizeBytes, err = parseSizeFlag("max-size", raw.MaxSize); err != nil {
+		return raw, err
+	}
+	if filter.MinAvailBytes, err = parseSizeFlag("min-avail", raw.MinAvail); err != nil {
+		return raw, err
+	}
+
+	if strings.TrimSpace(raw.Where) != "" {
+		whereExpr, err := expr.Parse(raw.Where)
+		if err != nil {
+			return raw, fmt.Errorf("invalid --where expression: %w", err)
+		}
+		filter.WhereExpr = whereExpr
+	}
+	return filter, nil
+}
+
+// normalizeList trims the values of a list flag, drops empty ones and applies
+// the optional transform.
+func normalizeList(values []string, transform func(string) string) []string {
+	var result []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if transform != nil {
+			value = transform(value)
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+// parseSizeFlag parses a size flag value via go-humanize. An empty value returns 0.
+func parseSizeFlag(name, value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	log.Debug().Str(name, value).Msg("parsing size")
+	size, err := humanize.ParseBytes(value)
+	if err != nil {
+		log.Debug().Str(name, value).Err(err).Msg("invalid size value")
+		return 0, fmt.Errorf("invalid %s value %q: %w", name, value, err)
+	}
+	return size, nil
 }
 
 // validateScanFilter validates the filter values before executing the scan.
+// It runs once, up front; applyFilters relies on a valid filter.
 func validateScanFilter(filter service.ScanFilter) error {
-	if filter.FSType != "" {
-		log.Debug().Str("fstype", filter.FSType).Msg("validating filesystem type")
+	// Excluded types are not validated: excluding a type that never occurs is harmless.
+	if len(filter.FSTypes) > 0 {
+		log.Debug().Strs("fstype", filter.FSTypes).Msg("validating filesystem types")
 		supportedTypes, err := readSupportedFileSystems()
 		if err != nil {
 			log.Debug().Err(err).Msg("cannot read supported filesystems")
 			return fmt.Errorf("cannot validate fstype: %w", err)
 		}
-		if !supportedTypes[strings.ToLower(filter.FSType)] {
-			log.Debug().Str("fstype", filter.FSType).Msg("unsupported filesystem type")
-			return fmt.Errorf("unsupported filesystem type %q, supported: %s",
-				filter.FSType, joinMapKeys(supportedTypes))
+		for _, fsType := range slices.Concat(probe.SignatureTypes, memberSignatureTypes) {
+			supportedTypes[strings.ToLower(fsType)] = true
+		}
+		for _, fsType := range filter.FSTypes {
+			if !supportedTypes[fsType] {
+				log.Debug().Str("fstype", fsType).Msg("unsupported filesystem type")
+				return fmt.Errorf("unsupported filesystem type %q, supported: %s",
+					fsType, joinMapKeys(supportedTypes))
+			}
 		}
-		log.Debug().Str("fstype", filter.FSType).Msg("filesystem type is valid")
+		log.Debug().Msg("filesystem types are valid")
+	}
+
+	if fsType, ok := firstCommon(filter.FSTypes, filter.ExcludeFSTypes); ok {
+		return fmt.Errorf("filesystem type %q is both included and excluded", fsType)
+	}
+	if deviceType, ok := firstCommon(filter.Types, filter.ExcludeTypes); ok {
+		return fmt.Errorf("device type %q is both included and excluded", deviceType)
+	}
+
+	if filter.MinSizeBytes > 0 && filter.MaxSizeBytes > 0 && filter.MinSizeBytes > filter.MaxSizeBytes {
+		return fmt.Errorf("min-size %q is larger than max-size %q", filter.MinSize, filter.MaxSize)
+	}
+
+	if filter.MaxUsedPercent < 0 || filter.MaxUsedPercent > 100 {
+		return fmt.Errorf("invalid max-used-percent value %g: must be between 0 and 100", filter.MaxUsedPercent)
 	}
 
-	if filter.MinSize != "" {
-		log.Debug().Str("minSize", filter.MinSize).Msg("validating min-size")
-		if _, err := humanize.ParseBytes(filter.MinSize); err != nil {
-			log.Debug().Str("minSize", filter.MinSize).Err(err).Msg("invalid min-size value")
-			return fmt.Errorf("invalid min-size value %q: %w", filter.MinSize, err)
+	if filter.WhereExpr != nil {
+		log.Debug().Str("where", filter.Where).Msg("validating where expression")
+		if err := filter.WhereExpr.Validate(); err != nil {
+			log.Debug().Str("where", filter.Where).Err(err).Msg("invalid where expression")
+			return fmt.Errorf("invalid --where expression: %w", err)
 		}
 	}
 
 	return nil
 }
 
+// firstCommon returns the first value of a that is also in b.
+func firstCommon(a, b []string) (string, bool) {
+	for _, value := range a {
+		if slices.Contains(b, value) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// memberSignatureTypes are the signatures lsblk reports for the members of volumes
+// that probe does not detect.
+var memberSignatureTypes = []string{"linux_raid_member", "zfs_member", "bcache", "BitLocker"}
+
 // readSupportedFileSystems reads /proc/filesystems and returns a set of supported types.
 func readSupportedFileSystems() (map[string]bool, error) {
 	log.Debug().Msg("reading /proc/filesystems")
@@ -123,43 +310,48 @@ func joinMapKeys(m map[string]bool) string {
 	return strings.Join(keys, ", ")
 }
 
-// runScan executes the scan and prints the results as a table.
-func runScan(scanner service.Scanner, filter service.ScanFilter) error {
-	devices, err := scanner.Scan(filter)
+// runScan executes the scan, sorts the devices and renders them with the given printer.
+// Diagnostics are printed to errOut; if there are any warnings or errors, an *ExitError
+// with the matching exit status is returned after the report has been printed.
+func runScan(ctx context.Context, out, errOut io.Writer, scanner service.Scanner, filter service.ScanFilter,
+	sortKeys []output.SortKey, printer output.Printer) error {
+	result, err := scanner.Scan(ctx, filter)
 	if err != nil {
 		return fmt.Errorf("scan failed: %w", err)
 	}
 
-	log.Info().Int("deviceCount", len(devices)).Msg("scan complete")
-	if len(devices) == 0 {
+	log.Info().
+		Int("deviceCount", len(result.Devices)).
+		Int("diagnosticCount", len(result.Diagnostics)).
+		Msg("scan complete")
+	if len(result.Devices) == 0 {
 		log.Warn().Msg("no devices matched the filter criteria")
 	}
 
-	printDeviceTable(devices)
-	return nil
+	output.SortDevices(result.Devices, sortKeys)
+	report := output.NewScanReport(result, newScanMetadata(filter))
+	if err := printer.Print(out, report); err != nil {
+		return err
+	}
+
+	for _, diagnostic := range report.Diagnostics {
+		fmt.Fprintln(errOut, diagnostic.String())
+	}
+	return exitErrorFor(result.WorstSeverity())
 }
 
-// printDeviceTable prints the device list in a formatted table.
-func printDeviceTable(devices []device.BlockDevice) {
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "UUID\tSERIAL\tDEVICE\tFSTYPE\tTYPE\tMOUNTPOINT\tSIZE\tFS SIZE\tFS AVAIL")
-	fmt.Fprintln(w, "----\t------\t------\t------\t----\t----------\t----\t-------\t--------")
-
-	for _, dev := range devices {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-			valueOrDash(dev.UUID),
-			valueOrDash(dev.Serial),
-			dev.Path,
-			valueOrDash(dev.FSType),
-			dev.Type,
-			valueOrDash(dev.MountPoint),
-			valueOrDash(dev.DeviceSize),
-			valueOrDash(dev.FileSystemSize),
-			valueOrDash(dev.FileSystemAvail),
-		)
-	}
-
-	w.Flush()
+// newScanMetadata describes the current scan for the report envelope.
+func newScanMetadata(filter service.ScanFilter) output.ScanMetadata {
+	host, err := os.Hostname()
+	if err != nil {
+		log.Debug().Err(err).Msg("cannot read hostname")
+	}
+	return output.ScanMetadata{
+		Host:        host,
+		Timestamp:   time.Now().UTC(),
+		ToolVersion: Version,
+		Filter:      filter,
+	}
 }
 
 // valueOrDash returns the value if non-empty, otherwise "-".
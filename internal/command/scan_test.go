@@ -0,0 +1,144 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/output"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+func TestBuildScanFilter(t *testing.T) {
+	filter, err := buildScanFilter(service.ScanFilter{
+		FSTypes:      []string{" EXT4", "xfs", ""},
+		ExcludeTypes: []string{"Loop"},
+		Labels:       []string{"Data "},
+		MinSize:      "100G",
+		MaxSize:      "4TiB",
+		MinAvail:     "10Gi",
+		Where:        `type == "part"`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(filter.FSTypes, []string{"ext4", "xfs"}) ||
+		!reflect.DeepEqual(filter.ExcludeTypes, []string{"loop"}) ||
+		!reflect.DeepEqual(filter.Labels, []string{"Data"}) {
+		t.Errorf("lists not normalized: %+v", filter)
+	}
+	if filter.MinSizeBytes != 100_000_000_000 || filter.MaxSizeBytes != 4<<40 || filter.MinAvailBytes != 10<<30 {
+		t.Errorf("sizes not parsed: min=%d max=%d avail=%d", filter.MinSizeBytes, filter.MaxSizeBytes, filter.MinAvailBytes)
+	}
+	if filter.WhereExpr == nil {
+		t.Error("where expression not parsed")
+	}
+}
+
+func TestBuildScanFilter_InvalidSize(t *testing.T) {
+	_, err := buildScanFilter(service.ScanFilter{MaxSize: "4 lots"})
+	if err == nil || !strings.Contains(err.Error(), "max-size") {
+		t.Fatalf("expected max-size error, got %v", err)
+	}
+}
+
+func TestValidateScanFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter service.ScanFilter
+		want   string
+	}{
+		{"size range", service.ScanFilter{MinSize: "4T", MaxSize: "100G"}, "larger than max-size"},
+		{"used percent", service.ScanFilter{MaxUsedPercent: 120}, "max-used-percent"},
+		{"type conflict", service.ScanFilter{Types: []string{"disk"}, ExcludeTypes: []string{"DISK"}}, "both included and excluded"},
+		{"where", service.ScanFilter{Where: "size < lots"}, "invalid --where expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := buildScanFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected build error: %v", err)
+			}
+			err = validateScanFilter(filter)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateScanFilter_SignatureTypes(t *testing.T) {
+	exclude, err := buildScanFilter(service.ScanFilter{ExcludeFSTypes: []string{"swap", "LVM2_member", "nosuchfs"}})
+	if err != nil {
+		t.Fatalf("unexpected build error: %v", err)
+	}
+	if err := validateScanFilter(exclude); err != nil {
+		t.Errorf("excluded types must not be validated: %v", err)
+	}
+
+	if _, err := os.Stat("/proc/filesystems"); err != nil {
+		t.Skip("/proc/filesystems is not available")
+	}
+	include, err := buildScanFilter(service.ScanFilter{FSTypes: []string{"swap", "crypto_LUKS", "linux_raid_member", "vfat"}})
+	if err != nil {
+		t.Fatalf("unexpected build error: %v", err)
+	}
+	if err := validateScanFilter(include); err != nil {
+		t.Errorf("signature types must be accepted: %v", err)
+	}
+	include.FSTypes = []string{"nosuchfs"}
+	if err := validateScanFilter(include); err == nil || !strings.Contains(err.Error(), "unsupported filesystem type") {
+		t.Errorf("expected an unsupported filesystem type error, got %v", err)
+	}
+}
+
+// fakeScanner returns a fixed scan result.
+type fakeScanner struct {
+	result service.ScanResult
+}
+
+func (s *fakeScanner) Scan(ctx context.Context, filter service.ScanFilter) (service.ScanResult, error) {
+	return s.result, nil
+}
+
+func TestRunScan_DiagnosticsSetExitStatus(t *testing.T) {
+	scanner := &fakeScanner{result: service.ScanResult{
+		Devices: []device.BlockDevice{{Name: "sda", Path: "/dev/sda", Type: "disk"}},
+		Diagnostics: []service.Diagnostic{
+			{Provider: "probe", Device: "/dev/sda", Severity: service.SeverityWarning, Err: errors.New("short read")},
+		},
+	}}
+
+	var out, errOut bytes.Buffer
+	err := runScan(context.Background(), &out, &errOut, scanner, service.ScanFilter{}, nil, &output.CSVPrinter{
+		Columns: output.DefaultColumns[2:3],
+	})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitStatusWarning {
+		t.Fatalf("expected exit status %d, got %v", ExitStatusWarning, err)
+	}
+	if out.String() != "DEVICE\n/dev/sda\n" {
+		t.Errorf("devices must still be printed, got:\n%s", out.String())
+	}
+	if errOut.String() != "warning: probe: /dev/sda: short read\n" {
+		t.Errorf("unexpected stderr:\n%s", errOut.String())
+	}
+}
+
+func TestRunScan_NoDiagnostics(t *testing.T) {
+	scanner := &fakeScanner{result: service.ScanResult{
+		Diagnostics: []service.Diagnostic{{Provider: "udev", Severity: service.SeverityInfo, Err: errors.New("no udev db")}},
+	}}
+
+	var out, errOut bytes.Buffer
+	if err := runScan(context.Background(), &out, &errOut, scanner, service.ScanFilter{}, nil, &output.JSONPrinter{}); err != nil {
+		t.Fatalf("info diagnostics must not change the exit status: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/topology"
+	"github.com/gigiozzz/driver-scanner/internal/output"
+)
+
+// TopologyOptions holds the configuration for the topology command.
+type TopologyOptions struct {
+	// SysRoot is the mount point of sysfs to read.
+	SysRoot string
+	// Output is the output format, one of output.TopologyFormats.
+	Output string
+	Out    io.Writer
+}
+
+// Run builds the device topology and prints it.
+func (o *TopologyOptions) Run(ctx context.Context) error {
+	roots, err := topology.Build(ctx, o.SysRoot)
+	if err != nil {
+		return err
+	}
+	log.Info().Int("rootCount", len(roots)).Str("sysRoot", o.SysRoot).Msg("device topology built")
+	return output.PrintTopologyReport(o.Out, o.Output, output.NewTopologyReport(roots))
+}
+
+// newTopologyCommand creates the "topology" subcommand.
+func newTopologyCommand() *cobra.Command {
+	o := &TopologyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "topology",
+		Short: "Show the controllers, buses and ports each disk hangs off",
+		Long: `Show the controllers, buses and ports each disk hangs off.
+
+The tree follows the sysfs device path of every disk: PCI root, PCI bridges and
+host controllers, then the SCSI host, target and LUN, the NVMe controller and
+namespace, or the USB hub chain, down to the disk and its partitions. Virtual
+devices (loop, device-mapper, md) are not shown.`,
+		Example: `  # Find the HBA and port behind each disk
+  driver-scanner topology
+
+  # Read the sysfs of the host from a container
+  driver-scanner topology --sysfs-root /host/sys -o json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.SysRoot = settings.SysRoot
+			o.Out = cmd.OutOrStdout()
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			return o.Run(ctx)
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", output.FormatTree,
+		"output format: "+strings.Join(output.TopologyFormats, ", "))
+
+	return cmd
+}
@@ -0,0 +1,286 @@
+// Package boot resolves the filesystems the system boots from, / and /boot, and the
+// EFI System Partitions down to the physical disks holding them.
+package boot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/graph"
+	"github.com/gigiozzz/driver-scanner/internal/device/parttable"
+)
+
+// MountPoints are the mount points resolved to disks, in report order.
+var MountPoints = []string{"/", "/boot", "/boot/efi"}
+
+// Mount is a boot mount point and the devices it is built on.
+type Mount struct {
+	// MountPoint is the mount point (e.g. "/boot").
+	MountPoint string `json:"mountPoint"`
+	// Source is the mount source from mountinfo (e.g. "/dev/mapper/vg0-root").
+	Source string `json:"source"`
+	// FSType is the filesystem type.
+	FSType string `json:"fstype"`
+	// Chain lists the devices the filesystem is built on, from the top down
+	// (e.g. /dev/mapper/vg0-root, /dev/mapper/luks-md0, /dev/md0, /dev/sda2, /dev/sda, ...).
+	Chain []string `json:"chain"`
+	// Disks are the paths of the physical disks at the bottom of the chain, sorted.
+	Disks []string `json:"disks"`
+}
+
+// ESP is an EFI System Partition found by its GPT type GUID.
+type ESP struct {
+	// Device is the partition path (e.g. "/dev/sda1"), empty when the kernel has no
+	// device for the partition.
+	Device string `json:"device"`
+	// Disk is the path of the disk holding the partition.
+	Disk string `json:"disk"`
+	// Partition is the partition number.
+	Partition int `json:"partition"`
+	// PartUUID is the unique partition GUID.
+	PartUUID string `json:"partuuid"`
+	// Label is the GPT partition name.
+	Label string `json:"label,omitempty"`
+	// Size is the partition size in human-readable form (e.g. "512 MiB").
+	Size string `json:"size"`
+	// SizeBytes is the partition size in bytes.
+	SizeBytes uint64 `json:"sizeBytes"`
+	// MountPoint is where the partition is mounted, empty if it is not.
+	MountPoint string `json:"mountPoint,omitempty"`
+}
+
+// Disk is a physical disk needed to boot.
+type Disk struct {
+	// Device is the disk as read from sysfs, with its model, serial and size.
+	Device device.BlockDevice `json:"device"`
+	// MountPoints are the boot mount points built on the disk.
+	MountPoints []string `json:"mountPoints,omitempty"`
+	// ESPs are the paths of the EFI System Partitions on the disk.
+	ESPs []string `json:"esps,omitempty"`
+}
+
+// Result is the outcome of Resolve.
+type Result struct {
+	// Mounts are the boot mount points found in mountinfo, in MountPoints order.
+	Mounts []Mount `json:"mounts"`
+	// ESPs are the EFI System Partitions of all disks, in disk and table order.
+	ESPs []ESP `json:"esps"`
+	// Disks are the disks holding a boot mount point or an ESP, sorted by path.
+	Disks []Disk `json:"disks"`
+	// Warnings report the disks whose partition table could not be read.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Resolver resolves the boot mount points and ESPs to disks.
+type Resolver struct {
+	// Mounts provides the mount table.
+	Mounts device.MountInfoProvider
+	// Devices provides the disk details. It must read the same sysfs as SysRoot.
+	Devices device.BlockDeviceProvider
+	// SysRoot is the mount point of sysfs (e.g. "/sys").
+	SysRoot string
+	// DevRoot is the directory holding the device nodes partition tables are read from (e.g. "/dev").
+	DevRoot string
+}
+
+// NewResolver creates a new Resolver reading sysfs at sysRoot and device nodes in devRoot.
+// An empty sysRoot reads /sys and an empty devRoot reads /dev.
+func NewResolver(mounts device.MountInfoProvider, sysRoot, devRoot string) *Resolver {
+	if sysRoot == "" {
+		sysRoot = "/sys"
+	}
+	if devRoot == "" {
+		devRoot = "/dev"
+	}
+	devices := device.NewSysfsProvider()
+	devices.SysRoot = sysRoot
+	return &Resolver{Mounts: mounts, Devices: devices, SysRoot: sysRoot, DevRoot: devRoot}
+}
+
+// Resolve builds the device graph, follows each boot mount point down to its disks
+// and reads the partition table of every partitioned disk looking for ESPs.
+// Disks whose partition table cannot be read are reported as warnings.
+func (r *Resolver) Resolve(ctx context.Context) (*Result, error) {
+	mounts, err := r.Mounts.GetMounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mounts: %w", err)
+	}
+	g, err := graph.Build(ctx, r.SysRoot, mounts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Mounts: []Mount{}, ESPs: []ESP{}, Disks: []Disk{}}
+	for _, mountPoint := range MountPoints {
+		if entry, ok := lastMount(mounts, mountPoint); ok {
+			result.Mounts = append(result.Mounts, resolveMount(g, entry))
+		}
+	}
+	if err := r.findESPs(ctx, g, result); err != nil {
+		return nil, err
+	}
+	if err := r.collectDisks(ctx, result); err != nil {
+		return nil, err
+	}
+
+	log.Debug().
+		Int("mounts", len(result.Mounts)).
+		Int("esps", len(result.ESPs)).
+		Int("disks", len(result.Disks)).
+		Msg("boot disks resolved")
+	return result, nil
+}
+
+// lastMount returns the mount entry of mountPoint. When it was mounted over, the last
+// entry is the one visible.
+func lastMount(mounts []device.MountEntry, mountPoint string) (device.MountEntry, bool) {
+	for i := len(mounts) - 1; i >= 0; i-- {
+		if mounts[i].MountPoint == mountPoint {
+			return mounts[i], true
+		}
+	}
+	return device.MountEntry{}, false
+}
+
+// resolveMount follows the mount node down to the disks.
+func resolveMount(g *graph.Graph, entry device.MountEntry) Mount {
+	mount := Mount{
+		MountPoint: entry.MountPoint,
+		Source:     entry.Source,
+		FSType:     entry.FSType,
+		Chain:      []string{},
+		Disks:      []string{},
+	}
+	for _, id := range g.Below(fmt.Sprintf("mount:%d", entry.MountID))[1:] {
+		node := g.Nodes[id]
+		mount.Chain = append(mount.Chain, node.Name)
+		if node.Kind == graph.KindDisk && len(node.Lowers) == 0 {
+			mount.Disks = append(mount.Disks, node.Name)
+		}
+	}
+	sort.Strings(mount.Disks)
+	return mount
+}
+
+// findESPs reads the partition table of every disk with partitions and adds the
+// partitions with the EFI System type GUID.
+func (r *Resolver) findESPs(ctx context.Context, g *graph.Graph, result *Result) error {
+	var disks []*graph.Node
+	for _, node := range g.Nodes {
+		if node.Kind == graph.KindDisk && slices.ContainsFunc(node.Uppers, func(id string) bool {
+			return g.Nodes[id].Kind == graph.KindPartition
+		}) {
+			disks = append(disks, node)
+		}
+	}
+	sort.Slice(disks, func(i, j int) bool { return disks[i].ID < disks[j].ID })
+
+	for _, disk := range disks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		table, err := parttable.ReadFile(filepath.Join(r.DevRoot, disk.ID))
+		if errors.Is(err, parttable.ErrNoPartitionTable) {
+			continue
+		}
+		if err != nil {
+			log.Debug().Str("disk", disk.Name).Err(err).Msg("partition table not read")
+			result.Warnings = append(result.Warnings, err.Error())
+			continue
+		}
+		if table.Type != parttable.TypeGPT {
+			continue
+		}
+		for _, p := range table.Partitions {
+			if p.TypeGUID != parttable.TypeGUIDEFISystem {
+				continue
+			}
+			esp := ESP{
+				Disk:      disk.Name,
+				Partition: p.Number,
+				PartUUID:  p.GUID,
+				Label:     p.Name,
+				Size:      humanize.IBytes(p.SizeBytes),
+				SizeBytes: p.SizeBytes,
+			}
+			if part := partitionNode(g, disk, p.Number); part != nil {
+				esp.Device = part.Name
+				esp.MountPoint = mountPointOf(g, part)
+			}
+			result.ESPs = append(result.ESPs, esp)
+		}
+	}
+	return nil
+}
+
+// partitionNode returns the partition node numbered number on disk, or nil.
+func partitionNode(g *graph.Graph, disk *graph.Node, number int) *graph.Node {
+	for _, id := range disk.Uppers {
+		if node := g.Nodes[id]; node.Kind == graph.KindPartition && node.Partition == number {
+			return node
+		}
+	}
+	return nil
+}
+
+// mountPointOf returns the mount point of the first mount directly on node, or "".
+func mountPointOf(g *graph.Graph, node *graph.Node) string {
+	for _, id := range node.Uppers {
+		if upper := g.Nodes[id]; upper.Kind == graph.KindMount {
+			return upper.Name
+		}
+	}
+	return ""
+}
+
+// collectDisks lists the disks referenced by the mounts and ESPs with their sysfs details.
+func (r *Resolver) collectDisks(ctx context.Context, result *Result) error {
+	byPath := make(map[string]*Disk)
+	disk := func(path string) *Disk {
+		if d, ok := byPath[path]; ok {
+			return d
+		}
+		d := &Disk{Device: device.BlockDevice{Name: filepath.Base(path), Path: path}}
+		byPath[path] = d
+		return d
+	}
+	for _, mount := range result.Mounts {
+		for _, path := range mount.Disks {
+			d := disk(path)
+			d.MountPoints = append(d.MountPoints, mount.MountPoint)
+		}
+	}
+	for _, esp := range result.ESPs {
+		d := disk(esp.Disk)
+		if esp.Device != "" {
+			d.ESPs = append(d.ESPs, esp.Device)
+		}
+	}
+	if len(byPath) == 0 {
+		return nil
+	}
+
+	devices, err := r.Devices.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list block devices: %w", err)
+	}
+	for _, dev := range devices {
+		if d, ok := byPath[dev.Path]; ok {
+			d.Device = dev
+		}
+	}
+
+	for _, d := range byPath {
+		result.Disks = append(result.Disks, *d)
+	}
+	sort.Slice(result.Disks, func(i, j int) bool { return result.Disks[i].Device.Path < result.Disks[j].Device.Path })
+	return nil
+}
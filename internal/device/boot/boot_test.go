@@ -0,0 +1,203 @@
+package boot
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/parttable"
+	"github.com/gigiozzz/driver-scanner/internal/device/sysfstest"
+)
+
+// fakeBlock creates the sysfs directory of a block device under devices/ with its
+// /sys/class/block link, and the /sys/block link of whole devices.
+func fakeBlock(t *testing.T, sysRoot, dir, devNum string, files map[string]string, slaves ...string) {
+	t.Helper()
+	sysfstest.Block(t, sysRoot, dir, devNum, files, slaves...)
+	if _, ok := files["partition"]; !ok {
+		sysfstest.Link(t, sysRoot, filepath.Join("block", filepath.Base(dir)), dir)
+	}
+}
+
+// encodeGUID encodes a GUID in the mixed-endian GPT layout.
+func encodeGUID(t *testing.T, s string) []byte {
+	t.Helper()
+	var a uint32
+	var b, c, d uint16
+	var e uint64
+	if _, err := fmt.Sscanf(s, "%08x-%04x-%04x-%04x-%012x", &a, &b, &c, &d, &e); err != nil {
+		t.Fatalf("invalid guid %q: %v", s, err)
+	}
+	out := make([]byte, 16)
+	binary.LittleEndian.PutUint32(out[0:], a)
+	binary.LittleEndian.PutUint16(out[4:], b)
+	binary.LittleEndian.PutUint16(out[6:], c)
+	binary.BigEndian.PutUint16(out[8:], d)
+	for i := 15; i >= 10; i-- {
+		out[i] = byte(e)
+		e >>= 8
+	}
+	return out
+}
+
+// writeGPTImage writes a 1 MiB disk image with a protective MBR and a primary and backup
+// GPT holding an ESP as partition 1 and a Linux RAID partition as partition 2.
+func writeGPTImage(t *testing.T, path, espGUID string) {
+	t.Helper()
+	const sectors, entries = 2048, 128
+	img := make([]byte, sectors*512)
+	img[446+4] = 0xEE
+	binary.LittleEndian.PutUint32(img[446+8:], 1)
+	binary.LittleEndian.PutUint32(img[446+12:], sectors-1)
+	binary.LittleEndian.PutUint16(img[510:], 0xAA55)
+
+	array := make([]byte, entries*128)
+	copy(array[0:], encodeGUID(t, parttable.TypeGUIDEFISystem))
+	copy(array[16:], encodeGUID(t, espGUID))
+	binary.LittleEndian.PutUint64(array[32:], 34)
+	binary.LittleEndian.PutUint64(array[40:], 1057)
+	for i, r := range "EFI" {
+		binary.LittleEndian.PutUint16(array[56+i*2:], uint16(r))
+	}
+	copy(array[128:], encodeGUID(t, "a19d880f-05fc-4d3b-a006-743f0f84911e"))
+	copy(array[144:], encodeGUID(t, "00000000-0000-0000-0000-000000000002"))
+	binary.LittleEndian.PutUint64(array[160:], 1058)
+	binary.LittleEndian.PutUint64(array[168:], 2000)
+
+	writeHeader := func(myLBA, alternateLBA, entriesLBA uint64) {
+		h := img[myLBA*512:]
+		copy(h[0:], "EFI PART")
+		binary.LittleEndian.PutUint32(h[8:], 0x00010000)
+		binary.LittleEndian.PutUint32(h[12:], 92)
+		binary.LittleEndian.PutUint64(h[24:], myLBA)
+		binary.LittleEndian.PutUint64(h[32:], alternateLBA)
+		binary.LittleEndian.PutUint64(h[40:], 34)
+		binary.LittleEndian.PutUint64(h[48:], sectors-34)
+		copy(h[56:], encodeGUID(t, "11223344-5566-7788-99aa-bbccddeeff00"))
+		binary.LittleEndian.PutUint64(h[72:], entriesLBA)
+		binary.LittleEndian.PutUint32(h[80:], entries)
+		binary.LittleEndian.PutUint32(h[84:], 128)
+		binary.LittleEndian.PutUint32(h[88:], crc32.ChecksumIEEE(array))
+		binary.LittleEndian.PutUint32(h[16:], crc32.ChecksumIEEE(h[:92]))
+		copy(img[entriesLBA*512:], array)
+	}
+	writeHeader(1, sectors-1, 2)
+	writeHeader(sectors-1, 1, sectors-1-entries*128/512)
+
+	if err := os.WriteFile(path, img, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// newResolver builds a fixture where / is an LVM volume on LUKS on the RAID1 md0 of
+// sda2 and sdb2, /boot is on sdc1 and /boot/efi is the ESP sda1. sdb1 is an unmounted
+// ESP, and sdc has no device node to read its partition table from.
+func newResolver(t *testing.T) *Resolver {
+	t.Helper()
+	sysRoot := t.TempDir()
+	devRoot := t.TempDir()
+	scsi := filepath.Join("devices", "pci0000:00", "0000:00:17.0", "block")
+	virtual := filepath.Join("devices", "virtual", "block")
+
+	for i, disk := range []string{"sda", "sdb", "sdc"} {
+		fakeBlock(t, sysRoot, filepath.Join(scsi, disk), fmt.Sprintf("8:%d", i*16),
+			map[string]string{"size": "2048", "device/model": "DISK-" + disk, "device/serial": "S" + disk})
+		for part := 1; part <= 2; part++ {
+			fakeBlock(t, sysRoot, filepath.Join(scsi, disk, fmt.Sprintf("%s%d", disk, part)),
+				fmt.Sprintf("8:%d", i*16+part), map[string]string{"partition": fmt.Sprint(part)})
+		}
+	}
+	fakeBlock(t, sysRoot, filepath.Join(virtual, "md0"), "9:0",
+		map[string]string{"md/level": "raid1", "md/raid_disks": "2"}, "sda2", "sdb2")
+	fakeBlock(t, sysRoot, filepath.Join(virtual, "dm-0"), "253:0",
+		map[string]string{"dm/name": "luks-md0", "dm/uuid": "CRYPT-LUKS2-0123-luks-md0"}, "md0")
+	fakeBlock(t, sysRoot, filepath.Join(virtual, "dm-1"), "253:1",
+		map[string]string{"dm/name": "vg0-root", "dm/uuid": "LVM-vg0root"}, "dm-0")
+
+	writeGPTImage(t, filepath.Join(devRoot, "sda"), "aaaaaaaa-0000-0000-0000-00000000000a")
+	writeGPTImage(t, filepath.Join(devRoot, "sdb"), "bbbbbbbb-0000-0000-0000-00000000000b")
+
+	mounts := device.NewFileMountInfoProvider(filepath.Join("testdata", "mountinfo"))
+	return NewResolver(mounts, sysRoot, devRoot)
+}
+
+func TestResolve(t *testing.T) {
+	result, err := newResolver(t).Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	wantMounts := []Mount{
+		{
+			MountPoint: "/", Source: "/dev/mapper/vg0-root", FSType: "ext4",
+			Chain: []string{"/dev/mapper/vg0-root", "/dev/mapper/luks-md0", "/dev/md0",
+				"/dev/sda2", "/dev/sda", "/dev/sdb2", "/dev/sdb"},
+			Disks: []string{"/dev/sda", "/dev/sdb"},
+		},
+		{
+			MountPoint: "/boot", Source: "/dev/sdc1", FSType: "ext4",
+			Chain: []string{"/dev/sdc1", "/dev/sdc"},
+			Disks: []string{"/dev/sdc"},
+		},
+		{
+			MountPoint: "/boot/efi", Source: "/dev/sda1", FSType: "vfat",
+			Chain: []string{"/dev/sda1", "/dev/sda"},
+			Disks: []string{"/dev/sda"},
+		},
+	}
+	if !reflect.DeepEqual(result.Mounts, wantMounts) {
+		t.Errorf("mounts:\ngot  %+v\nwant %+v", result.Mounts, wantMounts)
+	}
+
+	wantESPs := []ESP{
+		{Device: "/dev/sda1", Disk: "/dev/sda", Partition: 1, PartUUID: "aaaaaaaa-0000-0000-0000-00000000000a",
+			Label: "EFI", Size: "512 KiB", SizeBytes: 1024 * 512, MountPoint: "/boot/efi"},
+		{Device: "/dev/sdb1", Disk: "/dev/sdb", Partition: 1, PartUUID: "bbbbbbbb-0000-0000-0000-00000000000b",
+			Label: "EFI", Size: "512 KiB", SizeBytes: 1024 * 512},
+	}
+	if !reflect.DeepEqual(result.ESPs, wantESPs) {
+		t.Errorf("esps:\ngot  %+v\nwant %+v", result.ESPs, wantESPs)
+	}
+
+	var disks []string
+	for _, d := range result.Disks {
+		disks = append(disks, fmt.Sprintf("%s %s %s %v %v", d.Device.Path, d.Device.Model, d.Device.Serial, d.MountPoints, d.ESPs))
+	}
+	wantDisks := []string{
+		"/dev/sda DISK-sda Ssda [/ /boot/efi] [/dev/sda1]",
+		"/dev/sdb DISK-sdb Ssdb [/] [/dev/sdb1]",
+		"/dev/sdc DISK-sdc Ssdc [/boot] []",
+	}
+	if !reflect.DeepEqual(disks, wantDisks) {
+		t.Errorf("disks:\ngot  %q\nwant %q", disks, wantDisks)
+	}
+
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected one warning for sdc, got %q", result.Warnings)
+	}
+}
+
+func TestResolve_NoBootMounts(t *testing.T) {
+	r := newResolver(t)
+	r.Mounts = device.NewFileMountInfoProvider(filepath.Join(t.TempDir(), "missing"))
+	if _, err := r.Resolve(context.Background()); err == nil {
+		t.Fatal("expected an error for a missing mountinfo file")
+	}
+
+	path := filepath.Join(t.TempDir(), "mountinfo")
+	sysfstest.WriteFile(t, filepath.Dir(path), "mountinfo", "22 1 0:21 / /proc rw - proc proc rw\n")
+	r.Mounts = device.NewFileMountInfoProvider(path)
+	result, err := r.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if len(result.Mounts) != 0 || len(result.ESPs) != 2 || len(result.Disks) != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
@@ -0,0 +1,181 @@
+package crypt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+const luksUUID = "3f1a2b3c-4d5e-4f60-8172-8394a5b6c7d8"
+
+// luks1Image returns a LUKS1 header with two of its eight keyslots enabled.
+func luks1Image() []byte {
+	img := make([]byte, 4096)
+	copy(img[0:6], luksMagic)
+	binary.BigEndian.PutUint16(img[6:8], 1)
+	copy(img[8:40], "aes")
+	copy(img[40:72], "xts-plain64")
+	copy(img[72:104], "sha256")
+	binary.BigEndian.PutUint32(img[104:108], 4096)
+	binary.BigEndian.PutUint32(img[108:112], 64)
+	copy(img[168:208], luksUUID)
+	for i := 0; i < luks1Keyslots; i++ {
+		state := uint32(0x0000dead)
+		if i == 0 || i == 3 {
+			state = luks1KeyEnabled
+		}
+		binary.BigEndian.PutUint32(img[luks1KeyslotOffset+i*luks1KeyslotSize:], state)
+	}
+	return img
+}
+
+// luks2JSON is a LUKS2 JSON area as written by cryptsetup luksFormat, trimmed.
+const luks2JSON = `{
+  "keyslots": {
+    "1": {"type": "luks2", "key_size": 64, "kdf": {"type": "pbkdf2", "hash": "sha512", "iterations": 1000}},
+    "0": {"type": "luks2", "key_size": 64, "kdf": {"type": "argon2id", "time": 4, "memory": 1048576, "cpus": 4}}
+  },
+  "tokens": {},
+  "segments": {
+    "0": {"type": "crypt", "offset": "16777216", "size": "dynamic", "iv_tweak": "0", "encryption": "aes-xts-plain64", "sector_size": 512}
+  },
+  "digests": {"0": {"type": "pbkdf2", "keyslots": ["0", "1"], "segments": ["0"], "hash": "sha256"}},
+  "config": {"json_size": "12288", "keyslots_size": "16744448"}
+}`
+
+// luks2Image returns a LUKS2 primary header with a 16 KiB header area.
+func luks2Image(json string) []byte {
+	const hdrSize = 16384
+	img := make([]byte, hdrSize)
+	copy(img[0:6], luksMagic)
+	binary.BigEndian.PutUint16(img[6:8], 2)
+	binary.BigEndian.PutUint64(img[8:16], hdrSize)
+	copy(img[24:72], "data")
+	copy(img[168:208], luksUUID)
+	copy(img[luksHeaderSize:], json)
+	return img
+}
+
+// writeImage writes a header image to a temporary file and returns its path.
+func writeImage(t *testing.T, img []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "luks.img")
+	if err := os.WriteFile(path, img, 0o644); err != nil {
+		t.Fatalf("write image: %v", err)
+	}
+	return path
+}
+
+func TestReadHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		img  []byte
+		want device.LUKSHeader
+	}{
+		{
+			name: "luks1",
+			img:  luks1Image(),
+			want: device.LUKSHeader{Version: 1, UUID: luksUUID, Cipher: "aes-xts-plain64", KeySize: 512,
+				Keyslots: 2, PBKDF: "pbkdf2", Hash: "sha256"},
+		},
+		{
+			name: "luks2",
+			img:  luks2Image(luks2JSON),
+			want: device.LUKSHeader{Version: 2, UUID: luksUUID, Label: "data", Cipher: "aes-xts-plain64", KeySize: 512,
+				Keyslots: 2, PBKDF: "argon2id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadHeaderFile(writeImage(t, tt.img))
+			if err != nil {
+				t.Fatalf("ReadHeaderFile: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadHeader_Errors(t *testing.T) {
+	if _, err := ReadHeaderFile(writeImage(t, make([]byte, 4096))); !errors.Is(err, ErrNoHeader) {
+		t.Errorf("expected ErrNoHeader for a blank device, got %v", err)
+	}
+	if _, err := ReadHeaderFile(writeImage(t, []byte(luksMagic))); !errors.Is(err, ErrNoHeader) {
+		t.Errorf("expected ErrNoHeader for a short device, got %v", err)
+	}
+	if _, err := ReadHeaderFile(writeImage(t, luks2Image(`{"keyslots": `))); err == nil {
+		t.Error("expected an error for truncated LUKS2 metadata")
+	}
+	bad := luks1Image()
+	binary.BigEndian.PutUint16(bad[6:8], 3)
+	if _, err := ReadHeaderFile(writeImage(t, bad)); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func TestMappingType(t *testing.T) {
+	tests := map[string]string{
+		"CRYPT-LUKS2-3f1a2b3c4d5e4f6081728394a5b6c7d8-luks-root": "LUKS2",
+		"CRYPT-LUKS1-3f1a2b3c4d5e4f6081728394a5b6c7d8-swap":      "LUKS1",
+		"CRYPT-PLAIN-scratch":              "PLAIN",
+		"CRYPT-BITLK-5f1a-usb":             "BITLK",
+		"CRYPT-VERITY-abc-root":            "",
+		"CRYPT-INTEGRITY-data":             "",
+		"CRYPT-SUBDEV-LUKS2-3f1a-data_dif": "",
+		"LVM-abcdef":                       "",
+		"":                                 "",
+	}
+	for uuid, want := range tests {
+		if got := MappingType(uuid); got != want {
+			t.Errorf("MappingType(%q) = %q, want %q", uuid, got, want)
+		}
+	}
+}
+
+func TestEnricher(t *testing.T) {
+	sysRoot := t.TempDir()
+	dmDir := filepath.Join(sysRoot, "devices", "virtual", "block", "dm-0")
+	if err := os.MkdirAll(filepath.Join(dmDir, "dm"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dmDir, "dm", "uuid"), []byte("CRYPT-LUKS2-3f1a2b3c4d5e4f6081728394a5b6c7d8-luks-root\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(sysRoot, "dev", "block"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dmDir, filepath.Join(sysRoot, "dev", "block", "253:0")); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnricher(sysRoot)
+
+	mapping := device.BlockDevice{Path: "/dev/mapper/luks-root", Major: 253, Minor: 0, FSType: "ext4"}
+	if err := e.Enrich(context.Background(), &mapping); err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if mapping.CryptType != "LUKS2" || mapping.LUKS != nil {
+		t.Errorf("unexpected mapping %q %+v", mapping.CryptType, mapping.LUKS)
+	}
+
+	volume := device.BlockDevice{Path: writeImage(t, luks2Image(luks2JSON)), Major: 8, Minor: 2, FSType: "crypto_LUKS"}
+	if err := e.Enrich(context.Background(), &volume); err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if volume.CryptType != "" || volume.LUKS == nil || volume.LUKS.Cipher != "aes-xts-plain64" {
+		t.Errorf("unexpected LUKS volume %q %+v", volume.CryptType, volume.LUKS)
+	}
+
+	// Devices without read access keep their fields empty.
+	missing := device.BlockDevice{Path: filepath.Join(t.TempDir(), "sdz"), Major: 65, Minor: 0, FSType: "crypto_LUKS"}
+	if err := e.Enrich(context.Background(), &missing); err != nil || missing.LUKS != nil {
+		t.Errorf("expected an unreadable device to be skipped, got %v %+v", err, missing.LUKS)
+	}
+}
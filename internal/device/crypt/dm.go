@@ -0,0 +1,51 @@
+package crypt
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// cryptUUIDPrefix starts the device-mapper UUID of the mappings set up by cryptsetup:
+// "CRYPT-<TYPE>-<volume UUID without dashes>-<name>" (e.g. "CRYPT-LUKS2-3f1a...-luks-root").
+const cryptUUIDPrefix = "CRYPT-"
+
+// notEncrypted lists the cryptsetup mapping types that do not encrypt data: dm-verity
+// and dm-integrity devices, and the hidden integrity sub-devices of LUKS2.
+var notEncrypted = map[string]bool{
+	"VERITY":    true,
+	"INTEGRITY": true,
+	"SUBDEV":    true,
+}
+
+// MappingType returns the format of a dm-crypt mapping from its device-mapper UUID
+// (e.g. "LUKS2", "PLAIN", "BITLK"), and an empty string for devices that are not
+// encrypting dm-crypt mappings.
+func MappingType(dmUUID string) string {
+	rest, ok := strings.CutPrefix(dmUUID, cryptUUIDPrefix)
+	if !ok {
+		return ""
+	}
+	kind, _, _ := strings.Cut(rest, "-")
+	if kind == "" || notEncrypted[kind] {
+		return ""
+	}
+	return kind
+}
+
+// readDMUUID returns the device-mapper UUID of the block device major:minor, and an
+// empty string for devices that are not device-mapper devices.
+func readDMUUID(sysRoot string, major, minor int) (string, error) {
+	data, err := os.ReadFile(filepath.Join(device.SysfsDevBlockPath(sysRoot, major, minor), "dm", "uuid"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,69 @@
+package crypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// luksFSType is the probed type of LUKS volumes.
+const luksFSType = "crypto_LUKS"
+
+// Enricher fills the dm-crypt mapping type and the LUKS header of block devices.
+type Enricher struct {
+	// SysRoot is the mount point of sysfs (e.g. "/sys").
+	SysRoot string
+}
+
+// NewEnricher creates a new Enricher reading sysfs at sysRoot. An empty sysRoot reads /sys.
+func NewEnricher(sysRoot string) *Enricher {
+	if sysRoot == "" {
+		sysRoot = "/sys"
+	}
+	return &Enricher{SysRoot: sysRoot}
+}
+
+// Name returns "crypt".
+func (e *Enricher) Name() string {
+	return "crypt"
+}
+
+// Fields returns the encryption fields.
+func (e *Enricher) Fields() []string {
+	return []string{"cryptType", "luks"}
+}
+
+// Enrich sets the type of dm-crypt mappings from their device-mapper UUID, and reads the
+// header of LUKS volumes. It relies on the fstype set by the probe enricher to find them.
+func (e *Enricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	dmUUID, err := readDMUUID(e.SysRoot, dev.Major, dev.Minor)
+	if err != nil {
+		return fmt.Errorf("failed to read device-mapper UUID: %w", err)
+	}
+	dev.CryptType = MappingType(dmUUID)
+
+	if dev.FSType != luksFSType {
+		return nil
+	}
+	header, err := ReadHeaderFile(dev.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) || errors.Is(err, fs.ErrNotExist) {
+			log.Debug().Str("device", dev.Path).Err(err).Msg("cannot read LUKS header")
+			return nil
+		}
+		return fmt.Errorf("failed to read LUKS header: %w", err)
+	}
+	dev.LUKS = &header
+
+	log.Debug().
+		Str("device", dev.Path).
+		Int("version", header.Version).
+		Str("cipher", header.Cipher).
+		Msg("enriched device with LUKS header")
+	return nil
+}
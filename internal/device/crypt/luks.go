@@ -0,0 +1,170 @@
+// Package crypt recognises dm-crypt mappings and reads the headers of LUKS1 and
+// LUKS2 encrypted volumes.
+package crypt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/probe"
+)
+
+// ErrNoHeader is returned when a device does not start with a LUKS header.
+var ErrNoHeader = errors.New("no LUKS header")
+
+// LUKS on-disk layout, shared by the LUKS1 header and the LUKS2 binary header.
+const (
+	luksMagic = "LUKS\xba\xbe"
+	// luksHeaderSize is the size of the LUKS2 binary header, which the JSON area follows.
+	luksHeaderSize = 4096
+	// luks1KeyslotOffset and luks1KeyslotSize locate the 8 LUKS1 keyslots.
+	luks1KeyslotOffset = 208
+	luks1KeyslotSize   = 48
+	luks1Keyslots      = 8
+	// luks1KeyEnabled marks an active LUKS1 keyslot.
+	luks1KeyEnabled = 0x00ac71f3
+	// maxLUKS2HeaderSize bounds the LUKS2 header size read from disk.
+	maxLUKS2HeaderSize = 4 << 20
+)
+
+// ReadHeaderFile reads the LUKS header of the device or image at path.
+func ReadHeaderFile(path string) (device.LUKSHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return device.LUKSHeader{}, err
+	}
+	defer file.Close()
+	return ReadHeader(file)
+}
+
+// ReadHeader reads a LUKS1 or LUKS2 header at the start of r.
+func ReadHeader(r io.ReaderAt) (device.LUKSHeader, error) {
+	buf := make([]byte, luks1KeyslotOffset+luks1Keyslots*luks1KeyslotSize)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return device.LUKSHeader{}, ErrNoHeader
+		}
+		return device.LUKSHeader{}, err
+	}
+	if string(buf[0:6]) != luksMagic {
+		return device.LUKSHeader{}, ErrNoHeader
+	}
+
+	switch version := binary.BigEndian.Uint16(buf[6:8]); version {
+	case 1:
+		return parseLUKS1(buf), nil
+	case 2:
+		return readLUKS2(r, buf)
+	default:
+		return device.LUKSHeader{}, fmt.Errorf("unsupported LUKS version %d", version)
+	}
+}
+
+// parseLUKS1 decodes a LUKS1 header: fixed-size fields and 8 keyslots, all big endian.
+func parseLUKS1(buf []byte) device.LUKSHeader {
+	header := device.LUKSHeader{
+		Version: 1,
+		UUID:    probe.CString(buf[168:208]),
+		Cipher:  probe.CString(buf[8:40]) + "-" + probe.CString(buf[40:72]),
+		KeySize: int(binary.BigEndian.Uint32(buf[108:112])) * 8,
+		PBKDF:   "pbkdf2",
+		Hash:    probe.CString(buf[72:104]),
+	}
+	for i := 0; i < luks1Keyslots; i++ {
+		slot := buf[luks1KeyslotOffset+i*luks1KeyslotSize:]
+		if binary.BigEndian.Uint32(slot[0:4]) == luks1KeyEnabled {
+			header.Keyslots++
+		}
+	}
+	return header
+}
+
+// luks2Metadata is the part of the LUKS2 JSON area describing keyslots and segments.
+type luks2Metadata struct {
+	Keyslots map[string]struct {
+		KeySize int `json:"key_size"`
+		KDF     struct {
+			Type string `json:"type"`
+			Hash string `json:"hash"`
+		} `json:"kdf"`
+	} `json:"keyslots"`
+	Segments map[string]struct {
+		Type       string `json:"type"`
+		Encryption string `json:"encryption"`
+	} `json:"segments"`
+}
+
+// readLUKS2 decodes the LUKS2 binary header in buf and the JSON area that follows it.
+func readLUKS2(r io.ReaderAt, buf []byte) (device.LUKSHeader, error) {
+	header := device.LUKSHeader{
+		Version: 2,
+		UUID:    probe.CString(buf[168:208]),
+		Label:   probe.CString(buf[24:72]),
+	}
+
+	size := binary.BigEndian.Uint64(buf[8:16])
+	if size <= luksHeaderSize || size > maxLUKS2HeaderSize {
+		return device.LUKSHeader{}, fmt.Errorf("invalid LUKS2 header size %d", size)
+	}
+	area := make([]byte, size-luksHeaderSize)
+	if _, err := r.ReadAt(area, luksHeaderSize); err != nil {
+		return device.LUKSHeader{}, fmt.Errorf("failed to read LUKS2 metadata: %w", err)
+	}
+	// The JSON text is NUL-padded to the end of the area.
+	if i := strings.IndexByte(string(area), 0); i >= 0 {
+		area = area[:i]
+	}
+	var meta luks2Metadata
+	if err := json.Unmarshal(area, &meta); err != nil {
+		return device.LUKSHeader{}, fmt.Errorf("failed to parse LUKS2 metadata: %w", err)
+	}
+
+	header.Keyslots = len(meta.Keyslots)
+	if id, ok := firstID(meta.Keyslots); ok {
+		slot := meta.Keyslots[id]
+		header.KeySize = slot.KeySize * 8
+		header.PBKDF = slot.KDF.Type
+		header.Hash = slot.KDF.Hash
+	}
+	for _, id := range sortedIDs(meta.Segments) {
+		if segment := meta.Segments[id]; segment.Type == "crypt" {
+			header.Cipher = segment.Encryption
+			break
+		}
+	}
+	return header, nil
+}
+
+// firstID returns the lowest numeric key of a LUKS2 JSON object.
+func firstID[V any](objects map[string]V) (string, bool) {
+	ids := sortedIDs(objects)
+	if len(ids) == 0 {
+		return "", false
+	}
+	return ids[0], true
+}
+
+// sortedIDs returns the keys of a LUKS2 JSON object, which are decimal strings, in numeric order.
+func sortedIDs[V any](objects map[string]V) []string {
+	ids := make([]string, 0, len(objects))
+	for id := range objects {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA != nil || errB != nil {
+			return ids[i] < ids[j]
+		}
+		return a < b
+	})
+	return ids
+}
@@ -0,0 +1,111 @@
+// Package driver resolves the kernel driver, module, transport and host controller
+// of block devices from sysfs.
+package driver
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Info is the driver information of a block device.
+type Info struct {
+	// Driver is the driver bound to the device closest to the block device (e.g. "sd").
+	Driver string
+	// Module is the module providing Driver, empty for built-in drivers.
+	Module string
+	// Transport is the transport between the controller and the device (e.g. "sata").
+	Transport string
+	// Controller is the PCI controller the device hangs off, nil if there is none.
+	Controller *device.Controller
+}
+
+// pciAddress matches the sysfs directory name of a PCI function (domain:bus:slot.function).
+var pciAddress = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-7]$`)
+
+// transports maps sysfs directory name prefixes to transports, in priority order:
+// a USB disk also sits below a SCSI host, so the more specific rules come first.
+var transports = []struct {
+	prefix    string
+	transport string
+}{
+	{"usb", "usb"},
+	{"nvme", "nvme"},
+	{"session", "iscsi"},
+	{"rport-", "fc"},
+	{"end_device-", "sas"},
+	{"ata", "sata"},
+	{"virtio", "virtio"},
+	{"mmc", "mmc"},
+	{"host", "scsi"},
+}
+
+// Lookup reads the driver information of the block device major:minor from the sysfs
+// mounted at sysRoot. Partitions report the information of their disk. Virtual devices
+// (loop, device-mapper, md, zram) have no device link and return an empty Info.
+func Lookup(sysRoot string, major, minor int) (Info, error) {
+	top, err := filepath.EvalSymlinks(filepath.Join(sysRoot, "devices"))
+	if err != nil {
+		return Info{}, err
+	}
+	blockDir, err := filepath.EvalSymlinks(device.SysfsDevBlockPath(sysRoot, major, minor))
+	if err != nil {
+		return Info{}, err
+	}
+	if device.PathExists(filepath.Join(blockDir, "partition")) {
+		blockDir = filepath.Dir(blockDir)
+	}
+	deviceDir, err := filepath.EvalSymlinks(filepath.Join(blockDir, "device"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Info{}, nil
+		}
+		return Info{}, err
+	}
+
+	var info Info
+	var names []string
+	// Walk up from the device to the PCI controller: the first bound driver is the
+	// device driver (sd, virtio_blk), or the controller driver for NVMe namespaces.
+	for dir := deviceDir; strings.HasPrefix(dir, top+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		name := filepath.Base(dir)
+		if info.Driver == "" {
+			info.Driver, info.Module = device.SysfsDriver(dir)
+		}
+		if pciAddress.MatchString(name) && device.PathExists(filepath.Join(dir, "vendor")) {
+			info.Controller = readController(dir)
+			break
+		}
+		names = append(names, name)
+	}
+	info.Transport = transportOf(names)
+	return info, nil
+}
+
+// readController reads the IDs and driver of the PCI function at dir.
+func readController(dir string) *device.Controller {
+	controller := &device.Controller{
+		Address:  filepath.Base(dir),
+		VendorID: device.ReadSysfsID(filepath.Join(dir, "vendor")),
+		DeviceID: device.ReadSysfsID(filepath.Join(dir, "device")),
+	}
+	controller.Driver, controller.Module = device.SysfsDriver(dir)
+	return controller
+}
+
+// transportOf returns the transport matching the sysfs directory names between the
+// device and its controller, or an empty string if none matches.
+func transportOf(names []string) string {
+	for _, rule := range transports {
+		for _, name := range names {
+			if strings.HasPrefix(name, rule.prefix) {
+				return rule.transport
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package driver
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/sysfstest"
+)
+
+// fakeBlockDevice registers the block device dir under sysRoot/dev/block and links it to
+// its device dir. An empty deviceDir creates a virtual device.
+func fakeBlockDevice(t *testing.T, sysRoot, devNum, blockDir, deviceDir string) {
+	t.Helper()
+	sysfstest.WriteFile(t, sysRoot, filepath.Join(blockDir, "size"), "2048\n")
+	sysfstest.Link(t, sysRoot, filepath.Join("dev/block", devNum), blockDir)
+	if deviceDir != "" {
+		sysfstest.Link(t, sysRoot, filepath.Join(blockDir, "device"), deviceDir)
+	}
+}
+
+// fakePCIController creates a PCI function with the given IDs bound to driver.
+func fakePCIController(t *testing.T, sysRoot, address, vendor, deviceID, driver string) string {
+	t.Helper()
+	dir := "devices/pci0000:00/" + address
+	sysfstest.WriteFile(t, sysRoot, dir+"/vendor", "0x"+vendor+"\n")
+	sysfstest.WriteFile(t, sysRoot, dir+"/device", "0x"+deviceID+"\n")
+	sysfstest.Link(t, sysRoot, dir+"/driver", "bus/pci/drivers/"+driver)
+	return dir
+}
+
+// newFakeSysfs builds a sysfs with a SATA disk and its partition behind AHCI, an NVMe
+// namespace, a USB disk behind xHCI and a loop device.
+func newFakeSysfs(t *testing.T) string {
+	t.Helper()
+	sysRoot := filepath.Join(t.TempDir(), "sys")
+
+	// Built-in ahci and xhci_hcd, modular sd, nvme and usb-storage.
+	for _, driver := range []string{"pci/drivers/ahci", "pci/drivers/xhci_hcd", "usb/drivers/usb-storage"} {
+		sysfstest.WriteFile(t, sysRoot, "bus/"+driver+"/bind", "")
+	}
+	sysfstest.WriteFile(t, sysRoot, "module/sd_mod/refcnt", "2\n")
+	sysfstest.WriteFile(t, sysRoot, "module/nvme/refcnt", "1\n")
+	sysfstest.WriteFile(t, sysRoot, "module/usb_storage/refcnt", "1\n")
+	sysfstest.Link(t, sysRoot, "bus/scsi/drivers/sd/module", "module/sd_mod")
+	sysfstest.Link(t, sysRoot, "bus/pci/drivers/nvme/module", "module/nvme")
+	sysfstest.Link(t, sysRoot, "bus/usb/drivers/usb-storage/module", "module/usb_storage")
+
+	ahci := fakePCIController(t, sysRoot, "0000:00:17.0", "8086", "a352", "ahci")
+	sata := ahci + "/ata1/host0/target0:0:0/0:0:0:0"
+	sysfstest.Link(t, sysRoot, sata+"/driver", "bus/scsi/drivers/sd")
+	fakeBlockDevice(t, sysRoot, "8:0", sata+"/block/sda", sata)
+	sysfstest.WriteFile(t, sysRoot, sata+"/block/sda/sda1/partition", "1\n")
+	sysfstest.Link(t, sysRoot, "dev/block/8:1", sata+"/block/sda/sda1")
+
+	nvme := fakePCIController(t, sysRoot, "0000:01:00.0", "144d", "a808", "nvme")
+	fakeBlockDevice(t, sysRoot, "259:0", nvme+"/nvme/nvme0/nvme0n1", nvme+"/nvme/nvme0")
+
+	xhci := fakePCIController(t, sysRoot, "0000:00:14.0", "8086", "a36d", "xhci_hcd")
+	usbIntf := xhci + "/usb2/2-1/2-1:1.0"
+	sysfstest.Link(t, sysRoot, usbIntf+"/driver", "bus/usb/drivers/usb-storage")
+	usb := usbIntf + "/host6/target6:0:0/6:0:0:0"
+	sysfstest.Link(t, sysRoot, usb+"/driver", "bus/scsi/drivers/sd")
+	fakeBlockDevice(t, sysRoot, "8:16", usb+"/block/sdb", usb)
+
+	fakeBlockDevice(t, sysRoot, "7:0", "devices/virtual/block/loop0", "")
+	return sysRoot
+}
+
+func TestLookup(t *testing.T) {
+	sysRoot := newFakeSysfs(t)
+	ahci := &device.Controller{Address: "0000:00:17.0", VendorID: "8086", DeviceID: "a352", Driver: "ahci"}
+
+	tests := []struct {
+		name         string
+		major, minor int
+		want         Info
+	}{
+		{"sata disk", 8, 0, Info{Driver: "sd", Module: "sd_mod", Transport: "sata", Controller: ahci}},
+		{"partition", 8, 1, Info{Driver: "sd", Module: "sd_mod", Transport: "sata", Controller: ahci}},
+		{"nvme namespace", 259, 0, Info{
+			Driver: "nvme", Module: "nvme", Transport: "nvme",
+			Controller: &device.Controller{Address: "0000:01:00.0", VendorID: "144d", DeviceID: "a808", Driver: "nvme", Module: "nvme"},
+		}},
+		{"usb disk", 8, 16, Info{
+			Driver: "sd", Module: "sd_mod", Transport: "usb",
+			Controller: &device.Controller{Address: "0000:00:14.0", VendorID: "8086", DeviceID: "a36d", Driver: "xhci_hcd"},
+		}},
+		{"loop device", 7, 0, Info{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Lookup(sysRoot, tt.major, tt.minor)
+			if err != nil {
+				t.Fatalf("Lookup: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v (controller %+v), want %+v (controller %+v)", got, got.Controller, tt.want, tt.want.Controller)
+			}
+		})
+	}
+}
+
+func TestEnricher(t *testing.T) {
+	e := NewEnricher(newFakeSysfs(t))
+
+	dev := device.BlockDevice{Name: "nvme0n1", Path: "/dev/nvme0n1", Major: 259}
+	if err := e.Enrich(context.Background(), &dev); err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if dev.Driver != "nvme" || dev.Transport != "nvme" || dev.Controller == nil || dev.Controller.Address != "0000:01:00.0" {
+		t.Errorf("unexpected driver information: driver=%q transport=%q controller=%+v", dev.Driver, dev.Transport, dev.Controller)
+	}
+
+	// Devices missing from sysfs are left unchanged.
+	missing := device.BlockDevice{Name: "sdz", Path: "/dev/sdz", Major: 65, Minor: 160}
+	if err := e.Enrich(context.Background(), &missing); err != nil {
+		t.Fatalf("Enrich missing device: %v", err)
+	}
+	if missing.Driver != "" || missing.Controller != nil {
+		t.Errorf("unexpected driver information for a missing device: %+v", missing)
+	}
+}
@@ -0,0 +1,63 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Enricher fills the driver, module, transport and controller of block devices from sysfs.
+type Enricher struct {
+	// SysRoot is the mount point of sysfs (e.g. "/sys", or "/host/sys" in a container).
+	SysRoot string
+}
+
+// NewEnricher creates a new Enricher reading the sysfs mounted at sysRoot.
+// An empty sysRoot reads /sys.
+func NewEnricher(sysRoot string) *Enricher {
+	if sysRoot == "" {
+		sysRoot = "/sys"
+	}
+	return &Enricher{SysRoot: sysRoot}
+}
+
+// Name returns "driver".
+func (e *Enricher) Name() string {
+	return "driver"
+}
+
+// Fields returns the driver fields.
+func (e *Enricher) Fields() []string {
+	return []string{"driver", "module", "transport", "controller"}
+}
+
+// Enrich looks up the driver information of the device. Devices missing from sysfs,
+// as when sysfs is not mounted, are left unchanged.
+func (e *Enricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	info, err := Lookup(e.SysRoot, dev.Major, dev.Minor)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Debug().Str("device", dev.Path).Err(err).Msg("device not found in sysfs")
+			return nil
+		}
+		return fmt.Errorf("failed to read driver information: %w", err)
+	}
+
+	dev.Driver = info.Driver
+	dev.Module = info.Module
+	dev.Transport = info.Transport
+	dev.Controller = info.Controller
+
+	log.Debug().
+		Str("device", dev.Path).
+		Str("driver", dev.Driver).
+		Str("transport", dev.Transport).
+		Bool("controller", dev.Controller != nil).
+		Msg("enriched device with driver information")
+	return nil
+}
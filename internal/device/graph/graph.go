@@ -0,0 +1,310 @@
+// Package graph builds the dependency graph of block devices from sysfs, from the
+// physical disks up through partitions, device-mapper, md arrays and loop devices
+// to the mounted filesystems, and computes what depends on a device.
+package graph
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/md"
+)
+
+// Node kinds.
+const (
+	KindDisk      = "disk"
+	KindPartition = "part"
+	KindLVM       = "lvm"
+	KindCrypt     = "crypt"
+	KindMultipath = "mpath"
+	KindDM        = "dm"
+	KindRAID      = "raid"
+	KindLoop      = "loop"
+	// KindFile is the backing file of a loop device.
+	KindFile = "file"
+	// KindMount is a mounted filesystem.
+	KindMount = "mount"
+)
+
+// Node is a block device, loop backing file or mount in the dependency graph.
+type Node struct {
+	// ID identifies the node: the kernel name of block devices (e.g. "sda1"),
+	// "file:<path>" for backing files and "mount:<mount ID>" for mounts.
+	ID string `json:"id"`
+	// Kind is one of the Kind constants.
+	Kind string `json:"kind"`
+	// Name is the device path (e.g. "/dev/mapper/vg0-root"), the backing file path
+	// or the mount point.
+	Name string `json:"name"`
+	// Level is the RAID level of md arrays (e.g. "raid1").
+	Level string `json:"level,omitempty"`
+	// RaidDisks is the number of devices of md arrays, spares excluded.
+	RaidDisks int `json:"raidDisks,omitempty"`
+	// Degraded is the number of missing or failed devices of md arrays, from md/degraded.
+	Degraded int `json:"degraded,omitempty"`
+	// Roles maps the IDs of the members of md arrays to their role, one of the
+	// device.RAIDRole constants (e.g. "spare").
+	Roles map[string]string `json:"roles,omitempty"`
+	// Offline is true for SCSI devices whose device/state is not "running", like a
+	// failed multipath path.
+	Offline bool `json:"offline,omitempty"`
+	// Partition is the partition number of partitions (e.g. 1 for sda1).
+	Partition int `json:"partition,omitempty"`
+	// Lowers are the IDs of the nodes this node is built on, sorted.
+	Lowers []string `json:"lowers,omitempty"`
+	// Uppers are the IDs of the nodes built on this node, sorted.
+	Uppers []string `json:"uppers,omitempty"`
+}
+
+// Graph is the dependency graph of the block devices.
+type Graph struct {
+	// Nodes maps node IDs to nodes.
+	Nodes map[string]*Node
+}
+
+// Build reads the block devices of /sys/class/block and their partitions, slaves and
+// loop backing files, and adds a node for each mount of a block device.
+// ctx is checked before each device.
+func Build(ctx context.Context, sysRoot string, mounts []device.MountEntry) (*Graph, error) {
+	classDir := filepath.Join(sysRoot, "class", "block")
+	entries, err := os.ReadDir(classDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", classDir, err)
+	}
+
+	g := &Graph{Nodes: make(map[string]*Node)}
+	devNums := make(map[string]string)
+	var loops []*Node
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		name := entry.Name()
+		node := readBlockNode(filepath.Join(classDir, name), name)
+		g.Nodes[name] = node
+		if devNum := device.ReadSysfsAttr(filepath.Join(classDir, name, "dev")); devNum != "" {
+			devNums[devNum] = name
+		}
+		if node.Kind == KindLoop {
+			loops = append(loops, node)
+		}
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		dir := filepath.Join(classDir, name)
+		if _, err := os.Stat(filepath.Join(dir, "partition")); err == nil {
+			if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+				g.link(filepath.Base(filepath.Dir(resolved)), name)
+			}
+		}
+		for _, slave := range device.ReadDirNames(filepath.Join(dir, "slaves")) {
+			g.link(slave, name)
+		}
+	}
+
+	g.addMounts(mounts, devNums)
+	for _, loop := range loops {
+		g.addBackingFile(filepath.Join(classDir, loop.ID), loop)
+	}
+
+	for _, node := range g.Nodes {
+		sort.Strings(node.Lowers)
+		sort.Strings(node.Uppers)
+	}
+	log.Debug().Int("nodeCount", len(g.Nodes)).Msg("device graph built")
+	return g, nil
+}
+
+// readBlockNode classifies the block device in dir.
+func readBlockNode(dir, kernelName string) *Node {
+	node := &Node{ID: kernelName, Kind: KindDisk, Name: "/dev/" + kernelName}
+	dmUUID := device.ReadSysfsAttr(filepath.Join(dir, "dm", "uuid"))
+	if dmName := device.ReadSysfsAttr(filepath.Join(dir, "dm", "name")); dmName != "" {
+		node.Name = "/dev/mapper/" + dmName
+	}
+	switch {
+	case device.PathExists(filepath.Join(dir, "partition")):
+		node.Kind = KindPartition
+		node.Partition, _ = strconv.Atoi(device.ReadSysfsAttr(filepath.Join(dir, "partition")))
+	case device.PathExists(filepath.Join(dir, "md")):
+		node.Kind = KindRAID
+		node.Level = device.ReadSysfsAttr(filepath.Join(dir, "md", "level"))
+		node.RaidDisks, _ = strconv.Atoi(device.ReadSysfsAttr(filepath.Join(dir, "md", "raid_disks")))
+		node.Degraded, _ = strconv.Atoi(device.ReadSysfsAttr(filepath.Join(dir, "md", "degraded")))
+		node.Roles = readMemberRoles(filepath.Join(dir, "md"))
+	case device.PathExists(filepath.Join(dir, "loop")):
+		node.Kind = KindLoop
+	case device.PathExists(filepath.Join(dir, "dm")):
+		node.Kind = dmKind(dmUUID)
+	}
+	if state := device.ReadSysfsAttr(filepath.Join(dir, "device", "state")); state != "" && state != "running" {
+		node.Offline = true
+	}
+	return node
+}
+
+// readMemberRoles reads the role of each member of the md array in dir from its
+// md/dev-<kernelName> directory.
+func readMemberRoles(dir string) map[string]string {
+	roles := make(map[string]string)
+	for _, entry := range device.ReadDirNames(dir) {
+		kernelName, ok := strings.CutPrefix(entry, "dev-")
+		if !ok {
+			continue
+		}
+		state := device.ReadSysfsAttr(filepath.Join(dir, entry, "state"))
+		_, err := strconv.Atoi(device.ReadSysfsAttr(filepath.Join(dir, entry, "slot")))
+		roles[kernelName] = md.MemberRole(state, err == nil)
+	}
+	if len(roles) == 0 {
+		return nil
+	}
+	return roles
+}
+
+// dmKind classifies a device-mapper device by the prefix of its UUID.
+func dmKind(uuid string) string {
+	prefix, _, _ := strings.Cut(uuid, "-")
+	switch strings.ToUpper(prefix) {
+	case "LVM":
+		return KindLVM
+	case "CRYPT":
+		return KindCrypt
+	case "MPATH":
+		return KindMultipath
+	}
+	if strings.HasPrefix(strings.ToLower(prefix), "part") {
+		return KindPartition
+	}
+	return KindDM
+}
+
+// addMounts adds a node on top of the device of each mount, found by device number
+// or, for btrfs which uses anonymous device numbers, by source path.
+func (g *Graph) addMounts(mounts []device.MountEntry, devNums map[string]string) {
+	byPath := make(map[string]string, len(g.Nodes))
+	for id, node := range g.Nodes {
+		byPath[node.Name] = id
+	}
+	for _, m := range mounts {
+		lower, ok := devNums[m.DevNum()]
+		if !ok {
+			lower, ok = byPath[m.Source]
+		}
+		if !ok {
+			continue
+		}
+		id := "mount:" + strconv.Itoa(m.MountID)
+		g.Nodes[id] = &Node{ID: id, Kind: KindMount, Name: m.MountPoint}
+		g.link(lower, id)
+	}
+}
+
+// addBackingFile adds the backing file of a loop device below it, on top of the
+// mount holding the file.
+func (g *Graph) addBackingFile(dir string, loop *Node) {
+	path := device.ReadSysfsAttr(filepath.Join(dir, "loop", "backing_file"))
+	if path == "" {
+		return
+	}
+	id := "file:" + path
+	if _, ok := g.Nodes[id]; !ok {
+		g.Nodes[id] = &Node{ID: id, Kind: KindFile, Name: path}
+		if mount := g.mountHolding(path); mount != "" {
+			g.link(mount, id)
+		}
+	}
+	g.link(id, loop.ID)
+}
+
+// mountHolding returns the ID of the mount with the longest mount point containing path.
+func (g *Graph) mountHolding(path string) string {
+	best, bestLen := "", -1
+	for id, node := range g.Nodes {
+		if node.Kind != KindMount || !underMountPoint(path, node.Name) {
+			continue
+		}
+		if len(node.Name) > bestLen || (len(node.Name) == bestLen && id < best) {
+			best, bestLen = id, len(node.Name)
+		}
+	}
+	return best
+}
+
+// underMountPoint reports whether path is inside the mount point.
+func underMountPoint(path, mountPoint string) bool {
+	return mountPoint == "/" || path == mountPoint || strings.HasPrefix(path, strings.TrimSuffix(mountPoint, "/")+"/")
+}
+
+// link records that upper is built on lower. Unknown nodes are ignored.
+func (g *Graph) link(lower, upper string) {
+	l, ok1 := g.Nodes[lower]
+	u, ok2 := g.Nodes[upper]
+	if !ok1 || !ok2 {
+		return
+	}
+	l.Uppers = append(l.Uppers, upper)
+	u.Lowers = append(u.Lowers, lower)
+}
+
+// Lookup finds a node by ID, device path, kernel name or mount point.
+func (g *Graph) Lookup(name string) (*Node, bool) {
+	if node, ok := g.Nodes[name]; ok {
+		return node, true
+	}
+	if node, ok := g.Nodes[strings.TrimPrefix(name, "/dev/")]; ok && node.Kind != KindMount && node.Kind != KindFile {
+		return node, true
+	}
+	// Symlinks such as /dev/disk/by-id/... or /dev/vg0/root resolve to the kernel name.
+	if resolved, err := filepath.EvalSymlinks(name); err == nil {
+		if node, ok := g.Nodes[filepath.Base(resolved)]; ok && strings.HasPrefix(resolved, "/dev/") {
+			return node, true
+		}
+	}
+	for _, id := range g.sortedIDs() {
+		if g.Nodes[id].Name == name {
+			return g.Nodes[id], true
+		}
+	}
+	return nil, false
+}
+
+// Below returns the IDs of the nodes the node id is built on, directly or through
+// other nodes, in depth-first order starting with id itself.
+func (g *Graph) Below(id string) []string {
+	var below []string
+	visited := make(map[string]bool)
+	var walk func(id string)
+	walk = func(id string) {
+		node, ok := g.Nodes[id]
+		if !ok || visited[id] {
+			return
+		}
+		visited[id] = true
+		below = append(below, id)
+		for _, lower := range node.Lowers {
+			walk(lower)
+		}
+	}
+	walk(id)
+	return below
+}
+
+// sortedIDs returns the node IDs in order.
+func (g *Graph) sortedIDs() []string {
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,296 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/sysfstest"
+)
+
+// newFixture builds a sysfs where:
+//   - sda1 and sdb1 are plain partitions, sda2 and sdb2 the mirrors of the RAID1 md0;
+//   - md0 holds the LUKS mapping luks-md0, which is the PV of vg0-root and vg0-data;
+//   - mpatha has the two paths sdc and sdd;
+//   - loop0 is backed by a file on the /srv filesystem of sdb1.
+func newFixture(t *testing.T) (string, []device.MountEntry) {
+	t.Helper()
+	sysRoot := t.TempDir()
+	scsi := filepath.Join("devices", "pci0000:00", "0000:00:17.0", "block")
+	virtual := filepath.Join("devices", "virtual", "block")
+
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sda"), "8:0", nil)
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sda", "sda1"), "8:1", map[string]string{"partition": "1"})
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sda", "sda2"), "8:2", map[string]string{"partition": "2"})
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sdb"), "8:16", nil)
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sdb", "sdb1"), "8:17", map[string]string{"partition": "1"})
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sdb", "sdb2"), "8:18", map[string]string{"partition": "2"})
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sdc"), "8:32", nil)
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sdd"), "8:48", nil)
+
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "md0"), "9:0",
+		map[string]string{"md/level": "raid1", "md/raid_disks": "2"}, "sda2", "sdb2")
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "dm-0"), "253:0",
+		map[string]string{"dm/name": "luks-md0", "dm/uuid": "CRYPT-LUKS2-0123-luks-md0"}, "md0")
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "dm-1"), "253:1",
+		map[string]string{"dm/name": "vg0-root", "dm/uuid": "LVM-vg0root"}, "dm-0")
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "dm-2"), "253:2",
+		map[string]string{"dm/name": "vg0-data", "dm/uuid": "LVM-vg0data"}, "dm-0")
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "dm-3"), "253:3",
+		map[string]string{"dm/name": "mpatha", "dm/uuid": "mpath-3600508b400105e21"}, "sdc", "sdd")
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "loop0"), "7:0",
+		map[string]string{"loop/backing_file": "/srv/images/disk.img"})
+
+	mounts := []device.MountEntry{
+		{MountID: 21, MountPoint: "/", Major: 253, Minor: 1, Source: "/dev/mapper/vg0-root"},
+		{MountID: 22, MountPoint: "/boot", Major: 8, Minor: 1, Source: "/dev/sda1"},
+		{MountID: 23, MountPoint: "/srv", Major: 8, Minor: 17, Source: "/dev/sdb1"},
+		{MountID: 24, MountPoint: "/mnt/img", Major: 7, Minor: 0, Source: "/dev/loop0"},
+		{MountID: 25, MountPoint: "/data", Major: 0, Minor: 40, Source: "/dev/mapper/vg0-data"},
+		{MountID: 26, MountPoint: "/proc", Source: "proc"},
+	}
+	return sysRoot, mounts
+}
+
+func TestBuild(t *testing.T) {
+	sysRoot, mounts := newFixture(t)
+	g, err := Build(context.Background(), sysRoot, mounts)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	var lines []string
+	for _, id := range g.sortedIDs() {
+		node := g.Nodes[id]
+		lines = append(lines, fmt.Sprintf("%s %s %s <- %v", id, node.Kind, node.Name, node.Lowers))
+	}
+	want := []string{
+		"dm-0 crypt /dev/mapper/luks-md0 <- [md0]",
+		"dm-1 lvm /dev/mapper/vg0-root <- [dm-0]",
+		"dm-2 lvm /dev/mapper/vg0-data <- [dm-0]",
+		"dm-3 mpath /dev/mapper/mpatha <- [sdc sdd]",
+		"file:/srv/images/disk.img file /srv/images/disk.img <- [mount:23]",
+		"loop0 loop /dev/loop0 <- [file:/srv/images/disk.img]",
+		"md0 raid /dev/md0 <- [sda2 sdb2]",
+		"mount:21 mount / <- [dm-1]",
+		"mount:22 mount /boot <- [sda1]",
+		"mount:23 mount /srv <- [sdb1]",
+		"mount:24 mount /mnt/img <- [loop0]",
+		"mount:25 mount /data <- [dm-2]",
+		"sda disk /dev/sda <- []",
+		"sda1 part /dev/sda1 <- [sda]",
+		"sda2 part /dev/sda2 <- [sda]",
+		"sdb disk /dev/sdb <- []",
+		"sdb1 part /dev/sdb1 <- [sdb]",
+		"sdb2 part /dev/sdb2 <- [sdb]",
+		"sdc disk /dev/sdc <- []",
+		"sdd disk /dev/sdd <- []",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected graph:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+	if md0 := g.Nodes["md0"]; md0.Level != "raid1" || md0.RaidDisks != 2 {
+		t.Errorf("unexpected md0 %+v", md0)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	sysRoot, mounts := newFixture(t)
+	g, err := Build(context.Background(), sysRoot, mounts)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	for name, want := range map[string]string{
+		"sda":                  "sda",
+		"/dev/sda":             "sda",
+		"/dev/mapper/vg0-root": "dm-1",
+		"/srv":                 "mount:23",
+	} {
+		if node, ok := g.Lookup(name); !ok || node.ID != want {
+			t.Errorf("Lookup(%q) = %v, %t, want %s", name, node, ok, want)
+		}
+	}
+	if _, ok := g.Lookup("/dev/sdz"); ok {
+		t.Error("expected an unknown device not to be found")
+	}
+}
+
+func TestImpact(t *testing.T) {
+	sysRoot, mounts := newFixture(t)
+	g, err := Build(context.Background(), sysRoot, mounts)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	summary := func(id string) []string {
+		var lines []string
+		for _, e := range g.Impact(id) {
+			lines = append(lines, fmt.Sprintf("%d %s %s: %s", e.Depth, e.Impact, e.Name, e.Reason))
+		}
+		return lines
+	}
+
+	tests := map[string][]string{
+		"sdb": {
+			"1 fail /dev/sdb1: needs /dev/sdb",
+			"1 fail /dev/sdb2: needs /dev/sdb",
+			"2 degrade /dev/md0: raid1 loses 1 of 2 devices",
+			"2 fail /srv: needs /dev/sdb1",
+			"3 degrade /dev/mapper/luks-md0: built on degraded /dev/md0",
+			"3 fail /srv/images/disk.img: needs /srv",
+			"4 fail /dev/loop0: needs /srv/images/disk.img",
+			"4 degrade /dev/mapper/vg0-data: built on degraded /dev/mapper/luks-md0",
+			"4 degrade /dev/mapper/vg0-root: built on degraded /dev/mapper/luks-md0",
+			"5 degrade /: built on degraded /dev/mapper/vg0-root",
+			"5 degrade /data: built on degraded /dev/mapper/vg0-data",
+			"5 fail /mnt/img: needs /dev/loop0",
+		},
+		"md0": {
+			"1 fail /dev/mapper/luks-md0: needs /dev/md0",
+			"2 fail /dev/mapper/vg0-data: needs /dev/mapper/luks-md0",
+			"2 fail /dev/mapper/vg0-root: needs /dev/mapper/luks-md0",
+			"3 fail /: needs /dev/mapper/vg0-root",
+			"3 fail /data: needs /dev/mapper/vg0-data",
+		},
+		"sdc":      {"1 degrade /dev/mapper/mpatha: mpath loses 1 of 2 devices"},
+		"mount:22": nil,
+	}
+	for id, want := range tests {
+		if got := summary(id); !reflect.DeepEqual(got, want) {
+			t.Errorf("Impact(%s):\n%s\nwant:\n%s", id, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestImpact_RAIDTolerance(t *testing.T) {
+	g := &Graph{Nodes: map[string]*Node{}}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		g.Nodes[id] = &Node{ID: id, Kind: KindDisk, Name: "/dev/" + id}
+	}
+	g.Nodes["md0"] = &Node{ID: "md0", Kind: KindRAID, Name: "/dev/md0", Level: "raid5", RaidDisks: 4}
+	g.Nodes["md1"] = &Node{ID: "md1", Kind: KindRAID, Name: "/dev/md1", Level: "raid0", RaidDisks: 2}
+	for _, lower := range []string{"a", "b", "c", "d"} {
+		g.link(lower, "md0")
+	}
+	g.link("a", "md1")
+	g.link("b", "md1")
+	g.Nodes["md2"] = &Node{ID: "md2", Kind: KindRAID, Name: "/dev/md2", Level: "raid5", RaidDisks: 2}
+	g.link("md0", "md2")
+
+	effects := g.Impact("a")
+	got := make(map[string]string)
+	for _, e := range effects {
+		got[e.Name] = e.Impact
+	}
+	want := map[string]string{"/dev/md0": ImpactDegrade, "/dev/md1": ImpactFail, "/dev/md2": ImpactDegrade}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestImpact_DegradedAndOffline(t *testing.T) {
+	sysRoot := t.TempDir()
+	scsi := filepath.Join("devices", "pci0000:00", "0000:00:17.0", "block")
+	virtual := filepath.Join("devices", "virtual", "block")
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sda"), "8:0", map[string]string{"device/state": "running"})
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sdb"), "8:16", map[string]string{"device/state": "running"})
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sdc"), "8:32", map[string]string{"device/state": "running"})
+	sysfstest.Block(t, sysRoot, filepath.Join(scsi, "sdd"), "8:48", map[string]string{"device/state": "transport-offline"})
+	// md0 already runs without its third mirror.
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "md0"), "9:0",
+		map[string]string{"md/level": "raid1", "md/raid_disks": "3", "md/degraded": "1"}, "sda", "sdb")
+	// mpatha has lost its path through sdd.
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "dm-0"), "253:0",
+		map[string]string{"dm/name": "mpatha", "dm/uuid": "mpath-3600508b400105e21"}, "sdc", "sdd")
+
+	g, err := Build(context.Background(), sysRoot, nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if md0 := g.Nodes["md0"]; md0.Degraded != 1 || !g.Nodes["sdd"].Offline || g.Nodes["sdc"].Offline {
+		t.Fatalf("unexpected md0 %+v or path states", md0)
+	}
+
+	tests := map[string]Effect{
+		"sda": {Impact: ImpactDegrade, Reason: "raid1 loses 1 of 3 devices"},
+		"sdc": {Impact: ImpactFail, Reason: "mpath loses 1 of 2 devices"},
+		"sdd": {Impact: ImpactDegrade, Reason: "mpath loses 1 of 2 devices"},
+	}
+	for id, want := range tests {
+		effects := g.Impact(id)
+		if len(effects) != 1 || effects[0].Impact != want.Impact || effects[0].Reason != want.Reason {
+			t.Errorf("Impact(%s) = %+v, want %s: %s", id, effects, want.Impact, want.Reason)
+		}
+	}
+
+	// A raid5 already missing a device fails on the next loss.
+	g.Nodes["md0"].Level = "raid5"
+	if effects := g.Impact("sda"); len(effects) != 1 || effects[0].Impact != ImpactFail {
+		t.Errorf("a degraded raid5 must fail on a second loss, got %+v", effects)
+	}
+}
+
+func TestImpact_Spare(t *testing.T) {
+	sysRoot := t.TempDir()
+	scsi := filepath.Join("devices", "pci0000:00", "0000:00:17.0", "block")
+	virtual := filepath.Join("devices", "virtual", "block")
+	for i, name := range []string{"sda", "sdb", "sdc", "sdd", "sde"} {
+		sysfstest.Block(t, sysRoot, filepath.Join(scsi, name), fmt.Sprintf("8:%d", i*16), nil)
+	}
+	// md0 is a raid5 already missing a device, with sdc as its hot spare.
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "md0"), "9:0", map[string]string{
+		"md/level": "raid5", "md/raid_disks": "3", "md/degraded": "1",
+		"md/dev-sda/state": "in_sync", "md/dev-sda/slot": "0",
+		"md/dev-sdb/state": "in_sync", "md/dev-sdb/slot": "1",
+		"md/dev-sdc/state": "spare", "md/dev-sdc/slot": "none",
+	}, "sda", "sdb", "sdc")
+	// md1 is a healthy raid1 with sde as its hot spare.
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "md1"), "9:1", map[string]string{
+		"md/level": "raid1", "md/raid_disks": "1", "md/degraded": "0",
+		"md/dev-sdd/state": "in_sync", "md/dev-sdd/slot": "0",
+		"md/dev-sde/state": "spare", "md/dev-sde/slot": "none",
+	}, "sdd", "sde")
+	sysfstest.Block(t, sysRoot, filepath.Join(virtual, "dm-0"), "253:0",
+		map[string]string{"dm/name": "vg0-data", "dm/uuid": "LVM-abc"}, "md0")
+
+	g, err := Build(context.Background(), sysRoot, nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	wantRoles := map[string]string{"sda": device.RAIDRoleActive, "sdb": device.RAIDRoleActive, "sdc": device.RAIDRoleSpare}
+	if roles := g.Nodes["md0"].Roles; !reflect.DeepEqual(roles, wantRoles) {
+		t.Errorf("md0 roles = %v, want %v", roles, wantRoles)
+	}
+
+	for _, id := range []string{"sdc", "sde"} {
+		if effects := g.Impact(id); len(effects) != 0 {
+			t.Errorf("losing the spare %s must affect nothing, got %+v", id, effects)
+		}
+	}
+	got := make(map[string]string)
+	for _, e := range g.Impact("sda") {
+		got[e.ID] = e.Impact
+	}
+	if want := map[string]string{"md0": ImpactFail, "dm-0": ImpactFail}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Impact(sda) = %v, want %v", got, want)
+	}
+}
+
+func TestBelow(t *testing.T) {
+	sysRoot, mounts := newFixture(t)
+	g, err := Build(context.Background(), sysRoot, mounts)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	want := []string{"mount:21", "dm-1", "dm-0", "md0", "sda2", "sda", "sdb2", "sdb"}
+	if got := g.Below("mount:21"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if g.Nodes["sdb2"].Partition != 2 {
+		t.Errorf("unexpected partition number %d", g.Nodes["sdb2"].Partition)
+	}
+}
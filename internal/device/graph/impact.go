@@ -0,0 +1,229 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Impacts of losing a device on the nodes that depend on it.
+const (
+	// ImpactFail means the node stops working.
+	ImpactFail = "fail"
+	// ImpactDegrade means the node keeps working with reduced redundancy.
+	ImpactDegrade = "degrade"
+)
+
+// Effect is the impact of losing a device on one node that depends on it.
+type Effect struct {
+	// ID, Kind and Name identify the affected node, as in Node.
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Impact is ImpactFail or ImpactDegrade.
+	Impact string `json:"impact"`
+	// Reason explains the impact (e.g. "raid1 loses 1 of 2 devices").
+	Reason string `json:"reason"`
+	// Depth is the distance from the lost device, following the first failing or degraded lower.
+	Depth int `json:"depth"`
+}
+
+// Impact returns the nodes affected by the loss of the node id, in dependency order
+// (every node after the nodes it is built on), then by name. A node fails when a
+// device it needs fails; md arrays and multipath devices only degrade while they
+// keep enough devices, counting the devices an array already misses and the paths
+// already offline. Losing a spare of an array affects nothing.
+func (g *Graph) Impact(id string) []Effect {
+	affected := g.uppersOf(id)
+	states := map[string]Effect{id: {ID: id, Impact: ImpactFail}}
+
+	var effects []Effect
+	for _, upperID := range g.topologicalOrder(affected) {
+		node := g.Nodes[upperID]
+		effect, ok := g.evaluate(node, states)
+		if !ok {
+			continue
+		}
+		states[upperID] = effect
+		effects = append(effects, effect)
+	}
+	sort.SliceStable(effects, func(i, j int) bool {
+		if effects[i].Depth != effects[j].Depth {
+			return effects[i].Depth < effects[j].Depth
+		}
+		return effects[i].Name < effects[j].Name
+	})
+	return effects
+}
+
+// evaluate computes the impact on node from the impacts on its lowers, and false
+// when the node is not affected.
+func (g *Graph) evaluate(node *Node, states map[string]Effect) (Effect, bool) {
+	var failed, degraded []Effect
+	for _, lower := range node.Lowers {
+		state, ok := states[lower]
+		switch {
+		case !ok:
+		case node.Kind == KindRAID && !g.carries(node, lower):
+			// Spares, faulty and rebuilding members hold no data the array relies on.
+		case state.Impact == ImpactFail:
+			failed = append(failed, state)
+		default:
+			degraded = append(degraded, state)
+		}
+	}
+	if len(failed) == 0 && len(degraded) == 0 {
+		return Effect{}, false
+	}
+
+	effect := Effect{ID: node.ID, Kind: node.Kind, Name: node.Name}
+	if len(failed) > 0 {
+		effect.Depth = failed[0].Depth + 1
+	} else {
+		effect.Depth = degraded[0].Depth + 1
+	}
+
+	if len(failed) > 0 {
+		if tolerated, total := g.tolerance(node); g.lost(node, failed) <= tolerated {
+			effect.Impact = ImpactDegrade
+			effect.Reason = fmt.Sprintf("%s loses %d of %d devices", levelOrKind(node), len(failed), total)
+			return effect, true
+		}
+		effect.Impact = ImpactFail
+		if node.Kind == KindRAID || node.Kind == KindMultipath {
+			_, total := g.tolerance(node)
+			effect.Reason = fmt.Sprintf("%s loses %d of %d devices", levelOrKind(node), len(failed), total)
+		} else {
+			effect.Reason = "needs " + g.nameOf(failed[0].ID)
+		}
+		return effect, true
+	}
+	effect.Impact = ImpactDegrade
+	effect.Reason = "built on degraded " + g.nameOf(degraded[0].ID)
+	return effect, true
+}
+
+// tolerance returns how many more lowers a node can lose and keep working, and its
+// number of devices. A multipath device keeps working while one live path is left; an
+// md array tolerates what its level allows less the devices it already misses. RAID10
+// is assumed to survive a single loss, which holds for every layout. The tolerance is
+// negative for a node that has already stopped working.
+func (g *Graph) tolerance(node *Node) (tolerated, total int) {
+	total = len(node.Lowers)
+	switch node.Kind {
+	case KindMultipath:
+		live := 0
+		for _, lower := range node.Lowers {
+			if g.carries(node, lower) {
+				live++
+			}
+		}
+		return live - 1, total
+	case KindRAID:
+	default:
+		return 0, total
+	}
+	if node.RaidDisks > 0 {
+		total = node.RaidDisks
+	}
+	switch node.Level {
+	case "raid1":
+		tolerated = total - 1
+	case "raid4", "raid5", "raid10":
+		tolerated = 1
+	case "raid6":
+		tolerated = 2
+	}
+	return tolerated - node.Degraded, total
+}
+
+// lost returns how many of the failed lowers of node it relies on, see carries.
+func (g *Graph) lost(node *Node, failed []Effect) int {
+	lost := 0
+	for _, effect := range failed {
+		if g.carries(node, effect.ID) {
+			lost++
+		}
+	}
+	return lost
+}
+
+// carries reports whether node relies on its lower: every lower but the offline paths
+// of a multipath device and the members of an md array that are not active, like
+// spares, faulty devices and members still being rebuilt.
+func (g *Graph) carries(node *Node, lower string) bool {
+	switch node.Kind {
+	case KindMultipath:
+		n, ok := g.Nodes[lower]
+		return !ok || !n.Offline
+	case KindRAID:
+		switch node.Roles[lower] {
+		case device.RAIDRoleSpare, device.RAIDRoleFaulty, device.RAIDRoleRebuilding, device.RAIDRoleReplacement:
+			return false
+		}
+	}
+	return true
+}
+
+// uppersOf returns the IDs of every node built directly or indirectly on the node id.
+func (g *Graph) uppersOf(id string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := []string{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		node, ok := g.Nodes[current]
+		if !ok {
+			continue
+		}
+		for _, upper := range node.Uppers {
+			if !seen[upper] {
+				seen[upper] = true
+				queue = append(queue, upper)
+			}
+		}
+	}
+	return seen
+}
+
+// topologicalOrder returns the IDs of the set with every node after its lowers in the set.
+func (g *Graph) topologicalOrder(set map[string]bool) []string {
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+		for _, lower := range g.Nodes[id].Lowers {
+			if set[lower] {
+				visit(lower)
+			}
+		}
+		order = append(order, id)
+	}
+	for _, id := range g.sortedIDs() {
+		if set[id] {
+			visit(id)
+		}
+	}
+	return order
+}
+
+// nameOf returns the name of the node id, or the ID of unknown nodes.
+func (g *Graph) nameOf(id string) string {
+	if node, ok := g.Nodes[id]; ok {
+		return node.Name
+	}
+	return id
+}
+
+// levelOrKind returns the RAID level of md arrays and the kind of other nodes.
+func levelOrKind(node *Node) string {
+	if node.Level != "" {
+		return node.Level
+	}
+	return node.Kind
+}
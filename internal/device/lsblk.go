@@ -1,9 +1,13 @@
 package device
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
+	"strconv"
+	"strings"
 
 	"github.com/dustin/go-humanize"
 	"github.com/rs/zerolog/log"
@@ -18,24 +22,41 @@ type lsblkOutput struct {
 // lsblkDevice maps a single device entry from lsblk JSON output.
 // With -b flag, size fields are returned as numeric bytes in JSON.
 // JSON null values are unmarshalled to Go zero values (0 for uint64, "" for string).
+// Partitions, LVM volumes, crypt mappings and RAID arrays are nested under Children.
 type lsblkDevice struct {
 	Name       string `json:"name"`
 	Path       string `json:"path"`
+	MajMin     string `json:"maj:min"`
 	UUID       string `json:"uuid"`
 	Serial     string `json:"serial"`
+	Model      string `json:"model"`
 	FSType     string `json:"fstype"`
 	Type       string `json:"type"`
 	Label      string `json:"label"`
 	MountPoint string `json:"mountpoint"`
-	Size       uint64 `json:"size"`
-	FSSize     uint64 `json:"fssize"`
-	FSAvail    uint64 `json:"fsavail"`
+	// MountPoints lists every mount point (lsblk >= 2.37). JSON null entries become "".
+	MountPoints []string `json:"mountpoints"`
+	Size        uint64   `json:"size"`
+	FSSize      uint64   `json:"fssize"`
+	FSAvail     uint64   `json:"fsavail"`
+	// Children holds the devices stacked on top of this one (e.g. partitions of a disk).
+	Children []lsblkDevice `json:"children"`
 }
 
+// lsblkColumns is the column list requested from lsblk.
+const lsblkColumns = "NAME,PATH,MAJ:MIN,UUID,SERIAL,MODEL,FSTYPE,TYPE,LABEL,MOUNTPOINTS,SIZE,FSSIZE,FSAVAIL"
+
+// lsblkLegacyColumns is used with lsblk versions older than 2.37, which lack MOUNTPOINTS.
+// Additional mounts are then only found through mountinfo enrichment.
+const lsblkLegacyColumns = "NAME,PATH,MAJ:MIN,UUID,SERIAL,MODEL,FSTYPE,TYPE,LABEL,MOUNTPOINT,SIZE,FSSIZE,FSAVAIL"
+
 // BlockDeviceProvider abstracts the retrieval of block device information.
 type BlockDeviceProvider interface {
+	// Name identifies the provider in logs and errors (e.g. "lsblk").
+	Name() string
 	// List returns all block devices detected by the system.
-	List() ([]BlockDevice, error)
+	// It stops and returns an error when ctx is done.
+	List(ctx context.Context) ([]BlockDevice, error)
 }
 
 // LsblkProvider implements BlockDeviceProvider by executing the lsblk command.
@@ -46,55 +67,208 @@ func NewLsblkProvider() *LsblkProvider {
 	return &LsblkProvider{}
 }
 
+// Name returns "lsblk".
+func (l *LsblkProvider) Name() string {
+	return ProviderLsblk
+}
+
 // List executes lsblk with -b (bytes) and returns the parsed block devices.
-func (l *LsblkProvider) List() ([]BlockDevice, error) {
+// Nested devices (partitions, LVM, crypt, RAID) are included in the flattened result.
+// lsblk is killed when ctx is done.
+func (l *LsblkProvider) List(ctx context.Context) ([]BlockDevice, error) {
+	out, err := runLsblk(ctx, lsblkColumns)
+	if err != nil && isUnknownColumnError(err) {
+		log.Debug().Err(err).Msg("lsblk does not support MOUNTPOINTS, retrying with MOUNTPOINT")
+		out, err = runLsblk(ctx, lsblkLegacyColumns)
+	}
+	if err != nil {
+		log.Debug().Err(err).Msg("lsblk execution failed")
+		return nil, fmt.Errorf("lsblk execution failed: %w", err)
+	}
+
+	log.Debug().Int("bytes", len(out)).Msg("lsblk raw output received")
+
+	devices, err := parseLsblkOutput(out)
+	if err != nil {
+		log.Debug().Err(err).Msg("lsblk JSON parsing failed")
+		return nil, fmt.Errorf("lsblk JSON parsing failed: %w", err)
+	}
+
+	log.Debug().Int("deviceCount", len(devices)).Msg("block devices parsed from lsblk")
+	return devices, nil
+}
+
+// runLsblk executes lsblk with -b (bytes) and JSON output for the given columns.
+// If ctx is done before lsblk exits, the process is killed and the context error is returned.
+func runLsblk(ctx context.Context, columns string) ([]byte, error) {
 	args := []string{
 		"--json", "-b",
-		"-o", "NAME,PATH,UUID,SERIAL,FSTYPE,TYPE,LABEL,MOUNTPOINT,SIZE,FSSIZE,FSAVAIL",
+		"-o", columns,
 	}
 	log.Debug().Strs("args", args).Msg("executing lsblk")
 
-	out, err := exec.Command("lsblk", args...).Output()
+	out, err := exec.CommandContext(ctx, "lsblk", args...).Output()
 	if err != nil {
-		log.Debug().Err(err).Msg("lsblk execution failed")
-		return nil, fmt.Errorf("lsblk execution failed: %w", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
 	}
+	return out, nil
+}
 
-	log.Debug().Int("bytes", len(out)).Msg("lsblk raw output received")
+// isUnknownColumnError reports whether lsblk rejected a column it does not know.
+func isUnknownColumnError(err error) bool {
+	return strings.Contains(err.Error(), "unknown column")
+}
 
+// parseLsblkOutput decodes lsblk JSON output and flattens the nested device tree.
+func parseLsblkOutput(out []byte) ([]BlockDevice, error) {
 	var raw lsblkOutput
 	if err := json.Unmarshal(out, &raw); err != nil {
-		log.Debug().Err(err).Msg("lsblk JSON parsing failed")
-		return nil, fmt.Errorf("lsblk JSON parsing failed: %w", err)
+		return nil, err
 	}
+	return flattenLsblkDevices(raw.BlockDevices), nil
+}
+
+// flattenLsblkDevices walks the lsblk device tree depth-first and returns every node
+// as a flat list, with parent/children references and ancestry filled in.
+// lsblk repeats a device under each of its parents (e.g. a RAID array under every member),
+// so devices are deduplicated by path and their parents are merged.
+func flattenLsblkDevices(entries []lsblkDevice) []BlockDevice {
+	devices := make([]BlockDevice, 0, len(entries))
+	indexByPath := make(map[string]int)
+
+	var walk func(entries []lsblkDevice, ancestry []string)
+	walk = func(entries []lsblkDevice, ancestry []string) {
+		var parent string
+		if len(ancestry) > 0 {
+			parent = ancestry[len(ancestry)-1]
+		}
+
+		for _, entry := range entries {
+			key := lsblkDeviceKey(entry)
+			idx, seen := indexByPath[key]
+			if !seen {
+				dev := newBlockDeviceFromLsblk(entry)
+				if parent != "" {
+					dev.Ancestry = append([]string(nil), ancestry...)
+				}
+				idx = len(devices)
+				indexByPath[key] = idx
+				devices = append(devices, dev)
+			}
 
-	devices := make([]BlockDevice, 0, len(raw.BlockDevices))
-	for _, entry := range raw.BlockDevices {
-		devices = append(devices, BlockDevice{
-			Name:                 entry.Name,
-			Path:                 entry.Path,
-			UUID:                 entry.UUID,
-			Serial:               entry.Serial,
-			FSType:               entry.FSType,
-			Type:                 entry.Type,
-			Label:                entry.Label,
-			MountPoint:           entry.MountPoint,
-			DeviceSizeBytes:      entry.Size,
-			DeviceSize:           humanizeBytes(entry.Size),
-			FileSystemSizeBytes:  entry.FSSize,
-			FileSystemSize:       humanizeBytes(entry.FSSize),
-			FileSystemAvailBytes: entry.FSAvail,
-			FileSystemAvail:      humanizeBytes(entry.FSAvail),
-		})
+			if parent != "" {
+				devices[idx].Parents = appendUnique(devices[idx].Parents, parent)
+				parentIdx := indexByPath[parent]
+				devices[parentIdx].Children = appendUnique(devices[parentIdx].Children, key)
+			}
+
+			// Children of a repeated device were already walked on its first occurrence.
+			if !seen {
+				walk(entry.Children, append(ancestry, key))
+			}
+		}
 	}
+	walk(entries, nil)
 
-	log.Debug().Int("deviceCount", len(devices)).Msg("block devices parsed from lsblk")
-	return devices, nil
+	return devices
+}
+
+// lsblkDeviceKey returns the identifier used to reference a device in the tree.
+// The device path is preferred; the kernel name is used if lsblk did not report it.
+func lsblkDeviceKey(entry lsblkDevice) string {
+	if entry.Path != "" {
+		return entry.Path
+	}
+	return entry.Name
+}
+
+// newBlockDeviceFromLsblk converts a single lsblk entry to a BlockDevice, without hierarchy.
+func newBlockDeviceFromLsblk(entry lsblkDevice) BlockDevice {
+	var partitionNumber int
+	if entry.Type == "part" {
+		partitionNumber = partitionNumberFromName(entry.Name)
+	}
+
+	mountPoints := entry.MountPoints
+	if len(mountPoints) == 0 && entry.MountPoint != "" {
+		mountPoints = []string{entry.MountPoint}
+	}
+	var mounts []Mount
+	var firstMountPoint string
+	for _, mountPoint := range mountPoints {
+		if mountPoint == "" {
+			continue
+		}
+		if firstMountPoint == "" {
+			firstMountPoint = mountPoint
+		}
+		mounts = append(mounts, Mount{MountPoint: mountPoint})
+	}
+
+	major, minor := parseDevNum(entry.MajMin)
+
+	return BlockDevice{
+		Name:                 entry.Name,
+		Path:                 entry.Path,
+		Major:                major,
+		Minor:                minor,
+		UUID:                 entry.UUID,
+		Serial:               entry.Serial,
+		Model:                strings.TrimSpace(entry.Model),
+		FSType:               entry.FSType,
+		Type:                 entry.Type,
+		Label:                entry.Label,
+		MountPoint:           firstMountPoint,
+		Mounts:               mounts,
+		DeviceSizeBytes:      entry.Size,
+		DeviceSize:           HumanizeBytes(entry.Size),
+		FileSystemSizeBytes:  entry.FSSize,
+		FileSystemSize:       HumanizeBytes(entry.FSSize),
+		FileSystemAvailBytes: entry.FSAvail,
+		FileSystemAvail:      HumanizeBytes(entry.FSAvail),
+		PartitionNumber:      partitionNumber,
+	}
+}
+
+// parseDevNum parses a "major:minor" device number. Invalid values return 0:0.
+func parseDevNum(devNum string) (major, minor int) {
+	majorStr, minorStr, found := strings.Cut(strings.TrimSpace(devNum), ":")
+	if !found {
+		return 0, 0
+	}
+	major, errMajor := strconv.Atoi(strings.TrimSpace(majorStr))
+	minor, errMinor := strconv.Atoi(strings.TrimSpace(minorStr))
+	if errMajor != nil || errMinor != nil {
+		return 0, 0
+	}
+	return major, minor
+}
+
+// partitionNumberFromName extracts the partition number from the trailing digits
+// of a partition name (sda1, nvme0n1p2, mmcblk0p1, mpatha-part3). Returns 0 if there are none.
+func partitionNumberFromName(name string) int {
+	end := len(name)
+	start := end
+	for start > 0 && name[start-1] >= '0' && name[start-1] <= '9' {
+		start--
+	}
+	number, err := strconv.Atoi(name[start:end])
+	if err != nil {
+		return 0
+	}
+	return number
 }
 
-// humanizeBytes converts a byte count to a human-readable string.
+// HumanizeBytes converts a byte count to a human-readable string (e.g. "1.0 GiB").
 // Returns empty string for 0 (typically means the value was not available).
-func humanizeBytes(bytes uint64) string {
+func HumanizeBytes(bytes uint64) string {
 	if bytes == 0 {
 		return ""
 	}
@@ -0,0 +1,111 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const nestedLsblkJSON = `{
+   "blockdevices": [
+      {"name": "sda", "path": "/dev/sda", "type": "disk", "size": 1000,
+         "children": [
+            {"name": "sda1", "path": "/dev/sda1", "type": "part", "fstype": "vfat", "mountpoint": "/boot/efi", "size": 100},
+            {"name": "sda2", "path": "/dev/sda2", "type": "part", "fstype": "linux_raid_member", "size": 900,
+               "children": [
+                  {"name": "md0", "path": "/dev/md0", "type": "raid1", "fstype": "ext4", "mountpoint": "/", "size": 890}
+               ]
+            }
+         ]
+      },
+      {"name": "sdb", "path": "/dev/sdb", "type": "disk", "size": 1000,
+         "children": [
+            {"name": "sdb1", "path": "/dev/sdb1", "type": "part", "fstype": "linux_raid_member", "size": 900,
+               "children": [
+                  {"name": "md0", "path": "/dev/md0", "type": "raid1", "fstype": "ext4", "mountpoint": "/", "size": 890}
+               ]
+            }
+         ]
+      },
+      {"name": "loop0", "path": "/dev/loop0", "type": "loop", "fstype": null, "size": 0}
+   ]
+}`
+
+func TestParseLsblkOutput_FlattensTree(t *testing.T) {
+	devices, err := parseLsblkOutput([]byte(nestedLsblkJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var paths []string
+	byPath := make(map[string]BlockDevice)
+	for _, dev := range devices {
+		paths = append(paths, dev.Path)
+		byPath[dev.Path] = dev
+	}
+
+	wantPaths := []string{"/dev/sda", "/dev/sda1", "/dev/sda2", "/dev/md0", "/dev/sdb", "/dev/sdb1", "/dev/loop0"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("unexpected device order:\ngot:  %v\nwant: %v", paths, wantPaths)
+	}
+
+	tests := []struct {
+		path         string
+		wantParents  []string
+		wantChildren []string
+		wantAncestry []string
+	}{
+		{path: "/dev/sda", wantChildren: []string{"/dev/sda1", "/dev/sda2"}},
+		{path: "/dev/sda1", wantParents: []string{"/dev/sda"}, wantAncestry: []string{"/dev/sda"}},
+		{path: "/dev/sda2", wantParents: []string{"/dev/sda"}, wantChildren: []string{"/dev/md0"}, wantAncestry: []string{"/dev/sda"}},
+		{path: "/dev/md0", wantParents: []string{"/dev/sda2", "/dev/sdb1"}, wantAncestry: []string{"/dev/sda", "/dev/sda2"}},
+		{path: "/dev/sdb1", wantParents: []string{"/dev/sdb"}, wantChildren: []string{"/dev/md0"}, wantAncestry: []string{"/dev/sdb"}},
+		{path: "/dev/loop0"},
+	}
+
+	for _, tt := range tests {
+		dev := byPath[tt.path]
+		if !reflect.DeepEqual(dev.Parents, tt.wantParents) {
+			t.Errorf("%s parents: got %v, want %v", tt.path, dev.Parents, tt.wantParents)
+		}
+		if !reflect.DeepEqual(dev.Children, tt.wantChildren) {
+			t.Errorf("%s children: got %v, want %v", tt.path, dev.Children, tt.wantChildren)
+		}
+		if !reflect.DeepEqual(dev.Ancestry, tt.wantAncestry) {
+			t.Errorf("%s ancestry: got %v, want %v", tt.path, dev.Ancestry, tt.wantAncestry)
+		}
+	}
+
+	if got := byPath["/dev/md0"].MountPoint; got != "/" {
+		t.Errorf("md0 mountpoint: got %q, want %q", got, "/")
+	}
+	if got := byPath["/dev/sda1"].DeviceSize; got != "100 B" {
+		t.Errorf("sda1 size: got %q, want %q", got, "100 B")
+	}
+}
+
+func TestPartitionNumberFromName(t *testing.T) {
+	tests := map[string]int{
+		"sda1":         1,
+		"nvme0n1p12":   12,
+		"mmcblk0p2":    2,
+		"mpatha-part3": 3,
+		"sda":          0,
+	}
+	for name, want := range tests {
+		if got := partitionNumberFromName(name); got != want {
+			t.Errorf("partitionNumberFromName(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestLsblkProvider_List_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewLsblkProvider().List(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
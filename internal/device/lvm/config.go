@@ -0,0 +1,218 @@
+package lvm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// section is a section of LVM2 metadata text, e.g. `vg0 { extent_size = 8192 ... }`.
+// Values are strings, int64s or []any lists of them.
+type section struct {
+	values   map[string]any
+	sections map[string]*section
+	// order keeps the subsection names in file order.
+	order []string
+}
+
+func newSection() *section {
+	return &section{values: make(map[string]any), sections: make(map[string]*section)}
+}
+
+// str returns a string value, or "" if it is missing or not a string.
+func (s *section) str(key string) string {
+	value, _ := s.values[key].(string)
+	return value
+}
+
+// num returns a numeric value, or 0 if it is missing or not a number.
+func (s *section) num(key string) int64 {
+	value, _ := s.values[key].(int64)
+	return value
+}
+
+// list returns the string elements of a list value.
+func (s *section) list(key string) []string {
+	list, _ := s.values[key].([]any)
+	var result []string
+	for _, value := range list {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+// children returns the subsections in file order.
+func (s *section) children() []*section {
+	result := make([]*section, 0, len(s.order))
+	for _, name := range s.order {
+		result = append(result, s.sections[name])
+	}
+	return result
+}
+
+// configParser parses the LVM2 metadata text format: `key = value` assignments and
+// `name { ... }` sections, with "#" comments. Values are quoted strings, integers
+// or bracketed lists of them.
+type configParser struct {
+	src string
+	pos int
+}
+
+// parseConfig parses LVM2 metadata text into its top-level section.
+func parseConfig(src string) (*section, error) {
+	p := &configParser{src: src}
+	root, err := p.parseBody(true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LVM metadata at offset %d: %w", p.pos, err)
+	}
+	return root, nil
+}
+
+// parseBody parses assignments and sections up to "}" or, at the top level, the end of input.
+func (p *configParser) parseBody(top bool) (*section, error) {
+	s := newSection()
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			if !top {
+				return nil, fmt.Errorf(`missing "}"`)
+			}
+			return s, nil
+		}
+		if p.src[p.pos] == '}' {
+			if top {
+				return nil, fmt.Errorf(`unexpected "}"`)
+			}
+			p.pos++
+			return s, nil
+		}
+
+		name := p.word()
+		if name == "" {
+			return nil, fmt.Errorf("expected a name, found %q", p.src[p.pos])
+		}
+		p.skipSpace()
+		switch {
+		case p.consume('{'):
+			child, err := p.parseBody(false)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := s.sections[name]; !ok {
+				s.order = append(s.order, name)
+			}
+			s.sections[name] = child
+		case p.consume('='):
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			s.values[name] = value
+		default:
+			return nil, fmt.Errorf(`expected "=" or "{" after %q`, name)
+		}
+	}
+}
+
+// parseValue parses a string, an integer or a list.
+func (p *configParser) parseValue() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("expected a value")
+	}
+	switch p.src[p.pos] {
+	case '"':
+		return p.parseString()
+	case '[':
+		p.pos++
+		list := []any{}
+		for {
+			p.skipSpace()
+			if p.consume(']') {
+				return list, nil
+			}
+			if len(list) > 0 && !p.consume(',') {
+				return nil, fmt.Errorf(`expected "," or "]"`)
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+	}
+	word := p.word()
+	number, err := strconv.ParseInt(word, 10, 64)
+	if err != nil {
+		// Unquoted words are not produced by LVM, but keep them as strings.
+		if word == "" {
+			return nil, fmt.Errorf("expected a value, found %q", p.src[p.pos])
+		}
+		return word, nil
+	}
+	return number, nil
+}
+
+// parseString parses a double-quoted string with backslash escapes.
+func (p *configParser) parseString() (string, error) {
+	p.pos++ // opening quote
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.pos < len(p.src) {
+				b.WriteByte(p.src[p.pos])
+				p.pos++
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// word reads a name or unquoted number.
+func (p *configParser) word() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || c == '-' || c == '.' || c == '+' ||
+			c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+// skipSpace skips whitespace, NUL padding and comments.
+func (p *configParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == 0:
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips c if it is the next character.
+func (p *configParser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package lvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// lvmUUIDPrefix starts the device-mapper UUID of every LVM device:
+// "LVM-<32-char VG UUID><32-char LV UUID>[-<layer>]".
+const lvmUUIDPrefix = "LVM-"
+
+// blockDevice is a block device as seen in /sys/class/block.
+type blockDevice struct {
+	kernelName string
+	// dmName and dmUUID are set for device-mapper devices.
+	dmName    string
+	dmUUID    string
+	sizeBytes uint64
+	// slaves and holders are kernel names.
+	slaves  []string
+	holders []string
+}
+
+// path returns the device node of the block device, /dev/mapper/<name> for device-mapper
+// devices, matching the BlockDevice paths of the providers.
+func (d blockDevice) path() string {
+	if d.dmName != "" {
+		return "/dev/mapper/" + d.dmName
+	}
+	return "/dev/" + d.kernelName
+}
+
+// readBlockDevice reads the sysfs attributes of the block device kernelName.
+func readBlockDevice(sysRoot, kernelName string) (blockDevice, error) {
+	dir := filepath.Join(sysRoot, "class", "block", kernelName)
+	if _, err := os.Stat(dir); err != nil {
+		return blockDevice{}, err
+	}
+	dev := blockDevice{
+		kernelName: kernelName,
+		dmName:     device.ReadSysfsAttr(filepath.Join(dir, "dm", "name")),
+		dmUUID:     device.ReadSysfsAttr(filepath.Join(dir, "dm", "uuid")),
+		slaves:     device.ReadDirNames(filepath.Join(dir, "slaves")),
+		holders:    device.ReadDirNames(filepath.Join(dir, "holders")),
+	}
+	if sectors, err := device.ReadSysfsUint(filepath.Join(dir, "size")); err == nil {
+		dev.sizeBytes = sectors * 512
+	}
+	return dev, nil
+}
+
+// lvmIdentity is what a device-mapper device tells about the LV it belongs to.
+type lvmIdentity struct {
+	vg, lv string
+	// lvUUID is the dashed LV UUID.
+	lvUUID string
+	// layer is the device-mapper layer of the LV (e.g. "tpool", "real", "cow"),
+	// empty for the top-level device.
+	layer string
+}
+
+// identify returns the LVM identity of a device-mapper device, and false for
+// devices that are not LVM logical volumes.
+func identify(dev blockDevice) (lvmIdentity, bool) {
+	if !strings.HasPrefix(dev.dmUUID, lvmUUIDPrefix) || len(dev.dmUUID) < len(lvmUUIDPrefix)+64 {
+		return lvmIdentity{}, false
+	}
+	ids := dev.dmUUID[len(lvmUUIDPrefix):]
+	layer := strings.TrimPrefix(ids[64:], "-")
+	vg, lv := splitDMName(dev.dmName)
+	return lvmIdentity{
+		vg:     vg,
+		lv:     strings.TrimSuffix(lv, "-"+layer),
+		lvUUID: formatUUID(ids[32:64]),
+		layer:  layer,
+	}, true
+}
+
+// splitDMName splits a device-mapper name "<vg>-<lv>" into its VG and LV names.
+// Dashes within the names are doubled ("my--vg-root" is VG "my-vg", LV "root").
+func splitDMName(name string) (vg, lv string) {
+	for i := 0; i < len(name); i++ {
+		if name[i] != '-' {
+			continue
+		}
+		if i+1 < len(name) && name[i+1] == '-' {
+			i++
+			continue
+		}
+		return strings.ReplaceAll(name[:i], "--", "-"), strings.ReplaceAll(name[i+1:], "--", "-")
+	}
+	return strings.ReplaceAll(name, "--", "-"), ""
+}
+
+// StatusFunc returns the device-mapper status of the device named name, one line per
+// target, as printed by "dmsetup status <name>".
+type StatusFunc func(ctx context.Context, name string) (string, error)
+
+// DMSetupStatus runs "dmsetup status <name>". dmsetup is killed when ctx is done.
+// The error wraps fs.ErrPermission when dmsetup cannot open /dev/mapper/control,
+// as for a user without CAP_SYS_ADMIN.
+func DMSetupStatus(ctx context.Context, name string) (string, error) {
+	log.Debug().Str("name", name).Msg("executing dmsetup status")
+	out, err := exec.CommandContext(ctx, "dmsetup", "status", name).Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			stderr := strings.TrimSpace(string(exitErr.Stderr))
+			if strings.Contains(stderr, "Permission denied") {
+				return "", fmt.Errorf("%w: %w: %s", fs.ErrPermission, err, stderr)
+			}
+			return "", fmt.Errorf("%w: %s", err, stderr)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// dmsetupUnavailable reports whether err means dmsetup cannot be used at all, because
+// it is not installed or not allowed to talk to device-mapper, rather than failed for
+// one device.
+func dmsetupUnavailable(err error) bool {
+	return errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrPermission)
+}
+
+// targetStatus is the parsed status of a device-mapper device.
+type targetStatus struct {
+	// target is the target type of the first table line (e.g. "linear", "thin-pool").
+	target string
+	// dataPercent and metadataPercent are set for thin pools, thin volumes and snapshots.
+	dataPercent     *float64
+	metadataPercent *float64
+}
+
+// parseStatus parses "dmsetup status" output. Each line is "<start> <length> <target> <args...>".
+func parseStatus(status string) targetStatus {
+	var result targetStatus
+	var totalSectors uint64
+	var mappedSectors uint64
+	for i, line := range strings.Split(strings.TrimSpace(status), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		length, _ := strconv.ParseUint(fields[1], 10, 64)
+		totalSectors += length
+		args := fields[3:]
+		if i == 0 {
+			result.target = fields[2]
+		}
+		switch fields[2] {
+		case "thin-pool":
+			// <transaction id> <used meta>/<total meta> <used data>/<total data> ...
+			if len(args) >= 3 {
+				result.metadataPercent = ratioPercent(args[1])
+				result.dataPercent = ratioPercent(args[2])
+			}
+		case "snapshot":
+			// <allocated sectors>/<total sectors> <metadata sectors>
+			if len(args) >= 1 {
+				result.dataPercent = ratioPercent(args[0])
+			}
+		case "thin":
+			// <mapped sectors> <highest mapped sector>, or "Fail"
+			if len(args) >= 1 {
+				mapped, err := strconv.ParseUint(args[0], 10, 64)
+				if err == nil {
+					mappedSectors += mapped
+				}
+			}
+		}
+	}
+	if result.target == "thin" && totalSectors > 0 {
+		percent := float64(mappedSectors) * 100 / float64(totalSectors)
+		result.dataPercent = &percent
+	}
+	return result
+}
+
+// ratioPercent parses "<used>/<total>" as a percentage, or returns nil.
+func ratioPercent(ratio string) *float64 {
+	usedText, totalText, ok := strings.Cut(ratio, "/")
+	if !ok {
+		return nil
+	}
+	used, err1 := strconv.ParseUint(usedText, 10, 64)
+	total, err2 := strconv.ParseUint(totalText, 10, 64)
+	if err1 != nil || err2 != nil || total == 0 {
+		return nil
+	}
+	percent := float64(used) * 100 / float64(total)
+	return &percent
+}
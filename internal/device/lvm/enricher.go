@@ -0,0 +1,129 @@
+package lvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Enricher fills the LVM fields of logical volumes and physical volumes.
+type Enricher struct {
+	// SysRoot is the mount point of sysfs (e.g. "/sys").
+	SysRoot string
+	// Status returns the device-mapper status of a device. Nil leaves the LV type and usage empty.
+	Status StatusFunc
+}
+
+// NewEnricher creates a new Enricher reading sysfs at sysRoot and the status through dmsetup.
+// An empty sysRoot reads /sys.
+func NewEnricher(sysRoot string) *Enricher {
+	if sysRoot == "" {
+		sysRoot = "/sys"
+	}
+	return &Enricher{SysRoot: sysRoot, Status: DMSetupStatus}
+}
+
+// Name returns "lvm".
+func (e *Enricher) Name() string {
+	return "lvm"
+}
+
+// Fields returns the LVM fields.
+func (e *Enricher) Fields() []string {
+	return []string{"vg", "lv", "lvType", "lvDataPercent", "lvMetadataPercent"}
+}
+
+// Enrich sets the VG and LV of logical volumes, with their type and usage, and the VG
+// of physical volumes. The VG of a PV is found through the LVs stacked on it, or
+// from its LVM label when it has no active LV. It relies on the fstype set by the
+// probe enricher to find such PVs.
+func (e *Enricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	kernelName, err := device.SysfsKernelName(e.SysRoot, dev.Major, dev.Minor)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read device number link: %w", err)
+	}
+	sysDev, err := readBlockDevice(e.SysRoot, kernelName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read sysfs device: %w", err)
+	}
+
+	if id, ok := identify(sysDev); ok {
+		dev.VolumeGroup = id.vg
+		dev.LogicalVolume = id.lv
+		return e.enrichStatus(ctx, dev, sysDev, id)
+	}
+
+	for _, holder := range sysDev.holders {
+		holderDev, err := readBlockDevice(e.SysRoot, holder)
+		if err != nil {
+			continue
+		}
+		if id, ok := identify(holderDev); ok {
+			dev.VolumeGroup = id.vg
+			return nil
+		}
+	}
+
+	if dev.FSType != "LVM2_member" {
+		return nil
+	}
+	label, err := ReadPVLabelFile(dev.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) || errors.Is(err, fs.ErrNotExist) {
+			log.Debug().Str("device", dev.Path).Err(err).Msg("cannot read LVM label")
+			return nil
+		}
+		return fmt.Errorf("failed to read LVM label: %w", err)
+	}
+	if label.Metadata == "" {
+		return nil
+	}
+	meta, err := parseVGMetadata(label.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to parse LVM metadata: %w", err)
+	}
+	dev.VolumeGroup = meta.name
+	return nil
+}
+
+// enrichStatus sets the LV type and usage from the device-mapper status. The status of
+// a thin pool is read from the thin-pool target below its external "-pool" device.
+func (e *Enricher) enrichStatus(ctx context.Context, dev *device.BlockDevice, sysDev blockDevice, id lvmIdentity) error {
+	if e.Status == nil {
+		return nil
+	}
+	name := sysDev.dmName
+	if id.layer == "pool" && len(sysDev.slaves) == 1 {
+		if pool, err := readBlockDevice(e.SysRoot, sysDev.slaves[0]); err == nil && pool.dmName != "" {
+			name = pool.dmName
+		}
+	}
+
+	out, err := e.Status(ctx, name)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if dmsetupUnavailable(err) {
+			log.Debug().Err(err).Msg("dmsetup unavailable, skipping LVM usage")
+			return nil
+		}
+		return fmt.Errorf("failed to read device-mapper status: %w", err)
+	}
+	status := parseStatus(out)
+	dev.LVType = status.target
+	dev.LVDataPercent = status.dataPercent
+	dev.LVMetadataPercent = status.metadataPercent
+	return nil
+}
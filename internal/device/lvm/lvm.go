@@ -0,0 +1,438 @@
+// Package lvm builds the LVM view of the system: physical volumes, volume groups and
+// logical volumes. Active LVs are found through device-mapper in sysfs; the LVM2
+// metadata stored on the physical volumes adds free space, segment types and
+// inactive LVs when it is readable, and "dmsetup status" adds thin pool usage.
+package lvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// VolumeGroup is an LVM volume group.
+type VolumeGroup struct {
+	// Name is the VG name (e.g. "vg0").
+	Name string `json:"name"`
+	// UUID is the VG UUID. Empty without readable metadata.
+	UUID string `json:"uuid,omitempty"`
+	// Size is the total size of the PVs in human-readable format. Empty without readable metadata.
+	Size string `json:"size,omitempty"`
+	// SizeBytes is the total size of the PVs in bytes.
+	SizeBytes uint64 `json:"sizeBytes,omitempty"`
+	// Free is the unallocated space in human-readable format. Empty without readable metadata.
+	Free string `json:"free,omitempty"`
+	// FreeBytes is the unallocated space in bytes.
+	FreeBytes uint64 `json:"freeBytes,omitempty"`
+	// ExtentSizeBytes is the physical extent size in bytes.
+	ExtentSizeBytes uint64 `json:"extentSizeBytes,omitempty"`
+	// Seqno is the sequence number of the metadata the VG was read from.
+	Seqno int64 `json:"seqno,omitempty"`
+	// PhysicalVolumes are the PVs of the VG, sorted by path.
+	PhysicalVolumes []PhysicalVolume `json:"physicalVolumes"`
+	// LogicalVolumes are the LVs of the VG, including hidden sub-LVs, sorted by name.
+	LogicalVolumes []LogicalVolume `json:"logicalVolumes"`
+}
+
+// PhysicalVolume is a device holding extents of a volume group.
+type PhysicalVolume struct {
+	// Name is the PV name within the VG metadata (e.g. "pv0"). Empty without readable metadata.
+	Name string `json:"name,omitempty"`
+	// Path is the device path of the PV, as in the BlockDevice list (e.g. "/dev/sda2").
+	Path string `json:"path"`
+	// UUID is the PV UUID. Empty without readable metadata.
+	UUID string `json:"uuid,omitempty"`
+	// SizeBytes is the size of the PV in bytes.
+	SizeBytes uint64 `json:"sizeBytes,omitempty"`
+	// ExtentCount is the number of physical extents of the PV.
+	ExtentCount uint64 `json:"extentCount,omitempty"`
+}
+
+// LogicalVolume is an LVM logical volume.
+type LogicalVolume struct {
+	// Name is the LV name (e.g. "root", "pool_tdata").
+	Name string `json:"name"`
+	// UUID is the LV UUID.
+	UUID string `json:"uuid,omitempty"`
+	// Path is the device path of an active LV, as in the BlockDevice list (e.g. "/dev/mapper/vg0-root").
+	Path string `json:"path,omitempty"`
+	// KernelName is the device-mapper kernel name of an active LV (e.g. "dm-0").
+	KernelName string `json:"kernelName,omitempty"`
+	// Active reports whether the LV has a device-mapper device.
+	Active bool `json:"active"`
+	// Hidden reports internal sub-LVs (thin pool data and metadata, RAID images, ...).
+	Hidden bool `json:"hidden,omitempty"`
+	// Type is the segment type of the LV (e.g. "linear", "striped", "thin-pool", "thin", "raid1").
+	Type string `json:"type,omitempty"`
+	// Size is the size of the LV in human-readable format.
+	Size string `json:"size,omitempty"`
+	// SizeBytes is the size of the LV in bytes.
+	SizeBytes uint64 `json:"sizeBytes,omitempty"`
+	// Devices are the paths of the devices the LV maps onto (PVs or sub-LVs).
+	Devices []string `json:"devices,omitempty"`
+	// Segments are the segments of the LV. Empty without readable metadata.
+	Segments []Segment `json:"segments,omitempty"`
+	// DataPercent is the data usage of thin pools, thin volumes and snapshots.
+	DataPercent *float64 `json:"dataPercent,omitempty"`
+	// MetadataPercent is the metadata usage of thin pools.
+	MetadataPercent *float64 `json:"metadataPercent,omitempty"`
+}
+
+// Segment is a range of logical extents of an LV mapped with one segment type.
+type Segment struct {
+	// StartExtent is the first logical extent of the segment.
+	StartExtent uint64 `json:"startExtent"`
+	// ExtentCount is the number of logical extents of the segment.
+	ExtentCount uint64 `json:"extentCount"`
+	// Type is the segment type (e.g. "striped", "thin-pool", "thin", "raid1").
+	Type string `json:"type"`
+	// Stripes is the stripe count of striped segments.
+	Stripes int `json:"stripes,omitempty"`
+	// Areas are what the segment maps onto: PV paths for striped segments,
+	// sub-LV names (e.g. "pool_tdata") otherwise.
+	Areas []string `json:"areas,omitempty"`
+}
+
+// hiddenLVName matches the names LVM gives to internal sub-LVs.
+var hiddenLVName = regexp.MustCompile(`_(tdata|tmeta|cdata|cmeta|cpool|vdata|pmspare|mlog|[rm]image_\d+|rmeta_\d+)$|^lvol\d+_pmspare$`)
+
+// Collector builds the LVM view from sysfs, the PV labels and device-mapper status.
+type Collector struct {
+	// SysRoot is the mount point of sysfs (e.g. "/sys").
+	SysRoot string
+	// DevRoot is the directory of the device nodes PV labels are read from (e.g. "/dev").
+	DevRoot string
+	// Status returns the device-mapper status of a device. Nil skips thin pool usage.
+	Status StatusFunc
+}
+
+// NewCollector creates a new Collector reading sysfs at sysRoot, the device nodes in /dev
+// and the status through dmsetup. An empty sysRoot reads /sys.
+func NewCollector(sysRoot string) *Collector {
+	if sysRoot == "" {
+		sysRoot = "/sys"
+	}
+	return &Collector{SysRoot: sysRoot, DevRoot: "/dev", Status: DMSetupStatus}
+}
+
+// Collect returns the volume groups sorted by name. Unreadable PV labels and failing
+// status calls leave the affected details empty; only an unreadable sysfs fails.
+// ctx is checked before each device.
+func (c *Collector) Collect(ctx context.Context) ([]VolumeGroup, error) {
+	classDir := filepath.Join(c.SysRoot, "class", "block")
+	entries, err := os.ReadDir(classDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", classDir, err)
+	}
+
+	b := newBuilder()
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		dev, err := readBlockDevice(c.SysRoot, entry.Name())
+		if err != nil {
+			log.Debug().Str("device", entry.Name()).Err(err).Msg("cannot read block device")
+			continue
+		}
+		b.devices[dev.kernelName] = dev
+	}
+
+	for _, dev := range b.sortedDevices() {
+		if id, ok := identify(dev); ok {
+			b.addDM(dev, id)
+		}
+	}
+
+	for _, dev := range b.sortedDevices() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, ok := identify(dev); ok {
+			continue
+		}
+		b.addLabel(filepath.Join(c.DevRoot, dev.kernelName), dev)
+	}
+	b.applyMetadata()
+
+	if c.Status != nil {
+		if err := b.readStatus(ctx, c.Status); err != nil {
+			return nil, err
+		}
+	}
+	return b.volumeGroups(), nil
+}
+
+// builder accumulates the VGs, PVs and LVs found in the different sources.
+type builder struct {
+	devices map[string]blockDevice
+	vgs     map[string]*VolumeGroup
+	// pvs maps VG names to their PVs by path.
+	pvs map[string]map[string]*PhysicalVolume
+	// lvs maps LV UUIDs to their LVs, lvVG to their VG names.
+	lvs  map[string]*LogicalVolume
+	lvVG map[string]string
+	// statusNames maps LV UUIDs to the device-mapper name to read the status of.
+	statusNames map[string]string
+	// metadata holds the most recent metadata of each VG, pvPaths the device path
+	// of each PV UUID whose label was read.
+	metadata map[string]*vgMetadata
+	pvPaths  map[string]string
+}
+
+func newBuilder() *builder {
+	return &builder{
+		devices:     make(map[string]blockDevice),
+		vgs:         make(map[string]*VolumeGroup),
+		pvs:         make(map[string]map[string]*PhysicalVolume),
+		lvs:         make(map[string]*LogicalVolume),
+		lvVG:        make(map[string]string),
+		statusNames: make(map[string]string),
+		metadata:    make(map[string]*vgMetadata),
+		pvPaths:     make(map[string]string),
+	}
+}
+
+// sortedDevices returns the devices sorted by kernel name, for a stable result.
+func (b *builder) sortedDevices() []blockDevice {
+	devices := make([]blockDevice, 0, len(b.devices))
+	for _, dev := range b.devices {
+		devices = append(devices, dev)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].kernelName < devices[j].kernelName })
+	return devices
+}
+
+// vg returns the VG named name, creating it if needed.
+func (b *builder) vg(name string) *VolumeGroup {
+	vg, ok := b.vgs[name]
+	if !ok {
+		vg = &VolumeGroup{Name: name}
+		b.vgs[name] = vg
+		b.pvs[name] = make(map[string]*PhysicalVolume)
+	}
+	return vg
+}
+
+// lv returns the LV with the given UUID, creating it in vgName if needed.
+func (b *builder) lv(vgName, uuid, name string) *LogicalVolume {
+	b.vg(vgName)
+	lv, ok := b.lvs[uuid]
+	if !ok {
+		lv = &LogicalVolume{Name: name, UUID: uuid, Hidden: hiddenLVName.MatchString(name)}
+		b.lvs[uuid] = lv
+		b.lvVG[uuid] = vgName
+	}
+	return lv
+}
+
+// pv returns the PV of vgName at path, creating it if needed.
+func (b *builder) pv(vgName, path string) *PhysicalVolume {
+	b.vg(vgName)
+	pv, ok := b.pvs[vgName][path]
+	if !ok {
+		pv = &PhysicalVolume{Path: path}
+		b.pvs[vgName][path] = pv
+	}
+	return pv
+}
+
+// addDM records an active LV device-mapper device and the PVs below it.
+func (b *builder) addDM(dev blockDevice, id lvmIdentity) {
+	lv := b.lv(id.vg, id.lvUUID, id.lv)
+	switch id.layer {
+	case "", "pool":
+		// "pool" is the linear device LVM stacks on the thin pool target for external use.
+		lv.Path = dev.path()
+		lv.KernelName = dev.kernelName
+		lv.Active = true
+		lv.SizeBytes = dev.sizeBytes
+		for _, slave := range dev.slaves {
+			if slaveDev, ok := b.devices[slave]; ok {
+				lv.Devices = append(lv.Devices, slaveDev.path())
+			}
+		}
+		if b.statusNames[id.lvUUID] == "" {
+			b.statusNames[id.lvUUID] = dev.dmName
+		}
+	case "tpool":
+		// The thin pool target reports the pool usage.
+		b.statusNames[id.lvUUID] = dev.dmName
+	}
+
+	for _, slave := range dev.slaves {
+		slaveDev, ok := b.devices[slave]
+		if !ok {
+			continue
+		}
+		if slaveID, ok := identify(slaveDev); ok && slaveID.vg == id.vg {
+			continue
+		}
+		b.pv(id.vg, slaveDev.path()).SizeBytes = slaveDev.sizeBytes
+	}
+}
+
+// addLabel reads the PV label of the device node at nodePath and keeps its metadata
+// if it is the most recent of its VG.
+func (b *builder) addLabel(nodePath string, dev blockDevice) {
+	label, err := ReadPVLabelFile(nodePath)
+	if err != nil {
+		if !errors.Is(err, ErrNoLabel) && !errors.Is(err, fs.ErrNotExist) {
+			log.Debug().Str("device", nodePath).Err(err).Msg("cannot read LVM label")
+		}
+		return
+	}
+	b.pvPaths[label.UUID] = dev.path()
+	if label.Metadata == "" {
+		return
+	}
+	meta, err := parseVGMetadata(label.Metadata)
+	if err != nil {
+		log.Debug().Str("device", nodePath).Err(err).Msg("cannot parse LVM metadata")
+		return
+	}
+	if current, ok := b.metadata[meta.name]; !ok || meta.seqno > current.seqno {
+		b.metadata[meta.name] = meta
+	}
+}
+
+// applyMetadata merges the VG metadata into the VGs found through device-mapper.
+func (b *builder) applyMetadata() {
+	for name, meta := range b.metadata {
+		vg := b.vg(name)
+		extentBytes := meta.extentSize * 512
+		vg.UUID = meta.id
+		vg.Seqno = meta.seqno
+		vg.ExtentSizeBytes = extentBytes
+
+		pvPaths := make(map[string]string, len(meta.pvs))
+		var totalExtents uint64
+		for _, pvMeta := range meta.pvs {
+			path := b.pvPaths[pvMeta.id]
+			if path == "" {
+				// PV not found on this system (e.g. missing disk), use the recorded device.
+				path = pvMeta.device
+			}
+			pvPaths[pvMeta.name] = path
+			pv := b.pv(name, path)
+			pv.Name = pvMeta.name
+			pv.UUID = pvMeta.id
+			pv.SizeBytes = pvMeta.devSize * 512
+			pv.ExtentCount = pvMeta.peCount
+			totalExtents += pvMeta.peCount
+		}
+		vg.SizeBytes = totalExtents * extentBytes
+		vg.FreeBytes = (totalExtents - min(totalExtents, meta.allocatedExtents())) * extentBytes
+
+		for _, lvMeta := range meta.lvs {
+			lv := b.lv(name, lvMeta.id, lvMeta.name)
+			lv.Hidden = !lvMeta.visible
+			lv.Segments = nil
+			var extents uint64
+			for _, seg := range lvMeta.segments {
+				if seg.Type == "striped" {
+					for i, area := range seg.Areas {
+						seg.Areas[i] = pvPaths[area]
+					}
+				}
+				extents += seg.ExtentCount
+				lv.Segments = append(lv.Segments, seg)
+			}
+			if len(lv.Segments) > 0 {
+				lv.Type = segmentTypeName(lv.Segments[0])
+			}
+			if !lv.Active {
+				lv.SizeBytes = extents * extentBytes
+			}
+		}
+	}
+}
+
+// segmentTypeName returns the type of an LV as lvs reports it: single-stripe
+// striped segments are "linear".
+func segmentTypeName(seg Segment) string {
+	if seg.Type == "striped" && seg.Stripes <= 1 {
+		return "linear"
+	}
+	return seg.Type
+}
+
+// readStatus reads the device-mapper status of the active LVs for their type and usage.
+// A missing dmsetup binary, or one not allowed to talk to device-mapper, skips the
+// remaining calls.
+func (b *builder) readStatus(ctx context.Context, status StatusFunc) error {
+	uuids := make([]string, 0, len(b.statusNames))
+	for uuid := range b.statusNames {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	for _, uuid := range uuids {
+		name := b.statusNames[uuid]
+		out, err := status(ctx, name)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if dmsetupUnavailable(err) {
+				log.Debug().Err(err).Msg("dmsetup unavailable, skipping LVM usage")
+				return nil
+			}
+			log.Debug().Str("name", name).Err(err).Msg("cannot read device-mapper status")
+			continue
+		}
+		parsed := parseStatus(out)
+		lv := b.lvs[uuid]
+		if lv.Type == "" {
+			lv.Type = parsed.target
+		}
+		lv.DataPercent = parsed.dataPercent
+		lv.MetadataPercent = parsed.metadataPercent
+	}
+	return nil
+}
+
+// volumeGroups returns the VGs sorted by name, with their PVs and LVs.
+func (b *builder) volumeGroups() []VolumeGroup {
+	lvsByVG := make(map[string][]LogicalVolume)
+	for uuid, lv := range b.lvs {
+		lv.Size = device.HumanizeBytes(lv.SizeBytes)
+		vgName := b.lvVG[uuid]
+		lvsByVG[vgName] = append(lvsByVG[vgName], *lv)
+	}
+
+	vgs := make([]VolumeGroup, 0, len(b.vgs))
+	for name, vg := range b.vgs {
+		vg.Size = device.HumanizeBytes(vg.SizeBytes)
+		if vg.UUID != "" {
+			vg.Free = humanize.IBytes(vg.FreeBytes)
+		}
+		vg.PhysicalVolumes = []PhysicalVolume{}
+		for _, pv := range b.pvs[name] {
+			vg.PhysicalVolumes = append(vg.PhysicalVolumes, *pv)
+		}
+		sort.Slice(vg.PhysicalVolumes, func(i, j int) bool {
+			return vg.PhysicalVolumes[i].Path < vg.PhysicalVolumes[j].Path
+		})
+		vg.LogicalVolumes = lvsByVG[name]
+		if vg.LogicalVolumes == nil {
+			vg.LogicalVolumes = []LogicalVolume{}
+		}
+		sort.Slice(vg.LogicalVolumes, func(i, j int) bool {
+			return vg.LogicalVolumes[i].Name < vg.LogicalVolumes[j].Name
+		})
+		vgs = append(vgs, *vg)
+	}
+	sort.Slice(vgs, func(i, j int) bool { return vgs[i].Name < vgs[j].Name })
+	return vgs
+}
@@ -0,0 +1,531 @@
+package lvm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/sysfstest"
+)
+
+// Raw 32-character UUIDs of the fixture VG and LVs.
+const (
+	vgID    = "Vg0000aaaabbbbccccddddeeeeffff00"
+	pvID    = "Pv0000aaaabbbbccccddddeeeeffff00"
+	rootID  = "Root00aaaabbbbccccddddeeeeffff00"
+	poolID  = "Pool00aaaabbbbccccddddeeeeffff00"
+	tdataID = "Tdata0aaaabbbbccccddddeeeeffff00"
+	tmetaID = "Tmeta0aaaabbbbccccddddeeeeffff00"
+	spareID = "Spare0aaaabbbbccccddddeeeeffff00"
+	thinID  = "Thin00aaaabbbbccccddddeeeeffff00"
+)
+
+// fixtureMetadata is the metadata of vg0: a 255-extent PV holding a linear LV, a thin
+// pool with its hidden data, metadata and spare LVs, and a thin volume.
+var fixtureMetadata = fmt.Sprintf(`# Generated by LVM2 version 2.03.16(2) (2022-05-18): Mon Oct  6 10:00:00 2025
+
+contents = "Text Format Volume Group"
+version = 1
+
+vg0 {
+	id = "%s"
+	seqno = 7
+	format = "lvm2"
+	status = ["RESIZEABLE", "READ", "WRITE"]
+	extent_size = 8192		# 4 Megabytes
+
+	physical_volumes {
+
+		pv0 {
+			id = "%s"
+			device = "/dev/sda2"	# Hint only
+
+			status = ["ALLOCATABLE"]
+			dev_size = 2097152	# 1024 Megabytes
+			pe_start = 2048
+			pe_count = 255	# 1020 Megabytes
+		}
+	}
+
+	logical_volumes {
+
+		root {
+			id = "%s"
+			status = ["READ", "WRITE", "VISIBLE"]
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 100	# 400 Megabytes
+
+				type = "striped"
+				stripe_count = 1	# linear
+
+				stripes = [
+					"pv0", 0
+				]
+			}
+		}
+
+		pool {
+			id = "%s"
+			status = ["READ", "WRITE", "VISIBLE"]
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 50
+
+				type = "thin-pool"
+				metadata = "pool_tmeta"
+				pool = "pool_tdata"
+				transaction_id = 1
+				chunk_size = 128
+			}
+		}
+
+		thin1 {
+			id = "%s"
+			status = ["READ", "WRITE", "VISIBLE"]
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 25
+
+				type = "thin"
+				thin_pool = "pool"
+				device_id = 1
+			}
+		}
+
+		lvol0_pmspare {
+			id = "%s"
+			status = ["READ", "WRITE"]
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 1
+
+				type = "striped"
+				stripe_count = 1
+
+				stripes = [
+					"pv0", 100
+				]
+			}
+		}
+
+		pool_tmeta {
+			id = "%s"
+			status = ["READ", "WRITE"]
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 1
+
+				type = "striped"
+				stripe_count = 1
+
+				stripes = [
+					"pv0", 151
+				]
+			}
+		}
+
+		pool_tdata {
+			id = "%s"
+			status = ["READ", "WRITE"]
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 50
+
+				type = "striped"
+				stripe_count = 1
+
+				stripes = [
+					"pv0", 101
+				]
+			}
+		}
+	}
+}
+`, formatUUID(vgID), formatUUID(pvID), formatUUID(rootID), formatUUID(poolID), formatUUID(thinID),
+	formatUUID(spareID), formatUUID(tmetaID), formatUUID(tdataID))
+
+// writePVImage writes a 1 MiB PV image with the label in sector 1, one metadata area at
+// 4 KiB and the metadata text right after the area header.
+func writePVImage(t *testing.T, path, uuid, text string) {
+	t.Helper()
+	const size = 1 << 20
+	const mdaOffset = 4096
+	img := make([]byte, size)
+	le := binary.LittleEndian
+
+	label := img[512:1024]
+	copy(label[0:8], "LABELONE")
+	le.PutUint64(label[8:16], 1)
+	le.PutUint32(label[20:24], 32)
+	copy(label[24:32], "LVM2 001")
+	pvHeader := label[32:]
+	copy(pvHeader[0:32], uuid)
+	le.PutUint64(pvHeader[32:40], size)
+	le.PutUint64(pvHeader[40:48], size) // data area, followed by the terminator
+	le.PutUint64(pvHeader[72:80], mdaOffset)
+	le.PutUint64(pvHeader[80:88], size-mdaOffset) // metadata area, followed by the terminator
+
+	mda := img[mdaOffset:]
+	copy(mda[4:20], " LVM2 x[5A%r0N*>")
+	le.PutUint32(mda[20:24], 1)
+	le.PutUint64(mda[24:32], mdaOffset)
+	le.PutUint64(mda[32:40], size-mdaOffset)
+	le.PutUint64(mda[40:48], 512)
+	le.PutUint64(mda[48:56], uint64(len(text)+1))
+	copy(mda[512:], text)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, img, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// fakeDevice creates /sys/class/block/<name> with its device number link and relations.
+func fakeDevice(t *testing.T, sysRoot, name, devNum string, sectors int, dmName, dmUUID string, slaves ...string) {
+	t.Helper()
+	dir := filepath.Join("class", "block", name)
+	sysfstest.WriteFile(t, sysRoot, filepath.Join(dir, "dev"), devNum+"\n")
+	sysfstest.WriteFile(t, sysRoot, filepath.Join(dir, "size"), fmt.Sprintf("%d\n", sectors))
+	if dmName != "" {
+		sysfstest.WriteFile(t, sysRoot, filepath.Join(dir, "dm", "name"), dmName+"\n")
+		sysfstest.WriteFile(t, sysRoot, filepath.Join(dir, "dm", "uuid"), dmUUID+"\n")
+	}
+	for _, slave := range slaves {
+		sysfstest.WriteFile(t, sysRoot, filepath.Join(dir, "slaves", slave, ".keep"), "")
+		sysfstest.WriteFile(t, sysRoot, filepath.Join("class", "block", slave, "holders", name, ".keep"), "")
+	}
+	sysfstest.Symlink(t, sysRoot, filepath.Join("dev", "block", devNum), "../../class/block/"+name)
+}
+
+// newFixture builds a sysfs with vg0 active on sda2 and a /dev holding the sda2 PV image.
+func newFixture(t *testing.T) (sysRoot, devRoot string) {
+	t.Helper()
+	root := t.TempDir()
+	sysRoot = filepath.Join(root, "sys")
+	devRoot = filepath.Join(root, "dev")
+
+	fakeDevice(t, sysRoot, "sda", "8:0", 4194304, "", "")
+	fakeDevice(t, sysRoot, "sda2", "8:2", 2097152, "", "")
+	fakeDevice(t, sysRoot, "dm-0", "253:0", 819200, "vg0-root", "LVM-"+vgID+rootID, "sda2")
+	fakeDevice(t, sysRoot, "dm-1", "253:1", 409600, "vg0-pool_tdata", "LVM-"+vgID+tdataID, "sda2")
+	fakeDevice(t, sysRoot, "dm-2", "253:2", 8192, "vg0-pool_tmeta", "LVM-"+vgID+tmetaID, "sda2")
+	fakeDevice(t, sysRoot, "dm-3", "253:3", 409600, "vg0-pool-tpool", "LVM-"+vgID+poolID+"-tpool", "dm-1", "dm-2")
+	fakeDevice(t, sysRoot, "dm-4", "253:4", 409600, "vg0-pool", "LVM-"+vgID+poolID+"-pool", "dm-3")
+	fakeDevice(t, sysRoot, "dm-5", "253:5", 204800, "vg0-thin1", "LVM-"+vgID+thinID, "dm-3")
+	fakeDevice(t, sysRoot, "dm-6", "253:6", 1024, "luks-data", "CRYPT-LUKS2-abcdef-luks-data", "sda")
+
+	writePVImage(t, filepath.Join(devRoot, "sda2"), pvID, fixtureMetadata)
+	return sysRoot, devRoot
+}
+
+// fakeStatus returns canned "dmsetup status" output.
+func fakeStatus(ctx context.Context, name string) (string, error) {
+	switch name {
+	case "vg0-pool-tpool":
+		return "0 409600 thin-pool 1 256/1024 12800/51200 - rw no_discard_passdown queue_if_no_space - 1024\n", nil
+	case "vg0-thin1":
+		return "0 204800 thin 51200 204799\n", nil
+	case "vg0-root", "vg0-pool_tdata", "vg0-pool_tmeta":
+		return "0 819200 linear \n", nil
+	}
+	return "", fmt.Errorf("device %s not found", name)
+}
+
+func TestSplitDMName(t *testing.T) {
+	tests := map[string][2]string{
+		"vg0-root":        {"vg0", "root"},
+		"my--vg-my--lv":   {"my-vg", "my-lv"},
+		"vg0-pool-tpool":  {"vg0", "pool-tpool"},
+		"vg0-pool_tdata":  {"vg0", "pool_tdata"},
+		"nodash":          {"nodash", ""},
+		"data--vg-lv--01": {"data-vg", "lv-01"},
+	}
+	for name, want := range tests {
+		if vg, lv := splitDMName(name); vg != want[0] || lv != want[1] {
+			t.Errorf("splitDMName(%q) = %q, %q, want %q, %q", name, vg, lv, want[0], want[1])
+		}
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	pool := parseStatus("0 409600 thin-pool 1 256/1024 12800/51200 - rw no_discard_passdown queue_if_no_space - 1024")
+	if pool.target != "thin-pool" || *pool.dataPercent != 25 || *pool.metadataPercent != 25 {
+		t.Errorf("unexpected thin pool status %+v", pool)
+	}
+	thin := parseStatus("0 204800 thin 51200 204799\n")
+	if thin.target != "thin" || *thin.dataPercent != 25 || thin.metadataPercent != nil {
+		t.Errorf("unexpected thin status %+v", thin)
+	}
+	linear := parseStatus("0 819200 linear \n819200 1024 linear \n")
+	if linear.target != "linear" || linear.dataPercent != nil {
+		t.Errorf("unexpected linear status %+v", linear)
+	}
+}
+
+func TestReadPVLabel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pv.img")
+	writePVImage(t, path, pvID, fixtureMetadata)
+
+	label, err := ReadPVLabelFile(path)
+	if err != nil {
+		t.Fatalf("ReadPVLabelFile: %v", err)
+	}
+	if label.UUID != "Pv0000-aaaa-bbbb-cccc-dddd-eeee-ffff00" || label.DeviceSize != 1<<20 {
+		t.Errorf("unexpected label %q, %d", label.UUID, label.DeviceSize)
+	}
+	if label.Metadata != fixtureMetadata {
+		t.Errorf("unexpected metadata text:\n%s", label.Metadata)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.img")
+	if err := os.WriteFile(empty, make([]byte, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPVLabelFile(empty); !errors.Is(err, ErrNoLabel) {
+		t.Errorf("expected ErrNoLabel, got %v", err)
+	}
+}
+
+func TestReadMetadataArea_Hostile(t *testing.T) {
+	le := binary.LittleEndian
+	tests := []struct {
+		name                         string
+		areaSize, textOffset, length uint64
+	}{
+		{name: "area smaller than its header", areaSize: 256, textOffset: 512, length: 16},
+		{name: "huge text", areaSize: 1 << 40, textOffset: 512, length: 1 << 32},
+		{name: "text in the header", areaSize: 4096, textOffset: 8, length: 16},
+		{name: "text past the area", areaSize: 4096, textOffset: 4096, length: 16},
+		{name: "text larger than the area", areaSize: 4096, textOffset: 512, length: 4096},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mda := make([]byte, 4096)
+			copy(mda[4:20], " LVM2 x[5A%r0N*>")
+			le.PutUint64(mda[32:40], tt.areaSize)
+			le.PutUint64(mda[40:48], tt.textOffset)
+			le.PutUint64(mda[48:56], tt.length)
+			if _, err := readMetadataArea(bytes.NewReader(mda), 0, 4096); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseVGMetadata(t *testing.T) {
+	meta, err := parseVGMetadata(fixtureMetadata)
+	if err != nil {
+		t.Fatalf("parseVGMetadata: %v", err)
+	}
+	if meta.name != "vg0" || meta.seqno != 7 || meta.extentSize != 8192 || len(meta.pvs) != 1 || len(meta.lvs) != 6 {
+		t.Fatalf("unexpected metadata %+v", meta)
+	}
+	if meta.pvs[0].peCount != 255 || meta.pvs[0].device != "/dev/sda2" {
+		t.Errorf("unexpected PV %+v", meta.pvs[0])
+	}
+	pool := meta.lvs[1]
+	want := []Segment{{ExtentCount: 50, Type: "thin-pool", Areas: []string{"pool_tdata", "pool_tmeta"}}}
+	if pool.name != "pool" || !pool.visible || !reflect.DeepEqual(pool.segments, want) {
+		t.Errorf("unexpected pool LV %+v", pool)
+	}
+	if got := meta.allocatedExtents(); got != 152 {
+		t.Errorf("allocated extents = %d, want 152", got)
+	}
+
+	for _, text := range []string{`vg0 { id = "x"`, `vg0 { id = [1, 2 }`, `vg0 { id "x" }`, `a {} b {}`} {
+		if _, err := parseVGMetadata(text); err == nil {
+			t.Errorf("expected an error for %q", text)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	sysRoot, devRoot := newFixture(t)
+	c := &Collector{SysRoot: sysRoot, DevRoot: devRoot, Status: fakeStatus}
+
+	vgs, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if len(vgs) != 1 {
+		t.Fatalf("expected one VG, got %+v", vgs)
+	}
+	vg := vgs[0]
+	if vg.Name != "vg0" || vg.UUID != formatUUID(vgID) || vg.Size != "1020 MiB" || vg.Free != "412 MiB" {
+		t.Errorf("unexpected VG %s %s size=%s free=%s", vg.Name, vg.UUID, vg.Size, vg.Free)
+	}
+	wantPVs := []PhysicalVolume{{Name: "pv0", Path: "/dev/sda2", UUID: formatUUID(pvID), SizeBytes: 1 << 30, ExtentCount: 255}}
+	if !reflect.DeepEqual(vg.PhysicalVolumes, wantPVs) {
+		t.Errorf("got PVs %+v, want %+v", vg.PhysicalVolumes, wantPVs)
+	}
+
+	var summary []string
+	for _, lv := range vg.LogicalVolumes {
+		line := fmt.Sprintf("%s type=%s active=%t hidden=%t path=%s size=%s", lv.Name, lv.Type, lv.Active, lv.Hidden, lv.Path, lv.Size)
+		if lv.DataPercent != nil {
+			line += fmt.Sprintf(" data=%.0f", *lv.DataPercent)
+		}
+		if lv.MetadataPercent != nil {
+			line += fmt.Sprintf(" meta=%.0f", *lv.MetadataPercent)
+		}
+		summary = append(summary, line)
+	}
+	want := []string{
+		"lvol0_pmspare type=linear active=false hidden=true path= size=4.0 MiB",
+		"pool type=thin-pool active=true hidden=false path=/dev/mapper/vg0-pool size=200 MiB data=25 meta=25",
+		"pool_tdata type=linear active=true hidden=true path=/dev/mapper/vg0-pool_tdata size=200 MiB",
+		"pool_tmeta type=linear active=true hidden=true path=/dev/mapper/vg0-pool_tmeta size=4.0 MiB",
+		"root type=linear active=true hidden=false path=/dev/mapper/vg0-root size=400 MiB",
+		"thin1 type=thin active=true hidden=false path=/dev/mapper/vg0-thin1 size=100 MiB data=25",
+	}
+	if strings.Join(summary, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected LVs:\n%s\nwant:\n%s", strings.Join(summary, "\n"), strings.Join(want, "\n"))
+	}
+	root := vg.LogicalVolumes[4]
+	if !reflect.DeepEqual(root.Segments[0].Areas, []string{"/dev/sda2"}) || !reflect.DeepEqual(root.Devices, []string{"/dev/sda2"}) {
+		t.Errorf("root is not mapped onto /dev/sda2: %+v", root)
+	}
+}
+
+func TestCollect_WithoutMetadata(t *testing.T) {
+	sysRoot, _ := newFixture(t)
+	c := &Collector{SysRoot: sysRoot, DevRoot: t.TempDir(), Status: fakeStatus}
+
+	vgs, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	vg := vgs[0]
+	if vg.UUID != "" || vg.Free != "" || len(vg.PhysicalVolumes) != 1 || vg.PhysicalVolumes[0].Path != "/dev/sda2" {
+		t.Errorf("unexpected VG without metadata %+v", vg)
+	}
+	// The types come from device-mapper, hidden LVs from their names.
+	if len(vg.LogicalVolumes) != 5 || vg.LogicalVolumes[0].Name != "pool" || vg.LogicalVolumes[0].Type != "thin-pool" ||
+		!vg.LogicalVolumes[1].Hidden {
+		t.Errorf("unexpected LVs without metadata %+v", vg.LogicalVolumes)
+	}
+}
+
+func TestCollect_UnreadableSlave(t *testing.T) {
+	sysRoot, devRoot := newFixture(t)
+	// vg0-root also sits on sdz, whose /sys/class/block link dangles.
+	sysfstest.WriteFile(t, sysRoot, "class/block/dm-0/slaves/sdz/.keep", "")
+	sysfstest.Symlink(t, sysRoot, "class/block/sdz", "../../devices/gone/sdz")
+	c := &Collector{SysRoot: sysRoot, DevRoot: devRoot, Status: fakeStatus}
+
+	vgs, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	root := vgs[0].LogicalVolumes[4]
+	if root.Name != "root" || !reflect.DeepEqual(root.Devices, []string{"/dev/sda2"}) {
+		t.Errorf("an unreadable slave must be skipped: %+v", root)
+	}
+}
+
+func TestEnricher(t *testing.T) {
+	sysRoot, devRoot := newFixture(t)
+	e := &Enricher{SysRoot: sysRoot, Status: fakeStatus}
+
+	tests := []struct {
+		dev                device.BlockDevice
+		vg, lv, lvType     string
+		dataPercent        float64
+		hasMetadataPercent bool
+	}{
+		{dev: device.BlockDevice{Path: "/dev/mapper/vg0-pool", Major: 253, Minor: 4}, vg: "vg0", lv: "pool", lvType: "thin-pool", dataPercent: 25, hasMetadataPercent: true},
+		{dev: device.BlockDevice{Path: "/dev/mapper/vg0-pool-tpool", Major: 253, Minor: 3}, vg: "vg0", lv: "pool", lvType: "thin-pool", dataPercent: 25, hasMetadataPercent: true},
+		{dev: device.BlockDevice{Path: "/dev/mapper/vg0-thin1", Major: 253, Minor: 5}, vg: "vg0", lv: "thin1", lvType: "thin", dataPercent: 25},
+		{dev: device.BlockDevice{Path: "/dev/mapper/vg0-root", Major: 253, Minor: 0}, vg: "vg0", lv: "root", lvType: "linear"},
+		{dev: device.BlockDevice{Path: "/dev/sda2", Major: 8, Minor: 2}, vg: "vg0"},
+		{dev: device.BlockDevice{Path: "/dev/sda", Major: 8, Minor: 0}},
+		{dev: device.BlockDevice{Path: "/dev/mapper/luks-data", Major: 253, Minor: 6}},
+		{dev: device.BlockDevice{Path: "/dev/sdz", Major: 65, Minor: 0}},
+		// A PV without active LVs is identified from its label.
+		{dev: device.BlockDevice{Path: filepath.Join(devRoot, "sda2"), Major: 8, Minor: 0, FSType: "LVM2_member"}, vg: "vg0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dev.Path, func(t *testing.T) {
+			dev := tt.dev
+			if err := e.Enrich(context.Background(), &dev); err != nil {
+				t.Fatalf("Enrich: %v", err)
+			}
+			if dev.VolumeGroup != tt.vg || dev.LogicalVolume != tt.lv || dev.LVType != tt.lvType {
+				t.Errorf("got vg=%q lv=%q type=%q, want vg=%q lv=%q type=%q",
+					dev.VolumeGroup, dev.LogicalVolume, dev.LVType, tt.vg, tt.lv, tt.lvType)
+			}
+			if (tt.dataPercent == 0) != (dev.LVDataPercent == nil) || dev.LVDataPercent != nil && *dev.LVDataPercent != tt.dataPercent {
+				t.Errorf("unexpected data percent %v, want %v", dev.LVDataPercent, tt.dataPercent)
+			}
+			if tt.hasMetadataPercent != (dev.LVMetadataPercent != nil) {
+				t.Errorf("unexpected metadata percent %v", dev.LVMetadataPercent)
+			}
+		})
+	}
+}
+
+func TestEnricher_DMSetupPermissionDenied(t *testing.T) {
+	sysRoot, devRoot := newFixture(t)
+	calls := 0
+	denied := func(ctx context.Context, name string) (string, error) {
+		calls++
+		return "", fmt.Errorf("%w: exit status 1: /dev/mapper/control: open failed: Permission denied", fs.ErrPermission)
+	}
+
+	dev := device.BlockDevice{Path: "/dev/mapper/vg0-pool", Major: 253, Minor: 4}
+	e := &Enricher{SysRoot: sysRoot, Status: denied}
+	if err := e.Enrich(context.Background(), &dev); err != nil {
+		t.Fatalf("a user without access to device-mapper must not get a warning, got %v", err)
+	}
+	if dev.LogicalVolume != "pool" || dev.LVType != "" || dev.LVDataPercent != nil {
+		t.Errorf("expected the LV without type and usage, got %+v", dev)
+	}
+
+	c := &Collector{SysRoot: sysRoot, DevRoot: devRoot, Status: denied}
+	calls = 0
+	vgs, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if calls != 1 || vgs[0].LogicalVolumes[0].DataPercent != nil {
+		t.Errorf("expected the status calls to stop after the first denial, got %d calls", calls)
+	}
+}
+
+func TestDMSetupStatus_PermissionDenied(t *testing.T) {
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho '/dev/mapper/control: open failed: Permission denied' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(bin, "dmsetup"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	_, err := DMSetupStatus(context.Background(), "vg0-root")
+	if !errors.Is(err, fs.ErrPermission) || !strings.Contains(err.Error(), "/dev/mapper/control") {
+		t.Errorf("expected a permission error with the dmsetup message, got %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package lvm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// On-disk layout constants of LVM2 physical volumes.
+const (
+	labelSectorSize  = 512
+	labelScanSectors = 4
+	mdaHeaderSize    = 512
+	rawLocnSize      = 24
+	// maxMetadataSize bounds the metadata text read from a metadata area. LVM keeps
+	// the text of a VG well below it, even with hundreds of LVs.
+	maxMetadataSize = 1 << 20
+)
+
+var (
+	labelID      = []byte("LABELONE")
+	labelTypeLVM = []byte("LVM2 001")
+	mdaMagic     = []byte(" LVM2 x[5A%r0N*>")
+)
+
+// ErrNoLabel is returned when a device is not an LVM2 physical volume.
+var ErrNoLabel = errors.New("no LVM2 label found")
+
+// PVLabel is the label of an LVM2 physical volume with its current metadata text.
+type PVLabel struct {
+	// UUID is the PV UUID in the dashed LVM format.
+	UUID string
+	// DeviceSize is the device size recorded in the label, in bytes.
+	DeviceSize uint64
+	// Metadata is the text of the most recent VG metadata, empty for an orphan PV
+	// or one without metadata areas.
+	Metadata string
+}
+
+// ReadPVLabelFile reads the PV label of the device or image at path.
+func ReadPVLabelFile(path string) (*PVLabel, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadPVLabel(file)
+}
+
+// ReadPVLabel reads the LVM2 label from the first sectors of r, then the metadata
+// text of the first metadata area. Checksums are not verified.
+func ReadPVLabel(r io.ReaderAt) (*PVLabel, error) {
+	buf := make([]byte, labelSectorSize*labelScanSectors)
+	n, err := r.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read label sectors: %w", err)
+	}
+	buf = buf[:n]
+
+	for sector := 0; sector+labelSectorSize <= len(buf); sector += labelSectorSize {
+		header := buf[sector : sector+labelSectorSize]
+		if !bytes.Equal(header[0:8], labelID) || !bytes.Equal(header[24:32], labelTypeLVM) {
+			continue
+		}
+		offset := int(binary.LittleEndian.Uint32(header[20:24]))
+		if offset < 32 || offset+40 > labelSectorSize {
+			return nil, fmt.Errorf("invalid PV header offset %d", offset)
+		}
+		return readPVHeader(r, header[offset:])
+	}
+	return nil, ErrNoLabel
+}
+
+// readPVHeader parses the PV header following the label: the PV UUID, the device size,
+// then the zero-terminated lists of data areas and metadata areas.
+func readPVHeader(r io.ReaderAt, header []byte) (*PVLabel, error) {
+	label := &PVLabel{
+		UUID:       formatUUID(string(header[0:32])),
+		DeviceSize: binary.LittleEndian.Uint64(header[32:40]),
+	}
+
+	pos := 40
+	// nextArea returns the next disk_locn, or false at the list terminator.
+	nextArea := func() (offset, size uint64, ok bool) {
+		if pos+16 > len(header) {
+			return 0, 0, false
+		}
+		offset = binary.LittleEndian.Uint64(header[pos : pos+8])
+		size = binary.LittleEndian.Uint64(header[pos+8 : pos+16])
+		pos += 16
+		return offset, size, offset != 0
+	}
+	for _, _, ok := nextArea(); ok; _, _, ok = nextArea() {
+		// Data areas, not needed.
+	}
+	offset, size, ok := nextArea()
+	if !ok {
+		return label, nil
+	}
+
+	text, err := readMetadataArea(r, int64(offset), size)
+	if err != nil {
+		return nil, err
+	}
+	label.Metadata = text
+	return label, nil
+}
+
+// readMetadataArea reads the current metadata text of the metadata area at offset.
+// The area is a circular buffer after its header: text running past the end continues
+// right after the header.
+func readMetadataArea(r io.ReaderAt, offset int64, size uint64) (string, error) {
+	header := make([]byte, mdaHeaderSize)
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return "", fmt.Errorf("failed to read metadata area header: %w", err)
+	}
+	if !bytes.Equal(header[4:20], mdaMagic) {
+		return "", fmt.Errorf("invalid metadata area header magic")
+	}
+	if areaSize := binary.LittleEndian.Uint64(header[32:40]); areaSize != 0 {
+		size = areaSize
+	}
+	if size < mdaHeaderSize {
+		return "", fmt.Errorf("metadata area size %d is smaller than its header", size)
+	}
+
+	locn := header[40 : 40+rawLocnSize]
+	textOffset := binary.LittleEndian.Uint64(locn[0:8])
+	textSize := binary.LittleEndian.Uint64(locn[8:16])
+	if textOffset == 0 || textSize == 0 {
+		return "", nil
+	}
+	if textSize > maxMetadataSize {
+		return "", fmt.Errorf("metadata text size %d exceeds the %d-byte limit", textSize, maxMetadataSize)
+	}
+	if textOffset < mdaHeaderSize || textOffset >= size || textSize > size-mdaHeaderSize {
+		return "", fmt.Errorf("metadata text location %d+%d outside the %d-byte area", textOffset, textSize, size)
+	}
+
+	text := make([]byte, textSize)
+	first := min(textSize, size-textOffset)
+	if _, err := r.ReadAt(text[:first], offset+int64(textOffset)); err != nil {
+		return "", fmt.Errorf("failed to read metadata text: %w", err)
+	}
+	if first < textSize {
+		if _, err := r.ReadAt(text[first:], offset+mdaHeaderSize); err != nil {
+			return "", fmt.Errorf("failed to read wrapped metadata text: %w", err)
+		}
+	}
+	return strings.TrimRight(string(text), "\x00"), nil
+}
+
+// formatUUID formats a 32-character LVM UUID in the dashed 6-4-4-4-4-4-6 form.
+// Other values are returned unchanged.
+func formatUUID(id string) string {
+	if len(id) != 32 {
+		return id
+	}
+	return strings.Join([]string{id[0:6], id[6:10], id[10:14], id[14:18], id[18:22], id[22:26], id[26:32]}, "-")
+}
+
+// vgMetadata is the parsed metadata of a volume group.
+type vgMetadata struct {
+	name       string
+	id         string
+	seqno      int64
+	extentSize uint64 // in sectors
+	pvs        []pvMetadata
+	lvs        []lvMetadata
+}
+
+type pvMetadata struct {
+	name    string // e.g. "pv0"
+	id      string
+	device  string // device hint recorded when the metadata was written
+	devSize uint64 // in sectors
+	peCount uint64
+}
+
+type lvMetadata struct {
+	name     string
+	id       string
+	visible  bool
+	segments []Segment
+}
+
+// parseVGMetadata parses the metadata text of a volume group.
+func parseVGMetadata(text string) (*vgMetadata, error) {
+	root, err := parseConfig(text)
+	if err != nil {
+		return nil, err
+	}
+	// The top level holds the VG section and a few descriptive values (contents, version...).
+	if len(root.order) != 1 {
+		return nil, fmt.Errorf("expected one volume group in metadata, found %d", len(root.order))
+	}
+	name := root.order[0]
+	vg := root.sections[name]
+	meta := &vgMetadata{
+		name:       name,
+		id:         vg.str("id"),
+		seqno:      vg.num("seqno"),
+		extentSize: uint64(vg.num("extent_size")),
+	}
+
+	if pvs, ok := vg.sections["physical_volumes"]; ok {
+		for _, pvName := range pvs.order {
+			pv := pvs.sections[pvName]
+			meta.pvs = append(meta.pvs, pvMetadata{
+				name:    pvName,
+				id:      pv.str("id"),
+				device:  pv.str("device"),
+				devSize: uint64(pv.num("dev_size")),
+				peCount: uint64(pv.num("pe_count")),
+			})
+		}
+	}
+
+	if lvs, ok := vg.sections["logical_volumes"]; ok {
+		for _, lvName := range lvs.order {
+			lv := lvs.sections[lvName]
+			meta.lvs = append(meta.lvs, lvMetadata{
+				name:     lvName,
+				id:       lv.str("id"),
+				visible:  slices.Contains(lv.list("status"), "VISIBLE"),
+				segments: parseSegments(lv),
+			})
+		}
+	}
+	return meta, nil
+}
+
+// parseSegments parses the segment1..segmentN sections of an LV.
+func parseSegments(lv *section) []Segment {
+	var segments []Segment
+	for _, name := range lv.order {
+		if !strings.HasPrefix(name, "segment") {
+			continue
+		}
+		seg := lv.sections[name]
+		segment := Segment{
+			StartExtent: uint64(seg.num("start_extent")),
+			ExtentCount: uint64(seg.num("extent_count")),
+			Type:        seg.str("type"),
+			Stripes:     int(seg.num("stripe_count")),
+		}
+		// Areas are PV names for striped segments and sub-LV names otherwise.
+		for _, key := range []string{"stripes", "raids", "mirrors"} {
+			segment.Areas = append(segment.Areas, seg.list(key)...)
+		}
+		for _, key := range []string{"pool", "metadata", "thin_pool", "origin", "cow", "cache_pool"} {
+			if value := seg.str(key); value != "" {
+				segment.Areas = append(segment.Areas, value)
+			}
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// allocatedExtents returns the physical extents used by the LVs: striped segments are
+// the only ones mapped directly onto PVs, every other type maps onto sub-LVs.
+func (m *vgMetadata) allocatedExtents() uint64 {
+	var total uint64
+	for _, lv := range m.lvs {
+		for _, seg := range lv.segments {
+			if seg.Type == "striped" {
+				total += seg.ExtentCount
+			}
+		}
+	}
+	return total
+}
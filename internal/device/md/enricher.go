@@ -0,0 +1,93 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Enricher attaches the md array state to md devices and their members. The arrays
+// are collected once per scan, for the first device that is or belongs to an array,
+// and reused for the other devices of the scan.
+type Enricher struct {
+	// Collector reads the arrays.
+	Collector *Collector
+}
+
+// NewEnricher creates a new Enricher reading /proc/mdstat and sysfs at sysRoot.
+// An empty sysRoot reads /sys.
+func NewEnricher(sysRoot string) *Enricher {
+	return &Enricher{Collector: NewCollector(sysRoot)}
+}
+
+// Name returns "md".
+func (e *Enricher) Name() string {
+	return "md"
+}
+
+// Fields returns the RAID fields.
+func (e *Enricher) Fields() []string {
+	return []string{"raid", "raidMember"}
+}
+
+// Enrich sets the array state of md devices and the member role of the devices
+// they are built on. Devices that are neither are recognised from sysfs without
+// reading mdstat.
+func (e *Enricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	kernelName, err := device.SysfsKernelName(e.Collector.SysRoot, dev.Major, dev.Minor)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read device number link: %w", err)
+	}
+	arrayNames := e.arraysOf(kernelName)
+	if len(arrayNames) == 0 {
+		return nil
+	}
+
+	arrays, err := device.ScanCached(ctx, e, e.Collector.Collect)
+	if err != nil {
+		return fmt.Errorf("failed to read md arrays: %w", err)
+	}
+	for _, array := range arrays {
+		if array.Name == kernelName {
+			raid := array.RAIDArray
+			dev.RAID = &raid
+			continue
+		}
+		for _, member := range array.Members {
+			if filepath.Base(member.Device) == kernelName || member.Device == dev.Path {
+				m := member
+				dev.RAIDMember = &m
+			}
+		}
+	}
+	log.Debug().Str("device", dev.Path).Strs("arrays", arrayNames).Msg("enriched device with md data")
+	return nil
+}
+
+// arraysOf returns the md arrays the device is, or is a member of, from its md
+// directory and its holders in sysfs.
+func (e *Enricher) arraysOf(kernelName string) []string {
+	classDir := filepath.Join(e.Collector.SysRoot, "class", "block")
+	var names []string
+	if _, err := os.Stat(filepath.Join(classDir, kernelName, "md")); err == nil {
+		names = append(names, kernelName)
+	}
+	holders, _ := os.ReadDir(filepath.Join(classDir, kernelName, "holders"))
+	for _, holder := range holders {
+		if strings.HasPrefix(holder.Name(), "md") {
+			names = append(names, holder.Name())
+		}
+	}
+	return names
+}
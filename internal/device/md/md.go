@@ -0,0 +1,222 @@
+// Package md reads the state of Linux software RAID (md) arrays from /proc/mdstat
+// and the md directory of each array in sysfs (/sys/block/md*/md).
+package md
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Array is an md array with its kernel name.
+type Array struct {
+	// Name is the kernel name of the array (e.g. "md0").
+	Name string `json:"name"`
+	// Path is the device node of the array (e.g. "/dev/md0").
+	Path string `json:"path"`
+	device.RAIDArray
+}
+
+// Inactive reports whether the array is assembled but not running, as when too few
+// members were found to start it. Its data cannot be reached.
+func (a Array) Inactive() bool {
+	return a.State == "inactive"
+}
+
+// Collector reads the md arrays from /proc/mdstat and sysfs.
+type Collector struct {
+	// MdstatPath is the path of the mdstat file (e.g. "/proc/mdstat").
+	MdstatPath string
+	// SysRoot is the mount point of sysfs (e.g. "/sys").
+	SysRoot string
+}
+
+// NewCollector creates a new Collector reading /proc/mdstat and sysfs at sysRoot.
+// An empty sysRoot reads /sys.
+func NewCollector(sysRoot string) *Collector {
+	if sysRoot == "" {
+		sysRoot = "/sys"
+	}
+	return &Collector{MdstatPath: "/proc/mdstat", SysRoot: sysRoot}
+}
+
+// Collect returns the arrays listed in mdstat, sorted by name. The md attributes in
+// sysfs refine the state, slots and sync progress of each array; arrays without a
+// readable md directory keep what mdstat reports. A missing mdstat, when the md
+// driver is not loaded, returns no arrays. ctx is checked before each array.
+func (c *Collector) Collect(ctx context.Context) ([]Array, error) {
+	file, err := os.Open(c.MdstatPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Debug().Str("path", c.MdstatPath).Msg("no mdstat, md driver not loaded")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open mdstat: %w", err)
+	}
+	defer file.Close()
+
+	arrays, err := ParseMdstat(file)
+	if err != nil {
+		return nil, err
+	}
+	for i := range arrays {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := readSysfs(c.SysRoot, &arrays[i]); err != nil {
+			log.Debug().Str("array", arrays[i].Name).Err(err).Msg("cannot read md sysfs attributes")
+		}
+	}
+	sort.Slice(arrays, func(i, j int) bool { return arrays[i].Name < arrays[j].Name })
+	return arrays, nil
+}
+
+// readSysfs overrides the mdstat view of an array with the attributes of
+// <sysRoot>/block/<name>/md. A missing directory leaves the array unchanged.
+func readSysfs(sysRoot string, array *Array) error {
+	dir := filepath.Join(sysRoot, "block", array.Name, "md")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if level := device.ReadSysfsAttr(filepath.Join(dir, "level")); level != "" {
+		array.Level = level
+	}
+	if state := device.ReadSysfsAttr(filepath.Join(dir, "array_state")); state != "" {
+		array.State = state
+	}
+	if raidDisks, err := strconv.Atoi(device.ReadSysfsAttr(filepath.Join(dir, "raid_disks"))); err == nil {
+		array.RaidDisks = raidDisks
+	}
+	// Levels without redundancy (raid0, linear) have no degraded attribute.
+	if degraded, err := strconv.Atoi(device.ReadSysfsAttr(filepath.Join(dir, "degraded"))); err == nil {
+		array.ActiveDisks = array.RaidDisks - degraded
+		array.Degraded = degraded > 0
+	} else if array.ActiveDisks == 0 {
+		array.ActiveDisks = array.RaidDisks
+	}
+	if mismatches, err := strconv.ParseUint(device.ReadSysfsAttr(filepath.Join(dir, "mismatch_cnt")), 10, 64); err == nil {
+		array.MismatchCount = mismatches
+	}
+	readSyncStatus(dir, array)
+
+	var members []device.RAIDMember
+	for _, entry := range entries {
+		kernelName, ok := strings.CutPrefix(entry.Name(), "dev-")
+		if !ok {
+			continue
+		}
+		members = append(members, readMember(sysRoot, filepath.Join(dir, entry.Name()), array.Path, kernelName))
+	}
+	if len(members) > 0 {
+		sortMembers(members)
+		array.Members = members
+	}
+	return nil
+}
+
+// readSyncStatus sets the sync action and progress from md/sync_action and
+// md/sync_completed. The time left is only reported by mdstat and kept from there.
+func readSyncStatus(dir string, array *Array) {
+	action := device.ReadSysfsAttr(filepath.Join(dir, "sync_action"))
+	if action == "" {
+		return
+	}
+	if action == "idle" {
+		array.SyncAction = ""
+		array.SyncProgress = nil
+		array.SyncFinish = ""
+		array.SyncSpeed = ""
+		return
+	}
+	array.SyncAction = action
+
+	// sync_completed is "<done> / <total>" in sectors, "delayed" or "none".
+	done, total, ok := strings.Cut(device.ReadSysfsAttr(filepath.Join(dir, "sync_completed")), " / ")
+	if !ok {
+		return
+	}
+	doneSectors, err1 := strconv.ParseUint(done, 10, 64)
+	totalSectors, err2 := strconv.ParseUint(total, 10, 64)
+	if err1 == nil && err2 == nil && totalSectors > 0 {
+		progress := float64(doneSectors*1000/totalSectors) / 10
+		array.SyncProgress = &progress
+	}
+	if speed := device.ReadSysfsAttr(filepath.Join(dir, "sync_speed")); speed != "" && speed != "none" {
+		array.SyncSpeed = speed + "K/sec"
+	}
+}
+
+// readMember reads the md/dev-<kernelName> directory of a member.
+func readMember(sysRoot, dir, arrayPath, kernelName string) device.RAIDMember {
+	member := device.RAIDMember{
+		Array:  arrayPath,
+		Device: devicePath(sysRoot, kernelName),
+		State:  device.ReadSysfsAttr(filepath.Join(dir, "state")),
+	}
+	if slot, err := strconv.Atoi(device.ReadSysfsAttr(filepath.Join(dir, "slot"))); err == nil {
+		member.Slot = &slot
+	}
+	member.Role = MemberRole(member.State, member.Slot != nil)
+	return member
+}
+
+// MemberRole derives the role of a member, one of the device.RAIDRole constants, from
+// its md/dev-*/state flags and whether md/dev-*/slot holds a slot number.
+func MemberRole(state string, hasSlot bool) string {
+	flags := strings.Split(state, ",")
+	has := func(flag string) bool { return slices.Contains(flags, flag) }
+	switch {
+	case has("faulty"):
+		return device.RAIDRoleFaulty
+	case has("journal"):
+		return device.RAIDRoleJournal
+	case has("replacement"):
+		return device.RAIDRoleReplacement
+	case !hasSlot:
+		return device.RAIDRoleSpare
+	case !has("in_sync"):
+		return device.RAIDRoleRebuilding
+	}
+	return device.RAIDRoleActive
+}
+
+// sortMembers orders members by slot, with spares and faulty devices last by name.
+func sortMembers(members []device.RAIDMember) {
+	sort.Slice(members, func(i, j int) bool {
+		a, b := members[i], members[j]
+		aSlotted := a.Slot != nil && a.Role != device.RAIDRoleFaulty
+		bSlotted := b.Slot != nil && b.Role != device.RAIDRoleFaulty
+		switch {
+		case aSlotted != bSlotted:
+			return aSlotted
+		case aSlotted && *a.Slot != *b.Slot:
+			return *a.Slot < *b.Slot
+		}
+		return a.Device < b.Device
+	})
+}
+
+// devicePath returns the device node of a member, /dev/mapper/<name> for
+// device-mapper devices, matching the BlockDevice paths of the providers.
+func devicePath(sysRoot, kernelName string) string {
+	if dmName := device.ReadSysfsAttr(filepath.Join(sysRoot, "class", "block", kernelName, "dm", "name")); dmName != "" {
+		return "/dev/mapper/" + dmName
+	}
+	return "/dev/" + kernelName
+}
@@ -0,0 +1,282 @@
+package md
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/sysfstest"
+)
+
+func intPtr(i int) *int { return &i }
+
+func floatPtr(f float64) *float64 { return &f }
+
+// parseFixture parses testdata/<name>.mdstat.
+func parseFixture(t *testing.T, name string) []Array {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", name+".mdstat"))
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer file.Close()
+	arrays, err := ParseMdstat(file)
+	if err != nil {
+		t.Fatalf("ParseMdstat: %v", err)
+	}
+	return arrays
+}
+
+func member(array, dev, role, state string) device.RAIDMember {
+	return device.RAIDMember{Array: array, Device: dev, Role: role, State: state}
+}
+
+func TestParseMdstat_Healthy(t *testing.T) {
+	arrays := parseFixture(t, "healthy")
+
+	want := []Array{
+		{Name: "md1", Path: "/dev/md1", RAIDArray: device.RAIDArray{
+			Level: "raid10", State: "active", RaidDisks: 4, ActiveDisks: 4,
+			Members: []device.RAIDMember{
+				member("/dev/md1", "/dev/sda2", device.RAIDRoleActive, ""),
+				member("/dev/md1", "/dev/sdb2", device.RAIDRoleActive, ""),
+				member("/dev/md1", "/dev/sdc1", device.RAIDRoleActive, ""),
+				member("/dev/md1", "/dev/sdd1", device.RAIDRoleActive, ""),
+			},
+		}},
+		{Name: "md0", Path: "/dev/md0", RAIDArray: device.RAIDArray{
+			Level: "raid1", State: "active", RaidDisks: 2, ActiveDisks: 2,
+			Members: []device.RAIDMember{
+				member("/dev/md0", "/dev/sda1", device.RAIDRoleActive, "write_mostly"),
+				member("/dev/md0", "/dev/sdb1", device.RAIDRoleActive, ""),
+				member("/dev/md0", "/dev/sde1", device.RAIDRoleSpare, "spare"),
+			},
+		}},
+		{Name: "md2", Path: "/dev/md2", RAIDArray: device.RAIDArray{
+			Level: "raid0", State: "active",
+			Members: []device.RAIDMember{
+				member("/dev/md2", "/dev/nvme0n1", device.RAIDRoleActive, ""),
+				member("/dev/md2", "/dev/nvme1n1", device.RAIDRoleActive, ""),
+			},
+		}},
+	}
+	if !reflect.DeepEqual(arrays, want) {
+		t.Errorf("got %+v\nwant %+v", arrays, want)
+	}
+}
+
+func TestParseMdstat_Degraded(t *testing.T) {
+	arrays := parseFixture(t, "degraded")
+	if len(arrays) != 4 {
+		t.Fatalf("expected 4 arrays, got %+v", arrays)
+	}
+
+	md0 := arrays[0]
+	if md0.Degraded || md0.SyncAction != "resync" || md0.SyncProgress != nil {
+		t.Errorf("md0: expected a delayed resync, got %+v", md0.RAIDArray)
+	}
+
+	md1 := arrays[1]
+	if !md1.Degraded || md1.RaidDisks != 4 || md1.ActiveDisks != 3 {
+		t.Errorf("md1: expected degraded 3/4, got %+v", md1.RAIDArray)
+	}
+	if md1.SyncAction != "recover" || !reflect.DeepEqual(md1.SyncProgress, floatPtr(8.5)) ||
+		md1.SyncFinish != "7.6min" || md1.SyncSpeed != "208108K/sec" {
+		t.Errorf("md1: unexpected recovery %+v", md1.RAIDArray)
+	}
+	last := md1.Members[len(md1.Members)-1]
+	if last.Device != "/dev/sdb2" || last.Role != device.RAIDRoleFaulty {
+		t.Errorf("md1: expected the faulty member last, got %+v", md1.Members)
+	}
+
+	md2 := arrays[2]
+	if md2.State != "auto-read-only" || md2.Level != "raid1" || !md2.Degraded {
+		t.Errorf("md2: unexpected %+v", md2.RAIDArray)
+	}
+
+	md127 := arrays[3]
+	if md127.State != "inactive" || md127.Level != "" || md127.Degraded || md127.Members[0].Role != device.RAIDRoleSpare {
+		t.Errorf("md127: unexpected %+v", md127.RAIDArray)
+	}
+}
+
+func TestParseMdstat_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"md0 active raid1 sda1[0]\n",
+		"md0 : running raid1 sda1[0]\n",
+		"md0 : active raid1 sda1\n",
+	} {
+		if _, err := ParseMdstat(strings.NewReader(content)); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+}
+
+// newFixture builds a sysfs for the degraded fixture: md1 recovers onto sdf1 after
+// losing sdb2, md0 has no md directory, and sdx is not in any array.
+func newFixture(t *testing.T) *Collector {
+	t.Helper()
+	sysRoot := t.TempDir()
+	md := filepath.Join("block", "md1", "md")
+	for path, content := range map[string]string{
+		"level":          "raid10",
+		"array_state":    "clean",
+		"raid_disks":     "4",
+		"degraded":       "1",
+		"mismatch_cnt":   "128",
+		"sync_action":    "recover",
+		"sync_completed": "17825792 / 209582080",
+		"sync_speed":     "210000",
+		"dev-sda2/slot":  "0",
+		"dev-sda2/state": "in_sync",
+		"dev-sdb2/slot":  "none",
+		"dev-sdb2/state": "faulty",
+		"dev-sdc1/slot":  "2",
+		"dev-sdc1/state": "in_sync",
+		"dev-sdd1/slot":  "3",
+		"dev-sdd1/state": "in_sync,write_mostly",
+		"dev-sdf1/slot":  "1",
+		"dev-sdf1/state": "spare",
+	} {
+		sysfstest.WriteFile(t, sysRoot, filepath.Join(md, path), content+"\n")
+	}
+
+	for devNum, name := range map[string]string{"9:0": "md0", "9:1": "md1", "8:81": "sdf1", "8:18": "sdb2", "8:97": "sdg1", "8:1": "sda1"} {
+		sysfstest.Symlink(t, sysRoot, filepath.Join("dev", "block", devNum), filepath.Join("..", "..", "block", name))
+		sysfstest.WriteFile(t, sysRoot, filepath.Join("class", "block", name, "dev"), devNum+"\n")
+	}
+	for _, member := range []string{"sda2", "sdb2", "sdc1", "sdd1", "sdf1"} {
+		sysfstest.WriteFile(t, sysRoot, filepath.Join("class", "block", member, "holders", "md1", ".keep"), "")
+	}
+	sysfstest.WriteFile(t, sysRoot, filepath.Join("class", "block", "sda1", "holders", "md0", ".keep"), "")
+	sysfstest.WriteFile(t, sysRoot, filepath.Join("class", "block", "md1", "md", ".keep"), "")
+
+	return &Collector{MdstatPath: filepath.Join("testdata", "degraded.mdstat"), SysRoot: sysRoot}
+}
+
+func TestCollect(t *testing.T) {
+	c := newFixture(t)
+
+	arrays, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	var names []string
+	for _, array := range arrays {
+		names = append(names, array.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"md0", "md1", "md127", "md2"}) {
+		t.Fatalf("unexpected arrays %v", names)
+	}
+
+	// md0 has no md directory and keeps the mdstat view.
+	if arrays[0].SyncAction != "resync" || arrays[0].RaidDisks != 2 {
+		t.Errorf("md0: unexpected %+v", arrays[0].RAIDArray)
+	}
+
+	md1 := arrays[1].RAIDArray
+	if md1.State != "clean" || !md1.Degraded || md1.ActiveDisks != 3 || md1.MismatchCount != 128 {
+		t.Errorf("md1: unexpected %+v", md1)
+	}
+	if md1.SyncAction != "recover" || !reflect.DeepEqual(md1.SyncProgress, floatPtr(8.5)) ||
+		md1.SyncSpeed != "210000K/sec" || md1.SyncFinish != "7.6min" {
+		t.Errorf("md1: unexpected sync status %+v", md1)
+	}
+	wantMembers := []device.RAIDMember{
+		{Array: "/dev/md1", Device: "/dev/sda2", Role: device.RAIDRoleActive, Slot: intPtr(0), State: "in_sync"},
+		{Array: "/dev/md1", Device: "/dev/sdf1", Role: device.RAIDRoleRebuilding, Slot: intPtr(1), State: "spare"},
+		{Array: "/dev/md1", Device: "/dev/sdc1", Role: device.RAIDRoleActive, Slot: intPtr(2), State: "in_sync"},
+		{Array: "/dev/md1", Device: "/dev/sdd1", Role: device.RAIDRoleActive, Slot: intPtr(3), State: "in_sync,write_mostly"},
+		{Array: "/dev/md1", Device: "/dev/sdb2", Role: device.RAIDRoleFaulty, State: "faulty"},
+	}
+	if !reflect.DeepEqual(md1.Members, wantMembers) {
+		t.Errorf("md1: got members %+v\nwant %+v", md1.Members, wantMembers)
+	}
+}
+
+func TestCollect_NoMdstat(t *testing.T) {
+	c := &Collector{MdstatPath: filepath.Join(t.TempDir(), "mdstat"), SysRoot: t.TempDir()}
+	arrays, err := c.Collect(context.Background())
+	if err != nil || arrays != nil {
+		t.Errorf("expected no arrays and no error, got %v, %v", arrays, err)
+	}
+}
+
+func TestEnricher(t *testing.T) {
+	e := &Enricher{Collector: newFixture(t)}
+
+	tests := []struct {
+		dev        device.BlockDevice
+		wantLevel  string
+		wantArray  string
+		wantRole   string
+		wantIgnore bool
+	}{
+		{dev: device.BlockDevice{Path: "/dev/md1", Major: 9, Minor: 1}, wantLevel: "raid10"},
+		{dev: device.BlockDevice{Path: "/dev/md0", Major: 9, Minor: 0}, wantIgnore: true},
+		{dev: device.BlockDevice{Path: "/dev/sdf1", Major: 8, Minor: 81}, wantArray: "/dev/md1", wantRole: device.RAIDRoleRebuilding},
+		{dev: device.BlockDevice{Path: "/dev/sdb2", Major: 8, Minor: 18}, wantArray: "/dev/md1", wantRole: device.RAIDRoleFaulty},
+		{dev: device.BlockDevice{Path: "/dev/sda1", Major: 8, Minor: 1}, wantArray: "/dev/md0", wantRole: device.RAIDRoleActive},
+		{dev: device.BlockDevice{Path: "/dev/sdg1", Major: 8, Minor: 97}, wantIgnore: true},
+		{dev: device.BlockDevice{Path: "/dev/sdz", Major: 65, Minor: 0}, wantIgnore: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dev.Path, func(t *testing.T) {
+			dev := tt.dev
+			if err := e.Enrich(context.Background(), &dev); err != nil {
+				t.Fatalf("Enrich: %v", err)
+			}
+			switch {
+			case tt.wantIgnore:
+				if dev.RAID != nil || dev.RAIDMember != nil {
+					t.Errorf("expected no RAID data, got %+v %+v", dev.RAID, dev.RAIDMember)
+				}
+			case tt.wantLevel != "":
+				if dev.RAID == nil || dev.RAID.Level != tt.wantLevel || len(dev.RAID.Members) != 5 {
+					t.Errorf("unexpected array %+v", dev.RAID)
+				}
+			default:
+				if dev.RAIDMember == nil || dev.RAIDMember.Array != tt.wantArray || dev.RAIDMember.Role != tt.wantRole {
+					t.Errorf("unexpected member %+v", dev.RAIDMember)
+				}
+			}
+		})
+	}
+}
+
+func TestEnricher_CollectsOncePerScan(t *testing.T) {
+	c := newFixture(t)
+	mdstat, err := os.ReadFile(c.MdstatPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.MdstatPath = filepath.Join(t.TempDir(), "mdstat")
+	if err := os.WriteFile(c.MdstatPath, mdstat, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e := &Enricher{Collector: c}
+
+	scan := device.WithScanCache(context.Background())
+	md1 := device.BlockDevice{Path: "/dev/md1", Major: 9, Minor: 1}
+	if err := e.Enrich(scan, &md1); err != nil || md1.RAID == nil {
+		t.Fatalf("Enrich md1: %v, %+v", err, md1.RAID)
+	}
+	// The member is enriched from the arrays read for md1, not from mdstat again.
+	if err := os.Remove(c.MdstatPath); err != nil {
+		t.Fatal(err)
+	}
+	sdf1 := device.BlockDevice{Path: "/dev/sdf1", Major: 8, Minor: 81}
+	if err := e.Enrich(scan, &sdf1); err != nil || sdf1.RAIDMember == nil {
+		t.Errorf("Enrich sdf1: %v, %+v", err, sdf1.RAIDMember)
+	}
+
+	// The next scan reads the current state: md1 is gone from mdstat.
+	md1 = device.BlockDevice{Path: "/dev/md1", Major: 9, Minor: 1}
+	if err := e.Enrich(device.WithScanCache(context.Background()), &md1); err != nil || md1.RAID != nil {
+		t.Errorf("a new scan must not reuse the arrays of the previous one: %v, %+v", err, md1.RAID)
+	}
+}
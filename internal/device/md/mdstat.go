@@ -0,0 +1,166 @@
+package md
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+var (
+	// mdstatDisks matches the "[total/working]" disk counts of redundant arrays.
+	mdstatDisks = regexp.MustCompile(`\[(\d+)/(\d+)\]`)
+	// mdstatProgress matches a running sync, e.g. "recovery =  8.5% (8912896/104791040)".
+	mdstatProgress = regexp.MustCompile(`\b(resync|recovery|reshape|check|repair)\s*=\s*([0-9.]+)%`)
+	// mdstatPending matches a sync waiting for another array, e.g. "resync=DELAYED".
+	mdstatPending = regexp.MustCompile(`\b(resync|recovery|reshape|check|repair)\s*=\s*(DELAYED|PENDING)`)
+	// mdstatMember matches a member device, e.g. "sdb2[1](F)".
+	mdstatMember = regexp.MustCompile(`^(\S+)\[(\d+)\]((?:\([A-Z]\))*)$`)
+	// mdstatFlag matches one member flag, e.g. "(F)".
+	mdstatFlag = regexp.MustCompile(`\([A-Z]\)`)
+)
+
+// mdstatMemberFlags maps the member flags of /proc/mdstat to their roles and states.
+var mdstatMemberFlags = map[string]struct{ role, state string }{
+	"(F)": {role: device.RAIDRoleFaulty, state: "faulty"},
+	"(S)": {role: device.RAIDRoleSpare, state: "spare"},
+	"(J)": {role: device.RAIDRoleJournal, state: "journal"},
+	"(R)": {role: device.RAIDRoleReplacement, state: "replacement"},
+	"(W)": {state: "write_mostly"},
+}
+
+// ParseMdstat parses the content of /proc/mdstat. Member slots are unknown from
+// /proc/mdstat alone; members are sorted by their descriptor number instead.
+func ParseMdstat(r io.Reader) ([]Array, error) {
+	var arrays []Array
+	var current *Array
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			current = nil
+		case strings.HasPrefix(line, "Personalities") || strings.HasPrefix(line, "unused devices"):
+			current = nil
+		case line[0] != ' ' && line[0] != '\t':
+			array, err := parseMdstatHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			arrays = append(arrays, array)
+			current = &arrays[len(arrays)-1]
+		case current != nil:
+			parseMdstatDetail(current, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mdstat: %w", err)
+	}
+	return arrays, nil
+}
+
+// parseMdstatHeader parses the first line of an array,
+// e.g. "md1 : active (auto-read-only) raid10 sdd[3] sdb2[1](F) sda2[0]".
+func parseMdstatHeader(line string) (Array, error) {
+	name, rest, ok := strings.Cut(line, " : ")
+	if !ok {
+		return Array{}, fmt.Errorf("invalid mdstat line %q", line)
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || (fields[0] != "active" && fields[0] != "inactive") {
+		return Array{}, fmt.Errorf("invalid mdstat line %q", line)
+	}
+
+	array := Array{Name: name, Path: "/dev/" + name}
+	array.State = fields[0]
+	fields = fields[1:]
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "(") {
+		// "(read-only)" or "(auto-read-only)"
+		array.State = strings.Trim(fields[0], "()")
+		fields = fields[1:]
+	}
+	if array.State != "inactive" && len(fields) > 0 && !mdstatMember.MatchString(fields[0]) {
+		array.Level = fields[0]
+		fields = fields[1:]
+	}
+
+	type indexed struct {
+		index  int
+		member device.RAIDMember
+	}
+	var members []indexed
+	for _, field := range fields {
+		m := mdstatMember.FindStringSubmatch(field)
+		if m == nil {
+			return Array{}, fmt.Errorf("invalid mdstat member %q of %s", field, name)
+		}
+		index, _ := strconv.Atoi(m[2])
+		member := device.RAIDMember{Array: array.Path, Device: "/dev/" + m[1], Role: device.RAIDRoleActive}
+		var states []string
+		for _, flag := range mdstatFlag.FindAllString(m[3], -1) {
+			f := mdstatMemberFlags[flag]
+			if f.role != "" {
+				member.Role = f.role
+			}
+			if f.state != "" {
+				states = append(states, f.state)
+			}
+		}
+		member.State = strings.Join(states, ",")
+		members = append(members, indexed{index: index, member: member})
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		iActive, jActive := members[i].member.Role == device.RAIDRoleActive, members[j].member.Role == device.RAIDRoleActive
+		if iActive != jActive {
+			return iActive
+		}
+		return members[i].index < members[j].index
+	})
+	array.Members = make([]device.RAIDMember, 0, len(members))
+	for _, m := range members {
+		array.Members = append(array.Members, m.member)
+	}
+	return array, nil
+}
+
+// parseMdstatDetail parses an indented line following the array header: the size line
+// with the disk counts, a sync progress line or a bitmap line.
+func parseMdstatDetail(array *Array, line string) {
+	if m := mdstatDisks.FindStringSubmatch(line); m != nil && strings.Contains(line, "blocks") {
+		array.RaidDisks, _ = strconv.Atoi(m[1])
+		array.ActiveDisks, _ = strconv.Atoi(m[2])
+		array.Degraded = array.ActiveDisks < array.RaidDisks
+		return
+	}
+	if m := mdstatProgress.FindStringSubmatch(line); m != nil {
+		array.SyncAction = syncActionOf(m[1])
+		if progress, err := strconv.ParseFloat(m[2], 64); err == nil {
+			array.SyncProgress = &progress
+		}
+		for _, field := range strings.Fields(line) {
+			if value, ok := strings.CutPrefix(field, "finish="); ok {
+				array.SyncFinish = value
+			}
+			if value, ok := strings.CutPrefix(field, "speed="); ok {
+				array.SyncSpeed = value
+			}
+		}
+		return
+	}
+	if m := mdstatPending.FindStringSubmatch(line); m != nil {
+		array.SyncAction = syncActionOf(m[1])
+	}
+}
+
+// syncActionOf maps the sync names of /proc/mdstat to the md/sync_action values.
+func syncActionOf(name string) string {
+	if name == "recovery" {
+		return "recover"
+	}
+	return name
+}
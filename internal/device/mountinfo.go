@@ -1,7 +1,9 @@
 package device
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/moby/sys/mountinfo"
 	"github.com/rs/zerolog/log"
@@ -9,20 +11,39 @@ import (
 
 // MountEntry represents a single mount point with its metadata.
 type MountEntry struct {
+	// MountID is the unique ID of the mount.
+	MountID int
+	// ParentID is the ID of the parent mount, or of the mount itself for the root of the tree.
+	ParentID int
 	// MountPoint is the path where the filesystem is mounted.
 	MountPoint string
+	// Root is the pathname of the directory in the filesystem which forms the root of this mount.
+	Root string
 	// FSType is the filesystem type (e.g. "ext4", "tmpfs").
 	FSType string
+	// Major is the major number of the device holding the filesystem.
+	// Zero for virtual filesystems and for btrfs, which uses anonymous device numbers.
+	Major int
+	// Minor is the minor number of the device holding the filesystem.
+	Minor int
 	// Source is the device or source of the mount (e.g. "/dev/sda1").
 	Source string
 	// Options is a comma-separated list of mount options.
 	Options string
+	// SuperOptions is the comma-separated list of superblock options (e.g. "rw,errors=remount-ro").
+	SuperOptions string
+	// Optional holds the space-separated optional fields, the propagation of the mount
+	// (e.g. "shared:1 master:2"). Empty for private mounts.
+	Optional string
 }
 
 // MountInfoProvider abstracts the retrieval of system mount information.
 type MountInfoProvider interface {
+	// Name identifies the provider in logs and errors (e.g. "mountinfo").
+	Name() string
 	// GetMounts returns all current mount entries.
-	GetMounts() ([]MountEntry, error)
+	// It stops and returns an error when ctx is done.
+	GetMounts(ctx context.Context) ([]MountEntry, error)
 }
 
 // SystemMountInfoProvider implements MountInfoProvider using moby/sys/mountinfo.
@@ -33,8 +54,17 @@ func NewSystemMountInfoProvider() *SystemMountInfoProvider {
 	return &SystemMountInfoProvider{}
 }
 
+// Name returns "mountinfo".
+func (p *SystemMountInfoProvider) Name() string {
+	return "mountinfo"
+}
+
 // GetMounts reads /proc/self/mountinfo and returns all mount entries.
-func (p *SystemMountInfoProvider) GetMounts() ([]MountEntry, error) {
+// The read itself cannot be interrupted; ctx is only checked before it starts.
+func (p *SystemMountInfoProvider) GetMounts(ctx context.Context) ([]MountEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	log.Debug().Msg("reading mount info from /proc/self/mountinfo")
 
 	mounts, err := mountinfo.GetMounts(nil)
@@ -45,14 +75,69 @@ func (p *SystemMountInfoProvider) GetMounts() ([]MountEntry, error) {
 
 	entries := make([]MountEntry, 0, len(mounts))
 	for _, m := range mounts {
-		entries = append(entries, MountEntry{
-			MountPoint: m.Mountpoint,
-			FSType:     m.FSType,
-			Source:     m.Source,
-			Options:    m.Options,
-		})
+		entries = append(entries, entryFromInfo(m))
+	}
+
+	log.Debug().Int("count", len(entries)).Msg("mount entries loaded")
+	return entries, nil
+}
+
+// FileMountInfoProvider implements MountInfoProvider by parsing a mountinfo file, such as
+// /proc/1/mountinfo of the host seen from a container, or a fixture in tests.
+type FileMountInfoProvider struct {
+	// Path is the mountinfo file to read.
+	Path string
+}
+
+// NewFileMountInfoProvider creates a new FileMountInfoProvider reading path.
+func NewFileMountInfoProvider(path string) *FileMountInfoProvider {
+	return &FileMountInfoProvider{Path: path}
+}
+
+// Name returns "mountinfo".
+func (p *FileMountInfoProvider) Name() string {
+	return "mountinfo"
+}
+
+// GetMounts parses the mountinfo file. ctx is only checked before the read starts.
+func (p *FileMountInfoProvider) GetMounts(ctx context.Context) ([]MountEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+	log.Debug().Str("path", p.Path).Msg("reading mount info from file")
 
+	file, err := os.Open(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mount info: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := ParseMountInfo(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p.Path, err)
+	}
 	log.Debug().Int("count", len(entries)).Msg("mount entries loaded")
 	return entries, nil
 }
+
+// entryFromInfo converts a mount parsed by moby/sys/mountinfo.
+func entryFromInfo(m *mountinfo.Info) MountEntry {
+	return MountEntry{
+		MountID:      m.ID,
+		ParentID:     m.Parent,
+		MountPoint:   m.Mountpoint,
+		Root:         m.Root,
+		Major:        m.Major,
+		Minor:        m.Minor,
+		FSType:       m.FSType,
+		Source:       m.Source,
+		Options:      m.Options,
+		SuperOptions: m.VFSOptions,
+		Optional:     m.Optional,
+	}
+}
+
+// DevNum returns the device number of the mounted filesystem in "major:minor" format.
+func (m MountEntry) DevNum() string {
+	return formatDevNum(m.Major, m.Minor)
+}
@@ -0,0 +1,23 @@
+package device
+
+import (
+	"io"
+
+	"github.com/moby/sys/mountinfo"
+)
+
+// ParseMountInfo parses the content of a /proc/<pid>/mountinfo file with the parser of
+// moby/sys/mountinfo, e.g.
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+func ParseMountInfo(r io.Reader) ([]MountEntry, error) {
+	mounts, err := mountinfo.GetMountsFromReader(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]MountEntry, 0, len(mounts))
+	for _, m := range mounts {
+		entries = append(entries, entryFromInfo(m))
+	}
+	return entries, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package device
+
+import (
+	"errors"
+	"io"
+)
+
+// ParseMountInfo is only implemented on Linux, where mountinfo files exist.
+func ParseMountInfo(r io.Reader) ([]MountEntry, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,41 @@
+package device
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMountInfo(t *testing.T) {
+	input := `36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+21 1 253:1 / / rw,relatime shared:1 - ext4 /dev/mapper/vg0-root rw
+40 21 0:40 / /mnt/usb\040stick rw - tmpfs tmpfs rw
+`
+	entries, err := ParseMountInfo(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseMountInfo: %v", err)
+	}
+	want := []MountEntry{
+		{MountID: 36, ParentID: 35, MountPoint: "/mnt2", Root: "/mnt1", FSType: "ext3", Major: 98, Minor: 0,
+			Source: "/dev/root", Options: "rw,noatime", SuperOptions: "rw,errors=continue", Optional: "master:1"},
+		{MountID: 21, ParentID: 1, MountPoint: "/", Root: "/", FSType: "ext4", Major: 253, Minor: 1,
+			Source: "/dev/mapper/vg0-root", Options: "rw,relatime", SuperOptions: "rw", Optional: "shared:1"},
+		{MountID: 40, ParentID: 21, MountPoint: "/mnt/usb stick", Root: "/", FSType: "tmpfs", Major: 0, Minor: 40,
+			Source: "tmpfs", Options: "rw", SuperOptions: "rw"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %+v\nwant %+v", entries, want)
+	}
+}
+
+func TestParseMountInfo_Invalid(t *testing.T) {
+	for _, line := range []string{
+		"36 35 98:0 /mnt1 /mnt2 rw ext3 /dev/root rw",
+		"36 35 98 /mnt1 /mnt2 rw - ext3 /dev/root rw",
+		"36 35 98:0 /mnt1 /mnt2 rw - ext3 /dev/root",
+	} {
+		if _, err := ParseMountInfo(strings.NewReader(line)); err == nil {
+			t.Errorf("expected an error for %q", line)
+		}
+	}
+}
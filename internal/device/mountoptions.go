@@ -0,0 +1,120 @@
+package device
+
+import (
+	"slices"
+	"strings"
+)
+
+// Access time update modes of MountOptions.Atime.
+const (
+	// AtimeRelative updates the access time when it is older than the modification time
+	// or a day old, the kernel default.
+	AtimeRelative = "relatime"
+	// AtimeNone never updates the access time.
+	AtimeNone = "noatime"
+	// AtimeStrict updates the access time on every access.
+	AtimeStrict = "strictatime"
+)
+
+// MountOptions are parsed mount or superblock options.
+type MountOptions struct {
+	// ReadOnly is true for "ro". Later options win, so "ro,rw" is read-write.
+	ReadOnly bool `json:"readOnly"`
+	// NoSuid is true when set-user-ID and set-group-ID bits are ignored.
+	NoSuid bool `json:"nosuid"`
+	// NoDev is true when device files cannot be opened.
+	NoDev bool `json:"nodev"`
+	// NoExec is true when files cannot be executed.
+	NoExec bool `json:"noexec"`
+	// Atime is the access time update mode (AtimeRelative, AtimeNone, AtimeStrict),
+	// empty when the options do not set one.
+	Atime string `json:"atime,omitempty"`
+	// Flags are the options without a value, in order (e.g. "rw", "nosuid", "relatime").
+	Flags []string `json:"flags,omitempty"`
+	// Values are the key=value options (e.g. "errors": "remount-ro", "vers": "4.2").
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// ParseMountOptions parses a comma-separated option list as found in mountinfo
+// (e.g. "rw,nosuid,relatime" or "rw,errors=remount-ro").
+func ParseMountOptions(options string) MountOptions {
+	var o MountOptions
+	for _, option := range strings.Split(options, ",") {
+		if option == "" {
+			continue
+		}
+		if key, value, ok := strings.Cut(option, "="); ok {
+			if o.Values == nil {
+				o.Values = make(map[string]string)
+			}
+			o.Values[key] = value
+			continue
+		}
+		o.Flags = append(o.Flags, option)
+		switch option {
+		case "ro":
+			o.ReadOnly = true
+		case "rw":
+			o.ReadOnly = false
+		case "nosuid":
+			o.NoSuid = true
+		case "suid":
+			o.NoSuid = false
+		case "nodev":
+			o.NoDev = true
+		case "dev":
+			o.NoDev = false
+		case "noexec":
+			o.NoExec = true
+		case "exec":
+			o.NoExec = false
+		case AtimeRelative, AtimeNone, AtimeStrict:
+			o.Atime = option
+		}
+	}
+	return o
+}
+
+// Has reports whether the options include option. "key" matches the flag or any value
+// of key, "key=value" only that value.
+func (o MountOptions) Has(option string) bool {
+	key, want, hasValue := strings.Cut(option, "=")
+	value, ok := o.Values[key]
+	if hasValue {
+		return ok && value == want
+	}
+	return ok || slices.Contains(o.Flags, key)
+}
+
+// Flag reports whether a VFS flag (ro, rw, nosuid, suid, nodev, dev, noexec, exec or an
+// access time mode) is in effect, from the parsed fields so that later options win and
+// unset flags take their default. ok is false for the other options.
+func (o MountOptions) Flag(option string) (set, ok bool) {
+	switch option {
+	case "ro":
+		return o.ReadOnly, true
+	case "rw":
+		return !o.ReadOnly, true
+	case "nosuid":
+		return o.NoSuid, true
+	case "suid":
+		return !o.NoSuid, true
+	case "nodev":
+		return o.NoDev, true
+	case "dev":
+		return !o.NoDev, true
+	case "noexec":
+		return o.NoExec, true
+	case "exec":
+		return !o.NoExec, true
+	case AtimeRelative, AtimeNone, AtimeStrict:
+		return o.Atime == option, true
+	}
+	return false, false
+}
+
+// Value returns the value of a key=value option.
+func (o MountOptions) Value(key string) (string, bool) {
+	value, ok := o.Values[key]
+	return value, ok
+}
@@ -0,0 +1,65 @@
+package device
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMountOptions(t *testing.T) {
+	got := ParseMountOptions("ro,nosuid,nodev,noexec,noatime,errors=remount-ro,data=ordered")
+	want := MountOptions{
+		ReadOnly: true, NoSuid: true, NoDev: true, NoExec: true, Atime: AtimeNone,
+		Flags:  []string{"ro", "nosuid", "nodev", "noexec", "noatime"},
+		Values: map[string]string{"errors": "remount-ro", "data": "ordered"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if o := ParseMountOptions("ro,rw,relatime"); o.ReadOnly || o.Atime != AtimeRelative {
+		t.Errorf("later options must win, got %+v", o)
+	}
+	if o := ParseMountOptions(""); !reflect.DeepEqual(o, MountOptions{}) {
+		t.Errorf("expected no options, got %+v", o)
+	}
+}
+
+func TestMountOptions_Has(t *testing.T) {
+	o := ParseMountOptions("rw,nosuid,vers=4.2,sec=sys")
+	for option, want := range map[string]bool{
+		"nosuid":   true,
+		"noexec":   false,
+		"vers":     true,
+		"vers=4.2": true,
+		"vers=3":   false,
+		"rw=1":     false,
+	} {
+		if got := o.Has(option); got != want {
+			t.Errorf("Has(%q) = %v, want %v", option, got, want)
+		}
+	}
+	if value, ok := o.Value("sec"); !ok || value != "sys" {
+		t.Errorf("Value(sec) = %q, %v", value, ok)
+	}
+}
+
+func TestMountOptions_Flag(t *testing.T) {
+	o := ParseMountOptions("ro,nosuid,rw,noatime,vers=4.2")
+	for option, want := range map[string]bool{
+		"rw":       true,
+		"ro":       false,
+		"nosuid":   true,
+		"suid":     false,
+		"dev":      true,
+		"nodev":    false,
+		"noatime":  true,
+		"relatime": false,
+	} {
+		if got, ok := o.Flag(option); !ok || got != want {
+			t.Errorf("Flag(%q) = %v, %v, want %v", option, got, ok, want)
+		}
+	}
+	if _, ok := o.Flag("vers"); ok {
+		t.Error("vers is not a VFS flag")
+	}
+}
@@ -0,0 +1,264 @@
+// Package mounttable lists every mount of the mount table, block-device backed or not
+// (NFS, CIFS, tmpfs, overlay, fuse, cgroup, ...), with its propagation, its place in the
+// mount tree and the usage of its filesystem.
+package mounttable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/statfs"
+)
+
+// Propagation types, named as in findmnt.
+const (
+	// PropagationPrivate mounts neither send nor receive mount events.
+	PropagationPrivate = "private"
+	// PropagationShared mounts share mount events with their peer group.
+	PropagationShared = "shared"
+	// PropagationSlave mounts receive the mount events of their master peer group.
+	PropagationSlave = "slave"
+	// PropagationUnbindable mounts are private and cannot be bind mounted.
+	PropagationUnbindable = "unbindable"
+)
+
+// DefaultParallelism is the number of statfs calls made concurrently by ReadUsage.
+const DefaultParallelism = 8
+
+// Mount is a single entry of the mount table.
+type Mount struct {
+	// ID is the unique mount ID.
+	ID int `json:"id"`
+	// ParentID is the ID of the parent mount in the mount tree.
+	ParentID int `json:"parentId"`
+	// Target is the mount point.
+	Target string `json:"target"`
+	// Source is the mounted device or remote (e.g. "/dev/sda1", "server:/export", "tmpfs").
+	Source string `json:"source"`
+	// FSType is the filesystem type (e.g. "nfs4", "overlay", "cgroup2").
+	FSType string `json:"fstype"`
+	// FSRoot is the directory of the filesystem mounted at Target ("/" unless bind mounted).
+	FSRoot string `json:"fsroot"`
+	// MajMin is the device number of the filesystem (e.g. "8:1", "0:52" for virtual filesystems).
+	MajMin string `json:"majmin"`
+	// Options are the per-mount options (e.g. "rw,nosuid,relatime").
+	Options string `json:"options"`
+	// SuperOptions are the superblock options, shared by every mount of the filesystem.
+	SuperOptions string `json:"superOptions"`
+	// ParsedOptions are the per-mount options, parsed.
+	ParsedOptions device.MountOptions `json:"parsedOptions"`
+	// ParsedSuperOptions are the superblock options, parsed.
+	ParsedSuperOptions device.MountOptions `json:"parsedSuperOptions"`
+	// Propagation is the comma-separated propagation of the mount (e.g. "shared", "shared,slave").
+	Propagation string `json:"propagation"`
+	// PeerGroup is the ID of the peer group of a shared mount (shared:N).
+	PeerGroup int `json:"peerGroup,omitempty"`
+	// Master is the peer group a slave mount receives events from (master:N).
+	Master int `json:"master,omitempty"`
+	// PropagateFrom is the closest dominant peer group of a slave mount not reachable
+	// from the current root (propagate_from:N).
+	PropagateFrom int `json:"propagateFrom,omitempty"`
+	// Usage is the usage of the filesystem. Nil if not read.
+	Usage *device.FSUsage `json:"usage,omitempty"`
+}
+
+// FromEntries converts the entries of a MountInfoProvider, keeping their order.
+func FromEntries(entries []device.MountEntry) []Mount {
+	mounts := make([]Mount, 0, len(entries))
+	for _, e := range entries {
+		m := Mount{
+			ID:                 e.MountID,
+			ParentID:           e.ParentID,
+			Target:             e.MountPoint,
+			Source:             e.Source,
+			FSType:             e.FSType,
+			FSRoot:             e.Root,
+			MajMin:             e.DevNum(),
+			Options:            e.Options,
+			SuperOptions:       e.SuperOptions,
+			ParsedOptions:      device.ParseMountOptions(e.Options),
+			ParsedSuperOptions: device.ParseMountOptions(e.SuperOptions),
+		}
+		parsePropagation(&m, e.Optional)
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// HasOption reports whether the mount has option. The VFS flags (ro, rw, nosuid, nodev,
+// noexec, the access time modes and their opposites) are per mount: a read-only bind
+// mount of a read-write filesystem is "ro", not "rw", so they are only looked up in the
+// per-mount options. The other options are looked up in the per-mount and superblock
+// options; "key" matches the flag or any value of key, "key=value" only that value.
+func (m Mount) HasOption(option string) bool {
+	if set, ok := m.ParsedOptions.Flag(option); ok {
+		return set
+	}
+	return m.ParsedOptions.Has(option) || m.ParsedSuperOptions.Has(option)
+}
+
+// parsePropagation sets the propagation fields from the mountinfo optional fields.
+func parsePropagation(m *Mount, optional string) {
+	var propagation []string
+	for _, field := range strings.Fields(optional) {
+		tag, value, _ := strings.Cut(field, ":")
+		id, _ := strconv.Atoi(value)
+		switch tag {
+		case "shared":
+			m.PeerGroup = id
+			propagation = append(propagation, PropagationShared)
+		case "master":
+			m.Master = id
+			propagation = append(propagation, PropagationSlave)
+		case "propagate_from":
+			m.PropagateFrom = id
+		case "unbindable":
+			propagation = append(propagation, PropagationUnbindable)
+		}
+	}
+	if len(propagation) == 0 {
+		propagation = []string{PropagationPrivate}
+	}
+	m.Propagation = strings.Join(propagation, ",")
+}
+
+// Filter selects mounts. Empty fields match every mount.
+type Filter struct {
+	// FSTypes keeps the mounts of these filesystem types.
+	FSTypes []string `json:"fstypes,omitempty"`
+	// ExcludeFSTypes drops the mounts of these filesystem types.
+	ExcludeFSTypes []string `json:"excludeFstypes,omitempty"`
+	// Targets keeps the mounts at these mount points.
+	Targets []string `json:"targets,omitempty"`
+	// Sources keeps the mounts of these sources (e.g. "/dev/sda1", "server:/export").
+	Sources []string `json:"sources,omitempty"`
+	// TargetPrefix keeps the mounts at or below this path (e.g. "/var" matches /var and
+	// /var/lib, not /various).
+	TargetPrefix string `json:"targetPrefix,omitempty"`
+	// Options keeps the mounts having all of these options, as checked by Mount.HasOption.
+	// "key" matches "key" and "key=value"; "key=value" only itself.
+	Options []string `json:"options,omitempty"`
+}
+
+// Match reports whether m passes the filter.
+func (f Filter) Match(m Mount) bool {
+	switch {
+	case len(f.FSTypes) > 0 && !slices.Contains(f.FSTypes, m.FSType):
+		return false
+	case slices.Contains(f.ExcludeFSTypes, m.FSType):
+		return false
+	case len(f.Targets) > 0 && !slices.Contains(f.Targets, m.Target):
+		return false
+	case len(f.Sources) > 0 && !slices.Contains(f.Sources, m.Source):
+		return false
+	case f.TargetPrefix != "" && !underPath(m.Target, f.TargetPrefix):
+		return false
+	}
+	for _, option := range f.Options {
+		if !m.HasOption(option) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the mounts passing the filter, in order.
+func (f Filter) Apply(mounts []Mount) []Mount {
+	matched := make([]Mount, 0, len(mounts))
+	for _, m := range mounts {
+		if f.Match(m) {
+			matched = append(matched, m)
+		}
+	}
+	return matched
+}
+
+// underPath reports whether path is prefix or below it.
+func underPath(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// ReadUsage calls statfs on every mount, at most DefaultParallelism at a time, each call
+// bounded by timeout. Mounts that cannot be accessed are left without usage; the errors
+// of the other failed calls, timeouts included, are returned in mount order.
+func ReadUsage(ctx context.Context, mounts []Mount, timeout time.Duration) []error {
+	errs := make([]error, len(mounts))
+	slots := make(chan struct{}, DefaultParallelism)
+	var wg sync.WaitGroup
+	for i := range mounts {
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			u, err := statfs.Usage(ctx, mounts[i].Target, timeout)
+			switch {
+			case errors.Is(err, errors.ErrUnsupported), errors.Is(err, fs.ErrPermission), errors.Is(err, fs.ErrNotExist):
+				log.Debug().Str("mountpoint", mounts[i].Target).Err(err).Msg("statfs skipped")
+			case err != nil:
+				errs[i] = fmt.Errorf("failed to read usage of %s: %w", mounts[i].Target, err)
+			default:
+				mounts[i].Usage = u
+			}
+		}()
+	}
+	wg.Wait()
+	return slices.DeleteFunc(errs, func(err error) bool { return err == nil })
+}
+
+// Node is a mount and the mounts made on top of it.
+type Node struct {
+	Mount
+	// Children are the mounts whose parent is this mount, in mount table order.
+	Children []*Node `json:"children,omitempty"`
+}
+
+// Tree arranges the mounts by parent ID. Mounts whose parent is not among them,
+// like the root mount or the top of a filtered subtree, are the roots.
+func Tree(mounts []Mount) []*Node {
+	nodes := make(map[int]*Node, len(mounts))
+	for _, m := range mounts {
+		nodes[m.ID] = &Node{Mount: m}
+	}
+
+	var roots []*Node
+	parents := make(map[*Node]*Node, len(mounts))
+	for _, m := range mounts {
+		node := nodes[m.ID]
+		parent, ok := nodes[m.ParentID]
+		if !ok || isAncestor(parents, node, parent) {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+		parents[node] = parent
+	}
+	return roots
+}
+
+// isAncestor reports whether node is other or one of its ancestors in the tree built
+// so far, in which case making node a child of other would close a cycle. The kernel
+// never reports one, but mountinfo files can be edited.
+func isAncestor(parents map[*Node]*Node, node, other *Node) bool {
+	for ; other != nil; other = parents[other] {
+		if other == node {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package mounttable
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+const testMountInfo = `21 1 253:1 / / rw,relatime shared:1 - ext4 /dev/mapper/vg0-root rw
+22 21 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+23 21 0:22 / /sys rw,nosuid,nodev,noexec,relatime shared:2 - sysfs sysfs rw
+30 23 0:26 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:4 - cgroup2 cgroup2 rw,nsdelegate
+40 21 0:50 / /mnt/nfs rw,relatime shared:30 - nfs4 server:/export rw,vers=4.2,hard
+41 21 0:51 / /var/lib/docker/overlay2/abc/merged rw,relatime master:1 propagate_from:3 - overlay overlay rw,lowerdir=/l
+42 21 0:52 / /various rw,nosuid unbindable - tmpfs tmpfs rw,size=1024k
+`
+
+func testMounts(t *testing.T) []Mount {
+	t.Helper()
+	entries, err := device.ParseMountInfo(strings.NewReader(testMountInfo))
+	if err != nil {
+		t.Fatalf("ParseMountInfo: %v", err)
+	}
+	return FromEntries(entries)
+}
+
+func TestFromEntries(t *testing.T) {
+	mounts := testMounts(t)
+	if len(mounts) != 7 {
+		t.Fatalf("expected 7 mounts, got %d", len(mounts))
+	}
+	want := Mount{
+		ID: 40, ParentID: 21, Target: "/mnt/nfs", Source: "server:/export", FSType: "nfs4", FSRoot: "/",
+		MajMin: "0:50", Options: "rw,relatime", SuperOptions: "rw,vers=4.2,hard",
+		ParsedOptions: device.MountOptions{Atime: device.AtimeRelative, Flags: []string{"rw", "relatime"}},
+		ParsedSuperOptions: device.MountOptions{
+			Flags: []string{"rw", "hard"}, Values: map[string]string{"vers": "4.2"},
+		},
+		Propagation: PropagationShared, PeerGroup: 30,
+	}
+	if !reflect.DeepEqual(mounts[4], want) {
+		t.Errorf("got %+v\nwant %+v", mounts[4], want)
+	}
+
+	overlay := mounts[5]
+	if overlay.Propagation != PropagationSlave || overlay.Master != 1 || overlay.PropagateFrom != 3 {
+		t.Errorf("unexpected slave propagation %+v", overlay)
+	}
+	if mounts[6].Propagation != PropagationUnbindable {
+		t.Errorf("expected unbindable, got %q", mounts[6].Propagation)
+	}
+
+	var m Mount
+	parsePropagation(&m, "shared:5 master:2")
+	if m.Propagation != "shared,slave" || m.PeerGroup != 5 || m.Master != 2 {
+		t.Errorf("unexpected shared and slave propagation %+v", m)
+	}
+	parsePropagation(&m, "")
+	if m.Propagation != PropagationPrivate {
+		t.Errorf("expected private, got %q", m.Propagation)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	mounts := testMounts(t)
+	for _, tc := range []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"empty", Filter{}, []string{"/", "/proc", "/sys", "/sys/fs/cgroup", "/mnt/nfs",
+			"/var/lib/docker/overlay2/abc/merged", "/various"}},
+		{"fstypes", Filter{FSTypes: []string{"nfs4", "tmpfs"}}, []string{"/mnt/nfs", "/various"}},
+		{"exclude", Filter{ExcludeFSTypes: []string{"proc", "sysfs", "cgroup2", "overlay", "tmpfs"}}, []string{"/", "/mnt/nfs"}},
+		{"targets", Filter{Targets: []string{"/sys", "/various"}}, []string{"/sys", "/various"}},
+		{"source", Filter{Sources: []string{"/dev/mapper/vg0-root"}}, []string{"/"}},
+		{"target prefix", Filter{TargetPrefix: "/sys/"}, []string{"/sys", "/sys/fs/cgroup"}},
+		{"target prefix boundary", Filter{TargetPrefix: "/var"}, []string{"/var/lib/docker/overlay2/abc/merged"}},
+		{"option key", Filter{Options: []string{"nosuid", "noexec"}}, []string{"/proc", "/sys", "/sys/fs/cgroup"}},
+		{"super option", Filter{Options: []string{"vers"}}, []string{"/mnt/nfs"}},
+		{"option value", Filter{Options: []string{"size=1024k"}}, []string{"/various"}},
+		{"option other value", Filter{Options: []string{"vers=3"}}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, m := range tc.filter.Apply(mounts) {
+				got = append(got, m.Target)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMount_HasOption(t *testing.T) {
+	// A read-only bind mount of a read-write NFS filesystem.
+	m := FromEntries([]device.MountEntry{{
+		MountID: 40, ParentID: 1, MountPoint: "/srv/ro", Root: "/export", FSType: "nfs4",
+		Options: "ro,nosuid,relatime", SuperOptions: "rw,noexec,vers=4.2",
+	}})[0]
+	for option, want := range map[string]bool{
+		"ro":       true,
+		"rw":       false,
+		"nosuid":   true,
+		"noexec":   false,
+		"exec":     true,
+		"vers":     true,
+		"vers=4.2": true,
+	} {
+		if got := m.HasOption(option); got != want {
+			t.Errorf("HasOption(%q) = %v, want %v", option, got, want)
+		}
+	}
+}
+
+func TestTree(t *testing.T) {
+	roots := Tree(testMounts(t))
+	if len(roots) != 1 || roots[0].Target != "/" {
+		t.Fatalf("expected the root mount as the single root, got %d roots", len(roots))
+	}
+	var children []string
+	for _, child := range roots[0].Children {
+		children = append(children, child.Target)
+	}
+	want := []string{"/proc", "/sys", "/mnt/nfs", "/var/lib/docker/overlay2/abc/merged", "/various"}
+	if !reflect.DeepEqual(children, want) {
+		t.Errorf("got children %v, want %v", children, want)
+	}
+	if sys := roots[0].Children[1]; len(sys.Children) != 1 || sys.Children[0].Target != "/sys/fs/cgroup" {
+		t.Errorf("unexpected children of /sys %+v", sys.Children)
+	}
+
+	filtered := Tree(Filter{TargetPrefix: "/sys"}.Apply(testMounts(t)))
+	if len(filtered) != 1 || filtered[0].Target != "/sys" || len(filtered[0].Children) != 1 {
+		t.Errorf("the top of a filtered subtree must be its root, got %+v", filtered)
+	}
+
+	cycle := Tree([]Mount{{ID: 1, ParentID: 2, Target: "/a"}, {ID: 2, ParentID: 1, Target: "/b"}})
+	if len(cycle) != 1 || len(cycle[0].Children) != 1 {
+		t.Errorf("a cycle must be broken into a single tree, got %+v", cycle)
+	}
+}
+
+func TestReadUsage(t *testing.T) {
+	dir := t.TempDir()
+	mounts := []Mount{{Target: dir}, {Target: dir + "/missing"}}
+	if errs := ReadUsage(context.Background(), mounts, time.Second); len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if mounts[0].Usage == nil || mounts[0].Usage.TotalBytes == 0 || mounts[0].Usage.MountPoint != dir {
+		t.Errorf("unexpected usage %+v", mounts[0].Usage)
+	}
+	if mounts[1].Usage != nil {
+		t.Errorf("a missing mount point must be skipped, got %+v", mounts[1].Usage)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if errs := ReadUsage(ctx, []Mount{{Target: dir}}, time.Second); len(errs) != 1 {
+		t.Errorf("expected the cancellation error, got %v", errs)
+	}
+}
@@ -0,0 +1,115 @@
+package parttable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Enricher fills partition table information on disks and their partitions.
+type Enricher struct {
+	// readFile reads the partition table of a device path, replaceable in tests.
+	readFile func(path string) (*Table, error)
+}
+
+// NewEnricher creates a new Enricher reading the device nodes directly.
+func NewEnricher() *Enricher {
+	return &Enricher{readFile: ReadFile}
+}
+
+// Name returns "parttable".
+func (e *Enricher) Name() string {
+	return "parttable"
+}
+
+// Fields returns the partition table fields of disks and the partition entry fields of partitions.
+func (e *Enricher) Fields() []string {
+	return []string{
+		"pttype", "ptuuid", "partuuid", "parttype", "parttypename", "partlabel", "partflags",
+		"partStartLba", "partEndLba",
+	}
+}
+
+// Enrich sets the table type and UUID on a device holding a partition table, and fills
+// the partition fields of a partition from the table of its parent, matched by partition
+// number. The parent table is read again for each partition: tables are small and this
+// keeps the enricher free of state shared between concurrently enriched devices.
+func (e *Enricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	if dev.Type == "part" {
+		if len(dev.Parents) == 0 || dev.PartitionNumber == 0 {
+			return nil
+		}
+		// Errors reading the parent table are reported on the parent itself.
+		table, err := e.read(dev.Parents[0])
+		if err != nil || table == nil {
+			return nil
+		}
+		for _, p := range table.Partitions {
+			if p.Number == dev.PartitionNumber {
+				applyPartition(dev, p)
+				break
+			}
+		}
+		return nil
+	}
+
+	if !canHoldTable(*dev) {
+		return nil
+	}
+	table, err := e.read(dev.Path)
+	if err != nil || table == nil {
+		return err
+	}
+
+	log.Debug().
+		Str("device", dev.Path).
+		Str("pttype", table.Type).
+		Int("partitions", len(table.Partitions)).
+		Msg("enriched device with partition table")
+	dev.PartitionTableType = table.Type
+	dev.PartitionTableUUID = table.DiskGUID
+	return nil
+}
+
+// read reads the partition table of a device. Devices without a table or that cannot be
+// opened (e.g. permission denied, or no device node) return a nil table and no error.
+func (e *Enricher) read(path string) (*Table, error) {
+	table, err := e.readFile(path)
+	if err != nil {
+		if errors.Is(err, ErrNoPartitionTable) || errors.Is(err, fs.ErrPermission) || errors.Is(err, fs.ErrNotExist) {
+			log.Debug().Str("device", path).Err(err).Msg("no partition table read")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read partition table: %w", err)
+	}
+	return table, nil
+}
+
+// canHoldTable reports whether a device that is not a partition may carry a partition table.
+// Devices with a filesystem signature are skipped: FAT and NTFS boot sectors
+// end with the same 0x55AA marker as an MBR.
+func canHoldTable(dev device.BlockDevice) bool {
+	switch {
+	case dev.Type == "part", dev.Type == "rom", dev.DeviceSizeBytes == 0:
+		return false
+	case dev.FSType != "":
+		return false
+	}
+	return true
+}
+
+// applyPartition copies a partition table entry into the device fields.
+func applyPartition(dev *device.BlockDevice, p Partition) {
+	dev.PartitionUUID = p.GUID
+	dev.PartitionType = p.TypeGUID
+	dev.PartitionTypeName = p.TypeName
+	dev.PartitionLabel = p.Name
+	dev.PartitionFlags = p.Flags
+	dev.PartitionStartLBA = p.StartLBA
+	dev.PartitionEndLBA = p.EndLBA
+}
@@ -0,0 +1,240 @@
+package parttable
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/bits"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/rs/zerolog/log"
+)
+
+// GPT layout constants.
+const (
+	gptSignature      = "EFI PART"
+	gptMinHeaderSize  = 92
+	gptMinEntrySize   = 128
+	gptMaxEntrySize   = 4096
+	gptMaxEntries     = 1024
+	gptNameOffset     = 56
+	gptNameLength     = 72
+	gptHeaderCRCStart = 16
+)
+
+// gptHeader holds the fields of a GPT header needed to read its entries.
+type gptHeader struct {
+	myLBA          uint64
+	alternateLBA   uint64
+	firstUsableLBA uint64
+	lastUsableLBA  uint64
+	diskGUID       string
+	entriesLBA     uint64
+	numEntries     uint32
+	entrySize      uint32
+	entriesCRC     uint32
+}
+
+// hasGPTSignature reports whether the GPT header signature is found at the given offset.
+func hasGPTSignature(r io.ReaderAt, offset int64) bool {
+	buf, err := readAt(r, offset, len(gptSignature))
+	return err == nil && string(buf) == gptSignature
+}
+
+// readGPT reads the primary GPT and validates the backup copy.
+// If the primary header or entries are corrupt, the backup is used instead.
+func readGPT(r io.ReaderAt, size int64, sectorSize int) (*Table, error) {
+	lastLBA := uint64(size/int64(sectorSize)) - 1
+
+	primary, primaryEntries, primaryErr := readGPTCopy(r, 1, sectorSize)
+	if primaryErr != nil {
+		log.Debug().Err(primaryErr).Msg("primary GPT is invalid")
+	}
+
+	backupLBA := lastLBA
+	if primaryErr == nil && primary.alternateLBA != 0 {
+		backupLBA = primary.alternateLBA
+	}
+	backup, backupEntries, backupErr := readGPTCopy(r, backupLBA, sectorSize)
+	if backupErr != nil {
+		log.Debug().Err(backupErr).Uint64("lba", backupLBA).Msg("backup GPT is invalid")
+	}
+
+	header, entries := primary, primaryEntries
+	if primaryErr != nil {
+		if backupErr != nil {
+			return nil, fmt.Errorf("both GPT copies are invalid: primary: %v, backup: %w", primaryErr, backupErr)
+		}
+		header, entries = backup, backupEntries
+	}
+
+	table := &Table{
+		Type:               TypeGPT,
+		DiskGUID:           header.diskGUID,
+		SectorSize:         sectorSize,
+		FirstUsableLBA:     header.firstUsableLBA,
+		LastUsableLBA:      header.lastUsableLBA,
+		PrimaryHeaderValid: primaryErr == nil,
+		BackupHeaderValid:  backupErr == nil,
+	}
+
+	for i := uint32(0); i < header.numEntries; i++ {
+		offset := int(i) * int(header.entrySize)
+		if offset+gptMinEntrySize > len(entries) {
+			return nil, fmt.Errorf("GPT entry %d is beyond the %d-byte entry array", i+1, len(entries))
+		}
+		raw := entries[offset:]
+		typeGUID := formatGUID(raw[0:16])
+		if typeGUID == "" {
+			continue
+		}
+
+		start := binary.LittleEndian.Uint64(raw[32:])
+		end := binary.LittleEndian.Uint64(raw[40:])
+		attributes := binary.LittleEndian.Uint64(raw[48:])
+		var sizeBytes uint64
+		if end >= start {
+			sizeBytes = (end - start + 1) * uint64(sectorSize)
+		}
+
+		table.Partitions = append(table.Partitions, Partition{
+			Number:     int(i) + 1,
+			StartLBA:   start,
+			EndLBA:     end,
+			SizeBytes:  sizeBytes,
+			TypeGUID:   typeGUID,
+			TypeName:   gptTypeName(typeGUID),
+			GUID:       formatGUID(raw[16:32]),
+			Name:       decodeGPTName(raw[gptNameOffset : gptNameOffset+gptNameLength]),
+			Attributes: attributes,
+			Flags:      gptAttributeFlags(attributes),
+		})
+	}
+
+	return table, nil
+}
+
+// readGPTCopy reads and validates the GPT header at the given LBA and its entry array.
+func readGPTCopy(r io.ReaderAt, lba uint64, sectorSize int) (gptHeader, []byte, error) {
+	buf, err := readAt(r, int64(lba)*int64(sectorSize), sectorSize)
+	if err != nil {
+		return gptHeader{}, nil, err
+	}
+	if string(buf[0:8]) != gptSignature {
+		return gptHeader{}, nil, errors.New("missing GPT signature")
+	}
+
+	headerSize := binary.LittleEndian.Uint32(buf[12:])
+	if headerSize < gptMinHeaderSize || int(headerSize) > sectorSize {
+		return gptHeader{}, nil, fmt.Errorf("invalid GPT header size %d", headerSize)
+	}
+
+	wantCRC := binary.LittleEndian.Uint32(buf[gptHeaderCRCStart:])
+	check := make([]byte, headerSize)
+	copy(check, buf[:headerSize])
+	binary.LittleEndian.PutUint32(check[gptHeaderCRCStart:], 0)
+	if gotCRC := crc32.ChecksumIEEE(check); gotCRC != wantCRC {
+		return gptHeader{}, nil, fmt.Errorf("GPT header CRC mismatch: got %08x, want %08x", gotCRC, wantCRC)
+	}
+
+	header := gptHeader{
+		myLBA:          binary.LittleEndian.Uint64(buf[24:]),
+		alternateLBA:   binary.LittleEndian.Uint64(buf[32:]),
+		firstUsableLBA: binary.LittleEndian.Uint64(buf[40:]),
+		lastUsableLBA:  binary.LittleEndian.Uint64(buf[48:]),
+		diskGUID:       formatGUID(buf[56:72]),
+		entriesLBA:     binary.LittleEndian.Uint64(buf[72:]),
+		numEntries:     binary.LittleEndian.Uint32(buf[80:]),
+		entrySize:      binary.LittleEndian.Uint32(buf[84:]),
+		entriesCRC:     binary.LittleEndian.Uint32(buf[88:]),
+	}
+	if header.myLBA != lba {
+		return gptHeader{}, nil, fmt.Errorf("GPT header at LBA %d claims to be at LBA %d", lba, header.myLBA)
+	}
+	// Both limits keep the array below 4 MiB, and the length computed in 64 bits cannot wrap.
+	if header.entrySize < gptMinEntrySize || header.entrySize > gptMaxEntrySize || header.entrySize%8 != 0 ||
+		header.numEntries > gptMaxEntries {
+		return gptHeader{}, nil, fmt.Errorf("invalid GPT entry array: %d entries of %d bytes",
+			header.numEntries, header.entrySize)
+	}
+	length := uint64(header.numEntries) * uint64(header.entrySize)
+
+	entries, err := readAt(r, int64(header.entriesLBA)*int64(sectorSize), int(length))
+	if err != nil {
+		return gptHeader{}, nil, fmt.Errorf("failed to read GPT entries: %w", err)
+	}
+	if gotCRC := crc32.ChecksumIEEE(entries); gotCRC != header.entriesCRC {
+		return gptHeader{}, nil, fmt.Errorf("GPT entries CRC mismatch: got %08x, want %08x", gotCRC, header.entriesCRC)
+	}
+
+	return header, entries, nil
+}
+
+// formatGUID formats a 16-byte GPT GUID. The first three fields are stored little-endian.
+// An all-zero GUID (unused entry) is returned as empty string.
+func formatGUID(b []byte) string {
+	zero := true
+	for _, c := range b[:16] {
+		if c != 0 {
+			zero = false
+			break
+		}
+	}
+	if zero {
+		return ""
+	}
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10], b[10:16])
+}
+
+// decodeGPTName decodes the UTF-16LE partition name, stopping at the first NUL.
+func decodeGPTName(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := binary.LittleEndian.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+// gptAttributeNames maps GPT attribute bits to flag names. Bits 0-2 are defined by UEFI;
+// bits 60-63 are the type-specific bits used by Microsoft basic data and systemd.
+var gptAttributeNames = map[int]string{
+	0:  "required",
+	1:  "no-block-io",
+	2:  "legacy-bios-bootable",
+	60: "read-only",
+	61: "shadow-copy",
+	62: "hidden",
+	63: "no-automount",
+}
+
+// gptAttributeFlags decodes the GPT attribute field into flag names.
+// Unnamed bits are reported as "bit<N>".
+func gptAttributeFlags(attributes uint64) []string {
+	var flags []string
+	for attributes != 0 {
+		bit := bits.TrailingZeros64(attributes)
+		attributes &^= 1 << uint(bit)
+		name, ok := gptAttributeNames[bit]
+		if !ok {
+			name = fmt.Sprintf("bit%d", bit)
+		}
+		flags = append(flags, name)
+	}
+	return flags
+}
+
+// normalizeGUID lowercases a GUID for table lookups.
+func normalizeGUID(guid string) string {
+	return strings.ToLower(guid)
+}
@@ -0,0 +1,174 @@
+package parttable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MBR layout constants.
+const (
+	mbrSignatureOffset  = 510
+	mbrSignature        = 0xAA55
+	mbrDiskSigOffset    = 440
+	mbrEntriesOffset    = 446
+	mbrEntrySize        = 16
+	mbrPrimaryEntries   = 4
+	mbrBootIndicator    = 0x80
+	mbrTypeProtective   = 0xEE
+	mbrFirstLogicalPart = 5
+	// mbrMaxLogical bounds the EBR chain walk to protect against loops in corrupt tables.
+	mbrMaxLogical = 128
+)
+
+// mbrEntry is a raw 16-byte MBR or EBR partition entry.
+type mbrEntry struct {
+	status   byte
+	partType byte
+	startLBA uint32
+	sectors  uint32
+}
+
+// mbrSector is a parsed MBR or EBR sector.
+type mbrSector struct {
+	diskSignature uint32
+	entries       [mbrPrimaryEntries]mbrEntry
+}
+
+// isProtective reports whether the MBR is a GPT protective MBR.
+func (m mbrSector) isProtective() bool {
+	for _, e := range m.entries {
+		if e.partType == mbrTypeProtective {
+			return true
+		}
+	}
+	return false
+}
+
+// isExtendedType reports whether an MBR type byte marks an extended partition.
+func isExtendedType(t byte) bool {
+	return t == 0x05 || t == 0x0F || t == 0x85
+}
+
+// readMBR reads the sector at offset 0 and checks the boot signature.
+func readMBR(r io.ReaderAt) (mbrSector, error) {
+	return readMBRAt(r, 0)
+}
+
+// readMBRAt reads an MBR or EBR sector at the given byte offset.
+func readMBRAt(r io.ReaderAt, offset int64) (mbrSector, error) {
+	buf, err := readAt(r, offset, 512)
+	if err != nil {
+		return mbrSector{}, err
+	}
+	if binary.LittleEndian.Uint16(buf[mbrSignatureOffset:]) != mbrSignature {
+		return mbrSector{}, ErrNoPartitionTable
+	}
+
+	sector := mbrSector{diskSignature: binary.LittleEndian.Uint32(buf[mbrDiskSigOffset:])}
+	for i := range sector.entries {
+		raw := buf[mbrEntriesOffset+i*mbrEntrySize:]
+		sector.entries[i] = mbrEntry{
+			status:   raw[0],
+			partType: raw[4],
+			startLBA: binary.LittleEndian.Uint32(raw[8:]),
+			sectors:  binary.LittleEndian.Uint32(raw[12:]),
+		}
+	}
+	return sector, nil
+}
+
+// readDOS builds a DOS table from the MBR, following the EBR chain of the extended partition.
+func readDOS(r io.ReaderAt, mbr mbrSector, size int64, sectorSize int) (*Table, error) {
+	table := &Table{
+		Type:       TypeDOS,
+		DiskGUID:   fmt.Sprintf("%08x", mbr.diskSignature),
+		SectorSize: sectorSize,
+	}
+
+	for _, e := range mbr.entries {
+		// Boot sectors of unpartitioned FAT/NTFS volumes also end with 0x55AA,
+		// but their "entries" hold code and rarely a valid boot indicator.
+		if e.status != 0 && e.status != mbrBootIndicator {
+			return nil, ErrNoPartitionTable
+		}
+	}
+
+	var extendedStart uint64
+	for i, e := range mbr.entries {
+		if e.partType == 0 || e.sectors == 0 {
+			continue
+		}
+		table.Partitions = append(table.Partitions, newDOSPartition(table.DiskGUID, i+1, e, 0, sectorSize, false))
+		if isExtendedType(e.partType) && extendedStart == 0 {
+			extendedStart = uint64(e.startLBA)
+		}
+	}
+
+	if extendedStart > 0 {
+		logical, err := readLogicalPartitions(r, table.DiskGUID, extendedStart, size, sectorSize)
+		if err != nil {
+			// Primary partitions are still valid when the EBR chain is damaged.
+			log.Debug().Err(err).Msg("failed to read logical partitions")
+		}
+		table.Partitions = append(table.Partitions, logical...)
+	}
+
+	return table, nil
+}
+
+// readLogicalPartitions walks the EBR linked list starting at the extended partition.
+// Each EBR holds one logical partition (relative to the EBR) and a link to the next EBR
+// (relative to the start of the extended partition).
+func readLogicalPartitions(r io.ReaderAt, diskGUID string, extendedStart uint64, size int64,
+	sectorSize int) ([]Partition, error) {
+	var partitions []Partition
+	ebrLBA := extendedStart
+	visited := make(map[uint64]bool)
+
+	for number := mbrFirstLogicalPart; number < mbrFirstLogicalPart+mbrMaxLogical; number++ {
+		if visited[ebrLBA] || int64(ebrLBA)*int64(sectorSize) >= size {
+			return partitions, fmt.Errorf("invalid EBR link to LBA %d", ebrLBA)
+		}
+		visited[ebrLBA] = true
+
+		ebr, err := readMBRAt(r, int64(ebrLBA)*int64(sectorSize))
+		if err != nil {
+			return partitions, fmt.Errorf("failed to read EBR at LBA %d: %w", ebrLBA, err)
+		}
+
+		if data := ebr.entries[0]; data.partType != 0 && data.sectors != 0 {
+			partitions = append(partitions, newDOSPartition(diskGUID, number, data, ebrLBA, sectorSize, true))
+		}
+
+		next := ebr.entries[1]
+		if next.partType == 0 || next.startLBA == 0 {
+			return partitions, nil
+		}
+		ebrLBA = extendedStart + uint64(next.startLBA)
+	}
+	return partitions, nil
+}
+
+// newDOSPartition converts a raw entry into a Partition. base is the LBA the entry's
+// start is relative to (0 for primary partitions, the EBR LBA for logical ones).
+func newDOSPartition(diskGUID string, number int, e mbrEntry, base uint64, sectorSize int, logical bool) Partition {
+	start := base + uint64(e.startLBA)
+	p := Partition{
+		Number:     number,
+		StartLBA:   start,
+		EndLBA:     start + uint64(e.sectors) - 1,
+		SizeBytes:  uint64(e.sectors) * uint64(sectorSize),
+		TypeGUID:   fmt.Sprintf("0x%02x", e.partType),
+		TypeName:   mbrTypeName(e.partType),
+		GUID:       fmt.Sprintf("%s-%02x", diskGUID, number),
+		Attributes: uint64(e.status),
+		Logical:    logical,
+	}
+	if e.status&mbrBootIndicator != 0 {
+		p.Flags = []string{"boot"}
+	}
+	return p
+}
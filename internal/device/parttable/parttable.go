@@ -0,0 +1,133 @@
+// Package parttable reads GPT and MBR partition tables from a disk device or image file.
+// Both GPT headers (primary and backup) are validated, and MBR extended partitions
+// are followed to list logical partitions.
+package parttable
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Partition table types, using the blkid PTTYPE names.
+const (
+	// TypeGPT is a GUID Partition Table.
+	TypeGPT = "gpt"
+	// TypeDOS is a classic MBR partition table.
+	TypeDOS = "dos"
+)
+
+// DefaultSectorSize is the logical sector size assumed when reading image files.
+const DefaultSectorSize = 512
+
+// ErrNoPartitionTable is returned when the device has no MBR boot signature.
+var ErrNoPartitionTable = errors.New("no partition table found")
+
+// Table is a parsed partition table.
+type Table struct {
+	// Type is the partition table type (TypeGPT or TypeDOS).
+	Type string `json:"type"`
+	// DiskGUID is the GPT disk GUID, or the MBR disk signature as 8 hex digits.
+	DiskGUID string `json:"diskGuid"`
+	// SectorSize is the logical sector size LBAs are expressed in.
+	SectorSize int `json:"sectorSize"`
+	// FirstUsableLBA is the first LBA usable by partitions (GPT only).
+	FirstUsableLBA uint64 `json:"firstUsableLba,omitempty"`
+	// LastUsableLBA is the last LBA usable by partitions (GPT only).
+	LastUsableLBA uint64 `json:"lastUsableLba,omitempty"`
+	// PrimaryHeaderValid reports whether the primary GPT header and entries passed CRC checks.
+	PrimaryHeaderValid bool `json:"primaryHeaderValid,omitempty"`
+	// BackupHeaderValid reports whether the backup GPT header and entries passed CRC checks.
+	BackupHeaderValid bool `json:"backupHeaderValid,omitempty"`
+	// Partitions lists the used partition entries in table order.
+	Partitions []Partition `json:"partitions"`
+}
+
+// Partition is a single partition table entry.
+type Partition struct {
+	// Number is the partition number as used by the kernel (e.g. 1 for sda1).
+	// MBR logical partitions start at 5.
+	Number int `json:"number"`
+	// StartLBA is the first sector of the partition.
+	StartLBA uint64 `json:"startLba"`
+	// EndLBA is the last sector of the partition (inclusive).
+	EndLBA uint64 `json:"endLba"`
+	// SizeBytes is the partition size in bytes.
+	SizeBytes uint64 `json:"sizeBytes"`
+	// TypeGUID is the GPT partition type GUID, or the MBR type byte as "0x83".
+	TypeGUID string `json:"typeGuid"`
+	// TypeName is the human-readable partition type (e.g. "EFI System").
+	TypeName string `json:"typeName"`
+	// GUID is the GPT unique partition GUID, or the MBR PARTUUID ("<signature>-<number>").
+	GUID string `json:"guid"`
+	// Name is the GPT partition name. Always empty for MBR.
+	Name string `json:"name"`
+	// Attributes is the raw GPT attribute field, or the MBR boot indicator byte.
+	Attributes uint64 `json:"attributes"`
+	// Flags are the decoded attribute bits (e.g. "legacy-bios-bootable", "boot").
+	Flags []string `json:"flags,omitempty"`
+	// Logical reports whether this is an MBR logical partition inside an extended partition.
+	Logical bool `json:"logical,omitempty"`
+}
+
+// Read parses the partition table from r. size is the device size in bytes, used to locate
+// the GPT backup header; sectorSize is the logical sector size of the device.
+// A protective MBR is resolved to the GPT it protects.
+func Read(r io.ReaderAt, size int64, sectorSize int) (*Table, error) {
+	mbr, err := readMBR(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if mbr.isProtective() {
+		log.Debug().Msg("protective MBR found, reading GPT")
+		return readGPT(r, size, sectorSize)
+	}
+	return readDOS(r, mbr, size, sectorSize)
+}
+
+// ReadFile opens a disk device or image file read-only and parses its partition table.
+// The sector size is DefaultSectorSize unless a GPT header is found at 4096 bytes.
+func ReadFile(path string) (*Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	// Seeking works for both regular files and block devices, where Stat reports size 0.
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get size of %s: %w", path, err)
+	}
+
+	table, err := Read(file, size, detectSectorSize(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partition table of %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// detectSectorSize returns 4096 if a GPT header signature is found at LBA 1 of a
+// 4K-sector disk and none at LBA 1 of a 512-byte sector disk, DefaultSectorSize otherwise.
+func detectSectorSize(r io.ReaderAt) int {
+	if hasGPTSignature(r, DefaultSectorSize) {
+		return DefaultSectorSize
+	}
+	if hasGPTSignature(r, 4096) {
+		return 4096
+	}
+	return DefaultSectorSize
+}
+
+// readAt reads exactly n bytes at offset.
+func readAt(r io.ReaderAt, offset int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return nil, fmt.Errorf("read %d bytes at offset %d: %w", n, offset, err)
+	}
+	return buf, nil
+}
@@ -0,0 +1,312 @@
+package parttable
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+const (
+	testSectors  = 2048
+	testEntries  = 128
+	testDiskGUID = "11223344-5566-7788-99aa-bbccddeeff00"
+	testEFIGUID  = "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"
+)
+
+// encodeGUID is the inverse of formatGUID.
+func encodeGUID(t testing.TB, s string) []byte {
+	t.Helper()
+	var a uint32
+	var b, c, d uint16
+	var e uint64
+	if _, err := fmt.Sscanf(s, "%08x-%04x-%04x-%04x-%012x", &a, &b, &c, &d, &e); err != nil {
+		t.Fatalf("invalid guid %q: %v", s, err)
+	}
+	out := make([]byte, 16)
+	binary.LittleEndian.PutUint32(out[0:], a)
+	binary.LittleEndian.PutUint16(out[4:], b)
+	binary.LittleEndian.PutUint16(out[6:], c)
+	binary.BigEndian.PutUint16(out[8:], d)
+	for i := 15; i >= 10; i-- {
+		out[i] = byte(e)
+		e >>= 8
+	}
+	return out
+}
+
+// gptEntry describes a partition entry written by newGPTImage.
+type gptEntry struct {
+	typeGUID, guid, name string
+	start, end, attrs    uint64
+}
+
+// newGPTImage builds a disk image with a protective MBR, primary and backup GPT.
+func newGPTImage(t testing.TB, entries []gptEntry) []byte {
+	t.Helper()
+	img := make([]byte, testSectors*512)
+
+	// Protective MBR.
+	img[446+4] = 0xEE
+	binary.LittleEndian.PutUint32(img[446+8:], 1)
+	binary.LittleEndian.PutUint32(img[446+12:], testSectors-1)
+	binary.LittleEndian.PutUint16(img[510:], 0xAA55)
+
+	array := make([]byte, testEntries*128)
+	for i, e := range entries {
+		raw := array[i*128:]
+		copy(raw[0:], encodeGUID(t, e.typeGUID))
+		copy(raw[16:], encodeGUID(t, e.guid))
+		binary.LittleEndian.PutUint64(raw[32:], e.start)
+		binary.LittleEndian.PutUint64(raw[40:], e.end)
+		binary.LittleEndian.PutUint64(raw[48:], e.attrs)
+		for j, r := range e.name {
+			binary.LittleEndian.PutUint16(raw[56+j*2:], uint16(r))
+		}
+	}
+	arrayCRC := crc32.ChecksumIEEE(array)
+	arraySectors := uint64(len(array) / 512)
+
+	writeHeader := func(myLBA, alternateLBA, entriesLBA uint64) {
+		h := img[myLBA*512:]
+		copy(h[0:], gptSignature)
+		binary.LittleEndian.PutUint32(h[8:], 0x00010000)
+		binary.LittleEndian.PutUint32(h[12:], 92)
+		binary.LittleEndian.PutUint64(h[24:], myLBA)
+		binary.LittleEndian.PutUint64(h[32:], alternateLBA)
+		binary.LittleEndian.PutUint64(h[40:], 34)
+		binary.LittleEndian.PutUint64(h[48:], testSectors-34)
+		copy(h[56:], encodeGUID(t, testDiskGUID))
+		binary.LittleEndian.PutUint64(h[72:], entriesLBA)
+		binary.LittleEndian.PutUint32(h[80:], testEntries)
+		binary.LittleEndian.PutUint32(h[84:], 128)
+		binary.LittleEndian.PutUint32(h[88:], arrayCRC)
+		binary.LittleEndian.PutUint32(h[16:], crc32.ChecksumIEEE(h[:92]))
+		copy(img[entriesLBA*512:], array)
+	}
+
+	lastLBA := uint64(testSectors - 1)
+	writeHeader(1, lastLBA, 2)
+	writeHeader(lastLBA, 1, lastLBA-arraySectors)
+	return img
+}
+
+// newMBRImage builds a disk image with two primary partitions and an extended
+// partition holding two logical partitions.
+func newMBRImage() []byte {
+	img := make([]byte, testSectors*512)
+	binary.LittleEndian.PutUint32(img[440:], 0xDEADBEEF)
+	binary.LittleEndian.PutUint16(img[510:], 0xAA55)
+
+	putEntry := func(sector []byte, i int, status, partType byte, start, sectors uint32) {
+		e := sector[446+i*16:]
+		e[0] = status
+		e[4] = partType
+		binary.LittleEndian.PutUint32(e[8:], start)
+		binary.LittleEndian.PutUint32(e[12:], sectors)
+	}
+	putEntry(img, 0, 0x80, 0x83, 64, 256)
+	putEntry(img, 1, 0x00, 0x82, 320, 128)
+	putEntry(img, 2, 0x00, 0x05, 512, 1024)
+
+	// First EBR at LBA 512: logical at +32, next EBR at extended+512.
+	ebr1 := img[512*512:]
+	putEntry(ebr1, 0, 0, 0x8e, 32, 200)
+	putEntry(ebr1, 1, 0, 0x05, 512, 300)
+	binary.LittleEndian.PutUint16(ebr1[510:], 0xAA55)
+
+	ebr2 := img[1024*512:]
+	putEntry(ebr2, 0, 0, 0x83, 32, 100)
+	binary.LittleEndian.PutUint16(ebr2[510:], 0xAA55)
+	return img
+}
+
+func writeImage(t *testing.T, img []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "disk.img")
+	if err := os.WriteFile(path, img, 0o600); err != nil {
+		t.Fatalf("write image: %v", err)
+	}
+	return path
+}
+
+var testGPTEntries = []gptEntry{
+	{typeGUID: TypeGUIDEFISystem, guid: testEFIGUID, name: "EFI", start: 34, end: 233, attrs: 1},
+	{typeGUID: "e6d6d379-f507-44c2-a23c-238f2a3df928", guid: "01020304-0506-0708-090a-0b0c0d0e0f10",
+		name: "pv0", start: 234, end: 1999, attrs: 1<<2 | 1<<63},
+}
+
+func TestReadFile_GPT(t *testing.T) {
+	table, err := ReadFile(writeImage(t, newGPTImage(t, testGPTEntries)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if table.Type != TypeGPT || table.DiskGUID != testDiskGUID || table.SectorSize != 512 {
+		t.Errorf("unexpected table header: %+v", table)
+	}
+	if !table.PrimaryHeaderValid || !table.BackupHeaderValid {
+		t.Errorf("both headers should be valid: primary=%v backup=%v", table.PrimaryHeaderValid, table.BackupHeaderValid)
+	}
+
+	want := []Partition{
+		{
+			Number: 1, StartLBA: 34, EndLBA: 233, SizeBytes: 200 * 512,
+			TypeGUID: TypeGUIDEFISystem, TypeName: "EFI System", GUID: testEFIGUID, Name: "EFI",
+			Attributes: 1, Flags: []string{"required"},
+		},
+		{
+			Number: 2, StartLBA: 234, EndLBA: 1999, SizeBytes: 1766 * 512,
+			TypeGUID: "e6d6d379-f507-44c2-a23c-238f2a3df928", TypeName: "Linux LVM",
+			GUID: "01020304-0506-0708-090a-0b0c0d0e0f10", Name: "pv0",
+			Attributes: 1<<2 | 1<<63, Flags: []string{"legacy-bios-bootable", "no-automount"},
+		},
+	}
+	if !reflect.DeepEqual(table.Partitions, want) {
+		t.Errorf("unexpected partitions:\ngot:  %+v\nwant: %+v", table.Partitions, want)
+	}
+}
+
+func TestReadFile_GPTCorruptPrimaryUsesBackup(t *testing.T) {
+	img := newGPTImage(t, testGPTEntries)
+	// Flip a byte of the primary header CRC.
+	img[512+16] ^= 0xFF
+
+	table, err := ReadFile(writeImage(t, img))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if table.PrimaryHeaderValid || !table.BackupHeaderValid {
+		t.Errorf("expected corrupt primary and valid backup: primary=%v backup=%v",
+			table.PrimaryHeaderValid, table.BackupHeaderValid)
+	}
+	if len(table.Partitions) != 2 || table.Partitions[0].Name != "EFI" {
+		t.Errorf("partitions not read from backup: %+v", table.Partitions)
+	}
+}
+
+// setGPTEntryArray rewrites the entry array fields of both GPT headers of img and
+// recomputes their CRC, as a hostile disk would.
+func setGPTEntryArray(img []byte, numEntries, entrySize, entriesCRC uint32) {
+	for _, lba := range []int{1, testSectors - 1} {
+		h := img[lba*512:]
+		binary.LittleEndian.PutUint32(h[80:], numEntries)
+		binary.LittleEndian.PutUint32(h[84:], entrySize)
+		binary.LittleEndian.PutUint32(h[88:], entriesCRC)
+		binary.LittleEndian.PutUint32(h[16:], 0)
+		binary.LittleEndian.PutUint32(h[16:], crc32.ChecksumIEEE(h[:92]))
+	}
+}
+
+func TestReadFile_GPTHostileEntryArray(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		numEntries, entrySize uint32
+	}{
+		// 1024 * 4 MiB wraps to 0 in 32 bits: an empty array whose CRC is 0.
+		{"length wraps", 1024, 1 << 22},
+		{"huge entries", 1, 1 << 31},
+		{"array larger than the disk", 1024, 4096},
+		{"entries too small", 128, 64},
+		{"unaligned entries", 128, 130},
+		{"too many entries", 1 << 20, 128},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			img := newGPTImage(t, testGPTEntries)
+			setGPTEntryArray(img, tc.numEntries, tc.entrySize, 0)
+			if _, err := ReadFile(writeImage(t, img)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func FuzzRead(f *testing.F) {
+	f.Add(newGPTImage(f, testGPTEntries)[:64*512])
+	f.Add(newMBRImage()[:8*512])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Any input may be rejected, none may panic.
+		_, _ = Read(bytes.NewReader(data), int64(len(data)), DefaultSectorSize)
+	})
+}
+
+func TestReadFile_MBRWithLogicalPartitions(t *testing.T) {
+	table, err := ReadFile(writeImage(t, newMBRImage()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if table.Type != TypeDOS || table.DiskGUID != "deadbeef" {
+		t.Errorf("unexpected table header: %+v", table)
+	}
+
+	type summary struct {
+		Number   int
+		Start    uint64
+		End      uint64
+		TypeName string
+		GUID     string
+		Logical  bool
+		Flags    []string
+	}
+	var got []summary
+	for _, p := range table.Partitions {
+		got = append(got, summary{p.Number, p.StartLBA, p.EndLBA, p.TypeName, p.GUID, p.Logical, p.Flags})
+	}
+
+	want := []summary{
+		{1, 64, 319, "Linux", "deadbeef-01", false, []string{"boot"}},
+		{2, 320, 447, "Linux swap / Solaris", "deadbeef-02", false, nil},
+		{3, 512, 1535, "Extended", "deadbeef-03", false, nil},
+		{5, 544, 743, "Linux LVM", "deadbeef-05", true, nil},
+		{6, 1056, 1155, "Linux", "deadbeef-06", true, nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected partitions:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestReadFile_NoTable(t *testing.T) {
+	if _, err := ReadFile(writeImage(t, make([]byte, 4096))); err == nil {
+		t.Fatal("expected an error for an image without partition table")
+	}
+}
+
+func TestEnricher_FillsDiskAndPartitions(t *testing.T) {
+	path := writeImage(t, newGPTImage(t, testGPTEntries))
+	devices := []device.BlockDevice{
+		{Path: path, Type: "disk", DeviceSizeBytes: testSectors * 512},
+		{Path: path + "2", Type: "part", PartitionNumber: 2, Parents: []string{path}},
+		{Path: path + "9", Type: "part", PartitionNumber: 9, Parents: []string{path}},
+	}
+
+	enricher := NewEnricher()
+	for i := range devices {
+		if err := enricher.Enrich(context.Background(), &devices[i]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if devices[0].PartitionTableType != TypeGPT || devices[0].PartitionTableUUID != testDiskGUID {
+		t.Errorf("disk not enriched: %+v", devices[0])
+	}
+	if devices[1].PartitionTypeName != "Linux LVM" || devices[1].PartitionLabel != "pv0" || devices[1].PartitionStartLBA != 234 {
+		t.Errorf("partition not enriched: %+v", devices[1])
+	}
+	if devices[2].PartitionUUID != "" {
+		t.Errorf("partition without table entry must be left alone: %+v", devices[2])
+	}
+}
+
+func TestEnricher_SkipsMissingDeviceNode(t *testing.T) {
+	dev := device.BlockDevice{Path: filepath.Join(t.TempDir(), "sda"), Type: "disk", DeviceSizeBytes: 4096}
+	if err := NewEnricher().Enrich(context.Background(), &dev); err != nil {
+		t.Fatalf("a missing device node must be skipped, got %v", err)
+	}
+}
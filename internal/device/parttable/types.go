@@ -0,0 +1,99 @@
+package parttable
+
+import "fmt"
+
+// TypeGUIDEFISystem is the GPT type GUID of an EFI System Partition.
+const TypeGUIDEFISystem = "c12a7328-f81f-11d2-ba4b-00a0c93ec93b"
+
+// gptTypeNames maps GPT partition type GUIDs (lowercase) to readable names,
+// following the names used by fdisk.
+var gptTypeNames = map[string]string{
+	TypeGUIDEFISystem:                      "EFI System",
+	"024dee41-33e7-11d3-9d69-0008c781f39f": "MBR partition scheme",
+	"21686148-6449-6e6f-744e-656564454649": "BIOS boot",
+	"0fc63daf-8483-4772-8e79-3d69d8477de4": "Linux filesystem",
+	"0657fd6d-a4ab-43c4-84e5-0933c84b4f4f": "Linux swap",
+	"e6d6d379-f507-44c2-a23c-238f2a3df928": "Linux LVM",
+	"a19d880f-05fc-4d3b-a006-743f0f84911e": "Linux RAID",
+	"ca7d7ccb-63ed-4c53-861c-1742536059cc": "Linux LUKS",
+	"7ffec5c9-2d00-49b7-8941-3ea10a5586b7": "Linux dm-crypt",
+	"933ac7e1-2eb4-4f13-b844-0e14e2aef915": "Linux home",
+	"3b8f8425-20e0-4f3b-907f-1a25a76f98e8": "Linux server data",
+	"bc13c2ff-59e6-4262-a352-b275fd6f7172": "Linux extended boot",
+	"8da63339-0007-60c0-c436-083ac8230908": "Linux reserved",
+	"44479540-f297-41b2-9af7-d131d5f0458a": "Linux root (x86)",
+	"4f68bce3-e8cd-4db1-96e7-fbcaf984b709": "Linux root (x86-64)",
+	"b921b045-1df0-41c3-af44-4c6f280d3fae": "Linux root (ARM-64)",
+	"69dad710-2ce4-4e3c-b16c-21a1d49abed3": "Linux root (ARM)",
+	"8484680c-9521-48c6-9c11-b0720656f69e": "Linux /usr (x86-64)",
+	"4d21b016-b534-45c2-a9fb-5c16e091fd2d": "Linux variable data",
+	"7ec6f557-3bc5-4aca-b293-16ef5df639d1": "Linux temporary data",
+	"ebd0a0a2-b9e5-4433-87c0-68b6b72699c7": "Microsoft basic data",
+	"e3c9e316-0b5c-4db8-817d-f92df00215ae": "Microsoft reserved",
+	"5808c8aa-7e8f-42e0-85d2-e1e90434cfb3": "Microsoft LDM metadata",
+	"af9b60a0-1431-4f62-bc68-3311714a69ad": "Microsoft LDM data",
+	"de94bba4-06d1-4d40-a16a-bfd50179d6ac": "Windows recovery environment",
+	"e75caf8f-f680-4cee-afa3-b001e56efc2d": "Microsoft Storage Spaces",
+	"48465300-0000-11aa-aa11-00306543ecac": "Apple HFS/HFS+",
+	"7c3457ef-0000-11aa-aa11-00306543ecac": "Apple APFS",
+	"426f6f74-0000-11aa-aa11-00306543ecac": "Apple boot",
+	"516e7cb4-6ecf-11d6-8ff8-00022d09712b": "FreeBSD data",
+	"516e7cb6-6ecf-11d6-8ff8-00022d09712b": "FreeBSD UFS",
+	"516e7cba-6ecf-11d6-8ff8-00022d09712b": "FreeBSD ZFS",
+	"6a898cc3-1dd2-11b2-99a6-080020736631": "Solaris /usr & Apple ZFS",
+	"4fbd7e29-9d25-41b8-afd0-062c0ceff05d": "Ceph OSD",
+	"45b0969e-9b03-4f30-b4c6-b4b80ceff106": "Ceph journal",
+	"f4019732-066e-4e12-8273-346c5641494f": "Sony boot partition",
+	"c91818f9-8025-47af-89d2-f030d7000c2c": "Plan 9",
+	"9d275380-40ad-11db-bf97-000c2911d1b8": "VMware VMFS",
+}
+
+// mbrTypeNames maps MBR partition type bytes to readable names, following fdisk.
+var mbrTypeNames = map[byte]string{
+	0x01: "FAT12",
+	0x04: "FAT16 <32M",
+	0x05: "Extended",
+	0x06: "FAT16",
+	0x07: "HPFS/NTFS/exFAT",
+	0x0b: "W95 FAT32",
+	0x0c: "W95 FAT32 (LBA)",
+	0x0e: "W95 FAT16 (LBA)",
+	0x0f: "W95 Ext'd (LBA)",
+	0x11: "Hidden FAT12",
+	0x12: "Compaq diagnostics",
+	0x17: "Hidden HPFS/NTFS",
+	0x1b: "Hidden W95 FAT32",
+	0x1c: "Hidden W95 FAT32 (LBA)",
+	0x27: "Hidden NTFS WinRE",
+	0x42: "SFS",
+	0x82: "Linux swap / Solaris",
+	0x83: "Linux",
+	0x85: "Linux extended",
+	0x88: "Linux plaintext",
+	0x8e: "Linux LVM",
+	0xa5: "FreeBSD",
+	0xa6: "OpenBSD",
+	0xa9: "NetBSD",
+	0xaf: "HFS / HFS+",
+	0xda: "Non-FS data",
+	0xee: "GPT",
+	0xef: "EFI (FAT-12/16/32)",
+	0xfb: "VMware VMFS",
+	0xfd: "Linux raid autodetect",
+}
+
+// gptTypeName returns the readable name of a GPT type GUID, or "unknown".
+func gptTypeName(guid string) string {
+	if name, ok := gptTypeNames[normalizeGUID(guid)]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// mbrTypeName returns the readable name of an MBR type byte, or "unknown (0xNN)".
+func mbrTypeName(t byte) string {
+	if name, ok := mbrTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (0x%02x)", t)
+}
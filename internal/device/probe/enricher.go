@@ -0,0 +1,81 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Enricher fills the filesystem fields of block devices by probing their superblocks.
+// It is meant for providers that cannot read them, such as lsblk running unprivileged
+// or the sysfs provider. Values already reported by the provider are kept.
+type Enricher struct {
+	// probeFile probes a device path, replaceable in tests.
+	probeFile func(path string) (Result, error)
+}
+
+// NewEnricher creates a new Enricher reading the device nodes directly.
+func NewEnricher() *Enricher {
+	return &Enricher{probeFile: ProbeFile}
+}
+
+// Name returns "probe".
+func (e *Enricher) Name() string {
+	return "probe"
+}
+
+// Fields returns the filesystem fields filled from the superblock.
+func (e *Enricher) Fields() []string {
+	return []string{"fstype", "uuid", "label", "fsVersion"}
+}
+
+// Enrich probes a device missing its filesystem type or UUID and fills
+// the empty FSType, UUID, Label and FSVersion fields.
+// Devices without a signature or that cannot be opened (e.g. permission denied, or no
+// device node in a container reading the host sysfs) are skipped.
+func (e *Enricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	if !needsProbe(*dev) {
+		return nil
+	}
+
+	result, err := e.probeFile(dev.Path)
+	if err != nil {
+		if errors.Is(err, ErrNoSignature) || errors.Is(err, fs.ErrPermission) || errors.Is(err, fs.ErrNotExist) {
+			log.Debug().Str("device", dev.Path).Err(err).Msg("no signature probed")
+			return nil
+		}
+		return fmt.Errorf("failed to probe device: %w", err)
+	}
+
+	log.Debug().
+		Str("device", dev.Path).
+		Str("fstype", result.Type).
+		Str("uuid", result.UUID).
+		Msg("enriched device with probed signature")
+	fillEmpty(&dev.FSType, result.Type)
+	fillEmpty(&dev.UUID, result.UUID)
+	fillEmpty(&dev.Label, result.Label)
+	fillEmpty(&dev.FSVersion, result.Version)
+	return nil
+}
+
+// needsProbe reports whether a device is worth probing.
+// Optical drives are skipped because reading an empty tray can block for a long time.
+func needsProbe(dev device.BlockDevice) bool {
+	if dev.Type == "rom" || dev.DeviceSizeBytes == 0 {
+		return false
+	}
+	return dev.FSType == "" || dev.UUID == ""
+}
+
+// fillEmpty sets *field to value if the field is empty.
+func fillEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
@@ -0,0 +1,140 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ext2/3/4 superblock layout and feature flags.
+const (
+	extSuperblockOffset = 1024
+	extMagic            = 0xEF53
+
+	extFeatureCompatHasJournal   = 0x0004
+	extFeatureIncompatJournalDev = 0x0008
+	// ext3 only understands these incompat and ro_compat features, anything else means ext4.
+	ext3SupportedIncompat = 0x0002 | 0x0004 | 0x0010
+	ext3SupportedRoCompat = 0x0001 | 0x0002 | 0x0004
+)
+
+// probeExt detects ext2, ext3, ext4 and external ext journals (jbd).
+func probeExt(r io.ReaderAt) (Result, bool) {
+	sb, ok := readAt(r, extSuperblockOffset, 1024)
+	if !ok || binary.LittleEndian.Uint16(sb[0x38:]) != extMagic {
+		return Result{}, false
+	}
+
+	compat := binary.LittleEndian.Uint32(sb[0x5C:])
+	incompat := binary.LittleEndian.Uint32(sb[0x60:])
+	roCompat := binary.LittleEndian.Uint32(sb[0x64:])
+
+	var fsType string
+	switch {
+	case incompat&extFeatureIncompatJournalDev != 0:
+		fsType = "jbd"
+	case incompat&^ext3SupportedIncompat != 0 || roCompat&^ext3SupportedRoCompat != 0:
+		fsType = "ext4"
+	case compat&extFeatureCompatHasJournal != 0:
+		fsType = "ext3"
+	default:
+		fsType = "ext2"
+	}
+
+	return Result{
+		Type:    fsType,
+		UUID:    formatUUID(sb[0x68:0x78]),
+		Label:   CString(sb[0x78:0x88]),
+		Version: fmt.Sprintf("%d.%d", binary.LittleEndian.Uint32(sb[0x4C:]), binary.LittleEndian.Uint16(sb[0x3E:])),
+	}, true
+}
+
+// probeXFS detects XFS from its superblock at offset 0 (big-endian fields).
+func probeXFS(r io.ReaderAt) (Result, bool) {
+	sb, ok := readAt(r, 0, 512)
+	if !ok || string(sb[0:4]) != "XFSB" || binary.BigEndian.Uint32(sb[4:]) == 0 {
+		return Result{}, false
+	}
+
+	return Result{
+		Type:    "xfs",
+		UUID:    formatUUID(sb[32:48]),
+		Label:   CString(sb[108:120]),
+		Version: fmt.Sprintf("%d", binary.BigEndian.Uint16(sb[100:])&0x000F),
+	}, true
+}
+
+// btrfsSuperblockOffset is the offset of the primary btrfs superblock.
+const btrfsSuperblockOffset = 0x10000
+
+// probeBtrfs detects btrfs from its primary superblock.
+func probeBtrfs(r io.ReaderAt) (Result, bool) {
+	sb, ok := readAt(r, btrfsSuperblockOffset, 0x22B)
+	if !ok || string(sb[0x40:0x48]) != "_BHRfS_M" {
+		return Result{}, false
+	}
+
+	return Result{
+		Type:  "btrfs",
+		UUID:  formatUUID(sb[0x20:0x30]),
+		Label: CString(sb[0x12B:0x22B]),
+	}, true
+}
+
+// probeSquashfs detects squashfs images. Squashfs has no UUID or label.
+func probeSquashfs(r io.ReaderAt) (Result, bool) {
+	sb, ok := readAt(r, 0, 96)
+	if !ok || string(sb[0:4]) != "hsqs" {
+		return Result{}, false
+	}
+
+	major := binary.LittleEndian.Uint16(sb[28:])
+	minor := binary.LittleEndian.Uint16(sb[30:])
+	fsType := "squashfs"
+	if major < 4 {
+		fsType = "squashfs3"
+	}
+
+	return Result{
+		Type:    fsType,
+		Version: fmt.Sprintf("%d.%d", major, minor),
+	}, true
+}
+
+// swapPageSizes lists the page sizes the swap signature is searched at.
+// The signature sits in the last 10 bytes of the first page.
+var swapPageSizes = []int64{4096, 8192, 16384, 32768, 65536}
+
+// probeSwap detects Linux swap areas (version 0 and 1 signatures).
+func probeSwap(r io.ReaderAt) (Result, bool) {
+	for _, pageSize := range swapPageSizes {
+		magic, ok := readAt(r, pageSize-10, 10)
+		if !ok {
+			return Result{}, false
+		}
+
+		switch string(magic) {
+		case "SWAP-SPACE":
+			return Result{Type: "swap", Version: "0"}, true
+		case "SWAPSPACE2":
+			header, ok := readAt(r, 1024, 44)
+			if !ok {
+				return Result{}, false
+			}
+			return Result{
+				Type:    "swap",
+				UUID:    formatUUID(header[12:28]),
+				Label:   CString(header[28:44]),
+				Version: fmt.Sprintf("%d", binary.LittleEndian.Uint32(header[0:])),
+			}, true
+		}
+	}
+	return Result{}, false
+}
+
+// trimLabel removes the space padding used by FAT-style label fields.
+func trimLabel(b []byte) string {
+	return strings.TrimRight(string(bytes.TrimRight(b, "\x00")), " ")
+}
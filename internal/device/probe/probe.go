@@ -0,0 +1,144 @@
+// Package probe detects filesystem and volume signatures by reading superblocks
+// directly from a device node or image file, without libblkid.
+// All reads are read-only and limited to the first few kilobytes of each superblock.
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoSignature is returned when none of the supported signatures is found.
+var ErrNoSignature = errors.New("no known filesystem signature")
+
+// Result describes the signature found on a device.
+type Result struct {
+	// Type is the signature type using blkid names (e.g. "ext4", "crypto_LUKS", "LVM2_member").
+	Type string `json:"type"`
+	// UUID is the filesystem or volume UUID, formatted like blkid. Empty if the format has none.
+	UUID string `json:"uuid"`
+	// Label is the filesystem or volume label. Empty if not set.
+	Label string `json:"label"`
+	// Version is the on-disk format version (e.g. "1.0", "FAT32", "LVM2 001"). Empty if unknown.
+	Version string `json:"version"`
+}
+
+// prober checks a single signature. It returns ok=false when the signature does not match.
+type prober struct {
+	name  string
+	probe func(r io.ReaderAt) (Result, bool)
+}
+
+// probers lists the supported signatures in detection order.
+// Formats with strong magic values come first; vfat has the weakest check and comes last.
+var probers = []prober{
+	{name: "luks", probe: probeLUKS},
+	{name: "lvm2", probe: probeLVM2},
+	{name: "xfs", probe: probeXFS},
+	{name: "ext", probe: probeExt},
+	{name: "btrfs", probe: probeBtrfs},
+	{name: "squashfs", probe: probeSquashfs},
+	{name: "swap", probe: probeSwap},
+	{name: "ntfs", probe: probeNTFS},
+	{name: "exfat", probe: probeExFAT},
+	{name: "vfat", probe: probeVFAT},
+}
+
+// SignatureTypes lists the types Probe can return, using blkid names.
+var SignatureTypes = []string{
+	"crypto_LUKS", "LVM2_member", "xfs", "jbd", "ext2", "ext3", "ext4", "btrfs",
+	"squashfs", "squashfs3", "swap", "ntfs", "exfat", "vfat",
+}
+
+// Probe reads r and returns the first signature found.
+// It returns ErrNoSignature if the content does not match any supported format.
+func Probe(r io.ReaderAt) (Result, error) {
+	for _, p := range probers {
+		if result, ok := p.probe(r); ok {
+			log.Debug().Str("prober", p.name).Str("type", result.Type).Msg("signature detected")
+			return result, nil
+		}
+	}
+	return Result{}, ErrNoSignature
+}
+
+// ProbeFile opens a device node or image file read-only and probes it.
+func ProbeFile(path string) (Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	result, err := Probe(file)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to probe %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// readAt reads exactly n bytes at offset. It returns ok=false on short reads,
+// which happen when probing offsets beyond the end of a small device or image.
+func readAt(r io.ReaderAt, offset int64, n int) ([]byte, bool) {
+	buf := make([]byte, n)
+	read, err := r.ReadAt(buf, offset)
+	if read < n {
+		if err != nil && !errors.Is(err, io.EOF) {
+			log.Debug().Err(err).Int64("offset", offset).Msg("probe read failed")
+		}
+		return nil, false
+	}
+	return buf, true
+}
+
+// formatUUID formats 16 raw bytes as a canonical 8-4-4-4-12 UUID string.
+// An all-zero UUID is returned as empty string.
+func formatUUID(b []byte) string {
+	if isZero(b) {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// formatSerial32 formats a 32-bit volume serial number as blkid does for FAT and exFAT.
+func formatSerial32(serial uint32) string {
+	return fmt.Sprintf("%04X-%04X", serial>>16, serial&0xFFFF)
+}
+
+// CString returns the content of a NUL-padded fixed-size string field of an on-disk
+// structure, like the label of a superblock.
+func CString(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// utf16String decodes a little-endian UTF-16 string, stopping at the first NUL.
+func utf16String(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := uint16(b[i]) | uint16(b[i+1])<<8
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+// isZero reports whether every byte of b is zero.
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,266 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// testUUID is the raw UUID written into crafted superblocks.
+var testUUID = []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+
+const testUUIDString = "12345678-9abc-def0-0123-456789abcdef"
+
+// image is a sparse in-memory disk image written to a temporary file.
+type image []byte
+
+func (img image) put(offset int, data []byte) {
+	copy(img[offset:], data)
+}
+
+func (img image) putLE16(offset int, v uint16) { binary.LittleEndian.PutUint16(img[offset:], v) }
+func (img image) putLE32(offset int, v uint32) { binary.LittleEndian.PutUint32(img[offset:], v) }
+func (img image) putLE64(offset int, v uint64) { binary.LittleEndian.PutUint64(img[offset:], v) }
+
+// writeImage stores the image in a temporary file and returns its path.
+func writeImage(t *testing.T, img image) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "disk.img")
+	if err := os.WriteFile(path, img, 0o600); err != nil {
+		t.Fatalf("write image: %v", err)
+	}
+	return path
+}
+
+func utf16LE(s string) []byte {
+	out := make([]byte, 0, len(s)*2)
+	for _, r := range s {
+		out = append(out, byte(r), 0)
+	}
+	return out
+}
+
+func extImage(compat, incompat, roCompat uint32) image {
+	img := make(image, 4096)
+	sb := 1024
+	img.putLE16(sb+0x38, 0xEF53)
+	img.putLE32(sb+0x4C, 1)
+	img.putLE32(sb+0x5C, compat)
+	img.putLE32(sb+0x60, incompat)
+	img.putLE32(sb+0x64, roCompat)
+	img.put(sb+0x68, testUUID)
+	img.put(sb+0x78, []byte("rootfs"))
+	return img
+}
+
+func xfsImage() image {
+	img := make(image, 4096)
+	img.put(0, []byte("XFSB"))
+	binary.BigEndian.PutUint32(img[4:], 4096)
+	img.put(32, testUUID)
+	binary.BigEndian.PutUint16(img[100:], 0xB4A5)
+	img.put(108, []byte("data"))
+	return img
+}
+
+func btrfsImage() image {
+	img := make(image, 0x11000)
+	img.put(0x10000+0x20, testUUID)
+	img.put(0x10000+0x40, []byte("_BHRfS_M"))
+	img.put(0x10000+0x12B, []byte("pool"))
+	return img
+}
+
+func vfatImage() image {
+	img := make(image, 4096)
+	img.putLE16(11, 512)
+	img[13] = 8
+	img.putLE32(67, 0xABCD1234)
+	img.put(71, []byte("EFI        "))
+	img.put(82, []byte("FAT32   "))
+	img.putLE16(510, 0xAA55)
+	return img
+}
+
+func fat16Image() image {
+	img := make(image, 4096)
+	img.putLE16(11, 512)
+	img[13] = 4
+	img.putLE32(39, 0x00FF00FF)
+	img.put(43, []byte("NO NAME    "))
+	img.put(54, []byte("FAT16   "))
+	img.putLE16(510, 0xAA55)
+	return img
+}
+
+func exfatImage() image {
+	img := make(image, 16*1024)
+	img.put(3, []byte("EXFAT   "))
+	img.putLE32(88, 8) // cluster heap at sector 8
+	img.putLE32(96, 4) // root directory in cluster 4
+	img.putLE32(100, 0x1A2B3C4D)
+	img.putLE16(104, 0x0100)
+	img[108] = 9 // 512-byte sectors
+	img[109] = 1 // 2 sectors per cluster
+	img.putLE16(510, 0xAA55)
+
+	rootDir := 8*512 + (4-2)*1024
+	img[rootDir] = 0x81 // allocation bitmap entry, skipped
+	label := rootDir + 32
+	img[label] = 0x83
+	img[label+1] = 5
+	img.put(label+2, utf16LE("USBKY"))
+	return img
+}
+
+func ntfsImage() image {
+	const recordSize = 1024
+	img := make(image, 64*1024)
+	img.put(3, []byte("NTFS    "))
+	img.putLE16(0x0B, 512)
+	img[0x0D] = 8        // 4 KiB clusters
+	img.putLE64(0x30, 4) // $MFT at cluster 4
+	img[0x40] = 0xF6     // -10: 1 KiB records
+	img.putLE64(0x48, 0x0123456789ABCDEF)
+	img.putLE16(510, 0xAA55)
+
+	rec := 4*4096 + 3*recordSize
+	img.put(rec, []byte("FILE"))
+	img.putLE16(rec+4, 0x30) // update sequence array offset
+	img.putLE16(rec+6, 3)    // update sequence number + 2 sector entries
+	img.putLE16(rec+0x30, 0x0001)
+	img.putLE16(rec+0x32, 0x0000)
+	img.putLE16(rec+0x34, 0x0000)
+	img.putLE16(rec+510, 0x0001)
+	img.putLE16(rec+1022, 0x0001)
+	img.putLE16(rec+0x14, 0x38) // first attribute
+
+	attr := rec + 0x38
+	name := utf16LE("Windows")
+	img.putLE32(attr, 0x60)
+	img.putLE32(attr+4, 0x18+uint32(len(name))+2)
+	img[attr+8] = 0 // resident
+	img.putLE32(attr+0x10, uint32(len(name)))
+	img.putLE16(attr+0x14, 0x18)
+	img.put(attr+0x18, name)
+	end := attr + 0x18 + len(name) + 2
+	img.putLE32(end, 0xFFFFFFFF)
+	return img
+}
+
+func swapImage() image {
+	img := make(image, 8192)
+	img.putLE32(1024, 1)
+	img.put(1036, testUUID)
+	img.put(1052, []byte("swap0"))
+	img.put(4096-10, []byte("SWAPSPACE2"))
+	return img
+}
+
+func luksImage(version uint16) image {
+	img := make(image, 4096)
+	img.put(0, []byte(luksMagic))
+	binary.BigEndian.PutUint16(img[6:], version)
+	img.put(24, []byte("secret"))
+	img.put(168, []byte(testUUIDString))
+	return img
+}
+
+func lvmImage() image {
+	img := make(image, 4096)
+	label := 512
+	img.put(label, []byte("LABELONE"))
+	img.putLE64(label+8, 1)
+	img.putLE32(label+20, 32)
+	img.put(label+24, []byte("LVM2 001"))
+	img.put(label+32, []byte("r2Lm3LQDBlBzDeemQ1DpGHBoEN7Bk1CV"))
+	return img
+}
+
+func squashfsImage() image {
+	img := make(image, 4096)
+	img.put(0, []byte("hsqs"))
+	img.putLE16(28, 4)
+	img.putLE16(30, 0)
+	return img
+}
+
+func TestProbeFile(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image
+		want Result
+	}{
+		{name: "ext2", img: extImage(0, 0x2, 0x1), want: Result{Type: "ext2", UUID: testUUIDString, Label: "rootfs", Version: "1.0"}},
+		{name: "ext3", img: extImage(0x4, 0x2, 0x1), want: Result{Type: "ext3", UUID: testUUIDString, Label: "rootfs", Version: "1.0"}},
+		{name: "ext4", img: extImage(0x4, 0x2|0x40, 0x1), want: Result{Type: "ext4", UUID: testUUIDString, Label: "rootfs", Version: "1.0"}},
+		{name: "xfs", img: xfsImage(), want: Result{Type: "xfs", UUID: testUUIDString, Label: "data", Version: "5"}},
+		{name: "btrfs", img: btrfsImage(), want: Result{Type: "btrfs", UUID: testUUIDString, Label: "pool"}},
+		{name: "vfat32", img: vfatImage(), want: Result{Type: "vfat", UUID: "ABCD-1234", Label: "EFI", Version: "FAT32"}},
+		{name: "vfat16", img: fat16Image(), want: Result{Type: "vfat", UUID: "00FF-00FF", Version: "FAT16"}},
+		{name: "exfat", img: exfatImage(), want: Result{Type: "exfat", UUID: "1A2B-3C4D", Label: "USBKY", Version: "1.0"}},
+		{name: "ntfs", img: ntfsImage(), want: Result{Type: "ntfs", UUID: "0123456789ABCDEF", Label: "Windows"}},
+		{name: "swap", img: swapImage(), want: Result{Type: "swap", UUID: testUUIDString, Label: "swap0", Version: "1"}},
+		{name: "luks1", img: luksImage(1), want: Result{Type: "crypto_LUKS", UUID: testUUIDString, Version: "1"}},
+		{name: "luks2", img: luksImage(2), want: Result{Type: "crypto_LUKS", UUID: testUUIDString, Label: "secret", Version: "2"}},
+		{name: "lvm2", img: lvmImage(), want: Result{Type: "LVM2_member", UUID: "r2Lm3L-QDBl-BzDe-emQ1-DpGH-BoEN-7Bk1CV", Version: "LVM2 001"}},
+		{name: "squashfs", img: squashfsImage(), want: Result{Type: "squashfs", Version: "4.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProbeFile(writeImage(t, tt.img))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected result:\ngot:  %+v\nwant: %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeFile_NoSignature(t *testing.T) {
+	_, err := ProbeFile(writeImage(t, make(image, 4096)))
+	if !errors.Is(err, ErrNoSignature) {
+		t.Fatalf("expected ErrNoSignature, got %v", err)
+	}
+}
+
+func TestEnricher_FillsOnlyEmptyFields(t *testing.T) {
+	path := writeImage(t, extImage(0x4, 0x2|0x40, 0x1))
+	devices := []device.BlockDevice{
+		{Path: path, Type: "part", DeviceSizeBytes: 4096},
+		{Path: path, Type: "part", DeviceSizeBytes: 4096, FSType: "ext4", Label: "from-lsblk"},
+		{Path: path, Type: "rom", DeviceSizeBytes: 4096},
+	}
+
+	enricher := NewEnricher()
+	for i := range devices {
+		if err := enricher.Enrich(context.Background(), &devices[i]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if devices[0].FSType != "ext4" || devices[0].UUID != testUUIDString || devices[0].Label != "rootfs" {
+		t.Errorf("device not enriched: %+v", devices[0])
+	}
+	if devices[1].Label != "from-lsblk" || devices[1].UUID != testUUIDString {
+		t.Errorf("provider values must be kept: %+v", devices[1])
+	}
+	if devices[2].FSType != "" {
+		t.Errorf("rom device must not be probed: %+v", devices[2])
+	}
+}
+
+func TestEnricher_SkipsMissingDeviceNode(t *testing.T) {
+	dev := device.BlockDevice{Path: filepath.Join(t.TempDir(), "sda"), Type: "disk", DeviceSizeBytes: 4096}
+	if err := NewEnricher().Enrich(context.Background(), &dev); err != nil {
+		t.Fatalf("a missing device node must be skipped, got %v", err)
+	}
+}
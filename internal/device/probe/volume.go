@@ -0,0 +1,74 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// luksMagic is the signature at the start of a LUKS1 or LUKS2 primary header.
+const luksMagic = "LUKS\xba\xbe"
+
+// probeLUKS detects LUKS1 and LUKS2 encrypted volumes.
+func probeLUKS(r io.ReaderAt) (Result, bool) {
+	header, ok := readAt(r, 0, 208)
+	if !ok || string(header[0:6]) != luksMagic {
+		return Result{}, false
+	}
+
+	version := binary.BigEndian.Uint16(header[6:])
+	result := Result{
+		Type:    "crypto_LUKS",
+		UUID:    CString(header[168:208]),
+		Version: fmt.Sprintf("%d", version),
+	}
+	if version == 2 {
+		// LUKS2 stores an optional label in the binary header.
+		result.Label = CString(header[24:72])
+	}
+	return result, true
+}
+
+// LVM2 physical volume label layout.
+const (
+	lvmLabelID      = "LABELONE"
+	lvmLabelType    = "LVM2 001"
+	lvmLabelSectors = 4
+	lvmSectorSize   = 512
+	lvmUUIDLen      = 32
+)
+
+// probeLVM2 detects LVM2 physical volumes. The label can be in any of the first four sectors.
+func probeLVM2(r io.ReaderAt) (Result, bool) {
+	for sector := int64(0); sector < lvmLabelSectors; sector++ {
+		label, ok := readAt(r, sector*lvmSectorSize, lvmSectorSize)
+		if !ok {
+			return Result{}, false
+		}
+		if string(label[0:8]) != lvmLabelID || string(label[24:32]) != lvmLabelType {
+			continue
+		}
+
+		offset := binary.LittleEndian.Uint32(label[20:])
+		if int(offset)+lvmUUIDLen > len(label) {
+			return Result{}, false
+		}
+
+		return Result{
+			Type:    "LVM2_member",
+			UUID:    FormatLVMUUID(string(label[offset : offset+lvmUUIDLen])),
+			Version: lvmLabelType,
+		}, true
+	}
+	return Result{}, false
+}
+
+// FormatLVMUUID formats a raw 32 character LVM UUID with dashes, as shown by LVM tools
+// (6-4-4-4-4-4-6 groups). Strings of any other length are returned unchanged.
+func FormatLVMUUID(raw string) string {
+	if len(raw) != lvmUUIDLen {
+		return raw
+	}
+	return fmt.Sprintf("%s-%s-%s-%s-%s-%s-%s",
+		raw[0:6], raw[6:10], raw[10:14], raw[14:18], raw[18:22], raw[22:26], raw[26:32])
+}
@@ -0,0 +1,204 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// bootSectorSignature is the 0x55AA marker at the end of FAT, exFAT and NTFS boot sectors.
+const bootSectorSignature = 0xAA55
+
+// probeVFAT detects FAT12, FAT16 and FAT32 filesystems from the boot sector.
+func probeVFAT(r io.ReaderAt) (Result, bool) {
+	bs, ok := readAt(r, 0, 512)
+	if !ok || binary.LittleEndian.Uint16(bs[510:]) != bootSectorSignature {
+		return Result{}, false
+	}
+
+	bytesPerSector := binary.LittleEndian.Uint16(bs[11:])
+	sectorsPerCluster := bs[13]
+	if !isPowerOfTwo(uint64(bytesPerSector)) || bytesPerSector < 512 || bytesPerSector > 4096 ||
+		!isPowerOfTwo(uint64(sectorsPerCluster)) {
+		return Result{}, false
+	}
+
+	var serial uint32
+	var label []byte
+	var version string
+	switch {
+	case string(bs[82:87]) == "FAT32":
+		serial = binary.LittleEndian.Uint32(bs[67:])
+		label = bs[71:82]
+		version = "FAT32"
+	case string(bs[54:57]) == "FAT":
+		serial = binary.LittleEndian.Uint32(bs[39:])
+		label = bs[43:54]
+		version = trimLabel(bs[54:62])
+	default:
+		return Result{}, false
+	}
+
+	result := Result{
+		Type:    "vfat",
+		UUID:    formatSerial32(serial),
+		Label:   trimLabel(label),
+		Version: version,
+	}
+	if result.Label == "NO NAME" {
+		result.Label = ""
+	}
+	return result, true
+}
+
+// exFAT directory entry types used to find the volume label.
+const (
+	exfatEntrySize        = 32
+	exfatEntryEndOfDir    = 0x00
+	exfatEntryVolumeLabel = 0x83
+	exfatMaxLabelChars    = 11
+)
+
+// probeExFAT detects exFAT and reads its label from the root directory.
+func probeExFAT(r io.ReaderAt) (Result, bool) {
+	bs, ok := readAt(r, 0, 512)
+	if !ok || string(bs[3:11]) != "EXFAT   " {
+		return Result{}, false
+	}
+
+	result := Result{
+		Type:    "exfat",
+		UUID:    formatSerial32(binary.LittleEndian.Uint32(bs[100:])),
+		Version: fmt.Sprintf("%d.%d", bs[105], bs[104]),
+	}
+
+	sectorShift := bs[108]
+	clusterShift := bs[109]
+	if sectorShift < 9 || sectorShift > 12 || clusterShift > 25-sectorShift {
+		return result, true
+	}
+	sectorSize := int64(1) << sectorShift
+	clusterSize := sectorSize << clusterShift
+	heapOffset := int64(binary.LittleEndian.Uint32(bs[88:])) * sectorSize
+	rootCluster := int64(binary.LittleEndian.Uint32(bs[96:]))
+	if rootCluster < 2 {
+		return result, true
+	}
+
+	// Only the first cluster of the root directory is scanned; the label entry
+	// is created by mkfs at the start of the directory.
+	rootDir, ok := readAt(r, heapOffset+(rootCluster-2)*clusterSize, int(clusterSize))
+	if !ok {
+		return result, true
+	}
+	for off := 0; off+exfatEntrySize <= len(rootDir); off += exfatEntrySize {
+		entry := rootDir[off : off+exfatEntrySize]
+		if entry[0] == exfatEntryEndOfDir {
+			break
+		}
+		if entry[0] == exfatEntryVolumeLabel {
+			chars := int(entry[1])
+			if chars > exfatMaxLabelChars {
+				chars = exfatMaxLabelChars
+			}
+			result.Label = utf16String(entry[2 : 2+chars*2])
+			break
+		}
+	}
+	return result, true
+}
+
+// NTFS on-disk constants used to locate the $Volume MFT record.
+const (
+	ntfsVolumeRecord     = 3
+	ntfsAttrVolumeName   = 0x60
+	ntfsAttrEnd          = 0xFFFFFFFF
+	ntfsMaxMFTRecordSize = 64 * 1024
+)
+
+// probeNTFS detects NTFS and reads its label from the $Volume MFT record.
+func probeNTFS(r io.ReaderAt) (Result, bool) {
+	bs, ok := readAt(r, 0, 512)
+	if !ok || string(bs[3:11]) != "NTFS    " {
+		return Result{}, false
+	}
+
+	result := Result{
+		Type: "ntfs",
+		UUID: fmt.Sprintf("%016X", binary.LittleEndian.Uint64(bs[0x48:])),
+	}
+
+	bytesPerSector := int64(binary.LittleEndian.Uint16(bs[0x0B:]))
+	clusterSize := bytesPerSector * int64(bs[0x0D])
+	if clusterSize == 0 {
+		return result, true
+	}
+
+	// A negative clusters-per-record value n means a record size of 2^-n bytes.
+	var recordSize int64
+	if perRecord := int8(bs[0x40]); perRecord < 0 {
+		recordSize = int64(1) << uint(-perRecord)
+	} else {
+		recordSize = int64(perRecord) * clusterSize
+	}
+	if recordSize <= 0 || recordSize > ntfsMaxMFTRecordSize {
+		return result, true
+	}
+
+	mftOffset := int64(binary.LittleEndian.Uint64(bs[0x30:])) * clusterSize
+	record, ok := readAt(r, mftOffset+ntfsVolumeRecord*recordSize, int(recordSize))
+	if !ok || string(record[0:4]) != "FILE" || !applyNTFSFixups(record, bytesPerSector) {
+		return result, true
+	}
+
+	result.Label = ntfsVolumeName(record)
+	return result, true
+}
+
+// applyNTFSFixups restores the last two bytes of each sector of an MFT record
+// from the update sequence array. It returns false if the record is inconsistent.
+func applyNTFSFixups(record []byte, sectorSize int64) bool {
+	usaOffset := int(binary.LittleEndian.Uint16(record[4:]))
+	usaCount := int(binary.LittleEndian.Uint16(record[6:]))
+	if usaCount == 0 || usaOffset+usaCount*2 > len(record) {
+		return false
+	}
+
+	for i := 1; i < usaCount; i++ {
+		end := i * int(sectorSize)
+		if end > len(record) {
+			return false
+		}
+		copy(record[end-2:end], record[usaOffset+i*2:usaOffset+i*2+2])
+	}
+	return true
+}
+
+// ntfsVolumeName walks the attributes of the $Volume record and returns the
+// resident $VOLUME_NAME value.
+func ntfsVolumeName(record []byte) string {
+	off := int(binary.LittleEndian.Uint16(record[0x14:]))
+	for off+16 <= len(record) {
+		attrType := binary.LittleEndian.Uint32(record[off:])
+		attrLen := int(binary.LittleEndian.Uint32(record[off+4:]))
+		if attrType == ntfsAttrEnd || attrLen <= 0 || off+attrLen > len(record) {
+			break
+		}
+
+		nonResident := record[off+8] != 0
+		if attrType == ntfsAttrVolumeName && !nonResident && off+0x16 <= len(record) {
+			valueLen := int(binary.LittleEndian.Uint32(record[off+0x10:]))
+			valueOff := int(binary.LittleEndian.Uint16(record[off+0x14:]))
+			if off+valueOff+valueLen <= off+attrLen {
+				return utf16String(record[off+valueOff : off+valueOff+valueLen])
+			}
+		}
+		off += attrLen
+	}
+	return ""
+}
+
+// isPowerOfTwo reports whether n is a non-zero power of two.
+func isPowerOfTwo(n uint64) bool {
+	return n != 0 && n&(n-1) == 0
+}
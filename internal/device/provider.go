@@ -0,0 +1,39 @@
+package device
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Supported BlockDeviceProvider kinds for NewBlockDeviceProvider.
+const (
+	// ProviderAuto uses lsblk when the binary is available and sysfs otherwise.
+	ProviderAuto = "auto"
+	// ProviderLsblk always uses the lsblk binary.
+	ProviderLsblk = "lsblk"
+	// ProviderSysfs always reads sysfs and procfs directly.
+	ProviderSysfs = "sysfs"
+)
+
+// NewBlockDeviceProvider returns the BlockDeviceProvider for the given kind.
+// An empty kind is treated as ProviderAuto.
+func NewBlockDeviceProvider(kind string) (BlockDeviceProvider, error) {
+	switch kind {
+	case "", ProviderAuto:
+		if _, err := exec.LookPath("lsblk"); err != nil {
+			log.Debug().Err(err).Msg("lsblk not found, falling back to sysfs provider")
+			return NewSysfsProvider(), nil
+		}
+		log.Debug().Msg("lsblk found, using lsblk provider")
+		return NewLsblkProvider(), nil
+	case ProviderLsblk:
+		return NewLsblkProvider(), nil
+	case ProviderSysfs:
+		return NewSysfsProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown block device provider %q, supported: %s, %s, %s",
+			kind, ProviderAuto, ProviderLsblk, ProviderSysfs)
+	}
+}
@@ -0,0 +1,49 @@
+package device
+
+import (
+	"context"
+	"sync"
+)
+
+// scanCacheKey is the context key of the ScanCache of a scan.
+type scanCacheKey struct{}
+
+// ScanCache holds the values enrichers compute once per scan and share between the
+// devices of the scan, like the md arrays read from /proc/mdstat.
+type ScanCache struct {
+	mu     sync.Mutex
+	values map[any]scanValue
+}
+
+// scanValue is a cached result.
+type scanValue struct {
+	value any
+	err   error
+}
+
+// WithScanCache returns a copy of ctx carrying a new, empty ScanCache. Scanners call it
+// at the start of every scan so that no value outlives the scan.
+func WithScanCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scanCacheKey{}, &ScanCache{values: make(map[any]scanValue)})
+}
+
+// ScanCached returns the value of key in the ScanCache of ctx, computing it with load on
+// the first call of the scan. Concurrent calls wait for the first one. A result computed
+// while ctx is done is not kept and is computed again by the next call. Without a
+// ScanCache in ctx, load is called every time.
+func ScanCached[T any](ctx context.Context, key any, load func(context.Context) (T, error)) (T, error) {
+	cache, ok := ctx.Value(scanCacheKey{}).(*ScanCache)
+	if !ok {
+		return load(ctx)
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cached, ok := cache.values[key]; ok {
+		return cached.value.(T), cached.err
+	}
+	value, err := load(ctx)
+	if ctx.Err() == nil {
+		cache.values[key] = scanValue{value: value, err: err}
+	}
+	return value, err
+}
@@ -0,0 +1,42 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestScanCached(t *testing.T) {
+	calls := 0
+	load := func(ctx context.Context) (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	scan := WithScanCache(context.Background())
+	for range 2 {
+		if v, err := ScanCached(scan, "key", load); v != 1 || err != nil {
+			t.Fatalf("ScanCached = %d, %v, want the first value", v, err)
+		}
+	}
+	if v, _ := ScanCached(scan, "other", load); v != 2 {
+		t.Errorf("another key must be loaded on its own, got %d", v)
+	}
+	if v, _ := ScanCached(WithScanCache(context.Background()), "key", load); v != 3 {
+		t.Errorf("a new scan must load again, got %d", v)
+	}
+	if v, _ := ScanCached(context.Background(), "key", load); v != 4 {
+		t.Errorf("without a ScanCache every call must load, got %d", v)
+	}
+
+	// A load cut short by the end of the scan is not kept.
+	canceled, cancel := context.WithCancel(WithScanCache(context.Background()))
+	cancel()
+	failing := func(ctx context.Context) (int, error) { return 0, ctx.Err() }
+	if _, err := ScanCached(canceled, "key", failing); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if v, _ := ScanCached(canceled, "key", load); v != 5 {
+		t.Errorf("a canceled load must not be cached, got %d", v)
+	}
+}
@@ -0,0 +1,83 @@
+package statfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Enricher fills the filesystem usage of mounted devices with statfs. It also fills the
+// filesystem size and available space left empty by the provider, as older lsblk
+// versions lack FSSIZE and FSAVAIL and lsblk does not know mounts only found in mountinfo.
+type Enricher struct {
+	// Timeout bounds each statfs call.
+	Timeout time.Duration
+	// stat calls statfs on a path, replaceable in tests.
+	stat func(path string) (Stat, error)
+}
+
+// NewEnricher creates a new Enricher whose statfs calls time out after timeout.
+// A timeout of zero selects DefaultTimeout.
+func NewEnricher(timeout time.Duration) *Enricher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Enricher{Timeout: timeout, stat: statfs}
+}
+
+// Name returns "statfs".
+func (e *Enricher) Name() string {
+	return "statfs"
+}
+
+// Fields returns the filesystem usage fields.
+func (e *Enricher) Fields() []string {
+	return []string{"fsUsage", "fileSystemSize", "fileSystemSizeBytes", "fileSystemAvail", "fileSystemAvailBytes"}
+}
+
+// Enrich calls statfs on the mount points of the device, in order, until one answers.
+// Unmounted devices are skipped, and so are mount points that cannot be accessed
+// (e.g. hidden by another mount in a container). Other errors, timeouts included, stop
+// at the first mount point.
+func (e *Enricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	mountPoints := dev.MountPoints()
+	if len(mountPoints) == 0 && dev.MountPoint != "" {
+		mountPoints = []string{dev.MountPoint}
+	}
+
+	for _, mountPoint := range mountPoints {
+		u, err := usage(ctx, e.stat, mountPoint, e.Timeout)
+		if err != nil {
+			if errors.Is(err, errors.ErrUnsupported) || errors.Is(err, fs.ErrPermission) || errors.Is(err, fs.ErrNotExist) {
+				log.Debug().Str("device", dev.Path).Str("mountpoint", mountPoint).Err(err).Msg("statfs skipped")
+				continue
+			}
+			// The other mount points share the filesystem, a timeout would repeat.
+			return fmt.Errorf("failed to read filesystem usage: %w", err)
+		}
+
+		dev.FSUsage = u
+		if dev.FileSystemSize == "" {
+			dev.FileSystemSizeBytes = u.TotalBytes
+			dev.FileSystemSize = humanize.IBytes(u.TotalBytes)
+		}
+		if dev.FileSystemAvail == "" {
+			dev.FileSystemAvailBytes = u.AvailBytes
+			dev.FileSystemAvail = humanize.IBytes(u.AvailBytes)
+		}
+		log.Debug().
+			Str("device", dev.Path).
+			Str("mountpoint", mountPoint).
+			Float64("usedPercent", u.UsedPercent).
+			Msg("enriched device with statfs usage")
+		return nil
+	}
+	return nil
+}
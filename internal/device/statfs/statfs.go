@@ -0,0 +1,101 @@
+// Package statfs reads the space and inode usage of mounted filesystems with statfs(2).
+// Each call runs with a timeout, because statfs on a stale network mount can block forever.
+package statfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// DefaultTimeout is the time a single statfs call may take by default.
+const DefaultTimeout = 2 * time.Second
+
+// ErrTimeout is returned when statfs did not answer within the timeout.
+var ErrTimeout = errors.New("statfs timed out")
+
+// Stat holds the raw statfs counters of a filesystem.
+type Stat struct {
+	// BlockSize is the fragment size the block counts are expressed in (f_frsize).
+	BlockSize uint64
+	// Blocks is the total number of blocks.
+	Blocks uint64
+	// Free is the number of free blocks, reserved blocks included.
+	Free uint64
+	// Avail is the number of free blocks available to unprivileged users.
+	Avail uint64
+	// Files is the total number of inodes.
+	Files uint64
+	// FilesFree is the number of free inodes.
+	FilesFree uint64
+}
+
+// Usage calls statfs on path and converts the result. It returns ErrTimeout when the call
+// takes longer than timeout; the blocked call is left behind, as system calls cannot be
+// interrupted. A timeout of zero or less never expires.
+func Usage(ctx context.Context, path string, timeout time.Duration) (*device.FSUsage, error) {
+	return usage(ctx, statfs, path, timeout)
+}
+
+// usage is Usage with a replaceable statfs function.
+func usage(ctx context.Context, stat func(path string) (Stat, error), path string, timeout time.Duration) (*device.FSUsage, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type answer struct {
+		stat Stat
+		err  error
+	}
+	// Buffered, so that a call answering after the timeout does not block its goroutine.
+	answers := make(chan answer, 1)
+	go func() {
+		s, err := stat(path)
+		answers <- answer{s, err}
+	}()
+
+	select {
+	case a := <-answers:
+		if a.err != nil {
+			return nil, fmt.Errorf("statfs %s: %w", path, a.err)
+		}
+		return NewFSUsage(path, a.stat), nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && timeout > 0 {
+			return nil, fmt.Errorf("%w: %s after %s", ErrTimeout, path, timeout)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// NewFSUsage converts the statfs counters of the filesystem mounted at mountPoint.
+func NewFSUsage(mountPoint string, s Stat) *device.FSUsage {
+	used := (s.Blocks - min(s.Free, s.Blocks)) * s.BlockSize
+	avail := s.Avail * s.BlockSize
+	reserved := s.Free - min(s.Avail, s.Free)
+	u := &device.FSUsage{
+		MountPoint:     mountPoint,
+		BlockSize:      s.BlockSize,
+		TotalBytes:     s.Blocks * s.BlockSize,
+		UsedBytes:      used,
+		AvailBytes:     avail,
+		ReservedBlocks: reserved,
+		ReservedBytes:  reserved * s.BlockSize,
+		Inodes:         s.Files,
+		InodesUsed:     s.Files - min(s.FilesFree, s.Files),
+		InodesFree:     s.FilesFree,
+	}
+	if used+avail > 0 {
+		u.UsedPercent = math.Ceil(float64(used) * 100 / float64(used+avail))
+	}
+	if s.Files > 0 {
+		u.InodesUsedPercent = math.Ceil(float64(u.InodesUsed) * 100 / float64(s.Files))
+	}
+	return u
+}
@@ -0,0 +1,23 @@
+package statfs
+
+import "syscall"
+
+// statfs calls statfs(2) on path.
+func statfs(path string) (Stat, error) {
+	var buf syscall.Statfs_t
+	if err := syscall.Statfs(path, &buf); err != nil {
+		return Stat{}, err
+	}
+	blockSize := uint64(buf.Frsize)
+	if blockSize == 0 {
+		blockSize = uint64(buf.Bsize)
+	}
+	return Stat{
+		BlockSize: blockSize,
+		Blocks:    buf.Blocks,
+		Free:      buf.Bfree,
+		Avail:     buf.Bavail,
+		Files:     buf.Files,
+		FilesFree: buf.Ffree,
+	}, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package statfs
+
+import "errors"
+
+// statfs is only implemented on Linux, like the mount and sysfs providers it complements.
+func statfs(path string) (Stat, error) {
+	return Stat{}, errors.ErrUnsupported
+}
@@ -0,0 +1,146 @@
+package statfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// testStat is a 100 MiB filesystem with 4 KiB blocks, 30 MiB used and 5 MiB reserved for root.
+var testStat = Stat{
+	BlockSize: 4096,
+	Blocks:    25600,
+	Free:      17920,
+	Avail:     16640,
+	Files:     1000,
+	FilesFree: 750,
+}
+
+func TestNewFSUsage(t *testing.T) {
+	got := NewFSUsage("/data", testStat)
+	want := &device.FSUsage{
+		MountPoint:        "/data",
+		BlockSize:         4096,
+		TotalBytes:        100 << 20,
+		UsedBytes:         30 << 20,
+		AvailBytes:        65 << 20,
+		ReservedBlocks:    1280,
+		ReservedBytes:     5 << 20,
+		UsedPercent:       32, // 30 / (30 + 65), rounded up like df
+		Inodes:            1000,
+		InodesUsed:        250,
+		InodesFree:        750,
+		InodesUsedPercent: 25,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	empty := NewFSUsage("/proc", Stat{BlockSize: 4096})
+	if empty.UsedPercent != 0 || empty.InodesUsedPercent != 0 {
+		t.Errorf("an empty filesystem must report 0%%, got %+v", empty)
+	}
+}
+
+func TestUsage_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	hanging := func(path string) (Stat, error) {
+		<-release
+		return testStat, nil
+	}
+
+	start := time.Now()
+	_, err := usage(context.Background(), hanging, "/mnt/nfs", 20*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("the call was not abandoned after the timeout: %s", elapsed)
+	}
+}
+
+func TestUsage_Canceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	hanging := func(path string) (Stat, error) {
+		<-release
+		return testStat, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := usage(ctx, hanging, "/mnt/nfs", time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestEnricher(t *testing.T) {
+	var called []string
+	e := &Enricher{Timeout: time.Second, stat: func(path string) (Stat, error) {
+		called = append(called, path)
+		if path == "/hidden" {
+			return Stat{}, fs.ErrPermission
+		}
+		return testStat, nil
+	}}
+
+	dev := &device.BlockDevice{
+		Path:   "/dev/sda1",
+		Mounts: []device.Mount{{MountPoint: "/hidden"}, {MountPoint: "/data"}},
+	}
+	if err := e.Enrich(context.Background(), dev); err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if !reflect.DeepEqual(called, []string{"/hidden", "/data"}) {
+		t.Errorf("unexpected statfs calls %v", called)
+	}
+	if dev.FSUsage == nil || dev.FSUsage.MountPoint != "/data" {
+		t.Fatalf("unexpected usage %+v", dev.FSUsage)
+	}
+	if dev.FileSystemSize != "100 MiB" || dev.FileSystemAvailBytes != 65<<20 {
+		t.Errorf("empty size fields must be filled, got %q and %d", dev.FileSystemSize, dev.FileSystemAvailBytes)
+	}
+
+	lsblk := &device.BlockDevice{
+		Mounts:              []device.Mount{{MountPoint: "/data"}},
+		FileSystemSize:      "99 MiB",
+		FileSystemSizeBytes: 99 << 20,
+	}
+	if err := e.Enrich(context.Background(), lsblk); err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if lsblk.FileSystemSizeBytes != 99<<20 {
+		t.Errorf("the provider size must be kept, got %d", lsblk.FileSystemSizeBytes)
+	}
+
+	called = nil
+	if err := e.Enrich(context.Background(), &device.BlockDevice{Path: "/dev/sdb"}); err != nil || called != nil {
+		t.Errorf("unmounted devices must be skipped, got %v and calls %v", err, called)
+	}
+}
+
+func TestEnricher_TimeoutStops(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var calls atomic.Int32
+	e := &Enricher{Timeout: 10 * time.Millisecond, stat: func(path string) (Stat, error) {
+		calls.Add(1)
+		<-release
+		return testStat, nil
+	}}
+
+	dev := &device.BlockDevice{Mounts: []device.Mount{{MountPoint: "/a"}, {MountPoint: "/b"}}}
+	if err := e.Enrich(context.Background(), dev); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if calls.Load() != 1 || dev.FSUsage != nil {
+		t.Errorf("expected a single call and no usage, got %d calls and %+v", calls.Load(), dev.FSUsage)
+	}
+}
@@ -0,0 +1,261 @@
+package device
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sectorSize is the unit used by the kernel for the sysfs "size" attribute,
+// regardless of the logical block size of the device.
+const sectorSize = 512
+
+// SysfsProvider implements BlockDeviceProvider by reading sysfs and procfs directly.
+// It does not need the lsblk binary, so it also works in minimal or distroless images.
+// Filesystem fields (UUID, FSTYPE, LABEL, FSSIZE, FSAVAIL) are not available in sysfs
+// and are left empty.
+type SysfsProvider struct {
+	// SysRoot is the mount point of sysfs (e.g. "/sys").
+	SysRoot string
+	// ProcRoot is the mount point of procfs (e.g. "/proc").
+	ProcRoot string
+}
+
+// NewSysfsProvider creates a new SysfsProvider reading from /sys and /proc.
+func NewSysfsProvider() *SysfsProvider {
+	return &SysfsProvider{
+		SysRoot:  "/sys",
+		ProcRoot: "/proc",
+	}
+}
+
+// Name returns "sysfs".
+func (p *SysfsProvider) Name() string {
+	return ProviderSysfs
+}
+
+// List reads /sys/class/block and returns every block device, including partitions
+// and stacked devices, flattened with parent/children references.
+// ctx is checked before each device is read.
+func (p *SysfsProvider) List(ctx context.Context) ([]BlockDevice, error) {
+	classDir := filepath.Join(p.SysRoot, "class", "block")
+	log.Debug().Str("dir", classDir).Msg("reading block devices from sysfs")
+
+	entries, err := os.ReadDir(classDir)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to read sysfs block class")
+		return nil, fmt.Errorf("failed to read %s: %w", classDir, err)
+	}
+
+	partitionSizes, err := p.readProcPartitions()
+	if err != nil {
+		// Sizes are read from sysfs first, /proc/partitions is only a fallback.
+		log.Debug().Err(err).Msg("cannot read /proc/partitions, continuing without it")
+	}
+
+	diskOfPartition, err := p.mapPartitionsToDisks()
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to map partitions to disks")
+		return nil, err
+	}
+
+	// Stacked devices reference their slaves by kernel name, the tree uses paths.
+	pathByName := make(map[string]string, len(entries))
+	devices := make([]BlockDevice, 0, len(entries))
+	slavesByPath := make(map[string][]string)
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		name := entry.Name()
+		if skipSysfsDevice(name) {
+			continue
+		}
+
+		dev, slaves, err := p.readDevice(name, diskOfPartition[name], partitionSizes)
+		if err != nil {
+			log.Debug().Str("device", name).Err(err).Msg("skipping unreadable sysfs device")
+			continue
+		}
+		if dev.Type == "loop" && dev.DeviceSizeBytes == 0 {
+			// Unattached loop devices are not reported by lsblk either.
+			continue
+		}
+
+		pathByName[name] = dev.Path
+		slavesByPath[dev.Path] = slaves
+		devices = append(devices, dev)
+	}
+
+	for i := range devices {
+		if disk, ok := diskOfPartition[devices[i].Name]; ok {
+			if parent, ok := pathByName[disk]; ok {
+				devices[i].Parents = []string{parent}
+			}
+			continue
+		}
+		for _, slave := range slavesByPath[devices[i].Path] {
+			if parent, ok := pathByName[slave]; ok {
+				devices[i].Parents = append(devices[i].Parents, parent)
+			}
+		}
+	}
+
+	devices = linkDeviceTree(devices)
+	log.Debug().Int("deviceCount", len(devices)).Msg("block devices parsed from sysfs")
+	return devices, nil
+}
+
+// readDevice reads the sysfs attributes of a single block device.
+// It returns the device and the kernel names of its slaves.
+func (p *SysfsProvider) readDevice(name, disk string, partitionSizes map[string]uint64) (BlockDevice, []string, error) {
+	dir := filepath.Join(p.SysRoot, "class", "block", name)
+	if _, err := os.Stat(dir); err != nil {
+		return BlockDevice{}, nil, err
+	}
+
+	dev := BlockDevice{
+		Name: name,
+		Path: "/dev/" + name,
+	}
+	dev.Major, dev.Minor = parseDevNum(ReadSysfsAttr(filepath.Join(dir, "dev")))
+
+	sizeBytes, err := ReadSysfsUint(filepath.Join(dir, "size"))
+	if err != nil {
+		sizeBytes = partitionSizes[name]
+	} else {
+		sizeBytes *= sectorSize
+	}
+	dev.DeviceSizeBytes = sizeBytes
+	dev.DeviceSize = HumanizeBytes(sizeBytes)
+
+	switch {
+	case disk != "" || PathExists(filepath.Join(dir, "partition")):
+		dev.Type = "part"
+		if number, err := ReadSysfsUint(filepath.Join(dir, "partition")); err == nil {
+			dev.PartitionNumber = int(number)
+		}
+	case strings.HasPrefix(name, "dm-"):
+		dev.Type = dmDeviceType(ReadSysfsAttr(filepath.Join(dir, "dm", "uuid")))
+		if mapperName := ReadSysfsAttr(filepath.Join(dir, "dm", "name")); mapperName != "" {
+			dev.Name = mapperName
+			dev.Path = "/dev/mapper/" + mapperName
+		}
+		if dev.Type == "part" {
+			dev.PartitionNumber = partitionNumberFromName(dev.Name)
+		}
+	case strings.HasPrefix(name, "md"):
+		dev.Type = ReadSysfsAttr(filepath.Join(dir, "md", "level"))
+		if dev.Type == "" {
+			dev.Type = "md"
+		}
+	case strings.HasPrefix(name, "loop"):
+		dev.Type = "loop"
+	case strings.HasPrefix(name, "sr") || ReadSysfsAttr(filepath.Join(dir, "device", "type")) == "5":
+		dev.Type = "rom"
+	default:
+		dev.Type = "disk"
+	}
+
+	if dev.Type != "part" {
+		dev.Serial = ReadSysfsAttr(filepath.Join(dir, "device", "serial"))
+		if dev.Serial == "" {
+			dev.Serial = ReadSysfsAttr(filepath.Join(dir, "serial"))
+		}
+		dev.Model = ReadSysfsAttr(filepath.Join(dir, "device", "model"))
+	}
+
+	var slaves []string
+	slaveEntries, err := os.ReadDir(filepath.Join(dir, "slaves"))
+	if err == nil {
+		for _, slave := range slaveEntries {
+			slaves = append(slaves, slave.Name())
+		}
+	}
+
+	return dev, slaves, nil
+}
+
+// mapPartitionsToDisks scans /sys/block/<disk>/ for partition subdirectories
+// and returns a map from partition name to the name of the disk holding it.
+func (p *SysfsProvider) mapPartitionsToDisks() (map[string]string, error) {
+	blockDir := filepath.Join(p.SysRoot, "block")
+	disks, err := os.ReadDir(blockDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", blockDir, err)
+	}
+
+	diskOfPartition := make(map[string]string)
+	for _, disk := range disks {
+		children, err := os.ReadDir(filepath.Join(blockDir, disk.Name()))
+		if err != nil {
+			continue
+		}
+		for _, child := range children {
+			if PathExists(filepath.Join(blockDir, disk.Name(), child.Name(), "partition")) {
+				diskOfPartition[child.Name()] = disk.Name()
+			}
+		}
+	}
+	return diskOfPartition, nil
+}
+
+// readProcPartitions parses /proc/partitions and returns device sizes in bytes by name.
+// Each line has the format "major minor #blocks name", with sizes in 1 KiB blocks.
+func (p *SysfsProvider) readProcPartitions() (map[string]uint64, error) {
+	path := filepath.Join(p.ProcRoot, "partitions")
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	sizes := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		blocks, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			// Header line ("major minor #blocks name").
+			continue
+		}
+		sizes[fields[3]] = blocks * 1024
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return sizes, nil
+}
+
+// dmDeviceType maps a device-mapper UUID prefix to the device type reported by lsblk.
+func dmDeviceType(uuid string) string {
+	prefix, _, _ := strings.Cut(uuid, "-")
+	switch strings.ToUpper(prefix) {
+	case "LVM":
+		return "lvm"
+	case "CRYPT":
+		return "crypt"
+	case "MPATH":
+		return "mpath"
+	}
+	if strings.HasPrefix(strings.ToLower(prefix), "part") {
+		// kpartx partition mappings use "partN-<parent uuid>".
+		return "part"
+	}
+	return "dm"
+}
+
+// skipSysfsDevice reports whether a device is hidden by default, like lsblk does for RAM disks.
+func skipSysfsDevice(name string) bool {
+	return strings.HasPrefix(name, "ram")
+}
@@ -0,0 +1,157 @@
+package device
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/sysfstest"
+)
+
+// newFakeSysfs builds a sysfs/procfs tree with a disk, two partitions, an LVM volume
+// on the second partition, an unattached loop device and a RAM disk.
+func newFakeSysfs(t *testing.T) (sysRoot, procRoot string) {
+	t.Helper()
+	root := t.TempDir()
+	sysRoot = filepath.Join(root, "sys")
+	procRoot = filepath.Join(root, "proc")
+
+	sysfstest.WriteFile(t, sysRoot, "block/sda/size", "2097152\n")
+	sysfstest.WriteFile(t, sysRoot, "block/sda/device/serial", "DISK-0001\n")
+	sysfstest.WriteFile(t, sysRoot, "block/sda/sda1/partition", "1\n")
+	sysfstest.WriteFile(t, sysRoot, "block/sda/sda1/size", "2048\n")
+	sysfstest.WriteFile(t, sysRoot, "block/sda/sda2/partition", "2\n")
+	// No size attribute: the size must come from /proc/partitions.
+	sysfstest.WriteFile(t, sysRoot, "block/sda/sda2/holders/.keep", "")
+
+	sysfstest.WriteFile(t, sysRoot, "block/dm-0/size", "1024\n")
+	sysfstest.WriteFile(t, sysRoot, "block/dm-0/dm/name", "vg0-root\n")
+	sysfstest.WriteFile(t, sysRoot, "block/dm-0/dm/uuid", "LVM-abcdef\n")
+	sysfstest.WriteFile(t, sysRoot, "block/dm-0/slaves/sda2/.keep", "")
+
+	sysfstest.WriteFile(t, sysRoot, "block/loop0/size", "0\n")
+	sysfstest.WriteFile(t, sysRoot, "block/ram0/size", "8192\n")
+
+	for _, name := range []string{"sda", "dm-0", "loop0", "ram0"} {
+		sysfstest.Symlink(t, sysRoot, "class/block/"+name, "../../block/"+name)
+	}
+	sysfstest.Symlink(t, sysRoot, "class/block/sda1", "../../block/sda/sda1")
+	sysfstest.Symlink(t, sysRoot, "class/block/sda2", "../../block/sda/sda2")
+
+	sysfstest.WriteFile(t, procRoot, "partitions", `major minor  #blocks  name
+
+   8        0    1048576 sda
+   8        1       1024 sda1
+   8        2    1046528 sda2
+ 253        0        512 dm-0
+`)
+	return sysRoot, procRoot
+}
+
+func TestSysfsProvider_List(t *testing.T) {
+	sysRoot, procRoot := newFakeSysfs(t)
+	provider := &SysfsProvider{SysRoot: sysRoot, ProcRoot: procRoot}
+
+	devices, err := provider.List(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var paths []string
+	byPath := make(map[string]BlockDevice)
+	for _, dev := range devices {
+		paths = append(paths, dev.Path)
+		byPath[dev.Path] = dev
+	}
+
+	wantPaths := []string{"/dev/sda", "/dev/sda1", "/dev/sda2", "/dev/mapper/vg0-root"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("unexpected devices:\ngot:  %v\nwant: %v", paths, wantPaths)
+	}
+
+	tests := []struct {
+		path         string
+		wantName     string
+		wantType     string
+		wantSize     uint64
+		wantSerial   string
+		wantParents  []string
+		wantChildren []string
+		wantAncestry []string
+	}{
+		{
+			path: "/dev/sda", wantName: "sda", wantType: "disk", wantSize: 2097152 * 512,
+			wantSerial: "DISK-0001", wantChildren: []string{"/dev/sda1", "/dev/sda2"},
+		},
+		{
+			path: "/dev/sda1", wantName: "sda1", wantType: "part", wantSize: 2048 * 512,
+			wantParents: []string{"/dev/sda"}, wantAncestry: []string{"/dev/sda"},
+		},
+		{
+			path: "/dev/sda2", wantName: "sda2", wantType: "part", wantSize: 1046528 * 1024,
+			wantParents: []string{"/dev/sda"}, wantChildren: []string{"/dev/mapper/vg0-root"},
+			wantAncestry: []string{"/dev/sda"},
+		},
+		{
+			path: "/dev/mapper/vg0-root", wantName: "vg0-root", wantType: "lvm", wantSize: 1024 * 512,
+			wantParents: []string{"/dev/sda2"}, wantAncestry: []string{"/dev/sda", "/dev/sda2"},
+		},
+	}
+
+	for _, tt := range tests {
+		dev := byPath[tt.path]
+		if dev.Name != tt.wantName {
+			t.Errorf("%s name: got %q, want %q", tt.path, dev.Name, tt.wantName)
+		}
+		if dev.Type != tt.wantType {
+			t.Errorf("%s type: got %q, want %q", tt.path, dev.Type, tt.wantType)
+		}
+		if dev.DeviceSizeBytes != tt.wantSize {
+			t.Errorf("%s size: got %d, want %d", tt.path, dev.DeviceSizeBytes, tt.wantSize)
+		}
+		if dev.Serial != tt.wantSerial {
+			t.Errorf("%s serial: got %q, want %q", tt.path, dev.Serial, tt.wantSerial)
+		}
+		if !reflect.DeepEqual(dev.Parents, tt.wantParents) {
+			t.Errorf("%s parents: got %v, want %v", tt.path, dev.Parents, tt.wantParents)
+		}
+		if !reflect.DeepEqual(dev.Children, tt.wantChildren) {
+			t.Errorf("%s children: got %v, want %v", tt.path, dev.Children, tt.wantChildren)
+		}
+		if !reflect.DeepEqual(dev.Ancestry, tt.wantAncestry) {
+			t.Errorf("%s ancestry: got %v, want %v", tt.path, dev.Ancestry, tt.wantAncestry)
+		}
+	}
+}
+
+func TestNewBlockDeviceProvider(t *testing.T) {
+	if _, err := NewBlockDeviceProvider(ProviderSysfs); err != nil {
+		t.Errorf("sysfs provider: unexpected error: %v", err)
+	}
+	if _, err := NewBlockDeviceProvider(ProviderLsblk); err != nil {
+		t.Errorf("lsblk provider: unexpected error: %v", err)
+	}
+	if _, err := NewBlockDeviceProvider("udisks"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestSysfsProvider_List_UnreadableDisk(t *testing.T) {
+	root := t.TempDir()
+	sysRoot := filepath.Join(root, "sys")
+	sysfstest.WriteFile(t, sysRoot, "block/sdb/sdb1/partition", "1\n")
+	sysfstest.WriteFile(t, sysRoot, "block/sdb/sdb1/size", "2048\n")
+	// The disk entry is dangling, so the disk is skipped but not its partition.
+	sysfstest.Symlink(t, sysRoot, "class/block/sdb", "../../block/gone")
+	sysfstest.Symlink(t, sysRoot, "class/block/sdb1", "../../block/sdb/sdb1")
+
+	provider := &SysfsProvider{SysRoot: sysRoot, ProcRoot: filepath.Join(root, "proc")}
+	devices, err := provider.List(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Path != "/dev/sdb1" || devices[0].Parents != nil {
+		t.Errorf("the partition of a skipped disk must have no parent, got %+v", devices)
+	}
+}
@@ -0,0 +1,83 @@
+package device
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SysfsDevBlockPath returns the <sysRoot>/dev/block/<major>:<minor> link of a block
+// device, which points at its directory in sysfs.
+func SysfsDevBlockPath(sysRoot string, major, minor int) string {
+	return filepath.Join(sysRoot, "dev", "block", fmt.Sprintf("%d:%d", major, minor))
+}
+
+// SysfsKernelName returns the kernel name of the block device major:minor (e.g. "dm-0")
+// from its <sysRoot>/dev/block link.
+func SysfsKernelName(sysRoot string, major, minor int) (string, error) {
+	target, err := os.Readlink(SysfsDevBlockPath(sysRoot, major, minor))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(target), nil
+}
+
+// ReadSysfsAttr reads a sysfs attribute and returns its trimmed content.
+// Missing or unreadable attributes return an empty string.
+func ReadSysfsAttr(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// ReadSysfsUint reads a sysfs attribute holding an unsigned decimal number.
+func ReadSysfsUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// ReadSysfsID reads a PCI ID attribute ("0x8086") without its prefix.
+func ReadSysfsID(path string) string {
+	return strings.TrimPrefix(ReadSysfsAttr(path), "0x")
+}
+
+// ReadDirNames returns the entry names of a sysfs directory, such as the holders or
+// slaves of a block device, or nil if it cannot be read.
+func ReadDirNames(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// SysfsDriver returns the driver bound to the sysfs device dir and its module, from
+// the driver and driver/module links. Both are empty when no driver is bound; the
+// module is empty for built-in drivers.
+func SysfsDriver(dir string) (driver, module string) {
+	target, err := os.Readlink(filepath.Join(dir, "driver"))
+	if err != nil {
+		return "", ""
+	}
+	if link, err := os.Readlink(filepath.Join(dir, "driver", "module")); err == nil {
+		module = filepath.Base(link)
+	}
+	return filepath.Base(target), module
+}
+
+// PathExists reports whether path exists.
+func PathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,47 @@
+package device
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/sysfstest"
+)
+
+func TestSysfsAttributes(t *testing.T) {
+	sysRoot := t.TempDir()
+	pci := filepath.Join("devices", "pci0000:00", "0000:00:17.0")
+	sysfstest.WriteFile(t, sysRoot, filepath.Join(pci, "vendor"), "0x8086\n")
+	sysfstest.WriteFile(t, sysRoot, filepath.Join(pci, "ata1", "host0", "block", "sda", "size"), "1024\n")
+	sysfstest.WriteFile(t, sysRoot, filepath.Join(pci, "ata1", "host0", "block", "sda", "holders", "dm-0", ".keep"), "")
+	sysfstest.WriteFile(t, sysRoot, filepath.Join("bus", "pci", "drivers", "ahci", ".keep"), "")
+	sysfstest.Symlink(t, sysRoot, filepath.Join(pci, "driver"), "../../../bus/pci/drivers/ahci")
+	sysfstest.Symlink(t, sysRoot, filepath.Join("bus", "pci", "drivers", "ahci", "module"), "../../../../module/ahci")
+	sysfstest.Symlink(t, sysRoot, filepath.Join("dev", "block", "8:0"), "../../"+filepath.Join(pci, "ata1", "host0", "block", "sda"))
+
+	if name, err := SysfsKernelName(sysRoot, 8, 0); err != nil || name != "sda" {
+		t.Errorf("SysfsKernelName = %q, %v", name, err)
+	}
+	if _, err := SysfsKernelName(sysRoot, 8, 16); err == nil {
+		t.Error("expected an error for a missing device")
+	}
+	sda := SysfsDevBlockPath(sysRoot, 8, 0)
+	if size, err := ReadSysfsUint(filepath.Join(sda, "size")); err != nil || size != 1024 {
+		t.Errorf("ReadSysfsUint = %d, %v", size, err)
+	}
+	if holders := ReadDirNames(filepath.Join(sda, "holders")); !reflect.DeepEqual(holders, []string{"dm-0"}) {
+		t.Errorf("ReadDirNames = %v", holders)
+	}
+	if id := ReadSysfsID(filepath.Join(sysRoot, pci, "vendor")); id != "8086" {
+		t.Errorf("ReadSysfsID = %q", id)
+	}
+	if attr := ReadSysfsAttr(filepath.Join(sysRoot, pci, "missing")); attr != "" {
+		t.Errorf("a missing attribute must read as empty, got %q", attr)
+	}
+	if driver, module := SysfsDriver(filepath.Join(sysRoot, pci)); driver != "ahci" || module != "ahci" {
+		t.Errorf("SysfsDriver = %q, %q", driver, module)
+	}
+	if driver, module := SysfsDriver(sda); driver != "" || module != "" {
+		t.Errorf("expected no driver, got %q, %q", driver, module)
+	}
+}
@@ -0,0 +1,55 @@
+// Package sysfstest builds fake sysfs, procfs and /dev trees for the tests of the
+// device packages.
+package sysfstest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// WriteFile creates a file with the given content under root, creating parent directories.
+func WriteFile(t testing.TB, root, path, content string) {
+	t.Helper()
+	full := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", full, err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", full, err)
+	}
+}
+
+// Symlink creates a symlink at root/path pointing at target as given, creating parent
+// directories. A relative target is resolved from the directory of the link.
+func Symlink(t testing.TB, root, path, target string) {
+	t.Helper()
+	full := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", full, err)
+	}
+	if err := os.Symlink(target, full); err != nil {
+		t.Fatalf("symlink %s: %v", full, err)
+	}
+}
+
+// Link creates a symlink at root/path pointing at root/target, creating parent directories.
+func Link(t testing.TB, root, path, target string) {
+	t.Helper()
+	Symlink(t, root, path, filepath.Join(root, target))
+}
+
+// Block creates the sysfs directory dir of a block device, with its dev file, the given
+// attribute files and slaves, and links it from /sys/class/block. Partitions are created
+// inside the directory of their disk.
+func Block(t testing.TB, sysRoot, dir, devNum string, files map[string]string, slaves ...string) {
+	t.Helper()
+	WriteFile(t, sysRoot, filepath.Join(dir, "dev"), devNum+"\n")
+	for path, content := range files {
+		WriteFile(t, sysRoot, filepath.Join(dir, path), content+"\n")
+	}
+	for _, slave := range slaves {
+		WriteFile(t, sysRoot, filepath.Join(dir, "slaves", slave, ".keep"), "")
+	}
+	Link(t, sysRoot, filepath.Join("class", "block", filepath.Base(dir)), dir)
+}
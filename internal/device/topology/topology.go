@@ -0,0 +1,253 @@
+// Package topology builds the tree of buses and controllers that block devices hang off,
+// from the PCI root down to the disks and their partitions, as seen in sysfs.
+package topology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Node kinds, derived from the sysfs directory names.
+const (
+	KindPCIRoot        = "pci-root"
+	KindPCI            = "pci"
+	KindUSBRootHub     = "usb-root-hub"
+	KindUSBHub         = "usb-hub"
+	KindUSBDevice      = "usb-device"
+	KindUSBInterface   = "usb-interface"
+	KindATAPort        = "ata-port"
+	KindSASPort        = "sas-port"
+	KindSASExpander    = "sas-expander"
+	KindSASEndDevice   = "sas-end-device"
+	KindFCRemotePort   = "fc-remote-port"
+	KindISCSISession   = "iscsi-session"
+	KindSCSIHost       = "scsi-host"
+	KindSCSITarget     = "scsi-target"
+	KindSCSIDevice     = "scsi-device"
+	KindNVMeController = "nvme-controller"
+	KindNVMeNamespace  = "nvme-namespace"
+	KindVirtio         = "virtio"
+	KindDisk           = "disk"
+	KindPartition      = "partition"
+	KindDevice         = "device"
+)
+
+// Node is a sysfs device in the topology tree.
+type Node struct {
+	// Name is the sysfs directory name (e.g. "0000:00:17.0", "host0", "sda").
+	Name string `json:"name"`
+	// Kind classifies the node (e.g. "pci", "scsi-host", "disk").
+	Kind string `json:"kind"`
+	// Driver is the kernel driver bound to the node. Empty if none is bound.
+	Driver string `json:"driver,omitempty"`
+	// Description identifies the hardware: PCI and USB IDs with the product name,
+	// SCSI vendor and model.
+	Description string `json:"description,omitempty"`
+	// Path is the device node of disks and partitions (e.g. "/dev/sda").
+	Path string `json:"path,omitempty"`
+	// Size is the size of disks and partitions in human-readable format.
+	Size string `json:"size,omitempty"`
+	// Children are the nodes below this one, sorted by name.
+	Children []*Node `json:"children,omitempty"`
+}
+
+// nodeKinds classifies sysfs directory names, first match wins.
+var nodeKinds = []struct {
+	pattern *regexp.Regexp
+	kind    string
+}{
+	{regexp.MustCompile(`^pci[0-9a-f]{4}:[0-9a-f]{2}$`), KindPCIRoot},
+	{regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-7]$`), KindPCI},
+	{regexp.MustCompile(`^usb\d+$`), KindUSBRootHub},
+	{regexp.MustCompile(`^\d+-[\d.]+:\d+\.\d+$`), KindUSBInterface},
+	{regexp.MustCompile(`^\d+-[\d.]+$`), KindUSBDevice},
+	{regexp.MustCompile(`^ata\d+$`), KindATAPort},
+	{regexp.MustCompile(`^port-`), KindSASPort},
+	{regexp.MustCompile(`^expander-`), KindSASExpander},
+	{regexp.MustCompile(`^end_device-`), KindSASEndDevice},
+	{regexp.MustCompile(`^rport-`), KindFCRemotePort},
+	{regexp.MustCompile(`^session\d+$`), KindISCSISession},
+	{regexp.MustCompile(`^host\d+$`), KindSCSIHost},
+	{regexp.MustCompile(`^target\d+:\d+:\d+$`), KindSCSITarget},
+	{regexp.MustCompile(`^\d+:\d+:\d+:\d+$`), KindSCSIDevice},
+	{regexp.MustCompile(`^nvme\d+$`), KindNVMeController},
+	{regexp.MustCompile(`^virtio\d+$`), KindVirtio},
+}
+
+// groupingDirs are the class directories sysfs inserts between a device and its
+// children (e.g. .../0:0:0:0/block/sda); they are left out of the tree.
+var groupingDirs = map[string]bool{"block": true, "nvme": true}
+
+// Build resolves /sys/block/*/device of every block device under sysRoot and returns the
+// trees of devices they hang off, sorted by name. Virtual devices (loop, device-mapper,
+// md, zram) have no device link and are left out. ctx is checked before each device.
+func Build(ctx context.Context, sysRoot string) ([]*Node, error) {
+	top, err := filepath.EvalSymlinks(filepath.Join(sysRoot, "devices"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sysfs devices: %w", err)
+	}
+	blockDir := filepath.Join(sysRoot, "block")
+	entries, err := os.ReadDir(blockDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", blockDir, err)
+	}
+
+	root := &Node{}
+	byDir := map[string]*Node{top: root}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		name := entry.Name()
+		if _, err := os.Stat(filepath.Join(blockDir, name, "device")); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("failed to read device link of %s: %w", name, err)
+			}
+			log.Debug().Str("device", name).Msg("skipping virtual block device")
+			continue
+		}
+		diskDir, err := filepath.EvalSymlinks(filepath.Join(blockDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		// Multipath NVMe namespaces live under devices/virtual/nvme-subsystem; hang them
+		// off the controller their device link points at instead.
+		parentDir := filepath.Dir(diskDir)
+		if strings.HasPrefix(diskDir, filepath.Join(top, "virtual")+string(filepath.Separator)) {
+			parentDir, err = filepath.EvalSymlinks(filepath.Join(diskDir, "device"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve device link of %s: %w", name, err)
+			}
+		}
+		rel, err := filepath.Rel(top, parentDir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			log.Debug().Str("device", name).Str("dir", diskDir).Msg("block device outside sysfs devices, skipping")
+			continue
+		}
+
+		parent := root
+		dir := top
+		for _, component := range strings.Split(rel, string(filepath.Separator)) {
+			dir = filepath.Join(dir, component)
+			if groupingDirs[component] {
+				continue
+			}
+			node, ok := byDir[dir]
+			if !ok {
+				node = newNode(dir, parent)
+				byDir[dir] = node
+				parent.Children = append(parent.Children, node)
+			}
+			parent = node
+		}
+		disk := newNode(diskDir, parent)
+		parent.Children = append(parent.Children, disk)
+		addPartitions(disk, diskDir)
+	}
+
+	sortTree(root)
+	return root.Children, nil
+}
+
+// newNode reads the sysfs device at dir. parent is used to classify block devices.
+func newNode(dir string, parent *Node) *Node {
+	name := filepath.Base(dir)
+	node := &Node{Name: name, Kind: kindOf(dir, parent)}
+	node.Driver, _ = device.SysfsDriver(dir)
+
+	switch node.Kind {
+	case KindPCI:
+		node.Description = joinNonEmpty(":", device.ReadSysfsID(filepath.Join(dir, "vendor")), device.ReadSysfsID(filepath.Join(dir, "device")))
+	case KindUSBRootHub, KindUSBHub, KindUSBDevice:
+		node.Description = joinNonEmpty(" ",
+			joinNonEmpty(":", device.ReadSysfsAttr(filepath.Join(dir, "idVendor")), device.ReadSysfsAttr(filepath.Join(dir, "idProduct"))),
+			device.ReadSysfsAttr(filepath.Join(dir, "manufacturer")), device.ReadSysfsAttr(filepath.Join(dir, "product")))
+	case KindSCSIDevice:
+		node.Description = joinNonEmpty(" ", device.ReadSysfsAttr(filepath.Join(dir, "vendor")), device.ReadSysfsAttr(filepath.Join(dir, "model")))
+	case KindDisk, KindNVMeNamespace:
+		node.Path = "/dev/" + name
+		node.Size = readSize(dir)
+	}
+	return node
+}
+
+// kindOf classifies the sysfs device at dir.
+func kindOf(dir string, parent *Node) string {
+	name := filepath.Base(dir)
+	if device.PathExists(filepath.Join(dir, "dev")) && device.PathExists(filepath.Join(dir, "size")) {
+		if parent.Kind == KindNVMeController {
+			return KindNVMeNamespace
+		}
+		return KindDisk
+	}
+	for _, rule := range nodeKinds {
+		if rule.pattern.MatchString(name) {
+			// USB hubs have device class 09, other USB devices are leaves of the hub chain.
+			if rule.kind == KindUSBDevice && device.ReadSysfsAttr(filepath.Join(dir, "bDeviceClass")) == "09" {
+				return KindUSBHub
+			}
+			return rule.kind
+		}
+	}
+	return KindDevice
+}
+
+// addPartitions adds the partitions found in the disk directory as children of disk.
+func addPartitions(disk *Node, diskDir string) {
+	entries, err := os.ReadDir(diskDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		dir := filepath.Join(diskDir, entry.Name())
+		if !device.PathExists(filepath.Join(dir, "partition")) {
+			continue
+		}
+		disk.Children = append(disk.Children, &Node{
+			Name: entry.Name(),
+			Kind: KindPartition,
+			Path: "/dev/" + entry.Name(),
+			Size: readSize(dir),
+		})
+	}
+}
+
+// sortTree sorts the children of every node by name.
+func sortTree(node *Node) {
+	sort.Slice(node.Children, func(i, j int) bool { return node.Children[i].Name < node.Children[j].Name })
+	for _, child := range node.Children {
+		sortTree(child)
+	}
+}
+
+// readSize reads the size attribute of a block device, in 512-byte sectors, as a human-readable size.
+func readSize(dir string) string {
+	sectors, err := device.ReadSysfsUint(filepath.Join(dir, "size"))
+	if err != nil {
+		return ""
+	}
+	return humanize.IBytes(sectors * 512)
+}
+
+// joinNonEmpty joins the non-empty values with sep.
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, value := range values {
+		if value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,116 @@
+package topology
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/sysfstest"
+)
+
+// fakeDisk creates the block device dir of a disk below deviceDir and its /sys/block link.
+func fakeDisk(t *testing.T, sysRoot, deviceDir, blockDir, name string) {
+	t.Helper()
+	dir := filepath.Join(deviceDir, blockDir, name)
+	sysfstest.WriteFile(t, sysRoot, dir+"/dev", "8:0\n")
+	sysfstest.WriteFile(t, sysRoot, dir+"/size", "2097152\n")
+	sysfstest.Link(t, sysRoot, dir+"/device", deviceDir)
+	sysfstest.Link(t, sysRoot, "block/"+name, dir)
+}
+
+// newFakeSysfs builds a sysfs with a partitioned SATA disk behind AHCI, an NVMe
+// namespace, a USB disk behind an external hub and a loop device.
+func newFakeSysfs(t *testing.T) string {
+	t.Helper()
+	sysRoot := filepath.Join(t.TempDir(), "sys")
+
+	ahci := "devices/pci0000:00/0000:00:17.0"
+	sysfstest.WriteFile(t, sysRoot, ahci+"/vendor", "0x8086\n")
+	sysfstest.WriteFile(t, sysRoot, ahci+"/device", "0xa352\n")
+	sysfstest.Link(t, sysRoot, ahci+"/driver", "bus/pci/drivers/ahci")
+	lun := ahci + "/ata2/host1/target1:0:0/1:0:0:0"
+	sysfstest.WriteFile(t, sysRoot, lun+"/vendor", "ATA     \n")
+	sysfstest.WriteFile(t, sysRoot, lun+"/model", "Samsung SSD 870 \n")
+	sysfstest.Link(t, sysRoot, lun+"/driver", "bus/scsi/drivers/sd")
+	sysfstest.WriteFile(t, sysRoot, ahci+"/ata2/host1/scsi_host/host1/proc_name", "ahci\n")
+	fakeDisk(t, sysRoot, lun, "block", "sda")
+	sysfstest.WriteFile(t, sysRoot, lun+"/block/sda/sda1/partition", "1\n")
+	sysfstest.WriteFile(t, sysRoot, lun+"/block/sda/sda1/size", "2048\n")
+
+	nvme := "devices/pci0000:00/0000:00:1d.0/0000:3d:00.0"
+	sysfstest.WriteFile(t, sysRoot, nvme+"/vendor", "0x144d\n")
+	sysfstest.WriteFile(t, sysRoot, nvme+"/device", "0xa808\n")
+	sysfstest.WriteFile(t, sysRoot, nvme+"/nvme/nvme0/dev", "243:0\n")
+	fakeDisk(t, sysRoot, nvme+"/nvme/nvme0", "", "nvme0n1")
+
+	hub := "devices/pci0000:00/0000:00:14.0/usb2/2-1"
+	sysfstest.WriteFile(t, sysRoot, hub+"/bDeviceClass", "09\n")
+	sysfstest.WriteFile(t, sysRoot, hub+"/idVendor", "05e3\n")
+	sysfstest.WriteFile(t, sysRoot, hub+"/idProduct", "0626\n")
+	sysfstest.WriteFile(t, sysRoot, hub+"/2-1.4/bDeviceClass", "00\n")
+	sysfstest.WriteFile(t, sysRoot, hub+"/2-1.4/product", "Elements 25A2\n")
+	fakeDisk(t, sysRoot, hub+"/2-1.4/2-1.4:1.0/host6/target6:0:0/6:0:0:0", "block", "sdb")
+
+	sysfstest.WriteFile(t, sysRoot, "devices/virtual/block/loop0/size", "0\n")
+	sysfstest.Link(t, sysRoot, "block/loop0", "devices/virtual/block/loop0")
+	return sysRoot
+}
+
+// render flattens a tree into "indent kind name" lines.
+func render(nodes []*Node, depth int, b *strings.Builder) {
+	for _, node := range nodes {
+		b.WriteString(strings.Repeat("  ", depth) + node.Kind + " " + node.Name)
+		if node.Description != "" {
+			b.WriteString(" " + node.Description)
+		}
+		if node.Driver != "" {
+			b.WriteString(" (" + node.Driver + ")")
+		}
+		b.WriteString("\n")
+		render(node.Children, depth+1, b)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	roots, err := Build(context.Background(), newFakeSysfs(t))
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	var b strings.Builder
+	render(roots, 0, &b)
+	want := `pci-root pci0000:00
+  pci 0000:00:14.0
+    usb-root-hub usb2
+      usb-hub 2-1 05e3:0626
+        usb-device 2-1.4 Elements 25A2
+          usb-interface 2-1.4:1.0
+            scsi-host host6
+              scsi-target target6:0:0
+                scsi-device 6:0:0:0
+                  disk sdb
+  pci 0000:00:17.0 8086:a352 (ahci)
+    ata-port ata2
+      scsi-host host1
+        scsi-target target1:0:0
+          scsi-device 1:0:0:0 ATA Samsung SSD 870 (sd)
+            disk sda
+              partition sda1
+  pci 0000:00:1d.0
+    pci 0000:3d:00.0 144d:a808
+      nvme-controller nvme0
+        nvme-namespace nvme0n1
+`
+	if b.String() != want {
+		t.Errorf("unexpected tree:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestBuild_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Build(ctx, newFakeSysfs(t)); err == nil {
+		t.Fatal("expected an error for a canceled context")
+	}
+}
@@ -0,0 +1,85 @@
+package device
+
+import "sort"
+
+// linkDeviceTree completes the hierarchy of a device list where only Parents is set.
+// It fills Children and Ancestry and returns the devices in depth-first order,
+// top-level devices first, matching the order lsblk reports its tree in.
+// Devices are referenced by path; parents missing from the list are ignored.
+func linkDeviceTree(devices []BlockDevice) []BlockDevice {
+	indexByPath := make(map[string]int, len(devices))
+	for i := range devices {
+		indexByPath[devices[i].Path] = i
+	}
+
+	childrenOf := make(map[string][]string)
+	var roots []string
+	for i := range devices {
+		hasParent := false
+		for _, parent := range devices[i].Parents {
+			if _, ok := indexByPath[parent]; !ok {
+				continue
+			}
+			hasParent = true
+			childrenOf[parent] = appendUnique(childrenOf[parent], devices[i].Path)
+		}
+		if !hasParent {
+			roots = append(roots, devices[i].Path)
+		}
+	}
+
+	for i := range devices {
+		children := childrenOf[devices[i].Path]
+		sort.Strings(children)
+		devices[i].Children = children
+	}
+
+	ordered := make([]BlockDevice, 0, len(devices))
+	visited := make(map[string]bool, len(devices))
+
+	var walk func(path string, ancestry []string)
+	walk = func(path string, ancestry []string) {
+		if visited[path] {
+			return
+		}
+		visited[path] = true
+
+		dev := devices[indexByPath[path]]
+		if len(ancestry) > 0 {
+			dev.Ancestry = append([]string(nil), ancestry...)
+		}
+		ordered = append(ordered, dev)
+
+		for _, child := range dev.Children {
+			// Follow a child only from its first parent so its ancestry is deterministic.
+			if childDev := devices[indexByPath[child]]; len(childDev.Parents) > 0 && childDev.Parents[0] != path {
+				if _, ok := indexByPath[childDev.Parents[0]]; ok {
+					continue
+				}
+			}
+			walk(child, append(ancestry, path))
+		}
+	}
+	for _, root := range roots {
+		walk(root, nil)
+	}
+
+	// Devices caught in a cycle have no root; keep them rather than dropping them.
+	for i := range devices {
+		if !visited[devices[i].Path] {
+			walk(devices[i].Path, nil)
+		}
+	}
+
+	return ordered
+}
+
+// appendUnique appends value to list unless it is already present.
+func appendUnique(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -1,24 +1,79 @@
 package device
 
+import "fmt"
+
 // BlockDevice represents the parsed output of lsblk combined with mount information.
 // It is used as the domain DTO to carry block device data across layers.
+// Providers return the device tree flattened: every node is a BlockDevice and the
+// hierarchy is kept through the Parents, Children and Ancestry path references.
 type BlockDevice struct {
 	// Name is the kernel device name (e.g. "sda", "sda1").
 	Name string `json:"name"`
 	// Path is the full path to the device node (e.g. "/dev/sda").
 	Path string `json:"path"`
+	// Major is the device major number.
+	Major int `json:"major"`
+	// Minor is the device minor number.
+	Minor int `json:"minor"`
 	// UUID is the filesystem UUID assigned to the device.
 	UUID string `json:"uuid"`
 	// Serial is the disk serial number.
 	Serial string `json:"serial"`
+	// Model is the disk model identifier. Empty for partitions and virtual devices.
+	Model string `json:"model"`
+	// Vendor is the disk vendor (udev ID_VENDOR). Empty for partitions and virtual devices.
+	Vendor string `json:"vendor,omitempty"`
+	// WWN is the World Wide Name of the disk (udev ID_WWN_WITH_EXTENSION or ID_WWN).
+	WWN string `json:"wwn,omitempty"`
+	// Bus is the bus or transport the device is attached to (udev ID_BUS, e.g. "ata", "usb", "nvme").
+	Bus string `json:"bus,omitempty"`
+	// IDPath is the persistent hardware path of the device (udev ID_PATH, e.g. "pci-0000:00:17.0-ata-1").
+	IDPath string `json:"idPath,omitempty"`
+	// Links lists the /dev/disk symlinks pointing at the device (by-id, by-path, by-uuid, ...), sorted.
+	Links []string `json:"links,omitempty"`
+	// UdevProperties holds every property of the udev database entry of the device (e.g. "ID_BUS").
+	UdevProperties map[string]string `json:"udev,omitempty"`
+	// Driver is the kernel driver bound to the device (e.g. "sd", "nvme", "virtio_blk").
+	// Partitions report the driver of their disk. Empty for virtual devices.
+	Driver string `json:"driver,omitempty"`
+	// Module is the kernel module providing Driver (e.g. "sd_mod"). Empty for built-in drivers.
+	Module string `json:"module,omitempty"`
+	// Transport is the transport the device is attached through (e.g. "sata", "nvme", "usb", "sas").
+	Transport string `json:"transport,omitempty"`
+	// Controller is the PCI host controller the device is attached to. Nil for virtual devices.
+	Controller *Controller `json:"controller,omitempty"`
+	// VolumeGroup is the LVM volume group of a logical or physical volume (e.g. "vg0").
+	VolumeGroup string `json:"vg,omitempty"`
+	// LogicalVolume is the LVM logical volume name (e.g. "root"). Empty for non-LVM devices.
+	LogicalVolume string `json:"lv,omitempty"`
+	// LVType is the device-mapper target of a logical volume (e.g. "linear", "thin-pool", "thin").
+	LVType string `json:"lvType,omitempty"`
+	// LVDataPercent is the data usage of thin pools, thin volumes and snapshots.
+	LVDataPercent *float64 `json:"lvDataPercent,omitempty"`
+	// LVMetadataPercent is the metadata usage of thin pools.
+	LVMetadataPercent *float64 `json:"lvMetadataPercent,omitempty"`
+	// RAID describes the md array of an md RAID device (e.g. /dev/md0). Nil for other devices.
+	RAID *RAIDArray `json:"raid,omitempty"`
+	// RAIDMember describes the role of a device in an md array. Nil for devices outside arrays.
+	RAIDMember *RAIDMember `json:"raidMember,omitempty"`
+	// CryptType is the format of a dm-crypt mapping, from its device-mapper UUID
+	// (e.g. "LUKS2", "LUKS1", "PLAIN", "BITLK"). Empty for other devices.
+	CryptType string `json:"cryptType,omitempty"`
+	// LUKS is the header of a LUKS encrypted volume. Nil for other devices.
+	LUKS *LUKSHeader `json:"luks,omitempty"`
 	// FSType is the filesystem type (e.g. "ext4", "xfs", "ntfs"). Empty if unformatted.
 	FSType string `json:"fstype"`
 	// Type is the device type (e.g. "disk", "part", "loop").
 	Type string `json:"type"`
+	// FSVersion is the filesystem format version (e.g. "1.0" for ext4, "FAT32" for vfat). Empty if unknown.
+	FSVersion string `json:"fsVersion"`
 	// Label is the filesystem label, if set.
 	Label string `json:"label"`
-	// MountPoint is the path where the device is mounted. Empty if not mounted.
+	// MountPoint is the first path where the device is mounted. Empty if not mounted.
+	// See Mounts for every mount of the device.
 	MountPoint string `json:"mountpoint"`
+	// Mounts lists every mount of the device, including bind mounts and btrfs subvolumes.
+	Mounts []Mount `json:"mounts,omitempty"`
 	// DeviceSize is the total physical size of the block device in human-readable format (e.g. "1.0 TB").
 	DeviceSize string `json:"deviceSize"`
 	// DeviceSizeBytes is the total physical size of the block device in bytes.
@@ -31,4 +86,193 @@ type BlockDevice struct {
 	FileSystemAvail string `json:"fileSystemAvail"`
 	// FileSystemAvailBytes is the available free space in bytes. Zero if not mounted.
 	FileSystemAvailBytes uint64 `json:"fileSystemAvailBytes"`
+	// FSUsage is the space and inode usage of the mounted filesystem, from statfs.
+	// Every mount of a device shares the filesystem, so it is read once. Nil if not mounted.
+	FSUsage *FSUsage `json:"fsUsage,omitempty"`
+	// PartitionTableType is the partition table type of a disk ("gpt" or "dos"). Empty if none.
+	PartitionTableType string `json:"pttype"`
+	// PartitionTableUUID is the GPT disk GUID or the MBR disk signature. Empty if no table.
+	PartitionTableUUID string `json:"ptuuid"`
+	// PartitionNumber is the partition number within its disk (e.g. 1 for sda1). Zero if not a partition.
+	PartitionNumber int `json:"partn"`
+	// PartitionUUID is the GPT partition GUID, or "<disk signature>-<number>" for MBR partitions.
+	PartitionUUID string `json:"partuuid"`
+	// PartitionType is the GPT partition type GUID, or the MBR type byte (e.g. "0x83").
+	PartitionType string `json:"parttype"`
+	// PartitionTypeName is the readable partition type (e.g. "EFI System", "Linux LVM").
+	PartitionTypeName string `json:"parttypename"`
+	// PartitionLabel is the GPT partition name. Empty for MBR partitions.
+	PartitionLabel string `json:"partlabel"`
+	// PartitionFlags are the decoded partition attribute flags (e.g. "legacy-bios-bootable", "boot").
+	PartitionFlags []string `json:"partflags,omitempty"`
+	// PartitionStartLBA is the first sector of the partition within its disk.
+	PartitionStartLBA uint64 `json:"partStartLba"`
+	// PartitionEndLBA is the last sector of the partition within its disk (inclusive).
+	PartitionEndLBA uint64 `json:"partEndLba"`
+	// Parents lists the paths of the devices this device sits on (e.g. the disk of a partition).
+	// RAID arrays and volumes spanning several physical volumes have more than one parent.
+	// Empty for top-level devices.
+	Parents []string `json:"parents,omitempty"`
+	// Children lists the paths of the devices stacked directly on this device.
+	Children []string `json:"children,omitempty"`
+	// Ancestry is the chain of device paths from the top-level device down to the direct parent,
+	// following the first parent at each level. Empty for top-level devices.
+	Ancestry []string `json:"ancestry,omitempty"`
+}
+
+// Mount describes a single mount of a block device.
+// A device mounted several times (bind mounts, btrfs subvolumes) has one Mount per mount point.
+type Mount struct {
+	// MountPoint is the path where the filesystem is mounted.
+	MountPoint string `json:"mountpoint"`
+	// Root is the directory of the filesystem mounted at MountPoint ("/" for a regular mount,
+	// a subdirectory for bind mounts or the subvolume path for btrfs subvolumes).
+	Root string `json:"root"`
+	// Options is the comma-separated list of per-mount options (e.g. "rw,relatime").
+	Options string `json:"options"`
+	// SuperOptions is the comma-separated list of superblock options (e.g. "rw,errors=remount-ro").
+	// Empty if the mount was only reported by lsblk.
+	SuperOptions string `json:"superOptions,omitempty"`
+	// MountID is the unique mount ID from mountinfo. Zero if the mount was only reported by lsblk.
+	MountID int `json:"mountId"`
+}
+
+// Controller describes the PCI host controller of a block device (HBA, NVMe or USB controller).
+type Controller struct {
+	// Address is the PCI address of the controller (e.g. "0000:00:17.0").
+	Address string `json:"address"`
+	// VendorID is the PCI vendor ID (e.g. "8086").
+	VendorID string `json:"vendorId"`
+	// DeviceID is the PCI device ID (e.g. "a352").
+	DeviceID string `json:"deviceId"`
+	// Driver is the kernel driver bound to the controller (e.g. "ahci", "megaraid_sas").
+	Driver string `json:"driver,omitempty"`
+	// Module is the kernel module providing Driver. Empty for built-in drivers.
+	Module string `json:"module,omitempty"`
+}
+
+// RAIDArray describes an md software RAID array.
+type RAIDArray struct {
+	// Level is the RAID level (e.g. "raid1", "raid10", "linear").
+	Level string `json:"level"`
+	// State is the array state from md/array_state (e.g. "clean", "active", "inactive"),
+	// or "active"/"inactive" from /proc/mdstat when sysfs is not readable.
+	State string `json:"state"`
+	// RaidDisks is the number of devices the array is made of, spares excluded.
+	RaidDisks int `json:"raidDisks"`
+	// ActiveDisks is the number of working devices in the array.
+	ActiveDisks int `json:"activeDisks"`
+	// Degraded is true when the array runs with fewer devices than RaidDisks.
+	Degraded bool `json:"degraded"`
+	// SyncAction is the running sync operation ("resync", "recover", "check", "repair",
+	// "reshape"). Empty when the array is idle.
+	SyncAction string `json:"syncAction,omitempty"`
+	// SyncProgress is the completion of SyncAction in percent. Nil when idle or pending.
+	SyncProgress *float64 `json:"syncProgress,omitempty"`
+	// SyncFinish is the estimated time left reported by the kernel (e.g. "7.6min").
+	SyncFinish string `json:"syncFinish,omitempty"`
+	// SyncSpeed is the sync speed reported by the kernel (e.g. "208108K/sec").
+	SyncSpeed string `json:"syncSpeed,omitempty"`
+	// MismatchCount is the number of sectors found inconsistent by the last check or repair.
+	MismatchCount uint64 `json:"mismatchCount"`
+	// Members are the member devices of the array, sorted by slot with spares and
+	// faulty devices last.
+	Members []RAIDMember `json:"members"`
+}
+
+// RAIDMember describes a device of an md array.
+type RAIDMember struct {
+	// Array is the path of the md device (e.g. "/dev/md0").
+	Array string `json:"array"`
+	// Device is the path of the member device (e.g. "/dev/sda1").
+	Device string `json:"device"`
+	// Role is one of the RAIDRole constants.
+	Role string `json:"role"`
+	// Slot is the position of the member in the array. Nil for spares and faulty devices.
+	Slot *int `json:"slot,omitempty"`
+	// State is the comma-separated member state from md/dev-*/state (e.g. "in_sync,write_mostly").
+	State string `json:"state,omitempty"`
+}
+
+// Roles of RAID members.
+const (
+	// RAIDRoleActive is a member holding data of the array.
+	RAIDRoleActive = "active"
+	// RAIDRoleRebuilding is a member assigned a slot that is being recovered and is not in sync yet.
+	RAIDRoleRebuilding = "rebuilding"
+	// RAIDRoleSpare is a hot spare.
+	RAIDRoleSpare = "spare"
+	// RAIDRoleFaulty is a member that failed and was kicked out of the array.
+	RAIDRoleFaulty = "faulty"
+	// RAIDRoleJournal is the write journal device of a raid4/5/6 array.
+	RAIDRoleJournal = "journal"
+	// RAIDRoleReplacement is a device replacing an active member.
+	RAIDRoleReplacement = "replacement"
+)
+
+// LUKSHeader describes the header of a LUKS1 or LUKS2 encrypted volume.
+type LUKSHeader struct {
+	// Version is the LUKS version, 1 or 2.
+	Version int `json:"version"`
+	// UUID is the LUKS UUID, used by /etc/crypttab and the dm-crypt mapping UUID.
+	UUID string `json:"uuid"`
+	// Label is the LUKS2 label. Empty for LUKS1.
+	Label string `json:"label,omitempty"`
+	// Cipher is the data encryption cipher (e.g. "aes-xts-plain64").
+	Cipher string `json:"cipher"`
+	// KeySize is the size of the volume key in bits (e.g. 512 for AES-256 in XTS mode).
+	KeySize int `json:"keySize"`
+	// Keyslots is the number of keyslots in use.
+	Keyslots int `json:"keyslots"`
+	// PBKDF is the key derivation function of the first keyslot (e.g. "argon2id", "pbkdf2").
+	PBKDF string `json:"pbkdf,omitempty"`
+	// Hash is the hash of the PBKDF2 key derivation (e.g. "sha256"). Empty for Argon2.
+	Hash string `json:"hash,omitempty"`
+}
+
+// FSUsage is the usage of a mounted filesystem as reported by statfs(2).
+type FSUsage struct {
+	// MountPoint is the mount point statfs was called on.
+	MountPoint string `json:"mountpoint"`
+	// BlockSize is the fundamental block size the block counts are expressed in.
+	BlockSize uint64 `json:"blockSize"`
+	// TotalBytes is the size of the filesystem.
+	TotalBytes uint64 `json:"totalBytes"`
+	// UsedBytes is the space in use.
+	UsedBytes uint64 `json:"usedBytes"`
+	// AvailBytes is the space available to unprivileged users.
+	AvailBytes uint64 `json:"availBytes"`
+	// ReservedBlocks is the number of free blocks only root can use (e.g. the ext4 reserved blocks).
+	ReservedBlocks uint64 `json:"reservedBlocks"`
+	// ReservedBytes is the size of the reserved blocks.
+	ReservedBytes uint64 `json:"reservedBytes"`
+	// UsedPercent is the used space as df computes it: used / (used + available), rounded up.
+	UsedPercent float64 `json:"usedPercent"`
+	// Inodes is the number of inodes. Zero for filesystems allocating them dynamically (e.g. btrfs).
+	Inodes uint64 `json:"inodes"`
+	// InodesUsed is the number of inodes in use.
+	InodesUsed uint64 `json:"inodesUsed"`
+	// InodesFree is the number of free inodes.
+	InodesFree uint64 `json:"inodesFree"`
+	// InodesUsedPercent is the used inodes as df -i computes it, rounded up. Zero without inodes.
+	InodesUsedPercent float64 `json:"inodesUsedPercent"`
+}
+
+// DevNum returns the device number in "major:minor" format (e.g. "8:1").
+func (d BlockDevice) DevNum() string {
+	return formatDevNum(d.Major, d.Minor)
+}
+
+// MountPoints returns the mount point of every mount of the device.
+func (d BlockDevice) MountPoints() []string {
+	mountPoints := make([]string, 0, len(d.Mounts))
+	for _, m := range d.Mounts {
+		mountPoints = append(mountPoints, m.MountPoint)
+	}
+	return mountPoints
+}
+
+// formatDevNum formats a device number as "major:minor".
+func formatDevNum(major, minor int) string {
+	return fmt.Sprintf("%d:%d", major, minor)
 }
@@ -0,0 +1,110 @@
+package udev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Enricher fills hardware identifiers and /dev/disk links from the udev database.
+type Enricher struct {
+	// Root is prepended to /run/udev and /dev/disk (e.g. "/host" in a container with the
+	// host filesystem mounted there).
+	Root string
+	// SysRoot is the mount point of sysfs, used to map device numbers to kernel names.
+	SysRoot string
+}
+
+// NewEnricher creates a new Enricher reading the udev database under root and sysfs at
+// sysRoot. An empty root reads the local filesystem and an empty sysRoot reads /sys.
+func NewEnricher(root, sysRoot string) *Enricher {
+	if root == "" {
+		root = "/"
+	}
+	if sysRoot == "" {
+		sysRoot = "/sys"
+	}
+	return &Enricher{Root: root, SysRoot: sysRoot}
+}
+
+// Name returns "udev".
+func (e *Enricher) Name() string {
+	return "udev"
+}
+
+// Fields returns the udev fields. Model is only filled when the provider left it empty.
+func (e *Enricher) Fields() []string {
+	return []string{"model", "vendor", "wwn", "bus", "idPath", "links", "udev"}
+}
+
+// Enrich reads the udev database entry of the device and the /dev/disk links pointing at it.
+// Devices without an entry, as in containers without /run/udev, only get their links.
+func (e *Enricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	if dev.Major == 0 && dev.Minor == 0 {
+		return nil
+	}
+
+	entry, err := ReadEntry(e.Root, dev.Major, dev.Minor)
+	switch {
+	case errors.Is(err, ErrNoEntry), errors.Is(err, fs.ErrPermission):
+		log.Debug().Str("device", dev.Path).Err(err).Msg("no udev database entry read")
+	case err != nil:
+		return fmt.Errorf("failed to read udev database: %w", err)
+	}
+
+	kernelName, err := device.SysfsKernelName(e.SysRoot, dev.Major, dev.Minor)
+	if err != nil {
+		kernelName = filepath.Base(dev.Path)
+	}
+	walked, err := DiskLinks(e.Root, kernelName)
+	if err != nil {
+		return fmt.Errorf("failed to read /dev/disk links: %w", err)
+	}
+
+	var dbLinks []string
+	for _, link := range entry.Links {
+		if strings.HasPrefix(link, "disk/") {
+			dbLinks = append(dbLinks, "/dev/"+link)
+		}
+	}
+	dev.Links = mergeLinks(dbLinks, walked)
+
+	if len(entry.Properties) == 0 {
+		return nil
+	}
+	props := entry.Properties
+	dev.UdevProperties = props
+	if dev.Model == "" {
+		dev.Model = firstDecoded(props, "ID_MODEL_ENC", "ID_MODEL")
+	}
+	dev.Vendor = firstDecoded(props, "ID_VENDOR_ENC", "ID_VENDOR")
+	dev.WWN = firstDecoded(props, "ID_WWN_WITH_EXTENSION", "ID_WWN")
+	dev.Bus = props["ID_BUS"]
+	dev.IDPath = props["ID_PATH"]
+
+	log.Debug().
+		Str("device", dev.Path).
+		Str("model", dev.Model).
+		Str("wwn", dev.WWN).
+		Int("links", len(dev.Links)).
+		Msg("enriched device with udev data")
+	return nil
+}
+
+// firstDecoded returns the first non-empty property among keys, with \xHH escapes
+// decoded and padding spaces trimmed.
+func firstDecoded(props map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(Decode(props[key])); value != "" {
+			return value
+		}
+	}
+	return ""
+}
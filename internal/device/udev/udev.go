@@ -0,0 +1,130 @@
+// Package udev reads the udev database and the /dev/disk symlinks managed by udev.
+package udev
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoEntry is returned when the udev database has no entry for a device,
+// for example in containers without /run/udev or for devices udev ignores.
+var ErrNoEntry = errors.New("no udev database entry")
+
+// Entry is the udev database entry of a block device.
+type Entry struct {
+	// Properties are the E: lines (e.g. "ID_MODEL" -> "Samsung_SSD_870").
+	Properties map[string]string
+	// Links are the S: lines, the symlinks to the device relative to /dev (e.g. "disk/by-id/wwn-0x5002").
+	Links []string
+}
+
+// ReadEntry reads the database entry of the block device major:minor from
+// <root>/run/udev/data/b<major>:<minor>.
+func ReadEntry(root string, major, minor int) (Entry, error) {
+	path := filepath.Join(root, "run", "udev", "data", fmt.Sprintf("b%d:%d", major, minor))
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Entry{}, ErrNoEntry
+		}
+		return Entry{}, err
+	}
+	defer file.Close()
+
+	entry := Entry{Properties: make(map[string]string)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		kind, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch kind {
+		case "E":
+			if key, val, ok := strings.Cut(value, "="); ok {
+				entry.Properties[key] = val
+			}
+		case "S":
+			entry.Links = append(entry.Links, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Entry{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return entry, nil
+}
+
+// DiskLinks walks <root>/dev/disk/* and returns the symlinks pointing at the device
+// node /dev/<kernelName>, as absolute paths without the root (e.g. "/dev/disk/by-uuid/1234").
+// A missing /dev/disk returns no links.
+func DiskLinks(root, kernelName string) ([]string, error) {
+	diskDir := filepath.Join(root, "dev", "disk")
+	categories, err := os.ReadDir(diskDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var links []string
+	for _, category := range categories {
+		dir := filepath.Join(diskDir, category.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			target, err := os.Readlink(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			// udev creates relative links (../../sda1); absolute ones point into the host /dev.
+			if !filepath.IsAbs(target) {
+				target = filepath.Join("/dev/disk", category.Name(), target)
+			}
+			if filepath.Clean(target) == "/dev/"+kernelName {
+				links = append(links, filepath.Join("/dev/disk", category.Name(), entry.Name()))
+			}
+		}
+	}
+	return links, nil
+}
+
+// Decode decodes the \xHH escapes udev uses in *_ENC properties (e.g. "WDC\x20WD40").
+func Decode(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if c, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// mergeLinks returns the sorted union of two link lists.
+func mergeLinks(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var merged []string
+	for _, link := range append(append([]string(nil), a...), b...) {
+		if !seen[link] {
+			seen[link] = true
+			merged = append(merged, link)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
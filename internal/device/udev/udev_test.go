@@ -0,0 +1,148 @@
+package udev
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/sysfstest"
+)
+
+// newFakeRoot builds a root with a udev database entry for sda, /dev/disk links for
+// sda and sda1 and the sysfs device number links.
+func newFakeRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	sysfstest.WriteFile(t, root, "run/udev/data/b8:0", `S:disk/by-id/usb-WD_Elements_575834-0:0
+S:disk/by-path/pci-0000:00:14.0-usb-0:2:1.0-scsi-0:0:0:0
+W:3
+I:1234567
+E:ID_BUS=usb
+E:ID_MODEL=Elements_25A2
+E:ID_MODEL_ENC=Elements\x2025A2\x20\x20\x20
+E:ID_VENDOR=WD
+E:ID_WWN=0x50014ee2
+E:ID_WWN_WITH_EXTENSION=0x50014ee2b0a1
+E:ID_PATH=pci-0000:00:14.0-usb-0:2:1.0-scsi-0:0:0:0
+E:ID_USB_DRIVER=uas
+G:systemd
+`)
+	sysfstest.Symlink(t, root, "dev/disk/by-id/usb-WD_Elements_575834-0:0", "../../sda")
+	sysfstest.Symlink(t, root, "dev/disk/by-diskseq/9", "../../sda")
+	sysfstest.Symlink(t, root, "dev/disk/by-uuid/1234-ABCD", "../../sda1")
+	sysfstest.Symlink(t, root, "dev/disk/by-partlabel/data", "/dev/sda1")
+	sysfstest.Symlink(t, root, "sys/dev/block/8:0", "../../block/sda")
+	sysfstest.Symlink(t, root, "sys/dev/block/8:1", "../../block/sda/sda1")
+	return root
+}
+
+func TestReadEntry(t *testing.T) {
+	root := newFakeRoot(t)
+
+	entry, err := ReadEntry(root, 8, 0)
+	if err != nil {
+		t.Fatalf("ReadEntry: %v", err)
+	}
+	if entry.Properties["ID_BUS"] != "usb" || entry.Properties["ID_USB_DRIVER"] != "uas" {
+		t.Errorf("unexpected properties: %v", entry.Properties)
+	}
+	if len(entry.Links) != 2 || entry.Links[0] != "disk/by-id/usb-WD_Elements_575834-0:0" {
+		t.Errorf("unexpected links: %v", entry.Links)
+	}
+
+	if _, err := ReadEntry(root, 8, 1); !errors.Is(err, ErrNoEntry) {
+		t.Errorf("expected ErrNoEntry, got %v", err)
+	}
+}
+
+func TestDiskLinks(t *testing.T) {
+	root := newFakeRoot(t)
+
+	links, err := DiskLinks(root, "sda1")
+	if err != nil {
+		t.Fatalf("DiskLinks: %v", err)
+	}
+	want := []string{"/dev/disk/by-partlabel/data", "/dev/disk/by-uuid/1234-ABCD"}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("got %v, want %v", links, want)
+	}
+
+	links, err = DiskLinks(t.TempDir(), "sda")
+	if err != nil || links != nil {
+		t.Errorf("expected no links without /dev/disk, got %v, %v", links, err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := map[string]string{
+		`Elements\x2025A2`: "Elements 25A2",
+		`plain`:            "plain",
+		`a\x2fb\x5c`:       `a/b\`,
+		`bad\xZZ`:          `bad\xZZ`,
+		`trailing\x2`:      `trailing\x2`,
+	}
+	for in, want := range tests {
+		if got := Decode(in); got != want {
+			t.Errorf("Decode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEnricher(t *testing.T) {
+	root := newFakeRoot(t)
+	e := NewEnricher(root, filepath.Join(root, "sys"))
+
+	disk := device.BlockDevice{Name: "sda", Path: "/dev/sda", Major: 8, Minor: 0}
+	if err := e.Enrich(context.Background(), &disk); err != nil {
+		t.Fatalf("Enrich sda: %v", err)
+	}
+	if disk.Model != "Elements 25A2" || disk.Vendor != "WD" || disk.WWN != "0x50014ee2b0a1" || disk.Bus != "usb" {
+		t.Errorf("unexpected identifiers: model=%q vendor=%q wwn=%q bus=%q", disk.Model, disk.Vendor, disk.WWN, disk.Bus)
+	}
+	if disk.IDPath != "pci-0000:00:14.0-usb-0:2:1.0-scsi-0:0:0:0" {
+		t.Errorf("unexpected ID_PATH %q", disk.IDPath)
+	}
+	wantLinks := []string{
+		"/dev/disk/by-diskseq/9",
+		"/dev/disk/by-id/usb-WD_Elements_575834-0:0",
+		"/dev/disk/by-path/pci-0000:00:14.0-usb-0:2:1.0-scsi-0:0:0:0",
+	}
+	if !reflect.DeepEqual(disk.Links, wantLinks) {
+		t.Errorf("got links %v, want %v", disk.Links, wantLinks)
+	}
+
+	// A model reported by the provider is kept.
+	named := device.BlockDevice{Name: "sda", Path: "/dev/sda", Major: 8, Model: "WDC WD40"}
+	if err := e.Enrich(context.Background(), &named); err != nil {
+		t.Fatalf("Enrich sda: %v", err)
+	}
+	if named.Model != "WDC WD40" {
+		t.Errorf("provider model overwritten: %q", named.Model)
+	}
+
+	// Without a database entry only the links are filled.
+	part := device.BlockDevice{Name: "sda1", Path: "/dev/sda1", Major: 8, Minor: 1}
+	if err := e.Enrich(context.Background(), &part); err != nil {
+		t.Fatalf("Enrich sda1: %v", err)
+	}
+	if part.UdevProperties != nil || len(part.Links) != 2 {
+		t.Errorf("unexpected sda1 enrichment: props=%v links=%v", part.UdevProperties, part.Links)
+	}
+}
+
+func TestEnricher_SysRoot(t *testing.T) {
+	root, sysRoot := t.TempDir(), t.TempDir()
+	sysfstest.Symlink(t, root, "dev/disk/by-id/dm-name-vg0-root", "../../dm-0")
+	sysfstest.Symlink(t, sysRoot, "dev/block/253:0", "../../devices/virtual/block/dm-0")
+
+	dev := device.BlockDevice{Name: "vg0-root", Path: "/dev/mapper/vg0-root", Major: 253, Minor: 0}
+	if err := NewEnricher(root, sysRoot).Enrich(context.Background(), &dev); err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if want := []string{"/dev/disk/by-id/dm-name-vg0-root"}; !reflect.DeepEqual(dev.Links, want) {
+		t.Errorf("got links %v, want %v", dev.Links, want)
+	}
+}
@@ -0,0 +1,214 @@
+package expr
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// node is a node of the expression tree.
+type node interface {
+	// validate resolves fields and literals, returning an *Error on failure.
+	validate() error
+	// match evaluates the node against a device.
+	match(dev device.BlockDevice) bool
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) validate() error {
+	if err := n.left.validate(); err != nil {
+		return err
+	}
+	return n.right.validate()
+}
+
+func (n *orNode) match(dev device.BlockDevice) bool {
+	return n.left.match(dev) || n.right.match(dev)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) validate() error {
+	if err := n.left.validate(); err != nil {
+		return err
+	}
+	return n.right.validate()
+}
+
+func (n *andNode) match(dev device.BlockDevice) bool {
+	return n.left.match(dev) && n.right.match(dev)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) validate() error {
+	return n.operand.validate()
+}
+
+func (n *notNode) match(dev device.BlockDevice) bool {
+	return !n.operand.match(dev)
+}
+
+// presentNode is a field used on its own, true when the field is set.
+type presentNode struct {
+	field    token
+	resolved field
+}
+
+func (n *presentNode) validate() error {
+	f, err := lookupField(n.field)
+	if err != nil {
+		return err
+	}
+	n.resolved = f
+	return nil
+}
+
+func (n *presentNode) match(dev device.BlockDevice) bool {
+	if n.resolved.kind != kindString {
+		value, ok := n.resolved.number(dev)
+		return ok && value != 0
+	}
+	return slices.ContainsFunc(n.resolved.strings(dev), func(s string) bool { return s != "" })
+}
+
+// compareNode compares a field with one literal, or with a list for "in".
+type compareNode struct {
+	field  token
+	op     token
+	values []token
+
+	resolved field
+	regex    *regexp.Regexp
+	numbers  []uint64
+}
+
+func (n *compareNode) validate() error {
+	f, err := lookupField(n.field)
+	if err != nil {
+		return err
+	}
+	n.resolved = f
+
+	switch n.op.text {
+	case "=~", "!~":
+		if f.kind != kindString {
+			return newError(n.op.pos, "operator %q needs a string field, %q is a %s", n.op.text, n.field.text, kindName(f.kind))
+		}
+		n.regex, err = regexp.Compile(n.values[0].text)
+		if err != nil {
+			return newError(n.values[0].pos, "invalid regular expression: %v", err)
+		}
+		return nil
+	case "<", "<=", ">", ">=":
+		if f.kind == kindString {
+			return newError(n.op.pos, "operator %q needs a size or number field, %q is a string", n.op.text, n.field.text)
+		}
+	}
+
+	if f.kind == kindString {
+		return nil
+	}
+	n.numbers = make([]uint64, 0, len(n.values))
+	for _, value := range n.values {
+		number, err := parseNumber(f.kind, value.text)
+		if err != nil {
+			return newError(value.pos, "invalid %s %q for field %q", kindName(f.kind), value.text, n.field.text)
+		}
+		n.numbers = append(n.numbers, number)
+	}
+	return nil
+}
+
+func (n *compareNode) match(dev device.BlockDevice) bool {
+	if n.resolved.kind == kindString {
+		return n.matchStrings(n.resolved.strings(dev))
+	}
+	value, ok := n.resolved.number(dev)
+	if !ok {
+		return false
+	}
+	return n.matchNumber(value)
+}
+
+// matchStrings applies the operator to the values of a string field.
+// Positive operators match when any value matches, negative ones when none does.
+func (n *compareNode) matchStrings(values []string) bool {
+	switch n.op.text {
+	case "==":
+		return slices.Contains(values, n.values[0].text)
+	case "!=":
+		return !slices.Contains(values, n.values[0].text)
+	case "=~":
+		return slices.ContainsFunc(values, n.regex.MatchString)
+	case "!~":
+		return !slices.ContainsFunc(values, n.regex.MatchString)
+	case "in":
+		return slices.ContainsFunc(n.values, func(lit token) bool { return slices.Contains(values, lit.text) })
+	}
+	return false
+}
+
+// matchNumber applies the operator to the value of a size or number field.
+func (n *compareNode) matchNumber(value uint64) bool {
+	switch n.op.text {
+	case "==":
+		return value == n.numbers[0]
+	case "!=":
+		return value != n.numbers[0]
+	case "<":
+		return value < n.numbers[0]
+	case "<=":
+		return value <= n.numbers[0]
+	case ">":
+		return value > n.numbers[0]
+	case ">=":
+		return value >= n.numbers[0]
+	case "in":
+		return slices.Contains(n.numbers, value)
+	}
+	return false
+}
+
+// lookupField resolves a field name token.
+func lookupField(tok token) (field, error) {
+	if len(tok.text) > len(udevPrefix) && strings.EqualFold(tok.text[:len(udevPrefix)], udevPrefix) {
+		return udevField(tok.text[len(udevPrefix):]), nil
+	}
+	f, ok := fields[strings.ToLower(tok.text)]
+	if !ok {
+		return field{}, newError(tok.pos, "unknown field %q, supported: %s", tok.text, strings.Join(FieldNames(), ", "))
+	}
+	return f, nil
+}
+
+// parseNumber parses a literal for a size or number field.
+func parseNumber(kind fieldKind, text string) (uint64, error) {
+	if kind == kindSize {
+		return humanize.ParseBytes(text)
+	}
+	return strconv.ParseUint(text, 10, 64)
+}
+
+// kindName describes a field kind in error messages.
+func kindName(kind fieldKind) string {
+	switch kind {
+	case kindSize:
+		return "size"
+	case kindNumber:
+		return "number"
+	}
+	return "string"
+}
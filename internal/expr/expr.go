@@ -0,0 +1,227 @@
+// Package expr implements the filter expressions of "scan --where".
+//
+// An expression compares BlockDevice fields with literals and combines the
+// comparisons with boolean logic:
+//
+//	type == "part" && fsavail < 10Gi && !(mountpoint =~ "^/snap")
+//	fstype in [ext4, xfs] || label != ""
+//
+// Supported operators are ==, !=, <, <=, >, >= (size and number fields only),
+// =~ and !~ (regular expressions, string fields only), in, !, && and ||.
+// Size fields accept go-humanize literals (10Gi, 500M, 1TB). A field on its own
+// is true when it is set, e.g. `!uuid` matches devices without a UUID.
+// Comparisons against a size that is not known, such as fsavail of an unmounted
+// device, are always false.
+package expr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Error is a syntax or validation error at a position of the expression.
+type Error struct {
+	// Expr is the expression source.
+	Expr string
+	// Pos is the byte offset of the error in Expr.
+	Pos int
+	// Msg describes the error.
+	Msg string
+}
+
+// Error formats the message with the position and a caret under the expression.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d\n  %s\n  %s^", e.Msg, e.Pos+1, e.Expr, strings.Repeat(" ", e.Pos))
+}
+
+// newError creates an Error at pos. The caller sets Expr.
+func newError(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Expr is a parsed filter expression.
+// It must be validated with Validate before calling Match.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses an expression. It only checks the syntax; field names,
+// operators and literals are checked by Validate.
+func Parse(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, withSource(err, src)
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, withSource(err, src)
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, withSource(newError(tok.pos, "unexpected %s", tok.describe()), src)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Validate checks field names, operators and literals, and compiles
+// regular expressions and size literals for Match.
+func (e *Expr) Validate() error {
+	return withSource(e.root.validate(), e.src)
+}
+
+// Match reports whether the device satisfies the expression.
+func (e *Expr) Match(dev device.BlockDevice) bool {
+	return e.root.match(dev)
+}
+
+// String returns the expression source.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// withSource sets the expression source of an *Error.
+func withSource(err error, src string) error {
+	if exprErr, ok := err.(*Error); ok {
+		exprErr.Expr = src
+		return exprErr
+	}
+	return err
+}
+
+// parser is a recursive descent parser over the token list:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" or ")" | comparison
+//	comparison = WORD [ OP value | "in" "[" value { "," value } "]" ]
+//	value      = WORD | STRING
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNot:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, newError(closing.pos, "expected \")\" to close \"(\" at position %d, found %s",
+				tok.pos+1, closing.describe())
+		}
+		return inner, nil
+	case tokWord:
+		return p.parseComparison(tok)
+	default:
+		return nil, newError(tok.pos, "expected a field name, \"!\" or \"(\", found %s", tok.describe())
+	}
+}
+
+func (p *parser) parseComparison(fieldTok token) (node, error) {
+	switch op := p.peek(); op.kind {
+	case tokCompare:
+		p.next()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{field: fieldTok, op: op, values: []token{value}}, nil
+	case tokIn:
+		p.next()
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{field: fieldTok, op: op, values: values}, nil
+	default:
+		return &presentNode{field: fieldTok}, nil
+	}
+}
+
+func (p *parser) parseValue() (token, error) {
+	tok := p.next()
+	if tok.kind != tokWord && tok.kind != tokString {
+		return token{}, newError(tok.pos, "expected a value, found %s", tok.describe())
+	}
+	return tok, nil
+}
+
+func (p *parser) parseList() ([]token, error) {
+	if open := p.next(); open.kind != tokLBracket {
+		return nil, newError(open.pos, "expected \"[\" after \"in\", found %s", open.describe())
+	}
+	var values []token
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		switch sep := p.next(); sep.kind {
+		case tokComma:
+			continue
+		case tokRBracket:
+			return values, nil
+		default:
+			return nil, newError(sep.pos, "expected \",\" or \"]\", found %s", sep.describe())
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+func testDevices() []device.BlockDevice {
+	return []device.BlockDevice{
+		{
+			Name: "sda", Path: "/dev/sda", Major: 8, Type: "disk", PartitionTableType: "gpt",
+			DeviceSize: "100 GiB", DeviceSizeBytes: 100 << 30, Bus: "usb",
+			UdevProperties: map[string]string{"ID_BUS": "usb", "ID_USB_DRIVER": "uas"},
+		},
+		{
+			Name: "sda1", Path: "/dev/sda1", Major: 8, Minor: 1, Type: "part", FSType: "ext4", UUID: "u1",
+			PartitionNumber: 1, MountPoint: "/",
+			Mounts:     []device.Mount{{MountPoint: "/"}, {MountPoint: "/var/lib/docker"}},
+			DeviceSize: "60 GiB", DeviceSizeBytes: 60 << 30,
+			FileSystemAvail: "5 GiB", FileSystemAvailBytes: 5 << 30,
+		},
+		{
+			Name: "sda2", Path: "/dev/sda2", Major: 8, Minor: 2, Type: "part", FSType: "squashfs", UUID: "u2",
+			PartitionNumber: 2, MountPoint: "/snap/core/1",
+			Mounts:     []device.Mount{{MountPoint: "/snap/core/1"}},
+			DeviceSize: "1 GiB", DeviceSizeBytes: 1 << 30,
+			FileSystemAvail: "0 B",
+		},
+		{
+			Name: "sda3", Path: "/dev/sda3", Major: 8, Minor: 3, Type: "part", FSType: "xfs",
+			PartitionNumber: 3, DeviceSize: "39 GiB", DeviceSizeBytes: 39 << 30,
+		},
+	}
+}
+
+func matchingNames(t *testing.T, src string) string {
+	t.Helper()
+	e, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", src, err)
+	}
+	if err := e.Validate(); err != nil {
+		t.Fatalf("Validate(%q): %v", src, err)
+	}
+	var names []string
+	for _, dev := range testDevices() {
+		if e.Match(dev) {
+			names = append(names, dev.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`type == "part"`, "sda1,sda2,sda3"},
+		{`type == part && fsavail < 10Gi`, "sda1,sda2"},
+		{`type == "part" && fsavail < 10Gi && !(mountpoint =~ "^/snap")`, "sda1"},
+		{`fstype in [ext4, "xfs"]`, "sda1,sda3"},
+		{`!(fstype in [ext4, xfs])`, "sda,sda2"},
+		{`size >= 60GiB || partn == 3`, "sda,sda1,sda3"},
+		{`mountpoint == "/var/lib/docker"`, "sda1"},
+		{`mountpoint == ""`, "sda,sda3"},
+		{`mountpoint !~ '^/snap/'`, "sda,sda1,sda3"},
+		{`!uuid`, "sda,sda3"},
+		{`fsavail`, "sda1"},
+		{`majmin == 8:2 || pttype == gpt`, "sda,sda2"},
+		{`fsavail != 5Gi`, "sda2"},
+		{`type == "part" && fstype == ext4 || fstype == xfs`, "sda1,sda3"},
+		{`type == "part" && (fstype == ext4 || fstype == xfs)`, "sda1,sda3"},
+		{`TYPE==disk`, "sda"},
+		{`udev.ID_BUS == usb`, "sda"},
+		{`udev.ID_USB_DRIVER && bus == usb`, "sda"},
+		{`!udev.id_bus`, "sda,sda1,sda2,sda3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := matchingNames(t, tt.expr); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{`type == `, 8, "expected a value"},
+		{`type == "part" && )`, 18, "expected a field name"},
+		{`(type == disk`, 13, `expected ")"`},
+		{`type = disk`, 5, "unexpected character"},
+		{`label == "abc`, 9, "unterminated string"},
+		{`fstype in ext4`, 10, `expected "["`},
+		{`fstype in [ext4 xfs]`, 16, `expected "," or "]"`},
+		{`type == disk part`, 13, "unexpected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			assertError(t, err, tt.expr, tt.pos, tt.msg)
+		})
+	}
+}
+
+func TestValidate_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{`type == part && colour == red`, 16, `unknown field "colour"`},
+		{`fstype < ext4`, 7, "needs a size or number field"},
+		{`size =~ "^1"`, 5, "needs a string field"},
+		{`fsavail < 10Gx`, 10, `invalid size "10Gx"`},
+		{`partn in [1, two]`, 13, `invalid number "two"`},
+		{`label =~ "(unclosed"`, 9, "invalid regular expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			assertError(t, e.Validate(), tt.expr, tt.pos, tt.msg)
+		})
+	}
+}
+
+func assertError(t *testing.T, err error, src string, pos int, msg string) {
+	t.Helper()
+	var exprErr *Error
+	if !errors.As(err, &exprErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if exprErr.Expr != src || exprErr.Pos != pos || !strings.Contains(exprErr.Msg, msg) {
+		t.Errorf("unexpected error: pos=%d msg=%q, want pos=%d msg containing %q", exprErr.Pos, exprErr.Msg, pos, msg)
+	}
+	if caret := strings.Repeat(" ", pos) + "^"; !strings.HasSuffix(err.Error(), caret) {
+		t.Errorf("error does not point at position %d:\n%s", pos, err.Error())
+	}
+}
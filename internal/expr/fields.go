@@ -0,0 +1,165 @@
+package expr
+
+import (
+	"sort"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// fieldKind determines which operators and literals a field accepts.
+type fieldKind int
+
+const (
+	// kindString fields support ==, !=, =~, !~ and in.
+	kindString fieldKind = iota
+	// kindSize fields hold byte counts and accept size literals such as 10Gi or 500M.
+	kindSize
+	// kindNumber fields hold plain unsigned integers.
+	kindNumber
+)
+
+// field is a BlockDevice attribute that expressions can refer to.
+type field struct {
+	kind fieldKind
+	// strings returns the values of a string field. Multi-valued fields
+	// (e.g. mountpoint) match when any of their values matches.
+	strings func(dev device.BlockDevice) []string
+	// number returns the value of a size or number field, and false when it is unknown.
+	number func(dev device.BlockDevice) (uint64, bool)
+}
+
+// fields maps field names, which follow the JSON output, to their accessors.
+var fields = map[string]field{
+	"name":      stringField(func(dev device.BlockDevice) string { return dev.Name }),
+	"path":      stringField(func(dev device.BlockDevice) string { return dev.Path }),
+	"majmin":    stringField(func(dev device.BlockDevice) string { return dev.DevNum() }),
+	"uuid":      stringField(func(dev device.BlockDevice) string { return dev.UUID }),
+	"serial":    stringField(func(dev device.BlockDevice) string { return dev.Serial }),
+	"model":     stringField(func(dev device.BlockDevice) string { return dev.Model }),
+	"vendor":    stringField(func(dev device.BlockDevice) string { return dev.Vendor }),
+	"wwn":       stringField(func(dev device.BlockDevice) string { return dev.WWN }),
+	"bus":       stringField(func(dev device.BlockDevice) string { return dev.Bus }),
+	"idpath":    stringField(func(dev device.BlockDevice) string { return dev.IDPath }),
+	"links":     listField(func(dev device.BlockDevice) []string { return dev.Links }),
+	"driver":    stringField(func(dev device.BlockDevice) string { return dev.Driver }),
+	"module":    stringField(func(dev device.BlockDevice) string { return dev.Module }),
+	"transport": stringField(func(dev device.BlockDevice) string { return dev.Transport }),
+	"controller": stringField(func(dev device.BlockDevice) string {
+		if dev.Controller == nil {
+			return ""
+		}
+		return dev.Controller.Address
+	}),
+	"vg":     stringField(func(dev device.BlockDevice) string { return dev.VolumeGroup }),
+	"lv":     stringField(func(dev device.BlockDevice) string { return dev.LogicalVolume }),
+	"lvtype": stringField(func(dev device.BlockDevice) string { return dev.LVType }),
+	"raidlevel": stringField(func(dev device.BlockDevice) string {
+		if dev.RAID == nil {
+			return ""
+		}
+		return dev.RAID.Level
+	}),
+	"raidrole": stringField(func(dev device.BlockDevice) string {
+		if dev.RAIDMember == nil {
+			return ""
+		}
+		return dev.RAIDMember.Role
+	}),
+	"crypttype":    stringField(func(dev device.BlockDevice) string { return dev.CryptType }),
+	"fstype":       stringField(func(dev device.BlockDevice) string { return dev.FSType }),
+	"fsver":        stringField(func(dev device.BlockDevice) string { return dev.FSVersion }),
+	"label":        stringField(func(dev device.BlockDevice) string { return dev.Label }),
+	"type":         stringField(func(dev device.BlockDevice) string { return dev.Type }),
+	"pttype":       stringField(func(dev device.BlockDevice) string { return dev.PartitionTableType }),
+	"ptuuid":       stringField(func(dev device.BlockDevice) string { return dev.PartitionTableUUID }),
+	"partuuid":     stringField(func(dev device.BlockDevice) string { return dev.PartitionUUID }),
+	"parttype":     stringField(func(dev device.BlockDevice) string { return dev.PartitionType }),
+	"parttypename": stringField(func(dev device.BlockDevice) string { return dev.PartitionTypeName }),
+	"partlabel":    stringField(func(dev device.BlockDevice) string { return dev.PartitionLabel }),
+	"partflags":    listField(func(dev device.BlockDevice) []string { return dev.PartitionFlags }),
+	"mountpoint":   listField(func(dev device.BlockDevice) []string { return dev.MountPoints() }),
+	"parent":       listField(func(dev device.BlockDevice) []string { return dev.Parents }),
+	"size": sizeField(func(dev device.BlockDevice) (uint64, bool) {
+		return dev.DeviceSizeBytes, dev.DeviceSize != ""
+	}),
+	"fssize": sizeField(func(dev device.BlockDevice) (uint64, bool) {
+		return dev.FileSystemSizeBytes, dev.FileSystemSize != ""
+	}),
+	"fsavail": sizeField(func(dev device.BlockDevice) (uint64, bool) {
+		return dev.FileSystemAvailBytes, dev.FileSystemAvail != ""
+	}),
+	"fsused": sizeField(func(dev device.BlockDevice) (uint64, bool) {
+		if dev.FSUsage == nil {
+			return 0, false
+		}
+		return dev.FSUsage.UsedBytes, true
+	}),
+	"fsusepct": numberField(func(dev device.BlockDevice) (uint64, bool) {
+		if dev.FSUsage == nil {
+			return 0, false
+		}
+		return uint64(dev.FSUsage.UsedPercent), true
+	}),
+	"iusepct": numberField(func(dev device.BlockDevice) (uint64, bool) {
+		if dev.FSUsage == nil || dev.FSUsage.Inodes == 0 {
+			return 0, false
+		}
+		return uint64(dev.FSUsage.InodesUsedPercent), true
+	}),
+	"major": numberField(func(dev device.BlockDevice) (uint64, bool) { return uint64(dev.Major), true }),
+	"minor": numberField(func(dev device.BlockDevice) (uint64, bool) { return uint64(dev.Minor), true }),
+	"partn": numberField(func(dev device.BlockDevice) (uint64, bool) {
+		return uint64(dev.PartitionNumber), dev.PartitionNumber > 0
+	}),
+}
+
+// udevPrefix selects a udev property by name, e.g. "udev.ID_BUS". Property names are case-sensitive.
+const udevPrefix = "udev."
+
+// udevField returns the field reading a udev property. Devices without the property have it empty.
+func udevField(property string) field {
+	return stringField(func(dev device.BlockDevice) string { return dev.UdevProperties[property] })
+}
+
+// FieldNames returns the sorted names of the fields usable in expressions.
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, udevPrefix+"<PROPERTY>")
+}
+
+// stringField creates a single-valued string field.
+func stringField(value func(dev device.BlockDevice) string) field {
+	return field{
+		kind:    kindString,
+		strings: func(dev device.BlockDevice) []string { return []string{value(dev)} },
+	}
+}
+
+// listField creates a multi-valued string field. A device without values is
+// treated as having a single empty value, so that `mountpoint == ""` matches
+// unmounted devices.
+func listField(values func(dev device.BlockDevice) []string) field {
+	return field{
+		kind: kindString,
+		strings: func(dev device.BlockDevice) []string {
+			if v := values(dev); len(v) > 0 {
+				return v
+			}
+			return []string{""}
+		},
+	}
+}
+
+// sizeField creates a field holding a size in bytes.
+func sizeField(value func(dev device.BlockDevice) (uint64, bool)) field {
+	return field{kind: kindSize, number: value}
+}
+
+// numberField creates a field holding a plain unsigned integer.
+func numberField(value func(dev device.BlockDevice) (uint64, bool)) field {
+	return field{kind: kindNumber, number: value}
+}
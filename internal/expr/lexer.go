@@ -0,0 +1,150 @@
+package expr
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the kind of a lexical token.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	// tokWord is a field name or an unquoted literal (e.g. fstype, 10Gi, /dev/sda).
+	tokWord
+	// tokString is a single- or double-quoted literal.
+	tokString
+	// tokCompare is a comparison operator (==, !=, <, <=, >, >=, =~, !~).
+	tokCompare
+	tokIn
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+// token is a lexical token with its byte offset in the expression.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// describe returns the token as shown in error messages.
+func (t token) describe() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return "\"" + t.text + "\""
+}
+
+// compareOperators lists the two-character operators before their one-character prefixes.
+var compareOperators = []string{"==", "!=", "<=", ">=", "=~", "!~", "<", ">"}
+
+// tokenize splits an expression into tokens, always terminated by tokEOF.
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(src) {
+		c := src[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: pos})
+			pos++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokLBracket, text: "[", pos: pos})
+			pos++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokRBracket, text: "]", pos: pos})
+			pos++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: pos})
+			pos++
+		case strings.HasPrefix(src[pos:], "&&"):
+			tokens = append(tokens, token{kind: tokAnd, text: "&&", pos: pos})
+			pos += 2
+		case strings.HasPrefix(src[pos:], "||"):
+			tokens = append(tokens, token{kind: tokOr, text: "||", pos: pos})
+			pos += 2
+		case c == '"' || c == '\'':
+			text, end, err := readQuoted(src, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: pos})
+			pos = end
+		case isWordByte(c):
+			end := pos
+			for end < len(src) && isWordByte(src[end]) {
+				end++
+			}
+			text := src[pos:end]
+			kind := tokWord
+			if text == "in" {
+				kind = tokIn
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: pos})
+			pos = end
+		default:
+			op := matchOperator(src[pos:])
+			switch {
+			case op != "":
+				tokens = append(tokens, token{kind: tokCompare, text: op, pos: pos})
+				pos += len(op)
+			case c == '!':
+				tokens = append(tokens, token{kind: tokNot, text: "!", pos: pos})
+				pos++
+			default:
+				return nil, newError(pos, "unexpected character %q", rune(c))
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// matchOperator returns the comparison operator at the start of s, if any.
+func matchOperator(s string) string {
+	for _, op := range compareOperators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// readQuoted reads a quoted literal starting at pos and returns its unescaped
+// content and the offset just past the closing quote. A backslash escapes the
+// next character, so regular expressions only need their quotes escaped.
+func readQuoted(src string, pos int) (string, int, error) {
+	quote := src[pos]
+	var b strings.Builder
+	for i := pos + 1; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(src) && (src[i+1] == quote || src[i+1] == '\\'):
+			b.WriteByte(src[i+1])
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, newError(pos, "unterminated string")
+}
+
+// isWordByte reports whether c can be part of a field name or an unquoted literal.
+func isWordByte(c byte) bool {
+	if c >= unicode.MaxASCII {
+		return false
+	}
+	return unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || strings.IndexByte("_.:/+-", c) >= 0
+}
@@ -0,0 +1,109 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gigiozzz/driver-scanner/internal/audit"
+)
+
+// KindMountAuditReport is the kind of the mount audit report envelope.
+const KindMountAuditReport = "MountAuditReport"
+
+// MountAuditReport is the versioned envelope around the results of the mount rules.
+type MountAuditReport struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   AuditMetadata `json:"metadata"`
+	// Rules are the results of the rules, in rule order.
+	Rules []audit.RuleResult `json:"rules"`
+	// Failed lists the IDs of the failed rules.
+	Failed []string `json:"failed"`
+}
+
+// AuditMetadata describes where, when and against which rules the audit ran.
+type AuditMetadata struct {
+	// Host is the hostname of the machine.
+	Host string `json:"host"`
+	// Timestamp is the time the audit ran, in UTC.
+	Timestamp time.Time `json:"timestamp"`
+	// ToolVersion is the driver-scanner version that produced the report.
+	ToolVersion string `json:"toolVersion"`
+	// Presets are the built-in presets checked.
+	Presets []string `json:"presets,omitempty"`
+	// RuleFiles are the rules files checked.
+	RuleFiles []string `json:"ruleFiles,omitempty"`
+}
+
+// NewMountAuditReport wraps the rule results in a MountAuditReport envelope.
+func NewMountAuditReport(results []audit.RuleResult, metadata AuditMetadata) MountAuditReport {
+	if results == nil {
+		results = []audit.RuleResult{}
+	}
+	return MountAuditReport{
+		APIVersion: APIVersion,
+		Kind:       KindMountAuditReport,
+		Metadata:   metadata,
+		Rules:      results,
+		Failed:     audit.Failed(results),
+	}
+}
+
+// PrintMountAuditReport writes the report in one of the ReportFormats.
+func PrintMountAuditReport(w io.Writer, format string, report MountAuditReport) error {
+	switch format {
+	case "", FormatTable:
+		return printMountAuditTable(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s", format, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// printMountAuditTable writes one row per rule, then one row per violation if any.
+func printMountAuditTable(w io.Writer, report MountAuditReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tSTATUS\tMOUNTS\tDESCRIPTION")
+	fmt.Fprintln(tw, "----\t------\t------\t-----------")
+	for _, rule := range report.Rules {
+		status := rule.Status
+		if status == audit.StatusFail {
+			status = "FAIL"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			rule.ID,
+			status,
+			valueOrDash(strings.Join(rule.Mounts, ",")),
+			valueOrDash(rule.Description),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(report.Failed) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tTARGET\tSOURCE\tFSTYPE\tPROBLEM")
+	fmt.Fprintln(tw, "----\t------\t------\t------\t-------")
+	for _, rule := range report.Rules {
+		for _, violation := range rule.Violations {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				rule.ID,
+				valueOrDash(violation.Target),
+				valueOrDash(violation.Source),
+				valueOrDash(violation.FSType),
+				violation.Problem(),
+			)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,50 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/audit"
+)
+
+func TestPrintMountAuditReport_Table(t *testing.T) {
+	report := NewMountAuditReport([]audit.RuleResult{
+		{ID: "tmp-separate-mount", Description: "/tmp must be a separate mount", Status: audit.StatusFail,
+			Mounts: []string{}, Violations: []audit.Violation{{Target: "/tmp", NotMounted: true}}},
+		{ID: "dev-shm-options", Description: "/dev/shm must be mounted nodev,nosuid,noexec", Status: audit.StatusFail,
+			Mounts: []string{"/dev/shm"}, Violations: []audit.Violation{
+				{Target: "/dev/shm", Source: "tmpfs", FSType: "tmpfs", Missing: []string{"nodev", "noexec"}, Forbidden: []string{"rw"}},
+			}},
+		{ID: "home-options", Status: audit.StatusSkip, Mounts: []string{}},
+	}, AuditMetadata{Presets: []string{audit.PresetCISLevel1}})
+	if report.Kind != KindMountAuditReport || strings.Join(report.Failed, ",") != "tmp-separate-mount,dev-shm-options" {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	var out bytes.Buffer
+	if err := PrintMountAuditReport(&out, FormatTable, report); err != nil {
+		t.Fatalf("PrintMountAuditReport: %v", err)
+	}
+	for _, want := range []string{
+		"tmp-separate-mount  FAIL    -         /tmp must be a separate mount",
+		"home-options        skip    -         -",
+		"tmp-separate-mount  /tmp      -       -       not a separate mount",
+		"dev-shm-options     /dev/shm  tmpfs   tmpfs   missing nodev,noexec, has rw",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := PrintMountAuditReport(&out, FormatTable, NewMountAuditReport(nil, AuditMetadata{})); err != nil {
+		t.Fatalf("PrintMountAuditReport: %v", err)
+	}
+	if strings.Contains(out.String(), "PROBLEM") {
+		t.Errorf("the violations table must be omitted without failures:\n%s", out.String())
+	}
+	if err := PrintMountAuditReport(&out, FormatCSV, report); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
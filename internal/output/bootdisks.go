@@ -0,0 +1,105 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/boot"
+)
+
+// KindBootDisksReport is the kind of the boot disks report envelope.
+const KindBootDisksReport = "BootDisksReport"
+
+// BootDisksReport is the versioned envelope around the resolved boot disks.
+type BootDisksReport struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Mounts are /, /boot and /boot/efi with the devices they are built on.
+	Mounts []boot.Mount `json:"mounts"`
+	// ESPs are the EFI System Partitions found on any disk.
+	ESPs []boot.ESP `json:"esps"`
+	// Disks are the physical disks needed to boot.
+	Disks []boot.Disk `json:"disks"`
+	// Warnings report the disks whose partition table could not be read.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// NewBootDisksReport wraps a boot.Result in a BootDisksReport envelope.
+func NewBootDisksReport(result *boot.Result) BootDisksReport {
+	return BootDisksReport{
+		APIVersion: APIVersion,
+		Kind:       KindBootDisksReport,
+		Mounts:     result.Mounts,
+		ESPs:       result.ESPs,
+		Disks:      result.Disks,
+		Warnings:   result.Warnings,
+	}
+}
+
+// PrintBootDisksReport writes the report in one of the ReportFormats.
+func PrintBootDisksReport(w io.Writer, format string, report BootDisksReport) error {
+	switch format {
+	case "", FormatTable:
+		return printBootDisksTable(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s", format, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// printBootDisksTable writes the disks, then the mounts and the ESPs, each as a table.
+func printBootDisksTable(w io.Writer, report BootDisksReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DISK\tMODEL\tSERIAL\tSIZE\tMOUNTPOINTS\tESPS")
+	fmt.Fprintln(tw, "----\t-----\t------\t----\t-----------\t----")
+	for _, disk := range report.Disks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			disk.Device.Path,
+			valueOrDash(disk.Device.Model),
+			valueOrDash(disk.Device.Serial),
+			valueOrDash(disk.Device.DeviceSize),
+			valueOrDash(strings.Join(disk.MountPoints, ",")),
+			valueOrDash(strings.Join(disk.ESPs, ",")),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MOUNTPOINT\tSOURCE\tFSTYPE\tDISKS\tCHAIN")
+	fmt.Fprintln(tw, "----------\t------\t------\t-----\t-----")
+	for _, mount := range report.Mounts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			mount.MountPoint,
+			valueOrDash(mount.Source),
+			valueOrDash(mount.FSType),
+			valueOrDash(strings.Join(mount.Disks, ",")),
+			valueOrDash(strings.Join(mount.Chain, " <- ")),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ESP\tDISK\tPARTUUID\tSIZE\tMOUNTPOINT")
+	fmt.Fprintln(tw, "---\t----\t--------\t----\t----------")
+	for _, esp := range report.ESPs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			valueOrDash(esp.Device),
+			esp.Disk,
+			valueOrDash(esp.PartUUID),
+			valueOrDash(esp.Size),
+			valueOrDash(esp.MountPoint),
+		)
+	}
+	return tw.Flush()
+}
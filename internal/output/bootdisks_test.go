@@ -0,0 +1,57 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/boot"
+)
+
+func TestPrintBootDisksReport_Table(t *testing.T) {
+	report := NewBootDisksReport(&boot.Result{
+		Mounts: []boot.Mount{
+			{MountPoint: "/", Source: "/dev/md0", FSType: "ext4",
+				Chain: []string{"/dev/md0", "/dev/sda2", "/dev/sda", "/dev/sdb2", "/dev/sdb"},
+				Disks: []string{"/dev/sda", "/dev/sdb"}},
+			{MountPoint: "/boot/efi", Source: "/dev/sda1", FSType: "vfat",
+				Chain: []string{"/dev/sda1", "/dev/sda"}, Disks: []string{"/dev/sda"}},
+		},
+		ESPs: []boot.ESP{
+			{Device: "/dev/sda1", Disk: "/dev/sda", Partition: 1, PartUUID: "aaaa", Size: "512 MiB", MountPoint: "/boot/efi"},
+			{Device: "/dev/sdb1", Disk: "/dev/sdb", Partition: 1, PartUUID: "bbbb", Size: "512 MiB"},
+		},
+		Disks: []boot.Disk{
+			{Device: device.BlockDevice{Path: "/dev/sda", Model: "SSD", Serial: "S1", DeviceSize: "1 TiB"},
+				MountPoints: []string{"/", "/boot/efi"}, ESPs: []string{"/dev/sda1"}},
+			{Device: device.BlockDevice{Path: "/dev/sdb", DeviceSize: "1 TiB"},
+				MountPoints: []string{"/"}, ESPs: []string{"/dev/sdb1"}},
+		},
+	})
+	if report.Kind != KindBootDisksReport {
+		t.Errorf("unexpected kind %q", report.Kind)
+	}
+
+	var out bytes.Buffer
+	if err := PrintBootDisksReport(&out, FormatTable, report); err != nil {
+		t.Fatalf("PrintBootDisksReport: %v", err)
+	}
+	for _, want := range []string{
+		"/dev/sda  SSD    S1      1 TiB  /,/boot/efi  /dev/sda1",
+		"/dev/sdb  -      -       1 TiB  /            /dev/sdb1",
+		"/           /dev/md0   ext4    /dev/sda,/dev/sdb  /dev/md0 <- /dev/sda2 <- /dev/sda <- /dev/sdb2 <- /dev/sdb",
+		"/dev/sdb1  /dev/sdb  bbbb      512 MiB  -",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestPrintBootDisksReport_UnsupportedFormat(t *testing.T) {
+	err := PrintBootDisksReport(&bytes.Buffer{}, "dot", NewBootDisksReport(&boot.Result{}))
+	if err == nil || !strings.Contains(err.Error(), "unsupported output format") {
+		t.Errorf("expected an unsupported format error, got %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+package output
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Column is a named device field rendered by the table and CSV printers.
+type Column struct {
+	// Name is the identifier used by --columns and --sort-by (e.g. "fsavail").
+	Name string
+	// Header is the column title.
+	Header string
+	// Value extracts the column value from a device. Empty means "not available".
+	Value func(dev device.BlockDevice) string
+	// Compare orders two devices by this column. If nil, values are compared as strings.
+	Compare func(a, b device.BlockDevice) int
+}
+
+// columnRegistry lists every available column. Table and CSV output both select from it,
+// so they always agree on names, headers and values.
+var columnRegistry = []Column{
+	{Name: "name", Header: "NAME", Value: func(dev device.BlockDevice) string { return dev.Name }},
+	{Name: "path", Header: "DEVICE", Value: func(dev device.BlockDevice) string { return dev.Path }},
+	{
+		Name: "majmin", Header: "MAJ:MIN",
+		Value: func(dev device.BlockDevice) string { return dev.DevNum() },
+		Compare: func(a, b device.BlockDevice) int {
+			return cmp.Or(cmp.Compare(a.Major, b.Major), cmp.Compare(a.Minor, b.Minor))
+		},
+	},
+	{Name: "uuid", Header: "UUID", Value: func(dev device.BlockDevice) string { return dev.UUID }},
+	{Name: "serial", Header: "SERIAL", Value: func(dev device.BlockDevice) string { return dev.Serial }},
+	{Name: "model", Header: "MODEL", Value: func(dev device.BlockDevice) string { return dev.Model }},
+	{Name: "vendor", Header: "VENDOR", Value: func(dev device.BlockDevice) string { return dev.Vendor }},
+	{Name: "wwn", Header: "WWN", Value: func(dev device.BlockDevice) string { return dev.WWN }},
+	{Name: "bus", Header: "BUS", Value: func(dev device.BlockDevice) string { return dev.Bus }},
+	{Name: "idpath", Header: "ID_PATH", Value: func(dev device.BlockDevice) string { return dev.IDPath }},
+	{Name: "driver", Header: "DRIVER", Value: func(dev device.BlockDevice) string { return dev.Driver }},
+	{Name: "module", Header: "MODULE", Value: func(dev device.BlockDevice) string { return dev.Module }},
+	{Name: "tran", Header: "TRAN", Value: func(dev device.BlockDevice) string { return dev.Transport }},
+	{Name: "controller", Header: "CONTROLLER", Value: controllerSummary},
+	{Name: "vg", Header: "VG", Value: func(dev device.BlockDevice) string { return dev.VolumeGroup }},
+	{Name: "lv", Header: "LV", Value: func(dev device.BlockDevice) string { return dev.LogicalVolume }},
+	{Name: "lvtype", Header: "LV TYPE", Value: func(dev device.BlockDevice) string { return dev.LVType }},
+	{
+		Name: "lvdata", Header: "DATA%",
+		Value:   func(dev device.BlockDevice) string { return formatPercent(dev.LVDataPercent) },
+		Compare: func(a, b device.BlockDevice) int { return comparePercent(a.LVDataPercent, b.LVDataPercent) },
+	},
+	{Name: "raid", Header: "RAID", Value: raidSummary},
+	{Name: "crypt", Header: "CRYPT", Value: cryptSummary},
+	{Name: "fstype", Header: "FSTYPE", Value: func(dev device.BlockDevice) string { return dev.FSType }},
+	{Name: "fsver", Header: "FSVER", Value: func(dev device.BlockDevice) string { return dev.FSVersion }},
+	{Name: "label", Header: "LABEL", Value: func(dev device.BlockDevice) string { return dev.Label }},
+	{Name: "type", Header: "TYPE", Value: func(dev device.BlockDevice) string { return dev.Type }},
+	{Name: "pttype", Header: "PTTYPE", Value: func(dev device.BlockDevice) string { return dev.PartitionTableType }},
+	{Name: "parttype", Header: "PARTTYPE", Value: partitionTypeOf},
+	{Name: "partuuid", Header: "PARTUUID", Value: func(dev device.BlockDevice) string { return dev.PartitionUUID }},
+	{Name: "partlabel", Header: "PARTLABEL", Value: func(dev device.BlockDevice) string { return dev.PartitionLabel }},
+	{Name: "mountpoint", Header: "MOUNTPOINT", Value: mountPointSummary},
+	{
+		Name: "mountpoints", Header: "MOUNTPOINTS",
+		Value: func(dev device.BlockDevice) string { return strings.Join(dev.MountPoints(), ",") },
+	},
+	{
+		Name: "size", Header: "SIZE",
+		Value:   func(dev device.BlockDevice) string { return dev.DeviceSize },
+		Compare: func(a, b device.BlockDevice) int { return cmp.Compare(a.DeviceSizeBytes, b.DeviceSizeBytes) },
+	},
+	{
+		Name: "fssize", Header: "FS SIZE",
+		Value:   func(dev device.BlockDevice) string { return dev.FileSystemSize },
+		Compare: func(a, b device.BlockDevice) int { return cmp.Compare(a.FileSystemSizeBytes, b.FileSystemSizeBytes) },
+	},
+	{
+		Name: "fsavail", Header: "FS AVAIL",
+		Value:   func(dev device.BlockDevice) string { return dev.FileSystemAvail },
+		Compare: func(a, b device.BlockDevice) int { return cmp.Compare(a.FileSystemAvailBytes, b.FileSystemAvailBytes) },
+	},
+	{
+		Name: "fsused", Header: "FS USED",
+		Value: func(dev device.BlockDevice) string {
+			if dev.FSUsage == nil {
+				return ""
+			}
+			return humanize.IBytes(dev.FSUsage.UsedBytes)
+		},
+		Compare: func(a, b device.BlockDevice) int {
+			return compareUsage(a, b, func(u *device.FSUsage) float64 { return float64(u.UsedBytes) })
+		},
+	},
+	{
+		Name: "fsuse%", Header: "FS USE%",
+		Value: func(dev device.BlockDevice) string {
+			if dev.FSUsage == nil {
+				return ""
+			}
+			return fmt.Sprintf("%.0f%%", dev.FSUsage.UsedPercent)
+		},
+		Compare: func(a, b device.BlockDevice) int {
+			return compareUsage(a, b, func(u *device.FSUsage) float64 { return u.UsedPercent })
+		},
+	},
+	{
+		Name: "iuse%", Header: "IUSE%",
+		Value: func(dev device.BlockDevice) string {
+			if dev.FSUsage == nil || dev.FSUsage.Inodes == 0 {
+				return ""
+			}
+			return fmt.Sprintf("%.0f%%", dev.FSUsage.InodesUsedPercent)
+		},
+		Compare: func(a, b device.BlockDevice) int {
+			return compareUsage(a, b, func(u *device.FSUsage) float64 { return u.InodesUsedPercent })
+		},
+	},
+}
+
+// DefaultColumns are the columns of the table and CSV output.
+var DefaultColumns = mustColumns("uuid", "serial", "path", "fstype", "type", "parttype", "mountpoint",
+	"size", "fssize", "fsavail")
+
+// WideColumns are the columns of the wide table output.
+var WideColumns = mustColumns("uuid", "serial", "path", "majmin", "fstype", "label", "type", "parttype",
+	"model", "tran", "driver", "controller", "mountpoints", "size", "fssize", "fsavail", "fsuse%")
+
+// ColumnNames returns the names of every available column.
+func ColumnNames() []string {
+	names := make([]string, 0, len(columnRegistry))
+	for _, col := range columnRegistry {
+		names = append(names, col.Name)
+	}
+	return names
+}
+
+// LookupColumn returns the column with the given name (case-insensitive).
+func LookupColumn(name string) (Column, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, col := range columnRegistry {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return Column{}, false
+}
+
+// ParseColumns parses a comma-separated list of column names (e.g. "uuid,path,size").
+func ParseColumns(spec string) ([]Column, error) {
+	var columns []Column
+	for _, name := range strings.Split(spec, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		col, ok := LookupColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q, supported: %s", name, strings.Join(ColumnNames(), ", "))
+		}
+		columns = append(columns, col)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns given, supported: %s", strings.Join(ColumnNames(), ", "))
+	}
+	return columns, nil
+}
+
+// mustColumns resolves column names from the registry and panics on unknown names.
+func mustColumns(names ...string) []Column {
+	columns, err := ParseColumns(strings.Join(names, ","))
+	if err != nil {
+		panic(err)
+	}
+	return columns
+}
+
+// SortKey orders devices by a column, ascending unless Descending is set.
+type SortKey struct {
+	Column     Column
+	Descending bool
+}
+
+// ParseSortKeys parses a comma-separated list of column names, each optionally
+// prefixed with "-" for descending order (e.g. "size,-fsavail").
+func ParseSortKeys(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		descending := strings.HasPrefix(field, "-")
+		name := strings.TrimPrefix(field, "-")
+		col, ok := LookupColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown sort column %q, supported: %s", name, strings.Join(ColumnNames(), ", "))
+		}
+		keys = append(keys, SortKey{Column: col, Descending: descending})
+	}
+	return keys, nil
+}
+
+// SortDevices sorts devices in place by the given keys. Devices that compare equal
+// on every key keep their original (tree) order.
+func SortDevices(devices []device.BlockDevice, keys []SortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		for _, key := range keys {
+			if c := key.compare(devices[i], devices[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// compare orders two devices by the key column, honoring the sort direction.
+func (k SortKey) compare(a, b device.BlockDevice) int {
+	var c int
+	if k.Column.Compare != nil {
+		c = k.Column.Compare(a, b)
+	} else {
+		c = strings.Compare(k.Column.Value(a), k.Column.Value(b))
+	}
+	if k.Descending {
+		return -c
+	}
+	return c
+}
+
+// partitionTypeOf returns the partition type name of a partition,
+// or the partition table type of a disk.
+func partitionTypeOf(dev device.BlockDevice) string {
+	if dev.PartitionTypeName != "" {
+		return dev.PartitionTypeName
+	}
+	return dev.PartitionTableType
+}
+
+// mountPointSummary returns the first mount point of a device, with the number of
+// additional mounts appended (e.g. "/ (+2)"). The wide output lists all of them.
+func mountPointSummary(dev device.BlockDevice) string {
+	mountPoints := dev.MountPoints()
+	if len(mountPoints) == 0 {
+		return dev.MountPoint
+	}
+	if len(mountPoints) == 1 {
+		return mountPoints[0]
+	}
+	return fmt.Sprintf("%s (+%d)", mountPoints[0], len(mountPoints)-1)
+}
+
+// controllerSummary formats the controller as "<address> <driver>" (e.g. "0000:00:17.0 ahci").
+func controllerSummary(dev device.BlockDevice) string {
+	if dev.Controller == nil {
+		return ""
+	}
+	return strings.TrimSpace(dev.Controller.Address + " " + dev.Controller.Driver)
+}
+
+// raidSummary formats the md state of a device: "<level> <state>" for arrays, with
+// "degraded" when they are (e.g. "raid1 clean degraded"), "<array> <role>" for members
+// (e.g. "/dev/md0 active").
+func raidSummary(dev device.BlockDevice) string {
+	switch {
+	case dev.RAID != nil:
+		summary := strings.TrimSpace(dev.RAID.Level + " " + dev.RAID.State)
+		if dev.RAID.Degraded {
+			summary += " degraded"
+		}
+		return summary
+	case dev.RAIDMember != nil:
+		return dev.RAIDMember.Array + " " + dev.RAIDMember.Role
+	}
+	return ""
+}
+
+// cryptSummary formats the encryption of a device: the format of dm-crypt mappings
+// (e.g. "LUKS2") or "LUKS<version> <cipher>" for LUKS volumes (e.g. "LUKS2 aes-xts-plain64").
+func cryptSummary(dev device.BlockDevice) string {
+	switch {
+	case dev.CryptType != "":
+		return dev.CryptType
+	case dev.LUKS != nil:
+		return strings.TrimSpace(fmt.Sprintf("LUKS%d %s", dev.LUKS.Version, dev.LUKS.Cipher))
+	}
+	return ""
+}
+
+// formatPercent formats an optional percentage with two decimals, like lvs.
+func formatPercent(percent *float64) string {
+	if percent == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", *percent)
+}
+
+// compareUsage orders devices by a value of their filesystem usage, unmounted devices first.
+func compareUsage(a, b device.BlockDevice, value func(u *device.FSUsage) float64) int {
+	switch {
+	case a.FSUsage == nil && b.FSUsage == nil:
+		return 0
+	case a.FSUsage == nil:
+		return -1
+	case b.FSUsage == nil:
+		return 1
+	}
+	return cmp.Compare(value(a.FSUsage), value(b.FSUsage))
+}
+
+// comparePercent orders optional percentages, unknown values first.
+func comparePercent(a, b *float64) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return cmp.Compare(*a, *b)
+}
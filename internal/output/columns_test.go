@@ -0,0 +1,65 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+func TestParseColumns(t *testing.T) {
+	columns, err := ParseColumns("UUID, path,size")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, col := range columns {
+		names = append(names, col.Name)
+	}
+	if len(names) != 3 || names[0] != "uuid" || names[1] != "path" || names[2] != "size" {
+		t.Errorf("unexpected columns: %v", names)
+	}
+
+	for _, spec := range []string{"uuid,nope", "", " , "} {
+		if _, err := ParseColumns(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestSortDevices(t *testing.T) {
+	devices := []device.BlockDevice{
+		{Path: "/dev/sda", DeviceSizeBytes: 100, FileSystemAvailBytes: 10},
+		{Path: "/dev/sdb", DeviceSizeBytes: 50, FileSystemAvailBytes: 5},
+		{Path: "/dev/sdc", DeviceSizeBytes: 100, FileSystemAvailBytes: 30},
+		{Path: "/dev/sdd", DeviceSizeBytes: 1000},
+	}
+
+	keys, err := ParseSortKeys("size,-fsavail")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SortDevices(devices, keys)
+
+	want := []string{"/dev/sdb", "/dev/sdc", "/dev/sda", "/dev/sdd"}
+	for i, dev := range devices {
+		if dev.Path != want[i] {
+			t.Fatalf("unexpected order at %d: got %s, want %s", i, dev.Path, want[i])
+		}
+	}
+}
+
+func TestParseSortKeys_UnknownColumn(t *testing.T) {
+	if _, err := ParseSortKeys("size,-nope"); err == nil {
+		t.Fatal("expected error for unknown sort column")
+	}
+}
+
+func TestMountPointSummary(t *testing.T) {
+	dev := device.BlockDevice{
+		MountPoint: "/",
+		Mounts:     []device.Mount{{MountPoint: "/"}, {MountPoint: "/var"}, {MountPoint: "/home"}},
+	}
+	if got := mountPointSummary(dev); got != "/ (+2)" {
+		t.Errorf("unexpected summary: %q", got)
+	}
+}
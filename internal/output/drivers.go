@@ -0,0 +1,136 @@
+package output
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+// KindDriverReport is the kind of the drivers report envelope.
+const KindDriverReport = "DriverReport"
+
+// ReportFormats lists the output formats of the reports other than the scan report.
+var ReportFormats = []string{FormatTable, FormatJSON, FormatYAML}
+
+// DriverReport is the versioned envelope around the devices grouped by driver and controller.
+type DriverReport struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Metadata   ScanMetadata `json:"metadata"`
+	// Drivers are the groups, ordered by controller address and driver.
+	Drivers []DriverGroup `json:"drivers"`
+	// Diagnostics lists the problems met during the scan, worst first.
+	Diagnostics []service.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// DriverGroup lists the devices handled by one driver behind one controller.
+type DriverGroup struct {
+	// Driver is the kernel driver of the devices (e.g. "sd").
+	Driver string `json:"driver"`
+	// Module is the kernel module providing Driver. Empty for built-in drivers.
+	Module string `json:"module,omitempty"`
+	// Transport is the transport of the devices (e.g. "sata").
+	Transport string `json:"transport,omitempty"`
+	// Controller is the host controller of the devices. Nil for devices without a PCI controller.
+	Controller *device.Controller `json:"controller,omitempty"`
+	// Devices are the paths of the devices, sorted.
+	Devices []string `json:"devices"`
+}
+
+// NewDriverReport groups the devices of a scan by controller, driver and transport.
+// Partitions share the driver of their disk and virtual devices have none, so both are left out.
+func NewDriverReport(result service.ScanResult, metadata ScanMetadata) DriverReport {
+	scan := NewScanReport(result, metadata)
+	groups := make(map[string]*DriverGroup)
+	for _, dev := range scan.Devices {
+		if dev.Driver == "" || dev.PartitionNumber > 0 || dev.Type == "part" {
+			continue
+		}
+		key := controllerAddress(dev.Controller) + "\x00" + dev.Driver + "\x00" + dev.Transport
+		group, ok := groups[key]
+		if !ok {
+			group = &DriverGroup{
+				Driver:     dev.Driver,
+				Module:     dev.Module,
+				Transport:  dev.Transport,
+				Controller: dev.Controller,
+			}
+			groups[key] = group
+		}
+		group.Devices = append(group.Devices, dev.Path)
+	}
+
+	drivers := make([]DriverGroup, 0, len(groups))
+	for _, group := range groups {
+		slices.Sort(group.Devices)
+		drivers = append(drivers, *group)
+	}
+	slices.SortFunc(drivers, func(a, b DriverGroup) int {
+		return cmp.Or(
+			cmp.Compare(controllerAddress(a.Controller), controllerAddress(b.Controller)),
+			cmp.Compare(a.Driver, b.Driver),
+			cmp.Compare(a.Transport, b.Transport),
+		)
+	})
+
+	return DriverReport{
+		APIVersion:  APIVersion,
+		Kind:        KindDriverReport,
+		Metadata:    scan.Metadata,
+		Drivers:     drivers,
+		Diagnostics: scan.Diagnostics,
+	}
+}
+
+// PrintDriverReport writes the report in one of the ReportFormats.
+func PrintDriverReport(w io.Writer, format string, report DriverReport) error {
+	switch format {
+	case "", FormatTable:
+		return printDriverTable(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s", format, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// printDriverTable writes one row per driver group.
+func printDriverTable(w io.Writer, report DriverReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONTROLLER\tPCI ID\tCONTROLLER DRIVER\tTRAN\tDRIVER\tMODULE\tDEVICES")
+	fmt.Fprintln(tw, "----------\t------\t-----------------\t----\t------\t------\t-------")
+	for _, group := range report.Drivers {
+		address, pciID, controllerDriver := "", "", ""
+		if c := group.Controller; c != nil {
+			address = c.Address
+			pciID = c.VendorID + ":" + c.DeviceID
+			controllerDriver = c.Driver
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			valueOrDash(address),
+			valueOrDash(pciID),
+			valueOrDash(controllerDriver),
+			valueOrDash(group.Transport),
+			group.Driver,
+			valueOrDash(group.Module),
+			strings.Join(group.Devices, ","),
+		)
+	}
+	return tw.Flush()
+}
+
+// controllerAddress returns the PCI address of a controller, or an empty string for nil.
+func controllerAddress(c *device.Controller) string {
+	if c == nil {
+		return ""
+	}
+	return c.Address
+}
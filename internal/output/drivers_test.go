@@ -0,0 +1,46 @@
+package output
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+func TestNewDriverReport_GroupsByControllerAndDriver(t *testing.T) {
+	ahci := &device.Controller{Address: "0000:00:17.0", VendorID: "8086", DeviceID: "a352", Driver: "ahci"}
+	nvme := &device.Controller{Address: "0000:01:00.0", VendorID: "144d", DeviceID: "a808", Driver: "nvme", Module: "nvme"}
+	result := service.ScanResult{Devices: []device.BlockDevice{
+		{Path: "/dev/sdb", Type: "disk", Driver: "sd", Module: "sd_mod", Transport: "sata", Controller: ahci},
+		{Path: "/dev/sda", Type: "disk", Driver: "sd", Module: "sd_mod", Transport: "sata", Controller: ahci},
+		{Path: "/dev/sda1", Type: "part", PartitionNumber: 1, Driver: "sd", Module: "sd_mod", Transport: "sata", Controller: ahci},
+		{Path: "/dev/nvme0n1", Type: "disk", Driver: "nvme", Module: "nvme", Transport: "nvme", Controller: nvme},
+		{Path: "/dev/loop0", Type: "loop"},
+	}}
+
+	report := NewDriverReport(result, ScanMetadata{})
+	want := []DriverGroup{
+		{Driver: "sd", Module: "sd_mod", Transport: "sata", Controller: ahci, Devices: []string{"/dev/sda", "/dev/sdb"}},
+		{Driver: "nvme", Module: "nvme", Transport: "nvme", Controller: nvme, Devices: []string{"/dev/nvme0n1"}},
+	}
+	if !reflect.DeepEqual(report.Drivers, want) {
+		t.Fatalf("got %+v, want %+v", report.Drivers, want)
+	}
+	if report.Kind != KindDriverReport || report.APIVersion != APIVersion {
+		t.Errorf("unexpected envelope %q/%q", report.APIVersion, report.Kind)
+	}
+
+	var out bytes.Buffer
+	if err := PrintDriverReport(&out, FormatTable, report); err != nil {
+		t.Fatalf("PrintDriverReport: %v", err)
+	}
+	if !strings.Contains(out.String(), "0000:00:17.0  8086:a352  ahci") || !strings.Contains(out.String(), "/dev/sda,/dev/sdb") {
+		t.Errorf("unexpected table:\n%s", out.String())
+	}
+	if err := PrintDriverReport(&out, FormatCSV, report); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,264 @@
+package output
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+// KindEncryptionReport is the kind of the encryption report envelope.
+const KindEncryptionReport = "EncryptionReport"
+
+// EncryptionReport is the versioned envelope around the encryption status of every mount.
+type EncryptionReport struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Metadata   ScanMetadata `json:"metadata"`
+	// Mounts are the mounted filesystems, sorted by mount point.
+	Mounts []MountEncryption `json:"mounts"`
+	// Unencrypted lists the mount points with a path to a disk that does not cross an
+	// encryption layer, excluded mounts left out.
+	Unencrypted []string `json:"unencrypted"`
+	// Diagnostics lists the problems met during the scan, worst first.
+	Diagnostics []service.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// MountEncryption is the encryption status of one mount.
+type MountEncryption struct {
+	// MountPoint is the path where the filesystem is mounted.
+	MountPoint string `json:"mountpoint"`
+	// Device is the path of the mounted device.
+	Device string `json:"device"`
+	// FSType is the filesystem type of the mounted device.
+	FSType string `json:"fstype"`
+	// Encrypted is true when a dm-crypt mapping sits on every path from the filesystem
+	// down to the disks.
+	Encrypted bool `json:"encrypted"`
+	// Excluded is true for mounts excluded from the check (e.g. /boot/efi).
+	Excluded bool `json:"excluded,omitempty"`
+	// Layers are the dm-crypt mappings found between the filesystem and the disks.
+	Layers []EncryptionLayer `json:"layers,omitempty"`
+	// Chain lists the devices from the mounted device down to the disks, depth first.
+	Chain []string `json:"chain"`
+	// Disks are the devices at the bottom of the chain.
+	Disks []string `json:"disks"`
+	// UnencryptedPaths are the paths from the mounted device down to a disk that cross no
+	// dm-crypt mapping, each listed from the mounted device to the disk. A mount with a
+	// dm-crypt mapping on some paths only, like an LV spanning an encrypted and a plain
+	// PV, is not encrypted.
+	UnencryptedPaths [][]string `json:"unencryptedPaths,omitempty"`
+}
+
+// EncryptionLayer is a dm-crypt mapping below a mount.
+type EncryptionLayer struct {
+	// Device is the path of the dm-crypt mapping (e.g. "/dev/mapper/luks-root").
+	Device string `json:"device"`
+	// Type is the mapping format (e.g. "LUKS2", "PLAIN").
+	Type string `json:"type"`
+	// Backing are the paths of the devices the mapping decrypts.
+	Backing []string `json:"backing"`
+	// LUKS is the header of the backing LUKS volume, when it was readable.
+	LUKS *device.LUKSHeader `json:"luks,omitempty"`
+}
+
+// NewEncryptionReport walks each mounted filesystem of a scan down to its disks, through
+// every parent, and records the dm-crypt mappings on the way. A mount is encrypted when
+// every path down to a disk crosses one. Mounts whose mount point is in excluded are
+// reported but not flagged.
+func NewEncryptionReport(result service.ScanResult, metadata ScanMetadata, excluded []string) EncryptionReport {
+	scan := NewScanReport(result, metadata)
+	byPath := make(map[string]device.BlockDevice, len(scan.Devices))
+	for _, dev := range scan.Devices {
+		byPath[dev.Path] = dev
+	}
+
+	mounts := []MountEncryption{}
+	for _, dev := range scan.Devices {
+		if len(dev.Mounts) == 0 {
+			continue
+		}
+		chain, layers, disks := encryptionChain(byPath, dev.Path)
+		plain := unencryptedPaths(byPath, dev.Path)
+		for _, m := range dev.Mounts {
+			mounts = append(mounts, MountEncryption{
+				MountPoint:       m.MountPoint,
+				Device:           dev.Path,
+				FSType:           dev.FSType,
+				Encrypted:        len(layers) > 0 && len(plain) == 0,
+				Excluded:         slices.Contains(excluded, m.MountPoint),
+				Layers:           layers,
+				Chain:            chain,
+				Disks:            disks,
+				UnencryptedPaths: plain,
+			})
+		}
+	}
+	slices.SortFunc(mounts, func(a, b MountEncryption) int {
+		return cmp.Or(cmp.Compare(a.MountPoint, b.MountPoint), cmp.Compare(a.Device, b.Device))
+	})
+
+	unencrypted := []string{}
+	for _, m := range mounts {
+		if !m.Encrypted && !m.Excluded {
+			unencrypted = append(unencrypted, m.MountPoint)
+		}
+	}
+	return EncryptionReport{
+		APIVersion:  APIVersion,
+		Kind:        KindEncryptionReport,
+		Metadata:    scan.Metadata,
+		Mounts:      mounts,
+		Unencrypted: unencrypted,
+		Diagnostics: scan.Diagnostics,
+	}
+}
+
+// encryptionChain walks from the device at path through all its parents. It returns the
+// visited devices in depth-first order, the dm-crypt mappings among them and the disks
+// at the bottom.
+func encryptionChain(byPath map[string]device.BlockDevice, path string) (chain []string, layers []EncryptionLayer, disks []string) {
+	visited := make(map[string]bool)
+	var walk func(path string)
+	walk = func(path string) {
+		if visited[path] {
+			return
+		}
+		visited[path] = true
+		chain = append(chain, path)
+		dev, ok := byPath[path]
+		if !ok {
+			return
+		}
+		if dev.CryptType != "" {
+			layer := EncryptionLayer{Device: dev.Path, Type: dev.CryptType, Backing: dev.Parents}
+			for _, parent := range dev.Parents {
+				if backing := byPath[parent]; backing.LUKS != nil {
+					layer.LUKS = backing.LUKS
+				}
+			}
+			layers = append(layers, layer)
+		}
+		if len(dev.Parents) == 0 {
+			disks = append(disks, dev.Path)
+		}
+		for _, parent := range dev.Parents {
+			walk(parent)
+		}
+	}
+	walk(path)
+	return chain, layers, disks
+}
+
+// unencryptedPaths returns the paths from the device at path down to a disk, or to a
+// device missing from the scan, that do not cross a dm-crypt mapping. The walk stops at
+// the first mapping of a path: everything below it is encrypted.
+func unencryptedPaths(byPath map[string]device.BlockDevice, path string) [][]string {
+	var paths [][]string
+	onPath := make(map[string]bool)
+	var walk func(path string, leg []string)
+	walk = func(path string, leg []string) {
+		if onPath[path] {
+			return
+		}
+		leg = append(leg, path)
+		dev, ok := byPath[path]
+		switch {
+		case ok && dev.CryptType != "":
+			return
+		case !ok || len(dev.Parents) == 0:
+			paths = append(paths, slices.Clone(leg))
+			return
+		}
+		onPath[path] = true
+		for _, parent := range dev.Parents {
+			walk(parent, leg)
+		}
+		onPath[path] = false
+	}
+	walk(path, nil)
+	return paths
+}
+
+// PrintEncryptionReport writes the report in one of the ReportFormats.
+func PrintEncryptionReport(w io.Writer, format string, report EncryptionReport) error {
+	switch format {
+	case "", FormatTable:
+		return printEncryptionTable(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s", format, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// printEncryptionTable writes one row per mount, then the unencrypted paths of the
+// partially encrypted mounts if any.
+func printEncryptionTable(w io.Writer, report EncryptionReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MOUNTPOINT\tDEVICE\tFSTYPE\tENCRYPTED\tLAYERS\tDISKS")
+	fmt.Fprintln(tw, "----------\t------\t------\t---------\t------\t-----")
+	for _, m := range report.Mounts {
+		encrypted := "yes"
+		switch {
+		case m.Excluded && !m.Encrypted:
+			encrypted = "excluded"
+		case !m.Encrypted && len(m.Layers) > 0:
+			encrypted = "PARTIAL"
+		case !m.Encrypted:
+			encrypted = "NO"
+		}
+		layers := make([]string, 0, len(m.Layers))
+		for _, layer := range m.Layers {
+			layers = append(layers, layerSummary(layer))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			m.MountPoint,
+			m.Device,
+			valueOrDash(m.FSType),
+			encrypted,
+			valueOrDash(strings.Join(layers, "; ")),
+			valueOrDash(strings.Join(m.Disks, ",")),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var partial []MountEncryption
+	for _, m := range report.Mounts {
+		if !m.Encrypted && !m.Excluded && len(m.Layers) > 0 {
+			partial = append(partial, m)
+		}
+	}
+	if len(partial) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MOUNTPOINT\tUNENCRYPTED PATH")
+	fmt.Fprintln(tw, "----------\t----------------")
+	for _, m := range partial {
+		for _, path := range m.UnencryptedPaths {
+			fmt.Fprintf(tw, "%s\t%s\n", m.MountPoint, strings.Join(path, " -> "))
+		}
+	}
+	return tw.Flush()
+}
+
+// layerSummary formats a dm-crypt layer as "<mapping> <type> <cipher> <key size>-bit"
+// (e.g. "/dev/mapper/luks-root LUKS2 aes-xts-plain64 512-bit").
+func layerSummary(layer EncryptionLayer) string {
+	parts := []string{layer.Device, layer.Type}
+	if layer.LUKS != nil {
+		parts = append(parts, layer.LUKS.Cipher, fmt.Sprintf("%d-bit", layer.LUKS.KeySize))
+	}
+	return strings.Join(parts, " ")
+}
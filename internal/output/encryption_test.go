@@ -0,0 +1,108 @@
+package output
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+func TestNewEncryptionReport_FollowsEveryParent(t *testing.T) {
+	luks := &device.LUKSHeader{Version: 2, Cipher: "aes-xts-plain64", KeySize: 512}
+	result := service.ScanResult{Devices: []device.BlockDevice{
+		{Path: "/dev/sda", Type: "disk"},
+		{Path: "/dev/sdb", Type: "disk"},
+		{Path: "/dev/sda1", Type: "part", FSType: "vfat", Parents: []string{"/dev/sda"},
+			Mounts: []device.Mount{{MountPoint: "/boot/efi"}}},
+		{Path: "/dev/sda2", Type: "part", FSType: "linux_raid_member", Parents: []string{"/dev/sda"}},
+		{Path: "/dev/sdb2", Type: "part", FSType: "linux_raid_member", Parents: []string{"/dev/sdb"}},
+		{Path: "/dev/md0", Type: "raid1", FSType: "crypto_LUKS", Parents: []string{"/dev/sda2", "/dev/sdb2"}, LUKS: luks},
+		{Path: "/dev/mapper/luks-md0", Type: "crypt", FSType: "LVM2_member", CryptType: "LUKS2", Parents: []string{"/dev/md0"}},
+		{Path: "/dev/mapper/vg0-root", Type: "lvm", FSType: "ext4", Parents: []string{"/dev/mapper/luks-md0"},
+			Mounts: []device.Mount{{MountPoint: "/"}}},
+		{Path: "/dev/sdb1", Type: "part", FSType: "xfs", Parents: []string{"/dev/sdb"},
+			Mounts: []device.Mount{{MountPoint: "/srv"}, {MountPoint: "/var/lib/data"}}},
+	}}
+
+	report := NewEncryptionReport(result, ScanMetadata{}, []string{"/boot/efi"})
+
+	var mountPoints []string
+	for _, m := range report.Mounts {
+		mountPoints = append(mountPoints, m.MountPoint)
+	}
+	if !reflect.DeepEqual(mountPoints, []string{"/", "/boot/efi", "/srv", "/var/lib/data"}) {
+		t.Fatalf("unexpected mounts %v", mountPoints)
+	}
+	root := report.Mounts[0]
+	wantChain := []string{"/dev/mapper/vg0-root", "/dev/mapper/luks-md0", "/dev/md0", "/dev/sda2", "/dev/sda", "/dev/sdb2", "/dev/sdb"}
+	if !root.Encrypted || !reflect.DeepEqual(root.Chain, wantChain) || !reflect.DeepEqual(root.Disks, []string{"/dev/sda", "/dev/sdb"}) {
+		t.Errorf("unexpected root mount %+v", root)
+	}
+	wantLayers := []EncryptionLayer{{Device: "/dev/mapper/luks-md0", Type: "LUKS2", Backing: []string{"/dev/md0"}, LUKS: luks}}
+	if !reflect.DeepEqual(root.Layers, wantLayers) {
+		t.Errorf("got layers %+v, want %+v", root.Layers, wantLayers)
+	}
+	if esp := report.Mounts[1]; esp.Encrypted || !esp.Excluded {
+		t.Errorf("unexpected ESP mount %+v", esp)
+	}
+	if !reflect.DeepEqual(report.Unencrypted, []string{"/srv", "/var/lib/data"}) {
+		t.Errorf("unexpected unencrypted mounts %v", report.Unencrypted)
+	}
+
+	var out bytes.Buffer
+	if err := PrintEncryptionReport(&out, FormatTable, report); err != nil {
+		t.Fatalf("PrintEncryptionReport: %v", err)
+	}
+	for _, want := range []string{
+		"/              /dev/mapper/vg0-root  ext4    yes        /dev/mapper/luks-md0 LUKS2 aes-xts-plain64 512-bit  /dev/sda,/dev/sdb",
+		"/boot/efi      /dev/sda1             vfat    excluded",
+		"/srv           /dev/sdb1             xfs     NO         -",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestNewEncryptionReport_MixedLegs(t *testing.T) {
+	// vg0-data spans an encrypted PV on sda and a plain PV on sdb.
+	result := service.ScanResult{Devices: []device.BlockDevice{
+		{Path: "/dev/sda", Type: "disk"},
+		{Path: "/dev/sdb", Type: "disk"},
+		{Path: "/dev/sda1", Type: "part", FSType: "crypto_LUKS", Parents: []string{"/dev/sda"}},
+		{Path: "/dev/mapper/luks-sda1", Type: "crypt", FSType: "LVM2_member", CryptType: "LUKS2", Parents: []string{"/dev/sda1"}},
+		{Path: "/dev/sdb1", Type: "part", FSType: "LVM2_member", Parents: []string{"/dev/sdb"}},
+		{Path: "/dev/mapper/vg0-data", Type: "lvm", FSType: "xfs", Parents: []string{"/dev/mapper/luks-sda1", "/dev/sdb1"},
+			Mounts: []device.Mount{{MountPoint: "/data"}}},
+	}}
+
+	report := NewEncryptionReport(result, ScanMetadata{}, nil)
+
+	data := report.Mounts[0]
+	if data.Encrypted || len(data.Layers) != 1 {
+		t.Errorf("a mount with a plain leg must not be encrypted, got %+v", data)
+	}
+	wantPaths := [][]string{{"/dev/mapper/vg0-data", "/dev/sdb1", "/dev/sdb"}}
+	if !reflect.DeepEqual(data.UnencryptedPaths, wantPaths) {
+		t.Errorf("got unencrypted paths %v, want %v", data.UnencryptedPaths, wantPaths)
+	}
+	if !reflect.DeepEqual(report.Unencrypted, []string{"/data"}) {
+		t.Errorf("unexpected unencrypted mounts %v", report.Unencrypted)
+	}
+
+	var out bytes.Buffer
+	if err := PrintEncryptionReport(&out, FormatTable, report); err != nil {
+		t.Fatalf("PrintEncryptionReport: %v", err)
+	}
+	for _, want := range []string{
+		"/data       /dev/mapper/vg0-data  xfs     PARTIAL",
+		"/data       /dev/mapper/vg0-data -> /dev/sdb1 -> /dev/sdb",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+)
+
+// TablePrinter renders devices as an aligned text table.
+type TablePrinter struct {
+	Columns   []Column
+	NoHeaders bool
+}
+
+// Print writes one row per device, with "-" for unavailable values.
+func (p *TablePrinter) Print(w io.Writer, report ScanReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if !p.NoHeaders {
+		headers := make([]string, len(p.Columns))
+		underlines := make([]string, len(p.Columns))
+		for i, col := range p.Columns {
+			headers[i] = col.Header
+			underlines[i] = strings.Repeat("-", len(col.Header))
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+		fmt.Fprintln(tw, strings.Join(underlines, "\t"))
+	}
+
+	values := make([]string, len(p.Columns))
+	for _, dev := range report.Devices {
+		for i, col := range p.Columns {
+			values[i] = valueOrDash(col.Value(dev))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// JSONPrinter renders the full report envelope as indented JSON.
+type JSONPrinter struct{}
+
+// Print writes the report as JSON.
+func (p *JSONPrinter) Print(w io.Writer, report ScanReport) error {
+	return writeJSON(w, report)
+}
+
+// YAMLPrinter renders the full report envelope as YAML, using the JSON field names.
+type YAMLPrinter struct{}
+
+// Print writes the report as YAML.
+func (p *YAMLPrinter) Print(w io.Writer, report ScanReport) error {
+	return writeYAML(w, report)
+}
+
+// CSVPrinter renders devices as CSV with a header row.
+type CSVPrinter struct {
+	Columns   []Column
+	NoHeaders bool
+}
+
+// Print writes one CSV record per device. Unavailable values are empty.
+func (p *CSVPrinter) Print(w io.Writer, report ScanReport) error {
+	cw := csv.NewWriter(w)
+
+	record := make([]string, len(p.Columns))
+	if !p.NoHeaders {
+		for i, col := range p.Columns {
+			record[i] = col.Header
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+
+	for _, dev := range report.Devices {
+		for i, col := range p.Columns {
+			record[i] = col.Value(dev)
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// NDJSONPrinter renders one compact JSON device object per line, without the envelope.
+type NDJSONPrinter struct{}
+
+// Print writes each device as a single JSON line.
+func (p *NDJSONPrinter) Print(w io.Writer, report ScanReport) error {
+	encoder := json.NewEncoder(w)
+	for _, dev := range report.Devices {
+		if err := encoder.Encode(dev); err != nil {
+			return fmt.Errorf("failed to encode NDJSON output: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode JSON output: %w", err)
+	}
+	return nil
+}
+
+// writeYAML writes v as YAML, using the JSON field names.
+func writeYAML(w io.Writer, v any) error {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode YAML output: %w", err)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// valueOrDash returns the value if non-empty, otherwise "-".
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
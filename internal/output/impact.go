@@ -0,0 +1,152 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/graph"
+)
+
+// Output formats of the impact report.
+const (
+	// FormatText is the summary line and table of affected nodes.
+	FormatText = "text"
+	// FormatDOT is the affected part of the dependency graph in Graphviz DOT.
+	FormatDOT = "dot"
+)
+
+// ImpactFormats lists the output formats of the impact report.
+var ImpactFormats = []string{FormatText, FormatJSON, FormatYAML, FormatDOT}
+
+// KindImpactReport is the kind of the impact report envelope.
+const KindImpactReport = "ImpactReport"
+
+// ImpactReport is the versioned envelope around the impact of losing a device.
+type ImpactReport struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Device is the path of the lost device.
+	Device string `json:"device"`
+	// Failing and Degraded count the effects of each impact.
+	Failing  int `json:"failing"`
+	Degraded int `json:"degraded"`
+	// Effects are the affected nodes, closest to the lost device first.
+	Effects []graph.Effect `json:"effects"`
+	// Nodes are the lost device, the affected nodes and the other devices they are built
+	// on, sorted by ID. Their lowers and uppers only reference nodes of this list.
+	Nodes []graph.Node `json:"nodes"`
+}
+
+// NewImpactReport computes the impact of losing the node on the graph.
+func NewImpactReport(g *graph.Graph, lost *graph.Node) ImpactReport {
+	effects := g.Impact(lost.ID)
+	report := ImpactReport{
+		APIVersion: APIVersion,
+		Kind:       KindImpactReport,
+		Device:     lost.Name,
+		Effects:    []graph.Effect{},
+	}
+
+	inReport := map[string]bool{lost.ID: true}
+	for _, effect := range effects {
+		report.Effects = append(report.Effects, effect)
+		if effect.Impact == graph.ImpactFail {
+			report.Failing++
+		} else {
+			report.Degraded++
+		}
+		inReport[effect.ID] = true
+		for _, lower := range g.Nodes[effect.ID].Lowers {
+			inReport[lower] = true
+		}
+	}
+
+	for id := range inReport {
+		node := *g.Nodes[id]
+		node.Lowers = slices.DeleteFunc(slices.Clone(node.Lowers), func(l string) bool { return !inReport[l] })
+		node.Uppers = slices.DeleteFunc(slices.Clone(node.Uppers), func(u string) bool { return !inReport[u] })
+		report.Nodes = append(report.Nodes, node)
+	}
+	slices.SortFunc(report.Nodes, func(a, b graph.Node) int { return strings.Compare(a.ID, b.ID) })
+	return report
+}
+
+// PrintImpactReport writes the report in one of the ImpactFormats.
+func PrintImpactReport(w io.Writer, format string, report ImpactReport) error {
+	switch format {
+	case "", FormatText:
+		return printImpactText(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	case FormatDOT:
+		return printImpactDOT(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s", format, strings.Join(ImpactFormats, ", "))
+	}
+}
+
+// printImpactText writes a summary line followed by one row per affected node.
+func printImpactText(w io.Writer, report ImpactReport) error {
+	if len(report.Effects) == 0 {
+		_, err := fmt.Fprintf(w, "Nothing depends on %s.\n", report.Device)
+		return err
+	}
+	fmt.Fprintf(w, "Losing %s makes %d fail and %d degrade.\n\n", report.Device, report.Failing, report.Degraded)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMPACT\tKIND\tNAME\tREASON")
+	fmt.Fprintln(tw, "------\t----\t----\t------")
+	for _, effect := range report.Effects {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", effect.Impact, effect.Kind, effect.Name, effect.Reason)
+	}
+	return tw.Flush()
+}
+
+// printImpactDOT writes the nodes of the report as a Graphviz digraph with edges from
+// lower to upper devices. The lost device is filled grey, failing nodes red and
+// degraded nodes orange.
+func printImpactDOT(w io.Writer, report ImpactReport) error {
+	impacts := make(map[string]string, len(report.Effects))
+	for _, effect := range report.Effects {
+		impacts[effect.ID] = effect.Impact
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph impact {\n")
+	b.WriteString("  rankdir=BT;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	for _, node := range report.Nodes {
+		attrs := []string{"label=" + strconv.Quote(node.Name+"\n"+levelOrKind(node))}
+		switch {
+		case node.Name == report.Device && impacts[node.ID] == "":
+			attrs = append(attrs, `style="rounded,filled"`, "fillcolor=grey", "penwidth=2")
+		case impacts[node.ID] == graph.ImpactFail:
+			attrs = append(attrs, `style="rounded,filled"`, "fillcolor=tomato")
+		case impacts[node.ID] == graph.ImpactDegrade:
+			attrs = append(attrs, `style="rounded,filled"`, "fillcolor=orange")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", strconv.Quote(node.ID), strings.Join(attrs, ", "))
+	}
+	for _, node := range report.Nodes {
+		for _, upper := range node.Uppers {
+			fmt.Fprintf(&b, "  %s -> %s;\n", strconv.Quote(node.ID), strconv.Quote(upper))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// levelOrKind labels md arrays with their level and other nodes with their kind.
+func levelOrKind(node graph.Node) string {
+	if node.Level != "" {
+		return node.Level
+	}
+	return node.Kind
+}
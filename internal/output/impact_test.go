@@ -0,0 +1,71 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/graph"
+)
+
+// mirrorGraph returns a RAID1 of sda1 and sdb1 mounted on /srv.
+func mirrorGraph() *graph.Graph {
+	return &graph.Graph{Nodes: map[string]*graph.Node{
+		"sda1":    {ID: "sda1", Kind: graph.KindPartition, Name: "/dev/sda1", Uppers: []string{"md0"}},
+		"sdb1":    {ID: "sdb1", Kind: graph.KindPartition, Name: "/dev/sdb1", Uppers: []string{"md0"}},
+		"sdc1":    {ID: "sdc1", Kind: graph.KindPartition, Name: "/dev/sdc1", Uppers: []string{"mount:2"}},
+		"md0":     {ID: "md0", Kind: graph.KindRAID, Name: "/dev/md0", Level: "raid1", RaidDisks: 2, Lowers: []string{"sda1", "sdb1"}, Uppers: []string{"mount:1"}},
+		"mount:1": {ID: "mount:1", Kind: graph.KindMount, Name: "/srv", Lowers: []string{"md0"}},
+		"mount:2": {ID: "mount:2", Kind: graph.KindMount, Name: "/home", Lowers: []string{"sdc1"}},
+	}}
+}
+
+func TestPrintImpactReport(t *testing.T) {
+	g := mirrorGraph()
+	report := NewImpactReport(g, g.Nodes["sda1"])
+	if report.Failing != 0 || report.Degraded != 2 || len(report.Nodes) != 4 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	var out bytes.Buffer
+	if err := PrintImpactReport(&out, FormatText, report); err != nil {
+		t.Fatalf("PrintImpactReport: %v", err)
+	}
+	want := `Losing /dev/sda1 makes 0 fail and 2 degrade.
+
+IMPACT   KIND   NAME      REASON
+------   ----   ----      ------
+degrade  raid   /dev/md0  raid1 loses 1 of 2 devices
+degrade  mount  /srv      built on degraded /dev/md0
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := PrintImpactReport(&out, FormatDOT, report); err != nil {
+		t.Fatalf("PrintImpactReport: %v", err)
+	}
+	for _, line := range []string{
+		`"sda1" [label="/dev/sda1\npart", style="rounded,filled", fillcolor=grey, penwidth=2];`,
+		`"md0" [label="/dev/md0\nraid1", style="rounded,filled", fillcolor=orange];`,
+		`"sdb1" [label="/dev/sdb1\npart"];`,
+		`"sdb1" -> "md0";`,
+		`"md0" -> "mount:1";`,
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("DOT output does not contain %s:\n%s", line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "/home") {
+		t.Errorf("unaffected nodes must be left out:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := PrintImpactReport(&out, FormatText, NewImpactReport(g, g.Nodes["mount:2"])); err != nil {
+		t.Fatalf("PrintImpactReport: %v", err)
+	}
+	if out.String() != "Nothing depends on /home.\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
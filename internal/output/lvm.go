@@ -0,0 +1,91 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/lvm"
+)
+
+// KindLVMReport is the kind of the LVM report envelope.
+const KindLVMReport = "LVMReport"
+
+// LVMReport is the versioned envelope around the LVM volume groups.
+type LVMReport struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// VolumeGroups are the volume groups, sorted by name.
+	VolumeGroups []lvm.VolumeGroup `json:"volumeGroups"`
+}
+
+// NewLVMReport wraps the volume groups in an LVMReport envelope.
+func NewLVMReport(vgs []lvm.VolumeGroup) LVMReport {
+	if vgs == nil {
+		vgs = []lvm.VolumeGroup{}
+	}
+	return LVMReport{APIVersion: APIVersion, Kind: KindLVMReport, VolumeGroups: vgs}
+}
+
+// PrintLVMReport writes the report in one of the ReportFormats. The table has one
+// section for the volume groups and one for the logical volumes.
+func PrintLVMReport(w io.Writer, format string, report LVMReport) error {
+	switch format {
+	case "", FormatTable:
+		return printLVMTables(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s", format, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// printLVMTables writes the VG table followed by the LV table. Hidden sub-LVs are
+// left out of the table, as lvs does by default.
+func printLVMTables(w io.Writer, report LVMReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VG\tSIZE\tFREE\tPVS\tLVS")
+	fmt.Fprintln(tw, "--\t----\t----\t---\t---")
+	for _, vg := range report.VolumeGroups {
+		pvs := make([]string, 0, len(vg.PhysicalVolumes))
+		for _, pv := range vg.PhysicalVolumes {
+			pvs = append(pvs, pv.Path)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n",
+			vg.Name,
+			valueOrDash(vg.Size),
+			valueOrDash(vg.Free),
+			valueOrDash(strings.Join(pvs, ",")),
+			len(vg.LogicalVolumes),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(tw, "LV\tVG\tTYPE\tSIZE\tDATA%\tMETA%\tACTIVE\tPATH\tDEVICES")
+	fmt.Fprintln(tw, "--\t--\t----\t----\t-----\t-----\t------\t----\t-------")
+	for _, vg := range report.VolumeGroups {
+		for _, lv := range vg.LogicalVolumes {
+			if lv.Hidden {
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%t\t%s\t%s\n",
+				lv.Name,
+				vg.Name,
+				valueOrDash(lv.Type),
+				valueOrDash(lv.Size),
+				valueOrDash(formatPercent(lv.DataPercent)),
+				valueOrDash(formatPercent(lv.MetadataPercent)),
+				lv.Active,
+				valueOrDash(lv.Path),
+				valueOrDash(strings.Join(lv.Devices, ",")),
+			)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,43 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/lvm"
+)
+
+func TestPrintLVMReport_Table(t *testing.T) {
+	data := 42.5
+	report := NewLVMReport([]lvm.VolumeGroup{{
+		Name: "vg0", Size: "1020 MiB", Free: "412 MiB",
+		PhysicalVolumes: []lvm.PhysicalVolume{{Name: "pv0", Path: "/dev/sda2"}},
+		LogicalVolumes: []lvm.LogicalVolume{
+			{Name: "pool", Type: "thin-pool", Size: "200 MiB", Active: true, Path: "/dev/mapper/vg0-pool", DataPercent: &data},
+			{Name: "pool_tdata", Type: "linear", Hidden: true, Active: true},
+		},
+	}})
+
+	var out bytes.Buffer
+	if err := PrintLVMReport(&out, FormatTable, report); err != nil {
+		t.Fatalf("PrintLVMReport: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"vg0  1020 MiB  412 MiB  /dev/sda2  2", "pool  vg0  thin-pool  200 MiB  42.50  -"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("table does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "pool_tdata") {
+		t.Errorf("hidden LV printed:\n%s", got)
+	}
+
+	out.Reset()
+	if err := PrintLVMReport(&out, FormatJSON, NewLVMReport(nil)); err != nil {
+		t.Fatalf("PrintLVMReport: %v", err)
+	}
+	if !strings.Contains(out.String(), `"volumeGroups": []`) {
+		t.Errorf("unexpected JSON:\n%s", out.String())
+	}
+}
@@ -0,0 +1,350 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/mounttable"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+// KindMountsReport is the kind of the mounts report envelope.
+const KindMountsReport = "MountsReport"
+
+// MountFormats lists the output formats of the mounts report: the scan formats and the tree.
+var MountFormats = []string{FormatTable, FormatWide, FormatTree, FormatJSON, FormatYAML, FormatCSV, FormatNDJSON}
+
+// MountsReport is the versioned envelope around the mount table.
+type MountsReport struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   MountMetadata `json:"metadata"`
+	// Mounts are the mounts matching the filter, in mount table order.
+	Mounts []mounttable.Mount `json:"mounts"`
+	// Diagnostics lists the mounts whose usage could not be read.
+	Diagnostics []service.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// MountMetadata describes where, when and how the mount table was read.
+type MountMetadata struct {
+	// Host is the hostname of the machine.
+	Host string `json:"host"`
+	// Timestamp is the time the mount table was read, in UTC.
+	Timestamp time.Time `json:"timestamp"`
+	// ToolVersion is the driver-scanner version that produced the report.
+	ToolVersion string `json:"toolVersion"`
+	// Filter is the filter the mounts were selected with.
+	Filter mounttable.Filter `json:"filter"`
+}
+
+// NewMountsReport wraps the mounts in a MountsReport envelope.
+func NewMountsReport(mounts []mounttable.Mount, diagnostics []service.Diagnostic, metadata MountMetadata) MountsReport {
+	if mounts == nil {
+		mounts = []mounttable.Mount{}
+	}
+	return MountsReport{
+		APIVersion:  APIVersion,
+		Kind:        KindMountsReport,
+		Metadata:    metadata,
+		Mounts:      mounts,
+		Diagnostics: diagnostics,
+	}
+}
+
+// MountColumn is a named mount field rendered by the table, tree and CSV output.
+type MountColumn struct {
+	// Name is the identifier used by --columns (e.g. "target").
+	Name string
+	// Header is the column title.
+	Header string
+	// Value extracts the column value from a mount. Empty means "not available".
+	Value func(m mounttable.Mount) string
+}
+
+// mountColumnRegistry lists every available mount column, named after the findmnt columns.
+var mountColumnRegistry = []MountColumn{
+	{Name: "id", Header: "ID", Value: func(m mounttable.Mount) string { return strconv.Itoa(m.ID) }},
+	{Name: "parent", Header: "PARENT", Value: func(m mounttable.Mount) string { return strconv.Itoa(m.ParentID) }},
+	{Name: "target", Header: "TARGET", Value: func(m mounttable.Mount) string { return m.Target }},
+	{Name: "source", Header: "SOURCE", Value: func(m mounttable.Mount) string { return m.Source }},
+	{Name: "fstype", Header: "FSTYPE", Value: func(m mounttable.Mount) string { return m.FSType }},
+	{Name: "fsroot", Header: "FSROOT", Value: func(m mounttable.Mount) string { return m.FSRoot }},
+	{Name: "majmin", Header: "MAJ:MIN", Value: func(m mounttable.Mount) string { return m.MajMin }},
+	{Name: "options", Header: "OPTIONS", Value: func(m mounttable.Mount) string { return m.Options }},
+	{Name: "super-options", Header: "SUPER-OPTIONS", Value: func(m mounttable.Mount) string { return m.SuperOptions }},
+	{Name: "propagation", Header: "PROPAGATION", Value: func(m mounttable.Mount) string { return m.Propagation }},
+	{Name: "peer", Header: "PEER", Value: func(m mounttable.Mount) string { return optionalID(m.PeerGroup) }},
+	{Name: "master", Header: "MASTER", Value: func(m mounttable.Mount) string { return optionalID(m.Master) }},
+	{Name: "size", Header: "SIZE", Value: usageBytes(func(u *device.FSUsage) uint64 { return u.TotalBytes })},
+	{Name: "used", Header: "USED", Value: usageBytes(func(u *device.FSUsage) uint64 { return u.UsedBytes })},
+	{Name: "avail", Header: "AVAIL", Value: usageBytes(func(u *device.FSUsage) uint64 { return u.AvailBytes })},
+	{Name: "use%", Header: "USE%", Value: func(m mounttable.Mount) string {
+		if m.Usage == nil || m.Usage.TotalBytes == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.0f%%", m.Usage.UsedPercent)
+	}},
+	{Name: "inodes", Header: "INODES", Value: usageCount(func(u *device.FSUsage) uint64 { return u.Inodes })},
+	{Name: "iused", Header: "IUSED", Value: usageCount(func(u *device.FSUsage) uint64 { return u.InodesUsed })},
+	{Name: "iuse%", Header: "IUSE%", Value: func(m mounttable.Mount) string {
+		if m.Usage == nil || m.Usage.Inodes == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.0f%%", m.Usage.InodesUsedPercent)
+	}},
+}
+
+// DefaultMountColumns are the columns of the mounts table, tree and CSV output.
+var DefaultMountColumns = mustMountColumns("target", "source", "fstype", "options", "size", "used", "avail", "use%")
+
+// WideMountColumns are the columns of the wide mounts table.
+var WideMountColumns = mustMountColumns("id", "parent", "target", "source", "fstype", "fsroot", "majmin",
+	"options", "super-options", "propagation", "size", "used", "avail", "use%", "iuse%")
+
+// propagationMountColumns are added to the columns by MountsOptions.Propagation.
+var propagationMountColumns = mustMountColumns("propagation", "peer", "master")
+
+// MountColumnNames returns the names of every available mount column.
+func MountColumnNames() []string {
+	names := make([]string, 0, len(mountColumnRegistry))
+	for _, col := range mountColumnRegistry {
+		names = append(names, col.Name)
+	}
+	return names
+}
+
+// ParseMountColumns parses a comma-separated list of mount column names (e.g. "target,source").
+func ParseMountColumns(spec string) ([]MountColumn, error) {
+	var columns []MountColumn
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		i := indexOfMountColumn(mountColumnRegistry, name)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown column %q, supported: %s", name, strings.Join(MountColumnNames(), ", "))
+		}
+		columns = append(columns, mountColumnRegistry[i])
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns given, supported: %s", strings.Join(MountColumnNames(), ", "))
+	}
+	return columns, nil
+}
+
+// addMountColumns returns columns followed by the extra columns it does not have yet.
+func addMountColumns(columns []MountColumn, extra []MountColumn) []MountColumn {
+	result := append([]MountColumn(nil), columns...)
+	for _, col := range extra {
+		if indexOfMountColumn(result, col.Name) < 0 {
+			result = append(result, col)
+		}
+	}
+	return result
+}
+
+// mustMountColumns resolves mount column names and panics on unknown names.
+func mustMountColumns(names ...string) []MountColumn {
+	columns, err := ParseMountColumns(strings.Join(names, ","))
+	if err != nil {
+		panic(err)
+	}
+	return columns
+}
+
+// MountsOptions customizes the column-based mounts output (table, wide, tree and CSV).
+type MountsOptions struct {
+	// Columns overrides the columns of the format. Nil uses the format defaults.
+	Columns []MountColumn
+	// NoHeaders omits the header rows.
+	NoHeaders bool
+	// Propagation adds the propagation, peer group and master columns.
+	Propagation bool
+}
+
+// columns returns the selected columns, or defaults, with the propagation columns if requested.
+func (o MountsOptions) columns(defaults []MountColumn) []MountColumn {
+	columns := o.Columns
+	if len(columns) == 0 {
+		columns = defaults
+	}
+	if o.Propagation {
+		columns = addMountColumns(columns, propagationMountColumns)
+	}
+	return columns
+}
+
+// PrintMountsReport writes the report in one of the MountFormats or a template format.
+// The Go templates iterate over the mounts, JSONPath expressions apply to the envelope.
+func PrintMountsReport(w io.Writer, format string, report MountsReport, opts MountsOptions) error {
+	if printer, ok, err := newTemplatePrinter(format); ok {
+		if err != nil {
+			return err
+		}
+		return printer.execute(w, report, report.Mounts)
+	}
+
+	switch format {
+	case "", FormatTable:
+		return printMountsTable(w, report.Mounts, opts.columns(DefaultMountColumns), opts.NoHeaders)
+	case FormatWide:
+		return printMountsTable(w, report.Mounts, opts.columns(WideMountColumns), opts.NoHeaders)
+	case FormatTree:
+		return printMountsTree(w, report.Mounts, opts.columns(DefaultMountColumns), opts.NoHeaders)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	case FormatCSV:
+		return printMountsCSV(w, report.Mounts, opts.columns(DefaultMountColumns), opts.NoHeaders)
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, m := range report.Mounts {
+			if err := encoder.Encode(m); err != nil {
+				return fmt.Errorf("failed to encode NDJSON output: %w", err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s, %s", format,
+			strings.Join(MountFormats, ", "), strings.Join(TemplateFormats, "=..., ")+"=...")
+	}
+}
+
+// printMountsTable writes one row per mount.
+func printMountsTable(w io.Writer, mounts []mounttable.Mount, columns []MountColumn, noHeaders bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if !noHeaders {
+		writeMountHeaders(tw, columns)
+	}
+	for _, m := range mounts {
+		fmt.Fprintln(tw, strings.Join(mountRow(m, columns, ""), "\t"))
+	}
+	return tw.Flush()
+}
+
+// printMountsTree writes the mounts as a tree by parent ID, like findmnt. The target
+// column, or the first column without one, carries the tree drawing.
+func printMountsTree(w io.Writer, mounts []mounttable.Mount, columns []MountColumn, noHeaders bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if !noHeaders {
+		writeMountHeaders(tw, columns)
+	}
+	var walk func(nodes []*mounttable.Node, indent string)
+	walk = func(nodes []*mounttable.Node, indent string) {
+		for i, node := range nodes {
+			branch, next := "├─", "│ "
+			if i == len(nodes)-1 {
+				branch, next = "└─", "  "
+			}
+			if indent == "" && len(nodes) == 1 {
+				branch, next = "", ""
+			}
+			fmt.Fprintln(tw, strings.Join(mountRow(node.Mount, columns, indent+branch), "\t"))
+			walk(node.Children, indent+next)
+		}
+	}
+	walk(mounttable.Tree(mounts), "")
+	return tw.Flush()
+}
+
+// printMountsCSV writes one CSV record per mount. Unavailable values are empty.
+func printMountsCSV(w io.Writer, mounts []mounttable.Mount, columns []MountColumn, noHeaders bool) error {
+	cw := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	if !noHeaders {
+		for i, col := range columns {
+			record[i] = col.Header
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+	for _, m := range mounts {
+		for i, col := range columns {
+			record[i] = col.Value(m)
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMountHeaders writes the header and underline rows.
+func writeMountHeaders(w io.Writer, columns []MountColumn) {
+	headers := make([]string, len(columns))
+	underlines := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.Header
+		underlines[i] = strings.Repeat("-", len(col.Header))
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	fmt.Fprintln(w, strings.Join(underlines, "\t"))
+}
+
+// mountRow returns the values of a mount with "-" for unavailable ones. prefix is
+// prepended to the target column, or to the first column if there is none.
+func mountRow(m mounttable.Mount, columns []MountColumn, prefix string) []string {
+	treeColumn := 0
+	if i := indexOfMountColumn(columns, "target"); i >= 0 {
+		treeColumn = i
+	}
+	values := make([]string, len(columns))
+	for i, col := range columns {
+		values[i] = valueOrDash(col.Value(m))
+		if i == treeColumn {
+			values[i] = prefix + values[i]
+		}
+	}
+	return values
+}
+
+// indexOfMountColumn returns the index of the named column, or -1.
+func indexOfMountColumn(columns []MountColumn, name string) int {
+	for i, col := range columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// usageBytes returns a column value formatting a byte count of the usage.
+func usageBytes(value func(u *device.FSUsage) uint64) func(m mounttable.Mount) string {
+	return func(m mounttable.Mount) string {
+		if m.Usage == nil || m.Usage.TotalBytes == 0 {
+			return ""
+		}
+		return humanize.IBytes(value(m.Usage))
+	}
+}
+
+// usageCount returns a column value formatting an inode count of the usage.
+func usageCount(value func(u *device.FSUsage) uint64) func(m mounttable.Mount) string {
+	return func(m mounttable.Mount) string {
+		if m.Usage == nil || m.Usage.Inodes == 0 {
+			return ""
+		}
+		return strconv.FormatUint(value(m.Usage), 10)
+	}
+}
+
+// optionalID formats a peer group ID, empty for zero.
+func optionalID(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,141 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/mounttable"
+)
+
+func testMountsReport() MountsReport {
+	return NewMountsReport([]mounttable.Mount{
+		{ID: 21, ParentID: 1, Target: "/", Source: "/dev/sda2", FSType: "ext4", Options: "rw,relatime",
+			Propagation: mounttable.PropagationShared, PeerGroup: 1,
+			Usage: &device.FSUsage{TotalBytes: 100 << 30, UsedBytes: 40 << 30, AvailBytes: 55 << 30, UsedPercent: 43}},
+		{ID: 22, ParentID: 21, Target: "/proc", Source: "proc", FSType: "proc", Options: "rw,nosuid",
+			Propagation: mounttable.PropagationPrivate},
+		{ID: 30, ParentID: 21, Target: "/mnt/nfs", Source: "server:/export", FSType: "nfs4", Options: "rw",
+			Propagation: mounttable.PropagationSlave, Master: 1},
+		{ID: 31, ParentID: 30, Target: "/mnt/nfs/sub", Source: "tmpfs", FSType: "tmpfs", Options: "rw",
+			Propagation: mounttable.PropagationPrivate},
+	}, nil, MountMetadata{Host: "node-1"})
+}
+
+func TestPrintMountsReport_Table(t *testing.T) {
+	var out bytes.Buffer
+	if err := PrintMountsReport(&out, FormatTable, testMountsReport(), MountsOptions{}); err != nil {
+		t.Fatalf("PrintMountsReport: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected 6 lines, got %d:\n%s", len(lines), out.String())
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "TARGET SOURCE FSTYPE OPTIONS SIZE USED AVAIL USE%" {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "/ /dev/sda2 ext4 rw,relatime 100 GiB 40 GiB 55 GiB 43%" {
+		t.Errorf("unexpected root row %q", lines[2])
+	}
+	if fields := strings.Fields(lines[3]); strings.Join(fields, " ") != "/proc proc proc rw,nosuid - - - -" {
+		t.Errorf("unexpected proc row %q", lines[3])
+	}
+}
+
+func TestPrintMountsReport_Tree(t *testing.T) {
+	var out bytes.Buffer
+	opts := MountsOptions{Columns: mustMountColumns("target", "fstype"), NoHeaders: true}
+	if err := PrintMountsReport(&out, FormatTree, testMountsReport(), opts); err != nil {
+		t.Fatalf("PrintMountsReport: %v", err)
+	}
+	want := `/                 ext4
+├─/proc           proc
+└─/mnt/nfs        nfs4
+  └─/mnt/nfs/sub  tmpfs
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestPrintMountsReport_Propagation(t *testing.T) {
+	var out bytes.Buffer
+	opts := MountsOptions{Columns: mustMountColumns("target", "propagation"), Propagation: true}
+	if err := PrintMountsReport(&out, FormatTable, testMountsReport(), opts); err != nil {
+		t.Fatalf("PrintMountsReport: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "TARGET PROPAGATION PEER MASTER" {
+		t.Errorf("the propagation column must not be repeated, got %q", lines[0])
+	}
+	if fields := strings.Fields(lines[4]); strings.Join(fields, " ") != "/mnt/nfs slave - 1" {
+		t.Errorf("unexpected slave row %q", lines[4])
+	}
+}
+
+func TestPrintMountsReport_Formats(t *testing.T) {
+	report := testMountsReport()
+
+	var out bytes.Buffer
+	if err := PrintMountsReport(&out, FormatJSON, report, MountsOptions{}); err != nil {
+		t.Fatalf("PrintMountsReport json: %v", err)
+	}
+	var decoded MountsReport
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded.Kind != KindMountsReport || len(decoded.Mounts) != 4 || decoded.Mounts[0].Usage == nil {
+		t.Errorf("unexpected report %+v", decoded)
+	}
+
+	out.Reset()
+	if err := PrintMountsReport(&out, FormatCSV, report, MountsOptions{Columns: mustMountColumns("target", "size")}); err != nil {
+		t.Fatalf("PrintMountsReport csv: %v", err)
+	}
+	if want := "TARGET,SIZE\n/,100 GiB\n/proc,\n/mnt/nfs,\n/mnt/nfs/sub,\n"; out.String() != want {
+		t.Errorf("got CSV %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := PrintMountsReport(&out, FormatNDJSON, report, MountsOptions{}); err != nil {
+		t.Fatalf("PrintMountsReport ndjson: %v", err)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 4 {
+		t.Errorf("expected 4 NDJSON lines, got %d", lines)
+	}
+
+	out.Reset()
+	if err := PrintMountsReport(&out, `go-template={{range .}}{{.Target}} {{.FSType}}{{"\n"}}{{end}}`, report, MountsOptions{}); err != nil {
+		t.Fatalf("PrintMountsReport go-template: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "/ ext4\n/proc proc\n") {
+		t.Errorf("unexpected template output %q", out.String())
+	}
+
+	out.Reset()
+	if err := PrintMountsReport(&out, "jsonpath={.metadata.host}", report, MountsOptions{}); err != nil {
+		t.Fatalf("PrintMountsReport jsonpath: %v", err)
+	}
+	if out.String() != "node-1" {
+		t.Errorf("unexpected jsonpath output %q", out.String())
+	}
+
+	if err := PrintMountsReport(&out, "xml", report, MountsOptions{}); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestParseMountColumns(t *testing.T) {
+	columns, err := ParseMountColumns("target, USE%,iuse%")
+	if err != nil {
+		t.Fatalf("ParseMountColumns: %v", err)
+	}
+	if len(columns) != 3 || columns[1].Name != "use%" {
+		t.Errorf("unexpected columns %+v", columns)
+	}
+	if _, err := ParseMountColumns("target,bogus"); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+}
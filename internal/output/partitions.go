@@ -0,0 +1,94 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/parttable"
+)
+
+// KindPartitionsReport is the kind of the partitions report envelope.
+const KindPartitionsReport = "PartitionsReport"
+
+// PartitionsReport is the versioned envelope around the partition table of a disk.
+type PartitionsReport struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Device is the disk device node or image file the table was read from.
+	Device string `json:"device"`
+	// Table is the partition table.
+	Table parttable.Table `json:"table"`
+}
+
+// NewPartitionsReport wraps the partition table read from device in a PartitionsReport envelope.
+func NewPartitionsReport(device string, table parttable.Table) PartitionsReport {
+	if table.Partitions == nil {
+		table.Partitions = []parttable.Partition{}
+	}
+	return PartitionsReport{APIVersion: APIVersion, Kind: KindPartitionsReport, Device: device, Table: table}
+}
+
+// PrintPartitionsReport writes the report in one of the ReportFormats. The table
+// starts with the disk header, followed by one row per partition.
+func PrintPartitionsReport(w io.Writer, format string, report PartitionsReport) error {
+	switch format {
+	case "", FormatTable:
+		return printPartitionsTable(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s", format, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// printPartitionsTable writes the disk header followed by one row per partition.
+func printPartitionsTable(w io.Writer, report PartitionsReport) error {
+	table := report.Table
+	fmt.Fprintf(w, "Disk: %s\n", report.Device)
+	fmt.Fprintf(w, "Table type: %s\n", table.Type)
+	fmt.Fprintf(w, "Disk identifier: %s\n", table.DiskGUID)
+	fmt.Fprintf(w, "Sector size: %d\n", table.SectorSize)
+	if table.Type == parttable.TypeGPT {
+		fmt.Fprintf(w, "Usable LBAs: %d-%d\n", table.FirstUsableLBA, table.LastUsableLBA)
+		fmt.Fprintf(w, "Primary header: %s\n", validOrCorrupt(table.PrimaryHeaderValid))
+		fmt.Fprintf(w, "Backup header: %s\n", validOrCorrupt(table.BackupHeaderValid))
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NUMBER\tSTART\tEND\tSIZE\tTYPE\tTYPE NAME\tNAME\tGUID\tFLAGS")
+	fmt.Fprintln(tw, "------\t-----\t---\t----\t----\t---------\t----\t----\t-----")
+	for _, p := range table.Partitions {
+		number := strconv.Itoa(p.Number)
+		if p.Logical {
+			number += " (logical)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			number,
+			p.StartLBA,
+			p.EndLBA,
+			humanize.IBytes(p.SizeBytes),
+			p.TypeGUID,
+			p.TypeName,
+			valueOrDash(p.Name),
+			p.GUID,
+			valueOrDash(strings.Join(p.Flags, ",")),
+		)
+	}
+	return tw.Flush()
+}
+
+// validOrCorrupt describes a GPT header validity flag.
+func validOrCorrupt(valid bool) string {
+	if valid {
+		return "valid"
+	}
+	return "corrupt or missing"
+}
@@ -0,0 +1,53 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/parttable"
+)
+
+func TestPrintPartitionsReport(t *testing.T) {
+	report := NewPartitionsReport("/dev/sda", parttable.Table{
+		Type: parttable.TypeGPT, DiskGUID: "8a1f2c3d-0000-4000-8000-000000000001", SectorSize: 512,
+		FirstUsableLBA: 34, LastUsableLBA: 2097118, PrimaryHeaderValid: true,
+		Partitions: []parttable.Partition{
+			{Number: 1, StartLBA: 2048, EndLBA: 1050623, SizeBytes: 512 << 20, TypeName: "EFI System", Name: "esp"},
+			{Number: 5, StartLBA: 1050624, EndLBA: 2097118, SizeBytes: 1 << 20, TypeName: "Linux filesystem", Logical: true,
+				Flags: []string{"legacy-bios-bootable"}},
+		},
+	})
+
+	var out bytes.Buffer
+	if err := PrintPartitionsReport(&out, FormatTable, report); err != nil {
+		t.Fatalf("PrintPartitionsReport: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"Disk: /dev/sda\n",
+		"Usable LBAs: 34-2097118\n",
+		"Backup header: corrupt or missing\n",
+		"1            2048     1050623  512 MiB",
+		"5 (logical)",
+		"legacy-bios-bootable",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("table does not contain %q:\n%s", want, got)
+		}
+	}
+
+	out.Reset()
+	if err := PrintPartitionsReport(&out, FormatJSON, NewPartitionsReport("disk.img", parttable.Table{Type: parttable.TypeDOS})); err != nil {
+		t.Fatalf("PrintPartitionsReport: %v", err)
+	}
+	for _, want := range []string{`"kind": "PartitionsReport"`, `"device": "disk.img"`, `"partitions": []`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("JSON does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := PrintPartitionsReport(&out, "csv", report); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
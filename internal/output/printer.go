@@ -0,0 +1,130 @@
+// Package output renders scan results in the formats supported by the scan command.
+package output
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+// Supported output formats.
+const (
+	// FormatTable is the human-readable table (default).
+	FormatTable = "table"
+	// FormatWide is the table with additional columns (label, major:minor, model, driver, every mount point).
+	FormatWide = "wide"
+	// FormatJSON is the versioned JSON envelope.
+	FormatJSON = "json"
+	// FormatYAML is the versioned envelope encoded as YAML.
+	FormatYAML = "yaml"
+	// FormatCSV is one CSV record per device with a header row.
+	FormatCSV = "csv"
+	// FormatNDJSON is one JSON device object per line.
+	FormatNDJSON = "ndjson"
+)
+
+// Formats lists the supported output formats in help order.
+var Formats = []string{FormatTable, FormatWide, FormatJSON, FormatYAML, FormatCSV, FormatNDJSON}
+
+// Envelope identifiers written in the JSON and YAML output.
+const (
+	// APIVersion is the version of the scan report schema.
+	APIVersion = "driver-scanner/v1"
+	// KindScanReport is the kind of the scan report envelope.
+	KindScanReport = "ScanReport"
+)
+
+// ScanReport is the versioned envelope around the scanned devices.
+type ScanReport struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Metadata   ScanMetadata         `json:"metadata"`
+	Devices    []device.BlockDevice `json:"devices"`
+	// Diagnostics lists the problems met during the scan, worst first.
+	Diagnostics []service.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// ScanMetadata describes where, when and how a scan was run.
+type ScanMetadata struct {
+	// Host is the hostname of the scanned machine.
+	Host string `json:"host"`
+	// Timestamp is the time the scan completed, in UTC.
+	Timestamp time.Time `json:"timestamp"`
+	// ToolVersion is the driver-scanner version that produced the report.
+	ToolVersion string `json:"toolVersion"`
+	// Filter is the filter the devices were selected with.
+	Filter service.ScanFilter `json:"filter"`
+}
+
+// NewScanReport wraps the devices and diagnostics of a scan in a ScanReport envelope.
+func NewScanReport(result service.ScanResult, metadata ScanMetadata) ScanReport {
+	devices := result.Devices
+	if devices == nil {
+		devices = []device.BlockDevice{}
+	}
+	diagnostics := slices.Clone(result.Diagnostics)
+	slices.SortStableFunc(diagnostics, func(a, b service.Diagnostic) int {
+		return cmp.Compare(b.Severity, a.Severity)
+	})
+	return ScanReport{
+		APIVersion:  APIVersion,
+		Kind:        KindScanReport,
+		Metadata:    metadata,
+		Devices:     devices,
+		Diagnostics: diagnostics,
+	}
+}
+
+// Printer renders a scan report to a writer.
+type Printer interface {
+	// Print writes the report to w.
+	Print(w io.Writer, report ScanReport) error
+}
+
+// Options customizes the column-based printers (table, wide and CSV).
+type Options struct {
+	// Columns overrides the columns of the format. Nil uses the format defaults.
+	Columns []Column
+	// NoHeaders omits the header rows.
+	NoHeaders bool
+}
+
+// NewPrinter returns the Printer for the given format. An empty format selects the table.
+// Template formats carry their template after "=" (e.g. "go-template={{range .}}{{.Path}}{{end}}").
+func NewPrinter(format string, opts Options) (Printer, error) {
+	if printer, ok, err := newTemplatePrinter(format); ok {
+		return printer, err
+	}
+
+	switch format {
+	case "", FormatTable:
+		return &TablePrinter{Columns: columnsOrDefault(opts.Columns, DefaultColumns), NoHeaders: opts.NoHeaders}, nil
+	case FormatWide:
+		return &TablePrinter{Columns: columnsOrDefault(opts.Columns, WideColumns), NoHeaders: opts.NoHeaders}, nil
+	case FormatJSON:
+		return &JSONPrinter{}, nil
+	case FormatYAML:
+		return &YAMLPrinter{}, nil
+	case FormatCSV:
+		return &CSVPrinter{Columns: columnsOrDefault(opts.Columns, DefaultColumns), NoHeaders: opts.NoHeaders}, nil
+	case FormatNDJSON:
+		return &NDJSONPrinter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %q, supported: %s, %s", format,
+			strings.Join(Formats, ", "), strings.Join(TemplateFormats, "=..., ")+"=...")
+	}
+}
+
+// columnsOrDefault returns columns, or defaults if no columns were selected.
+func columnsOrDefault(columns, defaults []Column) []Column {
+	if len(columns) == 0 {
+		return defaults
+	}
+	return columns
+}
@@ -0,0 +1,181 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/service"
+)
+
+func testReport() ScanReport {
+	devices := []device.BlockDevice{
+		{
+			Name: "sda1", Path: "/dev/sda1", UUID: "1111-2222", FSType: "vfat", Type: "part",
+			PartitionTypeName: "EFI System", MountPoint: "/boot/efi",
+			Mounts:     []device.Mount{{MountPoint: "/boot/efi", Root: "/", MountID: 7}},
+			DeviceSize: "512 MiB", DeviceSizeBytes: 512 << 20,
+		},
+		{Name: "sdb", Path: "/dev/sdb", Type: "disk", Serial: "S1,2"},
+	}
+	diagnostics := []service.Diagnostic{
+		{Provider: "probe", Device: "/dev/sdb", Severity: service.SeverityWarning, Err: errors.New("short read")},
+		{Provider: "mountinfo", Severity: service.SeverityError, Err: errors.New("permission denied")},
+	}
+	return NewScanReport(service.ScanResult{Devices: devices, Diagnostics: diagnostics}, ScanMetadata{
+		Host:        "node-1",
+		Timestamp:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		ToolVersion: "1.2.3",
+		Filter:      service.ScanFilter{FSTypes: []string{"vfat"}},
+	})
+}
+
+func TestJSONPrinter_Envelope(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&JSONPrinter{}).Print(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Host        string `json:"host"`
+			Timestamp   string `json:"timestamp"`
+			ToolVersion string `json:"toolVersion"`
+			Filter      struct {
+				FSTypes []string `json:"fstypes"`
+			} `json:"filter"`
+		} `json:"metadata"`
+		Devices     []map[string]any    `json:"devices"`
+		Diagnostics []map[string]string `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if decoded.APIVersion != APIVersion || decoded.Kind != KindScanReport {
+		t.Errorf("unexpected envelope: apiVersion=%q kind=%q", decoded.APIVersion, decoded.Kind)
+	}
+	if decoded.Metadata.Host != "node-1" || decoded.Metadata.ToolVersion != "1.2.3" ||
+		decoded.Metadata.Timestamp != "2025-01-01T00:00:00Z" || len(decoded.Metadata.Filter.FSTypes) != 1 {
+		t.Errorf("unexpected metadata: %+v", decoded.Metadata)
+	}
+	if len(decoded.Devices) != 2 || decoded.Devices[0]["path"] != "/dev/sda1" {
+		t.Errorf("unexpected devices: %+v", decoded.Devices)
+	}
+	wantDiagnostics := []map[string]string{
+		{"provider": "mountinfo", "severity": "error", "error": "permission denied"},
+		{"provider": "probe", "device": "/dev/sdb", "severity": "warning", "error": "short read"},
+	}
+	if !reflect.DeepEqual(decoded.Diagnostics, wantDiagnostics) {
+		t.Errorf("unexpected diagnostics (worst first): %+v", decoded.Diagnostics)
+	}
+}
+
+func TestYAMLPrinter_UsesJSONFieldNames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&YAMLPrinter{}).Print(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"apiVersion: driver-scanner/v1", "kind: ScanReport", "host: node-1", "path: /dev/sda1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("YAML output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestCSVPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&CSVPrinter{Columns: DefaultColumns}).Print(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "UUID,SERIAL,DEVICE,FSTYPE,TYPE,PARTTYPE,MOUNTPOINT,SIZE,FS SIZE,FS AVAIL\n" +
+		"1111-2222,,/dev/sda1,vfat,part,EFI System,/boot/efi,512 MiB,,\n" +
+		",\"S1,2\",/dev/sdb,,disk,,,,,\n"
+	if buf.String() != want {
+		t.Errorf("unexpected CSV output:\ngot:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestNDJSONPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&NDJSONPrinter{}).Print(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var dev device.BlockDevice
+		if err := json.Unmarshal([]byte(line), &dev); err != nil {
+			t.Errorf("line is not a JSON device: %v\n%s", err, line)
+		}
+	}
+}
+
+func TestTablePrinter(t *testing.T) {
+	var buf bytes.Buffer
+	columns := []Column{DefaultColumns[2], DefaultColumns[3]}
+	if err := (&TablePrinter{Columns: columns}).Print(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "DEVICE     FSTYPE\n" +
+		"------     ------\n" +
+		"/dev/sda1  vfat\n" +
+		"/dev/sdb   -\n"
+	if buf.String() != want {
+		t.Errorf("unexpected table output:\ngot:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestNewPrinter_UnknownFormat(t *testing.T) {
+	if _, err := NewPrinter("xml", Options{}); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestNewPrinter_ColumnsAndNoHeaders(t *testing.T) {
+	columns, err := ParseColumns("path,fstype")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	printer, err := NewPrinter(FormatCSV, Options{Columns: columns, NoHeaders: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Print(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "/dev/sda1,vfat\n/dev/sdb,\n"; buf.String() != want {
+		t.Errorf("unexpected CSV output:\ngot:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestNewPrinter_Wide(t *testing.T) {
+	printer, err := NewPrinter(FormatWide, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Print(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"MAJ:MIN", "LABEL", "MODEL", "MOUNTPOINTS"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("wide output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
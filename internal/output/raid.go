@@ -0,0 +1,114 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/md"
+)
+
+// KindRAIDReport is the kind of the RAID report envelope.
+const KindRAIDReport = "RAIDReport"
+
+// RAIDReport is the versioned envelope around the md arrays.
+type RAIDReport struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Arrays are the md arrays, sorted by name.
+	Arrays []md.Array `json:"arrays"`
+	// Degraded lists the paths of the degraded arrays.
+	Degraded []string `json:"degraded"`
+	// Inactive lists the paths of the arrays assembled but not running.
+	Inactive []string `json:"inactive"`
+}
+
+// NewRAIDReport wraps the arrays in a RAIDReport envelope.
+func NewRAIDReport(arrays []md.Array) RAIDReport {
+	if arrays == nil {
+		arrays = []md.Array{}
+	}
+	degraded, inactive := []string{}, []string{}
+	for _, array := range arrays {
+		if array.Degraded {
+			degraded = append(degraded, array.Path)
+		}
+		if array.Inactive() {
+			inactive = append(inactive, array.Path)
+		}
+	}
+	return RAIDReport{APIVersion: APIVersion, Kind: KindRAIDReport, Arrays: arrays, Degraded: degraded, Inactive: inactive}
+}
+
+// PrintRAIDReport writes the report in one of the ReportFormats.
+func PrintRAIDReport(w io.Writer, format string, report RAIDReport) error {
+	switch format {
+	case "", FormatTable:
+		return printRAIDTable(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s", format, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// printRAIDTable writes one row per array, like /proc/mdstat condensed.
+func printRAIDTable(w io.Writer, report RAIDReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ARRAY\tLEVEL\tSTATE\tDISKS\tHEALTH\tSYNC\tMISMATCH\tMEMBERS")
+	fmt.Fprintln(tw, "-----\t-----\t-----\t-----\t------\t----\t--------\t-------")
+	for _, array := range report.Arrays {
+		disks := ""
+		if array.RaidDisks > 0 {
+			disks = fmt.Sprintf("%d/%d", array.ActiveDisks, array.RaidDisks)
+		}
+		health := "ok"
+		switch {
+		case array.Inactive():
+			health = "INACTIVE"
+		case array.Degraded:
+			health = "DEGRADED"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			array.Path,
+			valueOrDash(array.Level),
+			valueOrDash(array.State),
+			valueOrDash(disks),
+			health,
+			valueOrDash(syncSummary(array.RAIDArray)),
+			array.MismatchCount,
+			valueOrDash(membersSummary(array.Members)),
+		)
+	}
+	return tw.Flush()
+}
+
+// syncSummary formats the running sync (e.g. "recover 8.5% 7.6min"), or "pending"
+// when it waits for another array.
+func syncSummary(array device.RAIDArray) string {
+	if array.SyncAction == "" {
+		return ""
+	}
+	if array.SyncProgress == nil {
+		return array.SyncAction + " pending"
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %.1f%% %s", array.SyncAction, *array.SyncProgress, array.SyncFinish))
+}
+
+// membersSummary lists the member devices with their role when not active
+// (e.g. "/dev/sda1,/dev/sdb1(faulty)").
+func membersSummary(members []device.RAIDMember) string {
+	parts := make([]string, 0, len(members))
+	for _, m := range members {
+		if m.Role == device.RAIDRoleActive {
+			parts = append(parts, m.Device)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s(%s)", m.Device, m.Role))
+		}
+	}
+	return strings.Join(parts, ",")
+}
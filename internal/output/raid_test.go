@@ -0,0 +1,47 @@
+package output
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/device/md"
+)
+
+func TestPrintRAIDReport_Table(t *testing.T) {
+	progress := 42.0
+	report := NewRAIDReport([]md.Array{
+		{Name: "md0", Path: "/dev/md0", RAIDArray: device.RAIDArray{
+			Level: "raid1", State: "clean", RaidDisks: 2, ActiveDisks: 1, Degraded: true,
+			SyncAction: "recover", SyncProgress: &progress, SyncFinish: "3.1min",
+			Members: []device.RAIDMember{
+				{Array: "/dev/md0", Device: "/dev/sda1", Role: device.RAIDRoleActive},
+				{Array: "/dev/md0", Device: "/dev/sdb1", Role: device.RAIDRoleRebuilding},
+			},
+		}},
+		{Name: "md1", Path: "/dev/md1", RAIDArray: device.RAIDArray{Level: "raid0", State: "clean"}},
+		{Name: "md127", Path: "/dev/md127", RAIDArray: device.RAIDArray{State: "inactive"}},
+	})
+	if !reflect.DeepEqual(report.Degraded, []string{"/dev/md0"}) {
+		t.Errorf("unexpected degraded arrays %v", report.Degraded)
+	}
+	if !reflect.DeepEqual(report.Inactive, []string{"/dev/md127"}) {
+		t.Errorf("unexpected inactive arrays %v", report.Inactive)
+	}
+
+	var out bytes.Buffer
+	if err := PrintRAIDReport(&out, FormatTable, report); err != nil {
+		t.Fatalf("PrintRAIDReport: %v", err)
+	}
+	for _, want := range []string{
+		"/dev/md0    raid1  clean     1/2    DEGRADED  recover 42.0% 3.1min  0         /dev/sda1,/dev/sdb1(rebuilding)",
+		"/dev/md1    raid0  clean     -      ok        -",
+		"/dev/md127  -      inactive  -      INACTIVE  -",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
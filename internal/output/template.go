@@ -0,0 +1,163 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Template output formats. They take the template after "=" (e.g. "jsonpath={.devices[*].path}").
+const (
+	// FormatGoTemplate executes an inline Go template against the device list.
+	FormatGoTemplate = "go-template"
+	// FormatGoTemplateFile executes a Go template read from a file.
+	FormatGoTemplateFile = "go-template-file"
+	// FormatJSONPath evaluates an inline JSONPath expression against the JSON report.
+	FormatJSONPath = "jsonpath"
+	// FormatJSONPathFile evaluates a JSONPath expression read from a file.
+	FormatJSONPathFile = "jsonpath-file"
+)
+
+// TemplateFormats lists the template output formats in help order.
+var TemplateFormats = []string{FormatGoTemplate, FormatGoTemplateFile, FormatJSONPath, FormatJSONPathFile}
+
+// newTemplatePrinter parses a "<format>=<template>" output specification.
+// It returns ok=false if format is not a template format.
+func newTemplatePrinter(spec string) (printer templatePrinter, ok bool, err error) {
+	format, text, ok, err := parseTemplateSpec(spec)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	switch format {
+	case FormatGoTemplate, FormatGoTemplateFile:
+		printer, err = NewGoTemplatePrinter(text)
+	default:
+		printer, err = NewJSONPathPrinter(text)
+	}
+	return printer, true, err
+}
+
+// parseTemplateSpec splits a "<format>=<template>" output specification and reads the
+// template of the file formats. It returns ok=false if format is not a template format.
+func parseTemplateSpec(spec string) (format, text string, ok bool, err error) {
+	format, arg, _ := strings.Cut(spec, "=")
+
+	switch format {
+	case FormatGoTemplate, FormatJSONPath:
+		text = arg
+	case FormatGoTemplateFile, FormatJSONPathFile:
+		if arg == "" {
+			return format, "", true, fmt.Errorf("%s output requires a file name, e.g. -o %s=./devices.tmpl", format, format)
+		}
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return format, "", true, fmt.Errorf("failed to read %s template: %w", format, err)
+		}
+		text = string(data)
+	default:
+		return format, "", false, nil
+	}
+
+	if text == "" {
+		return format, "", true, fmt.Errorf("%s output requires a template, e.g. -o %s='...'", format, format)
+	}
+	return format, text, true, nil
+}
+
+// templatePrinter is a Printer that can also render reports other than the scan report.
+type templatePrinter interface {
+	Printer
+	// execute renders a report other than the scan report. report is the envelope and
+	// items the list the Go template iterates over.
+	execute(w io.Writer, report, items any) error
+}
+
+// GoTemplatePrinter renders the device list with a Go text/template.
+// The template data is the []device.BlockDevice slice, so fields use Go names (e.g. {{.Path}}).
+type GoTemplatePrinter struct {
+	template *template.Template
+}
+
+// NewGoTemplatePrinter parses a Go template.
+func NewGoTemplatePrinter(text string) (*GoTemplatePrinter, error) {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go-template: %w", err)
+	}
+	return &GoTemplatePrinter{template: tmpl}, nil
+}
+
+// Print executes the template against the report devices.
+func (p *GoTemplatePrinter) Print(w io.Writer, report ScanReport) error {
+	return p.execute(w, report, report.Devices)
+}
+
+// execute executes the template against items.
+func (p *GoTemplatePrinter) execute(w io.Writer, _, items any) error {
+	// Render to a buffer first so a failing template does not leave partial output.
+	var buf bytes.Buffer
+	if err := p.template.Execute(&buf, items); err != nil {
+		return fmt.Errorf("failed to execute go-template: %w", err)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// JSONPathPrinter renders the report with a kubectl-style JSONPath expression.
+// The expression is evaluated against the JSON envelope, so fields use JSON names
+// (e.g. {.devices[*].path}).
+type JSONPathPrinter struct {
+	jsonPath *jsonpath.JSONPath
+}
+
+// NewJSONPathPrinter parses a JSONPath expression. As in kubectl, the braces are
+// optional for a single expression ("devices[*].path" is "{.devices[*].path}").
+func NewJSONPathPrinter(expr string) (*JSONPathPrinter, error) {
+	jp := jsonpath.New("output").AllowMissingKeys(true)
+	if err := jp.Parse(relaxedJSONPath(expr)); err != nil {
+		return nil, fmt.Errorf("invalid jsonpath %q: %w", expr, err)
+	}
+	return &JSONPathPrinter{jsonPath: jp}, nil
+}
+
+// Print evaluates the expression against the JSON form of the report.
+func (p *JSONPathPrinter) Print(w io.Writer, report ScanReport) error {
+	return p.execute(w, report, nil)
+}
+
+// execute evaluates the expression against the JSON form of report.
+func (p *JSONPathPrinter) execute(w io.Writer, report, _ any) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report for jsonpath: %w", err)
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return fmt.Errorf("failed to decode report for jsonpath: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := p.jsonPath.Execute(&buf, generic); err != nil {
+		return fmt.Errorf("failed to execute jsonpath: %w", err)
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// relaxedJSONPath wraps a bare expression in braces and adds the leading dot,
+// turning "devices[*].path" into "{.devices[*].path}".
+func relaxedJSONPath(expr string) string {
+	if strings.Contains(expr, "{") {
+		return expr
+	}
+	if !strings.HasPrefix(expr, ".") {
+		expr = "." + expr
+	}
+	return "{" + expr + "}"
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewPrinter_GoTemplate(t *testing.T) {
+	printer, err := NewPrinter(`go-template={{range .}}{{.Path}} {{.UUID}}{{"\n"}}{{end}}`, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Print(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "/dev/sda1 1111-2222\n/dev/sdb \n"; buf.String() != want {
+		t.Errorf("unexpected output: got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNewPrinter_GoTemplateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.tmpl")
+	if err := os.WriteFile(path, []byte(`{{len .}}`), 0o600); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	printer, err := NewPrinter("go-template-file="+path, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Print(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "2" {
+		t.Errorf("unexpected output: got %q, want %q", buf.String(), "2")
+	}
+}
+
+func TestNewPrinter_JSONPath(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{spec: "jsonpath={.devices[*].path}", want: "/dev/sda1 /dev/sdb"},
+		{spec: "jsonpath=devices[0].mounts[0].mountpoint", want: "/boot/efi"},
+		{spec: `jsonpath={range .devices[*]}{.name}={.type}{"\n"}{end}`, want: "sda1=part\nsdb=disk\n"},
+		{spec: "jsonpath={.metadata.host}", want: "node-1"},
+	}
+
+	for _, tt := range tests {
+		printer, err := NewPrinter(tt.spec, Options{})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.spec, err)
+		}
+
+		var buf bytes.Buffer
+		if err := printer.Print(&buf, testReport()); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.spec, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.spec, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestNewPrinter_TemplateErrors(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{spec: "go-template={{range .}", wantErr: "invalid go-template"},
+		{spec: "go-template=", wantErr: "requires a template"},
+		{spec: "go-template-file=", wantErr: "requires a file name"},
+		{spec: "go-template-file=/does/not/exist", wantErr: "failed to read go-template-file template"},
+		{spec: "jsonpath={.devices[*", wantErr: "invalid jsonpath"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewPrinter(tt.spec, Options{})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.spec, tt.wantErr, err)
+		}
+	}
+}
+
+func TestGoTemplatePrinter_ExecutionError(t *testing.T) {
+	printer, err := NewPrinter("go-template={{range .}}{{.NoSuchField}}{{end}}", Options{})
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = printer.Print(&buf, testReport())
+	if err == nil || !strings.Contains(err.Error(), "failed to execute go-template") {
+		t.Fatalf("expected execution error, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no partial output, got %q", buf.String())
+	}
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/topology"
+)
+
+// FormatTree is the indented tree output of the topology report.
+const FormatTree = "tree"
+
+// TopologyFormats lists the output formats of the topology report.
+var TopologyFormats = []string{FormatTree, FormatJSON, FormatYAML}
+
+// KindTopologyReport is the kind of the topology report envelope.
+const KindTopologyReport = "TopologyReport"
+
+// TopologyReport is the versioned envelope around the device topology trees.
+type TopologyReport struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Roots are the top-level buses (e.g. "pci0000:00"), sorted by name.
+	Roots []*topology.Node `json:"roots"`
+}
+
+// NewTopologyReport wraps the topology trees in a TopologyReport envelope.
+func NewTopologyReport(roots []*topology.Node) TopologyReport {
+	if roots == nil {
+		roots = []*topology.Node{}
+	}
+	return TopologyReport{APIVersion: APIVersion, Kind: KindTopologyReport, Roots: roots}
+}
+
+// PrintTopologyReport writes the report in one of the TopologyFormats.
+func PrintTopologyReport(w io.Writer, format string, report TopologyReport) error {
+	switch format {
+	case "", FormatTree:
+		for _, root := range report.Roots {
+			fmt.Fprintln(w, topologyLabel(root))
+			printTopologyChildren(w, root, "")
+		}
+		return nil
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatYAML:
+		return writeYAML(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, supported: %s", format, strings.Join(TopologyFormats, ", "))
+	}
+}
+
+// printTopologyChildren writes the children of node with tree drawing characters.
+func printTopologyChildren(w io.Writer, node *topology.Node, indent string) {
+	for i, child := range node.Children {
+		branch, next := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintln(w, indent+branch+topologyLabel(child))
+		printTopologyChildren(w, child, indent+next)
+	}
+}
+
+// topologyLabel formats a node on one line, e.g. "0000:00:17.0 [pci] 8086:a352 (ahci)".
+func topologyLabel(node *topology.Node) string {
+	parts := []string{node.Name, "[" + node.Kind + "]"}
+	if node.Description != "" {
+		parts = append(parts, node.Description)
+	}
+	if node.Path != "" && node.Path != "/dev/"+node.Name {
+		parts = append(parts, node.Path)
+	}
+	if node.Size != "" {
+		parts = append(parts, node.Size)
+	}
+	if node.Driver != "" {
+		parts = append(parts, "("+node.Driver+")")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,38 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gigiozzz/driver-scanner/internal/device/topology"
+)
+
+func TestPrintTopologyReport_Tree(t *testing.T) {
+	roots := []*topology.Node{{
+		Name: "pci0000:00", Kind: topology.KindPCIRoot,
+		Children: []*topology.Node{
+			{
+				Name: "0000:00:17.0", Kind: topology.KindPCI, Description: "8086:a352", Driver: "ahci",
+				Children: []*topology.Node{{
+					Name: "sda", Kind: topology.KindDisk, Path: "/dev/sda", Size: "1.0 GiB",
+					Children: []*topology.Node{{Name: "sda1", Kind: topology.KindPartition, Path: "/dev/sda1"}},
+				}},
+			},
+			{Name: "0000:00:1d.0", Kind: topology.KindPCI},
+		},
+	}}
+
+	var out bytes.Buffer
+	if err := PrintTopologyReport(&out, FormatTree, NewTopologyReport(roots)); err != nil {
+		t.Fatalf("PrintTopologyReport: %v", err)
+	}
+	want := `pci0000:00 [pci-root]
+├── 0000:00:17.0 [pci] 8086:a352 (ahci)
+│   └── sda [disk] 1.0 GiB
+│       └── sda1 [partition]
+└── 0000:00:1d.0 [pci]
+`
+	if out.String() != want {
+		t.Errorf("unexpected tree:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
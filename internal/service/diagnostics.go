@@ -0,0 +1,120 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// Severity ranks diagnostics. Higher values are worse.
+type Severity int
+
+const (
+	// SeverityNone is the worst severity of a scan without diagnostics.
+	SeverityNone Severity = iota
+	// SeverityInfo reports something noteworthy that does not affect the results.
+	SeverityInfo
+	// SeverityWarning reports incomplete information about a device (e.g. a failed enricher).
+	SeverityWarning
+	// SeverityError reports a missing data source; results are incomplete for every device
+	// (e.g. mountinfo could not be read).
+	SeverityError
+)
+
+// severityNames maps severities to their names in output and logs.
+var severityNames = map[Severity]string{
+	SeverityNone:    "none",
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+// String returns the severity name (e.g. "warning").
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText encodes the severity as its name.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity name.
+func (s *Severity) UnmarshalText(text []byte) error {
+	for severity, name := range severityNames {
+		if name == string(text) {
+			*s = severity
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// Diagnostic is a problem found during a scan that did not prevent it from completing.
+type Diagnostic struct {
+	// Provider is the name of the provider or enricher that reported the problem (e.g. "mountinfo").
+	Provider string
+	// Device is the path of the affected device, empty if the problem affects the whole scan.
+	Device string
+	// Severity ranks the problem.
+	Severity Severity
+	// Err is the underlying error.
+	Err error
+}
+
+// diagnosticJSON is the JSON form of a Diagnostic.
+type diagnosticJSON struct {
+	Provider string   `json:"provider"`
+	Device   string   `json:"device,omitempty"`
+	Severity Severity `json:"severity"`
+	Error    string   `json:"error"`
+}
+
+// MarshalJSON encodes the diagnostic with its error message.
+func (d Diagnostic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(diagnosticJSON{
+		Provider: d.Provider,
+		Device:   d.Device,
+		Severity: d.Severity,
+		Error:    d.message(),
+	})
+}
+
+// String formats the diagnostic on one line, e.g. "warning: probe: /dev/sdb: failed to probe device".
+func (d Diagnostic) String() string {
+	parts := []string{d.Severity.String(), d.Provider}
+	if d.Device != "" {
+		parts = append(parts, d.Device)
+	}
+	return strings.Join(append(parts, d.message()), ": ")
+}
+
+// message returns the error message, or an empty string without an error.
+func (d Diagnostic) message() string {
+	if d.Err == nil {
+		return ""
+	}
+	return d.Err.Error()
+}
+
+// ScanResult holds the devices found by a scan and the problems met along the way.
+type ScanResult struct {
+	// Devices are the devices matching the filter.
+	Devices []device.BlockDevice
+	// Diagnostics are the problems affecting the scan or the returned devices.
+	Diagnostics []Diagnostic
+}
+
+// WorstSeverity returns the highest severity of the diagnostics, or SeverityNone.
+func (r ScanResult) WorstSeverity() Severity {
+	worst := SeverityNone
+	for _, d := range r.Diagnostics {
+		worst = max(worst, d.Severity)
+	}
+	return worst
+}
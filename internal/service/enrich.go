@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// DefaultParallelism is the number of devices enriched concurrently by default.
+const DefaultParallelism = 4
+
+// Enricher adds information to the devices returned by the BlockDeviceProvider.
+//
+// Devices are enriched concurrently, each device by a single goroutine that runs
+// the enrichers in registration order. Enrich may read every field of the device,
+// but only writes the fields it owns; changes to other fields are reverted and
+// reported as warning diagnostics. Data shared between the devices of a scan is
+// cached with device.ScanCached, never on the enricher, which serves every scan.
+type Enricher interface {
+	// Name identifies the enricher in logs and diagnostics (e.g. "probe").
+	Name() string
+	// Fields lists the BlockDevice fields the enricher owns, by JSON name (e.g. "fstype").
+	// No two enrichers of a scanner may own the same field.
+	Fields() []string
+	// Enrich fills the owned fields of a single device in place. An error is reported
+	// as a warning diagnostic for the device; it does not fail the scan.
+	Enrich(ctx context.Context, dev *device.BlockDevice) error
+}
+
+// Option configures a DeviceScanner.
+type Option func(*DeviceScanner)
+
+// WithEnrichers registers enrichers, which run in the given order after mount
+// information has been merged.
+func WithEnrichers(enrichers ...Enricher) Option {
+	return func(s *DeviceScanner) {
+		s.enrichers = append(s.enrichers, enrichers...)
+	}
+}
+
+// WithParallelism sets how many devices are enriched concurrently.
+// Values below 1 select DefaultParallelism.
+func WithParallelism(n int) Option {
+	return func(s *DeviceScanner) {
+		s.parallelism = n
+	}
+}
+
+// blockDeviceFields maps the JSON name of every BlockDevice field to its struct field index.
+var blockDeviceFields = func() map[string]int {
+	t := reflect.TypeFor[device.BlockDevice]()
+	fields := make(map[string]int, t.NumField())
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[name] = i
+	}
+	return fields
+}()
+
+// fieldOwnership resolves the fields declared by the enrichers to struct field indexes.
+// It panics on unknown field names and on fields owned by more than one enricher,
+// which are programming errors.
+func fieldOwnership(enrichers []Enricher) [][]int {
+	owners := make(map[string]string)
+	owned := make([][]int, len(enrichers))
+	for i, enricher := range enrichers {
+		for _, name := range enricher.Fields() {
+			index, ok := blockDeviceFields[name]
+			if !ok {
+				panic(fmt.Sprintf("enricher %s declares unknown field %q", enricher.Name(), name))
+			}
+			if owner, taken := owners[name]; taken {
+				panic(fmt.Sprintf("field %q is owned by both enricher %s and enricher %s", name, owner, enricher.Name()))
+			}
+			owners[name] = enricher.Name()
+			owned[i] = append(owned[i], index)
+		}
+	}
+	return owned
+}
+
+// enrichDevices runs the enrichers on every device, at most parallelism devices at a time,
+// and returns the diagnostics in device order. When ctx is done, the remaining enrichers
+// are skipped and reported once, as a scan-level warning.
+func enrichDevices(ctx context.Context, devices []device.BlockDevice, enrichers []Enricher, parallelism int) []Diagnostic {
+	owned := fieldOwnership(enrichers)
+	if parallelism < 1 {
+		parallelism = DefaultParallelism
+	}
+
+	// Each worker only writes the diagnostics and the stop point of the device it enriches.
+	diagnostics := make([][]Diagnostic, len(devices))
+	stoppedAt := make([]string, len(devices))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(parallelism, len(devices)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				diagnostics[i], stoppedAt[i] = enrichDevice(ctx, &devices[i], enrichers, owned)
+			}
+		}()
+	}
+	for i := range devices {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if i := slices.IndexFunc(stoppedAt, func(name string) bool { return name != "" }); i >= 0 {
+		err := ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = &TimeoutError{Provider: stoppedAt[i], Err: err}
+		}
+		log.Warn().Str("enricher", stoppedAt[i]).Err(err).Msg("enrichment stopped")
+		diagnostics = append(diagnostics, []Diagnostic{{
+			Provider: stoppedAt[i],
+			Severity: SeverityWarning,
+			Err:      fmt.Errorf("enrichment stopped, remaining enrichers skipped: %w", err),
+		}})
+	}
+	return slices.Concat(diagnostics...)
+}
+
+// enrichDevice runs the enrichers on a single device in order and returns their diagnostics.
+// When ctx is done it stops and returns the name of the enricher it stopped at; an enricher
+// failing because ctx is done is not reported on its own.
+func enrichDevice(ctx context.Context, dev *device.BlockDevice, enrichers []Enricher, owned [][]int) ([]Diagnostic, string) {
+	var diagnostics []Diagnostic
+	warn := func(enricher string, err error) {
+		log.Warn().Str("device", dev.Path).Str("enricher", enricher).Err(err).Msg("enrichment failed")
+		diagnostics = append(diagnostics, Diagnostic{
+			Provider: enricher,
+			Device:   dev.Path,
+			Severity: SeverityWarning,
+			Err:      err,
+		})
+	}
+
+	for i, enricher := range enrichers {
+		if ctx.Err() != nil {
+			return diagnostics, enricher.Name()
+		}
+
+		before := *dev
+		err := enricher.Enrich(ctx, dev)
+		if changed := revertUnownedFields(dev, before, owned[i]); len(changed) > 0 {
+			warn(enricher.Name(), fmt.Errorf("reverted changes to fields it does not own: %s",
+				strings.Join(changed, ", ")))
+		}
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			return diagnostics, enricher.Name()
+		}
+		if err != nil {
+			warn(enricher.Name(), err)
+		}
+	}
+	return diagnostics, ""
+}
+
+// revertUnownedFields restores the fields of dev changed by an enricher that does not own them,
+// and returns their JSON names.
+func revertUnownedFields(dev *device.BlockDevice, before device.BlockDevice, owned []int) []string {
+	after := reflect.ValueOf(dev).Elem()
+	original := reflect.ValueOf(before)
+
+	var changed []string
+	for name, index := range blockDeviceFields {
+		if slices.Contains(owned, index) {
+			continue
+		}
+		if !reflect.DeepEqual(after.Field(index).Interface(), original.Field(index).Interface()) {
+			after.Field(index).Set(original.Field(index))
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// mountEnricher merges the mountinfo entries of each device into its Mounts.
+// It always runs first, so that other enrichers see every mount.
+type mountEnricher struct {
+	mounts mountIndex
+}
+
+// Name returns "mountinfo".
+func (e *mountEnricher) Name() string {
+	return "mountinfo"
+}
+
+// Fields returns the mount fields.
+func (e *mountEnricher) Fields() []string {
+	return []string{"mountpoint", "mounts"}
+}
+
+// Enrich merges the mount entries of the device. Mounts already reported by lsblk are
+// completed with root, options and mount ID; mounts lsblk did not report are appended.
+func (e *mountEnricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	for _, mountEntry := range e.mounts.lookup(*dev) {
+		log.Debug().
+			Str("device", dev.Path).
+			Str("devnum", mountEntry.DevNum()).
+			Str("mountpoint", mountEntry.MountPoint).
+			Str("root", mountEntry.Root).
+			Msg("enriched device with mount info")
+		mergeMount(dev, mountEntry)
+	}
+	if dev.MountPoint == "" && len(dev.Mounts) > 0 {
+		dev.MountPoint = dev.Mounts[0].MountPoint
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
+
+// funcEnricher is an Enricher built from a function.
+type funcEnricher struct {
+	name   string
+	fields []string
+	enrich func(dev *device.BlockDevice) error
+}
+
+func (e *funcEnricher) Name() string     { return e.name }
+func (e *funcEnricher) Fields() []string { return e.fields }
+
+func (e *funcEnricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	return e.enrich(dev)
+}
+
+// diagnosticStrings formats diagnostics for comparison.
+func diagnosticStrings(diagnostics []Diagnostic) []string {
+	var lines []string
+	for _, d := range diagnostics {
+		lines = append(lines, d.String())
+	}
+	return lines
+}
+
+func TestDeviceScanner_Scan_RunsEnrichersInOrder(t *testing.T) {
+	devices := &fakeDeviceProvider{devices: []device.BlockDevice{
+		{Name: "sda", Path: "/dev/sda", Type: "disk"},
+		{Name: "sdb", Path: "/dev/sdb", Type: "disk"},
+	}}
+	fsType := &funcEnricher{name: "fstype", fields: []string{"fstype"}, enrich: func(dev *device.BlockDevice) error {
+		dev.FSType = "ext4"
+		return nil
+	}}
+	label := &funcEnricher{name: "label", fields: []string{"label"}, enrich: func(dev *device.BlockDevice) error {
+		// Runs after the fstype enricher on the same device.
+		dev.Label = dev.FSType + "-label"
+		return nil
+	}}
+
+	result, err := NewDeviceScanner(devices, &fakeMountProvider{},
+		WithEnrichers(fsType, label), WithParallelism(2)).Scan(context.Background(), ScanFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, dev := range result.Devices {
+		if dev.FSType != "ext4" || dev.Label != "ext4-label" {
+			t.Errorf("device not enriched in order: %+v", dev)
+		}
+	}
+	if len(result.Diagnostics) > 0 {
+		t.Errorf("unexpected diagnostics: %+v", result.Diagnostics)
+	}
+}
+
+func TestDeviceScanner_Scan_EnricherErrorsAreWarnings(t *testing.T) {
+	devices := &fakeDeviceProvider{devices: []device.BlockDevice{
+		{Name: "sda", Path: "/dev/sda", Type: "disk"},
+		{Name: "sdb", Path: "/dev/sdb", Type: "disk"},
+	}}
+	failing := &funcEnricher{name: "smart", fields: []string{"serial"}, enrich: func(dev *device.BlockDevice) error {
+		if dev.Name == "sdb" {
+			return errors.New("device not responding")
+		}
+		dev.Serial = "S1"
+		return nil
+	}}
+
+	result, err := NewDeviceScanner(devices, &fakeMountProvider{}, WithEnrichers(failing)).
+		Scan(context.Background(), ScanFilter{})
+	if result.WorstSeverity() != SeverityWarning {
+		t.Errorf("unexpected worst severity %s", result.WorstSeverity())
+	}
+	if err != nil {
+		t.Fatalf("enricher errors must not fail the scan: %v", err)
+	}
+	if len(result.Devices) != 2 || result.Devices[0].Serial != "S1" {
+		t.Errorf("unexpected devices: %+v", result.Devices)
+	}
+	if got := diagnosticStrings(result.Diagnostics); !reflect.DeepEqual(got, []string{
+		"warning: smart: /dev/sdb: device not responding",
+	}) {
+		t.Errorf("unexpected diagnostics: %v", got)
+	}
+}
+
+func TestDeviceScanner_Scan_RevertsUnownedFields(t *testing.T) {
+	devices := &fakeDeviceProvider{devices: []device.BlockDevice{
+		{Name: "sda", Path: "/dev/sda", Type: "disk", Model: "from-provider"},
+	}}
+	greedy := &funcEnricher{name: "greedy", fields: []string{"serial"}, enrich: func(dev *device.BlockDevice) error {
+		dev.Serial = "S1"
+		dev.Model = "overwritten"
+		dev.Type = "part"
+		return nil
+	}}
+
+	result, err := NewDeviceScanner(devices, &fakeMountProvider{}, WithEnrichers(greedy)).
+		Scan(context.Background(), ScanFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dev := result.Devices[0]
+	if dev.Serial != "S1" || dev.Model != "from-provider" || dev.Type != "disk" {
+		t.Errorf("unowned fields not reverted: %+v", dev)
+	}
+	if got := diagnosticStrings(result.Diagnostics); !reflect.DeepEqual(got, []string{
+		"warning: greedy: /dev/sda: reverted changes to fields it does not own: model, type",
+	}) {
+		t.Errorf("unexpected diagnostics: %v", got)
+	}
+}
+
+func TestDeviceScanner_Scan_BoundsParallelism(t *testing.T) {
+	var list []device.BlockDevice
+	for range 20 {
+		list = append(list, device.BlockDevice{Type: "disk"})
+	}
+
+	var running, peak atomic.Int32
+	counting := &funcEnricher{name: "counting", fields: []string{"model"}, enrich: func(dev *device.BlockDevice) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return nil
+	}}
+
+	_, err := NewDeviceScanner(&fakeDeviceProvider{devices: list}, &fakeMountProvider{},
+		WithEnrichers(counting), WithParallelism(3)).Scan(context.Background(), ScanFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if peak.Load() > 3 {
+		t.Errorf("expected at most 3 concurrent enrichments, got %d", peak.Load())
+	}
+}
+
+func TestEnrichDevices_TimeoutIsReportedOnce(t *testing.T) {
+	var list []device.BlockDevice
+	for _, name := range []string{"sda", "sdb", "sdc", "sdd", "sde"} {
+		list = append(list, device.BlockDevice{Name: name, Path: "/dev/" + name})
+	}
+	var calls atomic.Int32
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	// The first call waits for the deadline, like a stalled read, and fails with it.
+	stalling := &funcEnricher{name: "stalling", fields: []string{"model"}, enrich: func(dev *device.BlockDevice) error {
+		if calls.Add(1) > 1 {
+			return nil
+		}
+		cancel()
+		return fmt.Errorf("read stalled: %w", ctx.Err())
+	}}
+	other := &funcEnricher{name: "other", fields: []string{"serial"}, enrich: func(dev *device.BlockDevice) error {
+		return errors.New("must not run")
+	}}
+
+	diagnostics := enrichDevices(ctx, list, []Enricher{stalling, other}, 1)
+	if got := diagnosticStrings(diagnostics); !reflect.DeepEqual(got, []string{
+		"warning: stalling: enrichment stopped, remaining enrichers skipped: context canceled",
+	}) {
+		t.Errorf("unexpected diagnostics: %v", got)
+	}
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	diagnostics = enrichDevices(expired, list, []Enricher{other}, 2)
+	if len(diagnostics) != 1 || diagnostics[0].Device != "" {
+		t.Fatalf("expected a single scan-level diagnostic, got %v", diagnosticStrings(diagnostics))
+	}
+	var timeoutErr *TimeoutError
+	if !errors.As(diagnostics[0].Err, &timeoutErr) || timeoutErr.Provider != "other" {
+		t.Errorf("expected a *TimeoutError for enricher other, got %v", diagnostics[0].Err)
+	}
+}
+
+// cachingEnricher counts the loads of a value it caches for the scan.
+type cachingEnricher struct {
+	loads atomic.Int32
+}
+
+func (e *cachingEnricher) Name() string     { return "caching" }
+func (e *cachingEnricher) Fields() []string { return []string{"label"} }
+
+func (e *cachingEnricher) Enrich(ctx context.Context, dev *device.BlockDevice) error {
+	label, err := device.ScanCached(ctx, e, func(context.Context) (string, error) {
+		return fmt.Sprintf("scan-%d", e.loads.Add(1)), nil
+	})
+	dev.Label = label
+	return err
+}
+
+func TestDeviceScanner_Scan_CachesPerScan(t *testing.T) {
+	devices := &fakeDeviceProvider{devices: []device.BlockDevice{
+		{Name: "sda", Path: "/dev/sda", Type: "disk"},
+		{Name: "sdb", Path: "/dev/sdb", Type: "disk"},
+	}}
+	enricher := &cachingEnricher{}
+	scanner := NewDeviceScanner(devices, &fakeMountProvider{}, WithEnrichers(enricher))
+
+	for _, want := range []string{"scan-1", "scan-2"} {
+		result, err := scanner.Scan(context.Background(), ScanFilter{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, dev := range result.Devices {
+			if dev.Label != want {
+				t.Errorf("got label %q for %s, want %q", dev.Label, dev.Path, want)
+			}
+		}
+	}
+}
+
+func TestNewDeviceScanner_PanicsOnFieldConflict(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for a field owned by two enrichers")
+		}
+	}()
+	a := &funcEnricher{name: "a", fields: []string{"model"}}
+	b := &funcEnricher{name: "b", fields: []string{"model"}}
+	NewDeviceScanner(&fakeDeviceProvider{}, &fakeMountProvider{}, WithEnrichers(a, b))
+}
@@ -0,0 +1,21 @@
+package service
+
+import "fmt"
+
+// TimeoutError reports a provider that did not answer before the scan deadline.
+type TimeoutError struct {
+	// Provider is the name of the provider that stalled (e.g. "lsblk").
+	Provider string
+	// Err is the underlying context error.
+	Err error
+}
+
+// Error describes the stalled provider.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("provider %s timed out: %v", e.Provider, e.Err)
+}
+
+// Unwrap returns the underlying context error, so errors.Is(err, context.DeadlineExceeded) holds.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
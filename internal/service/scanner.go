@@ -1,138 +1,345 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
 	"strings"
 
-	"github.com/dustin/go-humanize"
 	"github.com/rs/zerolog/log"
 
 	"github.com/gigiozzz/driver-scanner/internal/device"
+	"github.com/gigiozzz/driver-scanner/internal/expr"
 )
 
 // ScanFilter holds the filter criteria for scanning devices.
+// Size criteria are kept as given for the report metadata; buildScanFilter in the
+// command layer parses them once into the *Bytes fields used by applyFilters.
 type ScanFilter struct {
-	// FSType filters by filesystem type (e.g. "ext4").
-	FSType string
+	// FSTypes keeps devices with any of the filesystem types (e.g. "ext4", "xfs").
+	FSTypes []string `json:"fstypes,omitempty"`
+	// ExcludeFSTypes drops devices with any of the filesystem types.
+	ExcludeFSTypes []string `json:"excludeFstypes,omitempty"`
+	// Types keeps devices with any of the device types (e.g. "disk", "part").
+	Types []string `json:"types,omitempty"`
+	// ExcludeTypes drops devices with any of the device types (e.g. "loop", "rom").
+	ExcludeTypes []string `json:"excludeTypes,omitempty"`
 	// MinSize filters by minimum device size (e.g. "1G", "500M"). Parsed via go-humanize.
-	MinSize string
+	MinSize string `json:"minSize,omitempty"`
+	// MaxSize filters by maximum device size (e.g. "4T"). Parsed via go-humanize.
+	MaxSize string `json:"maxSize,omitempty"`
+	// MinAvail filters by minimum available filesystem space (e.g. "10G"). Parsed via go-humanize.
+	MinAvail string `json:"minAvail,omitempty"`
+	// MaxUsedPercent filters by maximum filesystem usage in percent (0 disables the filter).
+	MaxUsedPercent float64 `json:"maxUsedPercent,omitempty"`
+	// Labels keeps devices with any of the filesystem labels.
+	Labels []string `json:"labels,omitempty"`
+	// UUIDs keeps devices with any of the filesystem UUIDs (case-insensitive).
+	UUIDs []string `json:"uuids,omitempty"`
 	// MountPoint filters by mount point substring match.
-	MountPoint string
+	MountPoint string `json:"mountPoint,omitempty"`
+	// Where is a filter expression over device fields (e.g. `type == "part" && fsavail < 10Gi`).
+	Where string `json:"where,omitempty"`
+
+	// MinSizeBytes is the parsed MinSize, 0 if unset.
+	MinSizeBytes uint64 `json:"-"`
+	// MaxSizeBytes is the parsed MaxSize, 0 if unset.
+	MaxSizeBytes uint64 `json:"-"`
+	// MinAvailBytes is the parsed MinAvail, 0 if unset.
+	MinAvailBytes uint64 `json:"-"`
+	// WhereExpr is the parsed and validated Where expression, nil if Where is empty.
+	WhereExpr *expr.Expr `json:"-"`
 }
 
 // Scanner abstracts the device scanning logic.
 type Scanner interface {
-	// Scan returns block devices, optionally filtered by the given criteria.
-	Scan(filter ScanFilter) ([]device.BlockDevice, error)
+	// Scan returns block devices, optionally filtered by the given criteria, with the
+	// diagnostics of the data sources that failed. It only returns an error when no
+	// device can be listed; a device provider that does not answer before ctx expires
+	// yields a *TimeoutError.
+	Scan(ctx context.Context, filter ScanFilter) (ScanResult, error)
 }
 
 // DeviceScanner implements Scanner combining lsblk and mount information.
 type DeviceScanner struct {
 	deviceProvider device.BlockDeviceProvider
 	mountProvider  device.MountInfoProvider
+	enrichers      []Enricher
+	parallelism    int
 }
 
 // NewDeviceScanner creates a new DeviceScanner with the given providers.
-func NewDeviceScanner(deviceProvider device.BlockDeviceProvider, mountProvider device.MountInfoProvider) *DeviceScanner {
-	return &DeviceScanner{
+// Enrichers are registered with WithEnrichers; NewDeviceScanner panics if two
+// of them own the same field.
+func NewDeviceScanner(deviceProvider device.BlockDeviceProvider, mountProvider device.MountInfoProvider,
+	opts ...Option) *DeviceScanner {
+	s := &DeviceScanner{
 		deviceProvider: deviceProvider,
 		mountProvider:  mountProvider,
+		parallelism:    DefaultParallelism,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	// Check field ownership up front, including the mount fields.
+	fieldOwnership(append([]Enricher{&mountEnricher{}}, s.enrichers...))
+	return s
 }
 
 // Scan retrieves block devices from lsblk, enriches them with mount info,
-// and applies filters.
-func (s *DeviceScanner) Scan(filter ScanFilter) ([]device.BlockDevice, error) {
+// and applies filters. Every node of the device tree is filtered on its own;
+// devices that pass keep their Parents and Ancestry even if their parents are filtered out.
+// Missing mount information and enricher failures are reported as diagnostics, and only
+// the diagnostics of the devices that pass the filter are kept.
+func (s *DeviceScanner) Scan(ctx context.Context, filter ScanFilter) (ScanResult, error) {
 	log.Info().Msg("starting device scan")
 
-	devices, err := s.deviceProvider.List()
+	devices, err := callProvider(ctx, s.deviceProvider.Name(), s.deviceProvider.List)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list devices: %w", err)
+		return ScanResult{}, fmt.Errorf("failed to list devices: %w", err)
 	}
 	log.Info().Int("deviceCount", len(devices)).Msg("block devices discovered")
 
-	mountEntries, err := s.mountProvider.GetMounts()
+	var diagnostics []Diagnostic
+	mountEntries, err := callProvider(ctx, s.mountProvider.Name(), s.mountProvider.GetMounts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get mount info: %w", err)
+		log.Warn().Err(err).Msg("continuing without mount info")
+		diagnostics = append(diagnostics, Diagnostic{
+			Provider: s.mountProvider.Name(),
+			Severity: SeverityError,
+			Err:      fmt.Errorf("failed to get mount info: %w", err),
+		})
 	}
 	log.Debug().Int("count", len(mountEntries)).Msg("mount entries retrieved")
 
-	mountsBySource := buildMountsBySource(mountEntries)
-	enrichDevicesWithMountInfo(devices, mountsBySource)
+	enrichers := append([]Enricher{&mountEnricher{mounts: newMountIndex(mountEntries)}}, s.enrichers...)
+	log.Debug().Int("enrichers", len(enrichers)).Int("parallelism", s.parallelism).Msg("enriching devices")
+	// Values the enrichers share between devices are kept for this scan only.
+	enrichCtx := device.WithScanCache(ctx)
+	diagnostics = append(diagnostics, enrichDevices(enrichCtx, devices, enrichers, s.parallelism)...)
 
 	log.Info().
 		Int("total", len(devices)).
-		Str("fstype", filter.FSType).
+		Strs("fstypes", filter.FSTypes).
+		Strs("types", filter.Types).
 		Str("minSize", filter.MinSize).
+		Str("maxSize", filter.MaxSize).
 		Str("mountPoint", filter.MountPoint).
 		Msg("applying filters")
 
-	filtered, err := applyFilters(devices, filter)
-	if err != nil {
-		return nil, err
-	}
+	filtered := applyFilters(devices, filter)
 
 	log.Info().
 		Int("before", len(devices)).
 		Int("after", len(filtered)).
 		Msg("filtering complete")
 
-	return filtered, nil
+	return ScanResult{
+		Devices:     filtered,
+		Diagnostics: diagnosticsOf(filtered, diagnostics),
+	}, nil
 }
 
-// buildMountsBySource creates a lookup map from device source path to MountEntry.
-func buildMountsBySource(mountEntries []device.MountEntry) map[string]device.MountEntry {
-	mountsBySource := make(map[string]device.MountEntry, len(mountEntries))
-	for _, entry := range mountEntries {
-		mountsBySource[entry.Source] = entry
+// diagnosticsOf keeps the diagnostics affecting the whole scan or one of the given devices.
+func diagnosticsOf(devices []device.BlockDevice, diagnostics []Diagnostic) []Diagnostic {
+	paths := make(map[string]bool, len(devices))
+	for _, dev := range devices {
+		paths[dev.Path] = true
+	}
+
+	var kept []Diagnostic
+	for _, d := range diagnostics {
+		if d.Device == "" || paths[d.Device] {
+			kept = append(kept, d)
+		}
 	}
-	return mountsBySource
+	return kept
 }
 
-// enrichDevicesWithMountInfo fills in mount point information from mountinfo
-// for devices where lsblk did not report a mount point.
-func enrichDevicesWithMountInfo(devices []device.BlockDevice, mountsBySource map[string]device.MountEntry) {
-	for i := range devices {
-		if devices[i].MountPoint != "" {
-			continue
+// callProvider calls a provider method and returns when it completes or when ctx is done,
+// whichever comes first. Providers are expected to honor ctx, but a read blocked in the
+// kernel (e.g. on a dead SAN path) cannot be interrupted; the call is then abandoned
+// and left to finish in the background. An expired deadline yields a *TimeoutError.
+func callProvider[T any](ctx context.Context, provider string, call func(context.Context) (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := call(ctx)
+		done <- result{value: value, err: err}
+	}()
+
+	var zero T
+	select {
+	case res := <-done:
+		if res.err != nil && errors.Is(res.err, context.DeadlineExceeded) {
+			return zero, &TimeoutError{Provider: provider, Err: res.err}
 		}
-		mountEntry, found := mountsBySource[devices[i].Path]
-		if found {
-			log.Debug().
-				Str("device", devices[i].Path).
-				Str("mountpoint", mountEntry.MountPoint).
-				Msg("enriched device with mount info")
-			devices[i].MountPoint = mountEntry.MountPoint
+		return res.value, res.err
+	case <-ctx.Done():
+		log.Debug().Str("provider", provider).Err(ctx.Err()).Msg("provider did not return before the context was done")
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zero, &TimeoutError{Provider: provider, Err: ctx.Err()}
 		}
+		return zero, ctx.Err()
 	}
 }
 
-// applyFilters filters the device list based on the given ScanFilter criteria.
-func applyFilters(devices []device.BlockDevice, filter ScanFilter) ([]device.BlockDevice, error) {
-	var minSizeBytes uint64
-	if filter.MinSize != "" {
-		parsed, err := humanize.ParseBytes(filter.MinSize)
-		if err != nil {
-			return nil, fmt.Errorf("invalid min-size value %q: %w", filter.MinSize, err)
+// mountIndex looks up the mountinfo entries of a block device.
+// Entries are matched by device number first; the source path is only a fallback
+// for filesystems with anonymous device numbers (e.g. btrfs) or devices without one.
+type mountIndex struct {
+	byDevNum map[string][]device.MountEntry
+	bySource map[string][]device.MountEntry
+}
+
+// newMountIndex indexes mount entries by device number and by source path.
+// Sources are indexed both as written and with symlinks resolved, so that
+// /dev/disk/by-uuid/* or /dev/mapper/* sources match the canonical device path.
+// A device mounted several times keeps all its entries, in mountinfo order.
+func newMountIndex(mountEntries []device.MountEntry) mountIndex {
+	index := mountIndex{
+		byDevNum: make(map[string][]device.MountEntry, len(mountEntries)),
+		bySource: make(map[string][]device.MountEntry, len(mountEntries)),
+	}
+	for _, entry := range mountEntries {
+		if entry.Major != 0 {
+			index.byDevNum[entry.DevNum()] = append(index.byDevNum[entry.DevNum()], entry)
+		}
+		index.bySource[entry.Source] = append(index.bySource[entry.Source], entry)
+		if resolved := resolvePath(entry.Source); resolved != entry.Source {
+			index.bySource[resolved] = append(index.bySource[resolved], entry)
 		}
-		minSizeBytes = parsed
 	}
+	return index
+}
 
-	result := make([]device.BlockDevice, 0, len(devices))
-	for _, dev := range devices {
-		if filter.FSType != "" && !strings.EqualFold(dev.FSType, filter.FSType) {
-			log.Debug().Str("device", dev.Path).Str("fstype", dev.FSType).Msg("filtered out by fstype")
-			continue
+// lookup returns the mount entries of a device. Path matches are ignored when both
+// the device and the mount entry have a device number and the numbers differ.
+func (idx mountIndex) lookup(dev device.BlockDevice) []device.MountEntry {
+	if dev.Major != 0 {
+		if entries, found := idx.byDevNum[dev.DevNum()]; found {
+			return entries
 		}
-		if minSizeBytes > 0 && dev.DeviceSizeBytes < minSizeBytes {
-			log.Debug().Str("device", dev.Path).Uint64("size", dev.DeviceSizeBytes).Msg("filtered out by min-size")
+	}
+
+	candidates, found := idx.bySource[dev.Path]
+	if !found {
+		candidates = idx.bySource[resolvePath(dev.Path)]
+	}
+
+	var entries []device.MountEntry
+	for _, entry := range candidates {
+		if dev.Major != 0 && entry.Major != 0 {
 			continue
 		}
-		if filter.MountPoint != "" && !strings.Contains(dev.MountPoint, filter.MountPoint) {
-			log.Debug().Str("device", dev.Path).Str("mountpoint", dev.MountPoint).Msg("filtered out by mount-point")
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// resolvePath returns path with symlinks resolved, or path unchanged if it cannot be resolved.
+func resolvePath(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return path
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+	return resolved
+}
+
+// mergeMount adds a mountinfo entry to the device mounts. An existing mount at the same
+// mount point without a mount ID (as reported by lsblk) is completed instead of duplicated.
+func mergeMount(dev *device.BlockDevice, entry device.MountEntry) {
+	mount := device.Mount{
+		MountPoint:   entry.MountPoint,
+		Root:         entry.Root,
+		Options:      entry.Options,
+		SuperOptions: entry.SuperOptions,
+		MountID:      entry.MountID,
+	}
+	for i := range dev.Mounts {
+		if dev.Mounts[i].MountPoint == entry.MountPoint && dev.Mounts[i].MountID == 0 {
+			dev.Mounts[i] = mount
+			return
+		}
+	}
+	dev.Mounts = append(dev.Mounts, mount)
+}
+
+// applyFilters filters the device list based on the given ScanFilter criteria.
+// The filter must have been validated: sizes are read from the parsed *Bytes fields.
+func applyFilters(devices []device.BlockDevice, filter ScanFilter) []device.BlockDevice {
+	result := make([]device.BlockDevice, 0, len(devices))
+	for _, dev := range devices {
+		if reason := filterOutReason(dev, filter); reason != "" {
+			log.Debug().Str("device", dev.Path).Str("filter", reason).Msg("filtered out")
 			continue
 		}
 		result = append(result, dev)
 	}
-	return result, nil
+	return result
+}
+
+// filterOutReason returns the name of the first criterion the device fails,
+// or an empty string if the device matches the filter.
+func filterOutReason(dev device.BlockDevice, filter ScanFilter) string {
+	switch {
+	case len(filter.FSTypes) > 0 && !containsFold(filter.FSTypes, dev.FSType):
+		return "fstype"
+	case containsFold(filter.ExcludeFSTypes, dev.FSType):
+		return "exclude-fstype"
+	case len(filter.Types) > 0 && !containsFold(filter.Types, dev.Type):
+		return "type"
+	case containsFold(filter.ExcludeTypes, dev.Type):
+		return "exclude-type"
+	case filter.MinSizeBytes > 0 && dev.DeviceSizeBytes < filter.MinSizeBytes:
+		return "min-size"
+	case filter.MaxSizeBytes > 0 && dev.DeviceSizeBytes > filter.MaxSizeBytes:
+		return "max-size"
+	case filter.MinAvailBytes > 0 && dev.FileSystemAvailBytes < filter.MinAvailBytes:
+		return "min-avail"
+	case filter.MaxUsedPercent > 0 && !usedPercentAtMost(dev, filter.MaxUsedPercent):
+		return "max-used-percent"
+	case len(filter.Labels) > 0 && !slices.Contains(filter.Labels, dev.Label):
+		return "label"
+	case len(filter.UUIDs) > 0 && !containsFold(filter.UUIDs, dev.UUID):
+		return "uuid"
+	case filter.MountPoint != "" && !matchesAnyMountPoint(dev, filter.MountPoint):
+		return "mount-point"
+	case filter.WhereExpr != nil && !filter.WhereExpr.Match(dev):
+		return "where"
+	}
+	return ""
+}
+
+// usedPercentAtMost reports whether the filesystem of the device is at most maxPercent full.
+// Devices without filesystem usage information never match.
+func usedPercentAtMost(dev device.BlockDevice, maxPercent float64) bool {
+	if dev.FileSystemSizeBytes == 0 || dev.FileSystemAvailBytes > dev.FileSystemSizeBytes {
+		return false
+	}
+	used := dev.FileSystemSizeBytes - dev.FileSystemAvailBytes
+	return float64(used)*100/float64(dev.FileSystemSizeBytes) <= maxPercent
+}
+
+// containsFold reports whether values contains s, ignoring case.
+func containsFold(values []string, s string) bool {
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+// matchesAnyMountPoint reports whether any mount point of the device contains substr.
+func matchesAnyMountPoint(dev device.BlockDevice, substr string) bool {
+	for _, mountPoint := range dev.MountPoints() {
+		if strings.Contains(mountPoint, substr) {
+			return true
+		}
+	}
+	return false
 }
@@ -1,7 +1,220 @@
 package service
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gigiozzz/driver-scanner/internal/device"
+)
 
 func TestScanner(t *testing.T) {
 
 }
+
+// fakeDeviceProvider returns a fixed device list.
+type fakeDeviceProvider struct {
+	devices []device.BlockDevice
+}
+
+func (p *fakeDeviceProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeDeviceProvider) List(ctx context.Context) ([]device.BlockDevice, error) {
+	return append([]device.BlockDevice(nil), p.devices...), nil
+}
+
+// fakeMountProvider returns a fixed mount list.
+type fakeMountProvider struct {
+	mounts []device.MountEntry
+}
+
+func (p *fakeMountProvider) Name() string {
+	return "fake-mounts"
+}
+
+func (p *fakeMountProvider) GetMounts(ctx context.Context) ([]device.MountEntry, error) {
+	return p.mounts, nil
+}
+
+// blockingMountProvider never returns, like a read stuck on a dead SAN path.
+type blockingMountProvider struct {
+	release chan struct{}
+}
+
+func (p *blockingMountProvider) Name() string {
+	return "stuck-mounts"
+}
+
+func (p *blockingMountProvider) GetMounts(ctx context.Context) ([]device.MountEntry, error) {
+	<-p.release
+	return nil, nil
+}
+
+// failingMountProvider cannot read mount information.
+type failingMountProvider struct{}
+
+func (p *failingMountProvider) Name() string {
+	return "mountinfo"
+}
+
+func (p *failingMountProvider) GetMounts(ctx context.Context) ([]device.MountEntry, error) {
+	return nil, errors.New("open /proc/self/mountinfo: no such file or directory")
+}
+
+func TestDeviceScanner_Scan_KeepsDevicesWithoutMounts(t *testing.T) {
+	devices := &fakeDeviceProvider{devices: []device.BlockDevice{
+		{Name: "sda", Path: "/dev/sda", Type: "disk"},
+	}}
+
+	result, err := NewDeviceScanner(devices, &failingMountProvider{}).Scan(context.Background(), ScanFilter{})
+	if err != nil {
+		t.Fatalf("missing mount info must not fail the scan: %v", err)
+	}
+	if len(result.Devices) != 1 {
+		t.Fatalf("expected the device list to be kept, got %+v", result.Devices)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Provider != "mountinfo" ||
+		result.Diagnostics[0].Device != "" || result.WorstSeverity() != SeverityError {
+		t.Errorf("unexpected diagnostics: %+v", result.Diagnostics)
+	}
+}
+
+func TestDeviceScanner_Scan_Timeout(t *testing.T) {
+	mounts := &blockingMountProvider{release: make(chan struct{})}
+	defer close(mounts.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result, err := NewDeviceScanner(&fakeDeviceProvider{}, mounts).Scan(ctx, ScanFilter{})
+	if err != nil {
+		t.Fatalf("a stalled mount provider must not fail the scan: %v", err)
+	}
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic, got %+v", result.Diagnostics)
+	}
+
+	diagnostic := result.Diagnostics[0]
+	var timeoutErr *TimeoutError
+	if !errors.As(diagnostic.Err, &timeoutErr) {
+		t.Fatalf("expected *TimeoutError, got %v", diagnostic.Err)
+	}
+	if timeoutErr.Provider != "stuck-mounts" || !errors.Is(diagnostic.Err, context.DeadlineExceeded) {
+		t.Errorf("unexpected timeout error: %v", diagnostic.Err)
+	}
+}
+
+func TestDeviceScanner_Scan_KeepsEveryMount(t *testing.T) {
+	devices := &fakeDeviceProvider{devices: []device.BlockDevice{
+		{Name: "sda", Path: "/dev/sda", Type: "disk"},
+		{
+			Name: "sda1", Path: "/dev/sda1", Type: "part", FSType: "btrfs",
+			MountPoint: "/", Mounts: []device.Mount{{MountPoint: "/"}},
+		},
+	}}
+	mounts := &fakeMountProvider{mounts: []device.MountEntry{
+		{MountID: 21, MountPoint: "/", Root: "/@", Source: "/dev/sda1", FSType: "btrfs", Options: "rw,relatime"},
+		{MountID: 22, MountPoint: "/home", Root: "/@home", Source: "/dev/sda1", FSType: "btrfs", Options: "rw,relatime"},
+		{MountID: 30, MountPoint: "/srv/data", Root: "/@home/data", Source: "/dev/sda1", FSType: "btrfs", Options: "ro"},
+		{MountID: 40, MountPoint: "/tmp", Source: "tmpfs", FSType: "tmpfs"},
+	}}
+
+	scanner := NewDeviceScanner(devices, mounts)
+	result, err := scanner.Scan(context.Background(), ScanFilter{MountPoint: "/srv"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Devices) != 1 || result.Devices[0].Path != "/dev/sda1" {
+		t.Fatalf("expected only /dev/sda1 to match, got %+v", result.Devices)
+	}
+
+	want := []device.Mount{
+		{MountPoint: "/", Root: "/@", Options: "rw,relatime", MountID: 21},
+		{MountPoint: "/home", Root: "/@home", Options: "rw,relatime", MountID: 22},
+		{MountPoint: "/srv/data", Root: "/@home/data", Options: "ro", MountID: 30},
+	}
+	if !reflect.DeepEqual(result.Devices[0].Mounts, want) {
+		t.Errorf("unexpected mounts:\ngot:  %+v\nwant: %+v", result.Devices[0].Mounts, want)
+	}
+	if result.Devices[0].MountPoint != "/" {
+		t.Errorf("unexpected primary mount point %q", result.Devices[0].MountPoint)
+	}
+}
+
+func TestDeviceScanner_Scan_MatchesMountsByDevNum(t *testing.T) {
+	devices := &fakeDeviceProvider{devices: []device.BlockDevice{
+		{Name: "vg0-root", Path: "/dev/mapper/vg0-root", Type: "lvm", Major: 253, Minor: 0},
+		{Name: "sda1", Path: "/dev/sda1", Type: "part", Major: 8, Minor: 1},
+		{Name: "sdb1", Path: "/dev/sdb1", Type: "part", Major: 8, Minor: 17},
+	}}
+	mounts := &fakeMountProvider{mounts: []device.MountEntry{
+		{MountID: 1, MountPoint: "/", Root: "/", Source: "/dev/root", Major: 253, Minor: 0},
+		{MountID: 2, MountPoint: "/boot", Root: "/", Source: "/dev/disk/by-uuid/0000-0001", Major: 8, Minor: 1},
+		// Same source path as sdb1 but a different device number: must not match by path.
+		{MountID: 3, MountPoint: "/mnt", Root: "/", Source: "/dev/sdb1", Major: 8, Minor: 33},
+	}}
+
+	result, err := NewDeviceScanner(devices, mounts).Scan(context.Background(), ScanFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[string][]string)
+	for _, dev := range result.Devices {
+		got[dev.Path] = dev.MountPoints()
+	}
+	want := map[string][]string{
+		"/dev/mapper/vg0-root": {"/"},
+		"/dev/sda1":            {"/boot"},
+		"/dev/sdb1":            {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected mount points:\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestApplyFilters(t *testing.T) {
+	devices := []device.BlockDevice{
+		{Path: "/dev/sda", Type: "disk", DeviceSizeBytes: 8 << 40},
+		{
+			Path: "/dev/sda1", Type: "part", FSType: "ext4", Label: "data", UUID: "AAAA-1111",
+			DeviceSizeBytes: 500 << 30, FileSystemSizeBytes: 500 << 30, FileSystemAvailBytes: 200 << 30,
+		},
+		{
+			Path: "/dev/sda2", Type: "part", FSType: "xfs", Label: "logs", UUID: "bbbb-2222",
+			DeviceSizeBytes: 200 << 30, FileSystemSizeBytes: 200 << 30, FileSystemAvailBytes: 10 << 30,
+		},
+		{Path: "/dev/loop0", Type: "loop", FSType: "squashfs", DeviceSizeBytes: 100 << 20},
+	}
+
+	tests := []struct {
+		name   string
+		filter ScanFilter
+		want   []string
+	}{
+		{"fstypes", ScanFilter{FSTypes: []string{"ext4", "XFS"}}, []string{"/dev/sda1", "/dev/sda2"}},
+		{"exclude fstypes", ScanFilter{ExcludeFSTypes: []string{"squashfs"}}, []string{"/dev/sda", "/dev/sda1", "/dev/sda2"}},
+		{"types", ScanFilter{Types: []string{"disk", "loop"}}, []string{"/dev/sda", "/dev/loop0"}},
+		{"exclude types", ScanFilter{ExcludeTypes: []string{"loop", "rom"}}, []string{"/dev/sda", "/dev/sda1", "/dev/sda2"}},
+		{"size range", ScanFilter{MinSizeBytes: 100 << 30, MaxSizeBytes: 4 << 40}, []string{"/dev/sda1", "/dev/sda2"}},
+		{"min avail", ScanFilter{MinAvailBytes: 50 << 30}, []string{"/dev/sda1"}},
+		{"max used percent", ScanFilter{MaxUsedPercent: 80}, []string{"/dev/sda1"}},
+		{"labels", ScanFilter{Labels: []string{"logs"}}, []string{"/dev/sda2"}},
+		{"uuids", ScanFilter{UUIDs: []string{"aaaa-1111", "BBBB-2222"}}, []string{"/dev/sda1", "/dev/sda2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, dev := range applyFilters(devices, tt.filter) {
+				got = append(got, dev.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}